@@ -60,6 +60,58 @@ var commands = Command{
 				unfinishedCmd(cmd)
 			},
 		},
+		"auth-keys": {
+			subcommands: map[string]Command{
+				"list": {
+					exec: func(_cmd Command, _args []string) {
+						listAuthKeys()
+					},
+				},
+				"generate": {
+					exec: func(_cmd Command, _args []string) {
+						generateAuthKey()
+					},
+				},
+				"rotate": {
+					exec: func(_cmd Command, args []string) {
+						window := parseCmdFlagOrDefault(args, "--window", "-w", rest.AuthKeyRotationWindow.String())
+						rotateAuthKeys(window)
+					},
+				},
+			},
+			exec: func(cmd Command, _args []string) {
+				unfinishedCmd(cmd)
+			},
+		},
+		"tokens": {
+			subcommands: map[string]Command{
+				"list": {
+					exec: func(_cmd Command, args []string) {
+						userId := parseCmdFlag(args, "--user", "-u")
+						listPersonalTokens(userId)
+					},
+				},
+				"create": {
+					exec: func(_cmd Command, args []string) {
+						userId := parseCmdFlag(args, "--user", "-u")
+						name := parseCmdFlag(args, "--name", "-n")
+						scopes := parseCmdFlag(args, "--scopes", "-s")
+						expires := parseCmdFlagOrDefault(args, "--expires", "-e", "720h")
+						createPersonalToken(userId, name, scopes, expires)
+					},
+				},
+				"revoke": {
+					exec: func(_cmd Command, args []string) {
+						userId := parseCmdFlag(args, "--user", "-u")
+						id := parseCmdFlag(args, "--id", "-i")
+						revokePersonalToken(userId, id)
+					},
+				},
+			},
+			exec: func(cmd Command, _args []string) {
+				unfinishedCmd(cmd)
+			},
+		},
 		"dev": {
 			subcommands: map[string]Command{
 				"make-account": {
@@ -79,10 +131,7 @@ var commands = Command{
 						_, err = queries.UsersCreate(context.Background(), sqlc.UsersCreateParams{ID: id, Name: name, Email: email, Provider: "google"})
 						assert.Nil(err)
 
-						tokens := rest.GenAuthTokens(id, email)
-
-						params := sqlc.UsersSetTokensParams{ID: id, AccessToken: utils.SqlNullStrWrapped(tokens.AccessToken), RefreshToken: utils.SqlNullStrWrapped(tokens.RefreshToken)}
-						err = queries.UsersSetTokens(context.Background(), params)
+						tokens, err := rest.NewSession(context.Background(), queries, rest.NewAuthKeys(queries), id, email, "dev make-account", "")
 						assert.Nil(err)
 
 						err = tx.Commit()
@@ -177,6 +226,18 @@ func parseCreateMigrationFlags(args []string) map[string]any {
 	}
 }
 
+func parseCmdFlagOrDefault(args []string, long string, short string, fallback string) string {
+	idx := utils.IdxOf(args, func(arg string) bool {
+		return arg == long || arg == short
+	})
+
+	if idx == -1 {
+		return fallback
+	}
+
+	return parseCmdFlag(args, long, short)
+}
+
 func parseCmdFlag(args []string, long string, short string) string {
 	idx := utils.IdxOf(args, func(arg string) bool {
 		return arg == long || arg == short
@@ -194,6 +255,118 @@ func parseCmdFlag(args []string, long string, short string) string {
 	return *value
 }
 
+func listAuthKeys() {
+	db := setupDb()
+	queries := sqlc.New(db)
+
+	keys, err := queries.AuthKeysGetMany(context.Background())
+	assert.Nil(err)
+
+	if len(keys) == 0 {
+		fmt.Println("No auth keys exist. Create one with `auth-keys generate`.")
+		return
+	}
+
+	for _, key := range keys {
+		retires := "never"
+		if key.RetiresAt.Valid {
+			retires = key.RetiresAt.String
+		}
+		fmt.Printf("- %s (created: %s, retires: %s)\n", key.ID, key.CreatedAt, retires)
+	}
+}
+
+// Creates a new key that seals tokens from now on. Tokens of the older keys
+// stay valid, use `rotate` to retire them.
+func generateAuthKey() {
+	db := setupDb()
+	queries := sqlc.New(db)
+
+	key, err := rest.GenerateAuthKey(context.Background(), queries)
+	assert.Nil(err)
+
+	fmt.Println("Created auth key :", key.ID)
+}
+
+// Creates a new key and retires the older keys once `window` passed. Tokens
+// sealed with the older keys are rejected after that.
+func rotateAuthKeys(window string) {
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration < 0 {
+		log.Fatalln("Invalid rotation window. Expected a duration like `24h`.", "window", window)
+	}
+
+	db := setupDb()
+	queries := sqlc.New(db)
+
+	key, err := rest.RotateAuthKeys(context.Background(), queries, duration)
+	assert.Nil(err)
+
+	fmt.Println("Created auth key :", key.ID)
+	fmt.Println("Older keys retire in", duration)
+}
+
+func listPersonalTokens(userId string) {
+	db := setupDb()
+	queries := sqlc.New(db)
+
+	tokens, err := queries.PersonalTokensGetByUser(context.Background(), userId)
+	assert.Nil(err)
+
+	if len(tokens) == 0 {
+		fmt.Println("The user has no personal access tokens. Create one with `tokens create`.")
+		return
+	}
+
+	for _, token := range tokens {
+		lastUsed := "never"
+		if token.LastUsedAt.Valid {
+			lastUsed = token.LastUsedAt.String
+		}
+		fmt.Printf("- %s '%s' (scopes: %s, expires: %s, last used: %s)\n", token.ID, token.Name, token.Scopes, token.ExpiresAt, lastUsed)
+	}
+}
+
+// Creates a personal access token for the user. `scopes` is a comma separated
+// list and `expires` the duration the token is valid for.
+func createPersonalToken(userId string, name string, scopes string, expires string) {
+	duration, err := time.ParseDuration(expires)
+	if err != nil || duration <= 0 {
+		log.Fatalln("Invalid expiry. Expected a duration like `720h`.", "expires", expires)
+	}
+
+	db := setupDb()
+	queries := sqlc.New(db)
+
+	_, err = queries.UsersGetById(context.Background(), userId)
+	if err != nil {
+		log.Fatalln("No user exists with the id.", "user", userId, "error", err)
+	}
+
+	token, secret, err := rest.CreatePersonalToken(context.Background(), queries, userId, name, strings.Split(scopes, ","), time.Now().Add(duration))
+	if err != nil {
+		log.Fatalln("Failed to create personal access token.", "error", err)
+	}
+
+	fmt.Println("Created personal access token :", token.ID)
+	fmt.Println("Expires at :", token.ExpiresAt)
+	fmt.Println("Token (only shown once) :", secret)
+}
+
+func revokePersonalToken(userId string, id string) {
+	db := setupDb()
+	queries := sqlc.New(db)
+
+	deleted, err := queries.PersonalTokensDelete(context.Background(), sqlc.PersonalTokensDeleteParams{ID: id, UserID: userId})
+	assert.Nil(err)
+
+	if deleted == 0 {
+		log.Fatalln("The user has no personal access token with the id.", "user", userId, "id", id)
+	}
+
+	fmt.Println("Revoked personal access token :", id)
+}
+
 func setupDb() *sql.DB {
 	dbFile := utils.GetEnvRequired(common.ENV_DB_NAME)
 	err := utils.CreateDbFileIfNotExists(dbFile)
@@ -1,14 +1,34 @@
 package common
 
 const (
-	ENV_NO_TLS               = "RS_NO_TLS"
-	ENV_TLS_KEY              = "RS_TLS_KEY"
-	ENV_TLS_CERT             = "RS_TLS_CERT"
-	ENV_DB_NAME              = "RS_DB_NAME"
-	ENV_HOST_ADDR            = "RS_HOST_ADDR"
-	ENV_WEB_APP_URL          = "RS_WEB_APP_URL"
-	ENV_SECRET_AUTH_TOKEN    = "RS_SECRET_AUTH_TOKEN"
-	ENV_GOOGLE_REDIRECT_URL  = "RS_GOOGLE_REDIRECT_URL"
-	ENV_GOOGLE_CLIENT_ID     = "RS_GOOGLE_CLIENT_ID"
-	ENV_GOOGLE_CLIENT_SECRET = "RS_GOOGLE_CLIENT_SECRET"
+	ENV_NO_TLS                  = "RS_NO_TLS"
+	ENV_TLS_KEY                 = "RS_TLS_KEY"
+	ENV_TLS_CERT                = "RS_TLS_CERT"
+	ENV_DB_NAME                 = "RS_DB_NAME"
+	ENV_HOST_ADDR               = "RS_HOST_ADDR"
+	ENV_WEB_APP_URL             = "RS_WEB_APP_URL"
+	ENV_GOOGLE_REDIRECT_URL     = "RS_GOOGLE_REDIRECT_URL"
+	ENV_GOOGLE_CLIENT_ID        = "RS_GOOGLE_CLIENT_ID"
+	ENV_GOOGLE_CLIENT_SECRET    = "RS_GOOGLE_CLIENT_SECRET"
+	ENV_MICROSOFT_REDIRECT_URL  = "RS_MICROSOFT_REDIRECT_URL"
+	ENV_MICROSOFT_CLIENT_ID     = "RS_MICROSOFT_CLIENT_ID"
+	ENV_MICROSOFT_CLIENT_SECRET = "RS_MICROSOFT_CLIENT_SECRET"
+	ENV_MICROSOFT_TENANT        = "RS_MICROSOFT_TENANT"
+	ENV_MICROSOFT_AUTH_URL      = "RS_MICROSOFT_AUTH_URL"
+	ENV_MICROSOFT_TOKEN_URL     = "RS_MICROSOFT_TOKEN_URL"
+	ENV_MICROSOFT_USER_INFO_URL = "RS_MICROSOFT_USER_INFO_URL"
+	ENV_OIDC_PROVIDERS          = "RS_OIDC_PROVIDERS"
+	ENV_SCHEDULER_TICK          = "RS_SCHEDULER_TICK"
+	ENV_SCHEDULER_LOOKAHEAD     = "RS_SCHEDULER_LOOKAHEAD"
+)
+
+// Settings of the OpenID Connect providers listed in 'RS_OIDC_PROVIDERS'. The
+// placeholder is the name of the provider in upper case with '-' replaced by
+// '_', e.g. 'RS_OIDC_PARTNER_SCHOOL_ISSUER' for 'partner-school'.
+const (
+	ENV_OIDC_ISSUER        = "RS_OIDC_%s_ISSUER"
+	ENV_OIDC_CLIENT_ID     = "RS_OIDC_%s_CLIENT_ID"
+	ENV_OIDC_CLIENT_SECRET = "RS_OIDC_%s_CLIENT_SECRET"
+	ENV_OIDC_REDIRECT_URL  = "RS_OIDC_%s_REDIRECT_URL"
+	ENV_OIDC_DISPLAY_NAME  = "RS_OIDC_%s_DISPLAY_NAME"
 )
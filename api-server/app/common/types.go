@@ -6,3 +6,12 @@ type GoogleProfile struct {
 	VerifiedEmail *bool   `json:"verified_email" validate:"required"`
 	Name          *string `json:"name" validate:"required"`
 }
+
+// Profile of the signed in user from the Microsoft Graph API. Only the
+// 'userPrincipalName' is used as the email address, see
+// `microsoftAuthProvider.Identity`.
+type MicrosoftProfile struct {
+	Id                *string `json:"id" validate:"required"`
+	DisplayName       *string `json:"displayName" validate:"required"`
+	UserPrincipalName *string `json:"userPrincipalName" validate:"required,email"`
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"ride_sharing_api/app/common"
 	"ride_sharing_api/app/rest"
@@ -25,28 +34,79 @@ func main() {
 		log.Fatalln("Failed to initialize database.", dbFile, err)
 	}
 
+	schedulerConfig, err := readSchedulerConfig()
+	if err != nil {
+		log.Fatalln("Invalid scheduler configuration.", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	handler := rest.NewRESTApi(db)
 	server := &http.Server{
 		Addr:    utils.GetEnvRequired(common.ENV_HOST_ADDR),
 		Handler: handler,
+		// Ends long-lived streams on shutdown, the server only waits for them
+		// to close.
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		rest.NewScheduler(db, schedulerConfig).Run(ctx)
+	}()
+
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Println("Error: Failed to shut down HTTP server.", "error:", err)
+		}
+	}()
+
 	log.Println("Listening on", server.Addr)
 
 	if strings.ToLower(os.Getenv(common.ENV_NO_TLS)) == "true" {
 		err = server.ListenAndServe()
-		if err != nil {
-			log.Fatalln(err)
-		} else {
-			log.Println("Closing HTTP server.")
-		}
 	} else {
 		err = server.ListenAndServeTLS(utils.GetEnvRequired(common.ENV_TLS_CERT), utils.GetEnvRequired(common.ENV_TLS_KEY))
-		if err != nil {
-			log.Fatalln(err)
-		} else {
-			log.Println("Closing HTTP server.")
-		}
 	}
 
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalln(err)
+	}
+
+	// stop the scheduler if the server closed without a signal
+	stop()
+	wg.Wait()
+	log.Println("Closing HTTP server.")
+}
+
+func readSchedulerConfig() (rest.SchedulerConfig, error) {
+	var config rest.SchedulerConfig
+
+	tick := utils.GetEnvOrDefault(common.ENV_SCHEDULER_TICK, rest.SCHEDULER_TICK_DEFAULT.String())
+	parsedTick, err := time.ParseDuration(tick)
+	if err != nil || parsedTick <= 0 {
+		return config, fmt.Errorf("%s must be a positive duration (e.g. '30s'). Received '%s'.", common.ENV_SCHEDULER_TICK, tick)
+	}
+
+	lookahead := utils.GetEnvOrDefault(common.ENV_SCHEDULER_LOOKAHEAD, strconv.Itoa(rest.SCHEDULER_LOOKAHEAD_DEFAULT))
+	parsedLookahead, err := strconv.ParseInt(lookahead, 10, 64)
+	if err != nil || parsedLookahead <= 0 {
+		return config, fmt.Errorf("%s must be a positive integer. Received '%s'.", common.ENV_SCHEDULER_LOOKAHEAD, lookahead)
+	}
+
+	config.Tick = parsedTick
+	config.Lookahead = parsedLookahead
+
+	return config, nil
 }
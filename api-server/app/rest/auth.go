@@ -1,10 +1,14 @@
 package rest
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,12 +19,28 @@ import (
 	"ride_sharing_api/app/common"
 	"ride_sharing_api/app/sqlc"
 	"ride_sharing_api/app/utils"
+	"strings"
 	"time"
 )
 
 const (
 	AUTH_PROVIDER_GOOGLE    = "google"
 	AUTH_PROVIDER_MICROSOFT = "microsoft"
+	// Followed by the name of the provider, e.g. 'oidc/partner-school'.
+	AUTH_PROVIDER_OIDC_PREFIX = "oidc/"
+)
+
+const (
+	accessTokenLifetime = 24 * time.Hour
+	// Access tokens are sealed with AES-256-GCM, the header is authenticated
+	// as well.
+	accessTokenAlgorithm = "A256GCM"
+	// How long after a refresh the old refresh token can be sent again without
+	// signing out the session. Clients that refresh from several tabs at once
+	// would sign themselves out otherwise.
+	refreshTokenReuseGrace = 10 * time.Second
+	// Sessions that weren't refreshed for this long have to sign in again.
+	refreshTokenLifetime = 30 * 24 * time.Hour
 )
 
 var clientUrlAuth = utils.GetEnvRequired(common.ENV_WEB_APP_URL) + "/authenticate"
@@ -30,27 +50,32 @@ type authTokens struct {
 	RefreshToken string `json:"refreshToken"`
 }
 
+// Access tokens have the format '<header>.<sealed claims>', both parts are
+// base64url encoded JSON. The header names the key the claims were sealed
+// with.
+type accessTokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
 type accessToken struct {
 	Id        *string   `json:"id" validate:"required"`
 	Email     *string   `json:"email" validate:"required"`
-	Random    *string   `json:"random" validate:"required"`
 	ExpiresAt time.Time `json:"expiresAt" validate:"required"`
 }
 
 func authHandlers(h *http.ServeMux) {
-	h.HandleFunc("POST /auth/refresh", handle(refreshAuthTokens).with(bearerAuth(true)).build())
+	h.HandleFunc("POST /auth/refresh", refreshAuthTokens)
+	h.HandleFunc("POST /auth/exchange", exchangeAuthCode)
 }
 
 type refreshAuthTokensBody struct {
 	RefreshToken *string `json:"refreshToken" validate:"required"`
 }
 
+// Exchanges a refresh token for new tokens. Every refresh token can only be
+// used once, using one again signs out its session.
 func refreshAuthTokens(w http.ResponseWriter, r *http.Request) {
-	// Locking to prevent change of access token during refresh
-	state.mutex.Lock()
-	defer state.mutex.Unlock()
-
-	user := getMiddlewareData[sqlc.User](r, "user")
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Failed to read request body.", http.StatusBadRequest)
@@ -70,17 +95,48 @@ func refreshAuthTokens(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !user.RefreshToken.Valid || user.RefreshToken.String != *refresh.RefreshToken {
+	session, err := state.queries.SessionsGetByRefreshTokenHash(r.Context(), hashToken(*refresh.RefreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		rejectUsedRefreshToken(w, r, *refresh.RefreshToken)
+		return
+	}
+	assert.Nil(err)
+
+	// Locking to prevent change of tokens during refresh
+	unlock := state.sessionLocks.lock(session.ID)
+	defer unlock()
+
+	// The session might have been refreshed or deleted while waiting for the lock
+	session, err = state.queries.SessionsGetById(r.Context(), session.ID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && session.RefreshTokenHash != hashToken(*refresh.RefreshToken)) {
 		http.Error(w, "Invalid refresh token cannot be used to get new tokens.", http.StatusUnauthorized)
 		return
 	}
+	assert.Nil(err)
 
-	tokens := GenAuthTokens(user.ID, user.Email)
+	refreshedAt, err := time.Parse(time.RFC3339, session.RefreshedAt)
+	assert.Nil(err, "Invalid refresh time of session.", "session id:", session.ID)
+	if time.Since(refreshedAt) > refreshTokenLifetime {
+		http.Error(w, "Refresh token expired, sign in again.", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := state.queries.UsersGetById(r.Context(), session.UserID)
+	if err != nil {
+		http.Error(w, "Invalid refresh token cannot be used to get new tokens.", http.StatusUnauthorized)
+		return
+	}
+
+	if user.IsBlocked {
+		http.Error(w, "User is blocked", http.StatusUnauthorized)
+		return
+	}
+
+	tokens := GenAuthTokens(r.Context(), state.authKeys, user.ID, user.Email)
 	bytes, err := json.Marshal(tokens)
 	assert.True(err == nil, "Failed to serialize authentication tokens.", tokens, "error:", func() any { return err })
 
-	args := sqlc.UsersSetTokensParams{ID: user.ID, AccessToken: utils.SqlNullStrWrapped(tokens.AccessToken), RefreshToken: utils.SqlNullStrWrapped(tokens.RefreshToken)}
-	err = state.queries.UsersSetTokens(r.Context(), args)
+	err = rotateSessionTokens(r.Context(), session, tokens)
 	if err != nil {
 		log.Println("Failed to update authentication tokens.", "error:", err)
 		http.Error(w, "Failed to update authentication tokens.", http.StatusInternalServerError)
@@ -90,38 +146,145 @@ func refreshAuthTokens(w http.ResponseWriter, r *http.Request) {
 	w.Write(bytes)
 }
 
-func GenAuthTokens(userId string, email string) authTokens {
+// Replaces the tokens of the session and remembers the old refresh token as
+// used. Used refresh tokens that would have expired by now are forgotten.
+func rotateSessionTokens(ctx context.Context, session sqlc.Session, tokens authTokens) error {
+	tx, err := state.getDBTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queriesTx := state.queries.WithTx(tx)
+
+	argsUsed := sqlc.UsedRefreshTokensCreateParams{
+		TokenHash: session.RefreshTokenHash,
+		SessionID: session.ID,
+	}
+	err = queriesTx.UsedRefreshTokensCreate(ctx, argsUsed)
+	if err != nil {
+		return err
+	}
+
+	expiredAt := time.Now().Add(-refreshTokenReuseGrace - refreshTokenLifetime).UTC().Format(time.RFC3339)
+	err = queriesTx.UsedRefreshTokensDeleteBefore(ctx, expiredAt)
+	if err != nil {
+		return err
+	}
+
+	args := sqlc.SessionsSetTokensParams{
+		ID:               session.ID,
+		AccessTokenHash:  hashToken(tokens.AccessToken),
+		RefreshTokenHash: hashToken(tokens.RefreshToken),
+	}
+	_, err = queriesTx.SessionsSetTokens(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Responds to a refresh token that doesn't belong to a session. If it was
+// already used for a refresh someone else has the tokens of the session, so
+// the session is signed out.
+func rejectUsedRefreshToken(w http.ResponseWriter, r *http.Request, token string) {
+	used, err := state.queries.UsedRefreshTokensGet(r.Context(), hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Invalid refresh token cannot be used to get new tokens.", http.StatusUnauthorized)
+		return
+	}
+	assert.Nil(err)
+
+	usedAt, err := time.Parse(time.RFC3339, used.UsedAt)
+	assert.Nil(err, "Invalid time of used refresh token.", "session id:", used.SessionID)
+
+	if time.Since(usedAt) > refreshTokenReuseGrace {
+		unlock := state.sessionLocks.lock(used.SessionID)
+		defer unlock()
+
+		// the session might have been signed out already
+		session, err := state.queries.SessionsGetById(r.Context(), used.SessionID)
+		if err == nil {
+			log.Println("Error: Refresh token was used again, signing out the session.", "session id:", session.ID, "user id:", session.UserID)
+			_, err = state.queries.SessionsDelete(r.Context(), sqlc.SessionsDeleteParams{ID: session.ID, UserID: session.UserID})
+			assert.Nil(err)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			assert.Nil(err)
+		}
+	}
+
+	http.Error(w, "Refresh token was already used.", http.StatusUnauthorized)
+}
+
+// Only hashes of used refresh tokens and auth codes are kept, they are never
+// compared against anything but a token that is sent again.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func GenAuthTokens(ctx context.Context, keys *AuthKeys, userId string, email string) authTokens {
 	return authTokens{
-		AccessToken:  encodeAccessToken(userId, email),
+		AccessToken:  encodeAccessToken(ctx, keys, userId, email),
 		RefreshToken: genRandBase64(512),
 	}
 }
 
-func encodeAccessToken(userId string, email string) string {
-	token := genRandBase64(512)
+func encodeAccessToken(ctx context.Context, keys *AuthKeys, userId string, email string) string {
+	kid, key := keys.active(ctx)
+
+	header, err := json.Marshal(accessTokenHeader{Alg: accessTokenAlgorithm, Kid: kid})
+	assert.True(err == nil, "Invalid token header JSON.", "error", func() any { return err })
+	headerPart := base64.RawURLEncoding.EncodeToString(header)
+
 	at := accessToken{
 		Id:        &userId,
 		Email:     &email,
-		Random:    &token,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
+		ExpiresAt: time.Now().Add(accessTokenLifetime),
 	}
 
 	plain, err := json.Marshal(at)
-	assert.True(err == nil, "Invalid token JSON.", "access-token:", at, "error", func() any { return err.Error() })
+	assert.True(err == nil, "Invalid token JSON.", "access-token:", at, "error", func() any { return err })
 
-	ciphertext, err := encrypt(plain, []byte(utils.GetEnvRequired(common.ENV_SECRET_AUTH_TOKEN)))
-	assert.True(err == nil, "Encryption error on server defined data.", "error:", func() any { return err.Error() })
+	sealed, err := encrypt(plain, key, []byte(headerPart))
+	assert.True(err == nil, "Encryption error on server defined data.", "error:", func() any { return err })
 
-	return base64.URLEncoding.EncodeToString(ciphertext)
+	return headerPart + "." + base64.RawURLEncoding.EncodeToString(sealed)
 }
 
-func decodeAccessToken(token []byte) (*accessToken, error) {
-	token, err := base64.URLEncoding.DecodeString(string(token))
+func decodeAccessToken(ctx context.Context, keys *AuthKeys, token string) (*accessToken, error) {
+	headerPart, sealedPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("Malformed access token.")
+	}
+
+	headerJson, err := base64.RawURLEncoding.DecodeString(headerPart)
+	if err != nil {
+		return nil, err
+	}
+
+	var header accessTokenHeader
+	err = json.Unmarshal(headerJson, &header)
 	if err != nil {
 		return nil, err
 	}
 
-	plain, err := decrypt(token, []byte(utils.GetEnvRequired(common.ENV_SECRET_AUTH_TOKEN)))
+	if header.Alg != accessTokenAlgorithm {
+		return nil, fmt.Errorf("Unsupported access token algorithm '%s'.", header.Alg)
+	}
+
+	key, ok := keys.get(ctx, header.Kid)
+	if !ok {
+		return nil, fmt.Errorf("Unknown or retired key '%s'.", header.Kid)
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(sealedPart)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := decrypt(sealed, key, []byte(headerPart))
 	if err != nil {
 		return nil, err
 	}
@@ -140,46 +303,45 @@ func decodeAccessToken(token []byte) (*accessToken, error) {
 	return &at, nil
 }
 
-func encrypt(plain []byte, key []byte) ([]byte, error) {
+// Encrypts and authenticates `plain`. `additional` is only authenticated.
+func encrypt(plain []byte, key []byte, additional []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return []byte{}, err
 	}
-	if len(plain) < aes.BlockSize {
-		return []byte{}, fmt.Errorf("Plaintext length too short.")
-	}
 
-	ciphertext := make([]byte, aes.BlockSize+len(plain))
-	iv := ciphertext[:aes.BlockSize]
-	_, err = io.ReadFull(rand.Reader, iv)
+	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	cfb := cipher.NewCFBEncrypter(block, iv)
-	cfb.XORKeyStream(ciphertext[aes.BlockSize:], plain)
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return []byte{}, err
+	}
 
-	return ciphertext, nil
+	return gcm.Seal(nonce, nonce, plain, additional), nil
 }
 
-func decrypt(ciphertext []byte, key []byte) ([]byte, error) {
+// Fails if the ciphertext or `additional` were changed.
+func decrypt(ciphertext []byte, key []byte, additional []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	if len(ciphertext) < aes.BlockSize {
-		return []byte{}, errors.New("Ciphertext length too short.")
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return []byte{}, err
 	}
 
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-
-	plaintext := make([]byte, len(ciphertext))
-	cfb := cipher.NewCFBDecrypter(block, iv)
-	cfb.XORKeyStream(plaintext, ciphertext)
+	if len(ciphertext) < gcm.NonceSize() {
+		return []byte{}, errors.New("Ciphertext length too short.")
+	}
 
-	return plaintext, nil
+	nonce := ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], additional)
 }
 
 func genRandBase64(size int) string {
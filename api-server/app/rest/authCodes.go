@@ -0,0 +1,154 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"time"
+)
+
+// After a login the web app is redirected to with a one-time code instead of
+// the tokens, so tokens never end up in the browser history or in logs. The
+// web app exchanges the code for the tokens of a new session at
+// '/auth/exchange'.
+//
+// Clients can bind the code to a secret verifier (PKCE, RFC 7636) by sending
+// the 'code_challenge' of the verifier when starting the login. The code can
+// then only be exchanged together with the verifier.
+
+const (
+	// How long a code can be exchanged after the login.
+	authCodeLifetime = time.Minute
+	// Only SHA-256 challenges are supported.
+	codeChallengeMethod = "S256"
+)
+
+type exchangeAuthCodeBody struct {
+	Code         *string `json:"code" validate:"required"`
+	CodeVerifier *string `json:"codeVerifier" validate:"omitempty,min=43,max=128"`
+}
+
+// Exchanges a code from a login for the tokens of a new session. Every code
+// can only be exchanged once.
+func exchangeAuthCode(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body.", http.StatusBadRequest)
+		return
+	}
+
+	var exchange exchangeAuthCodeBody
+	err = json.Unmarshal(body, &exchange)
+	if err != nil {
+		http.Error(w, "Failed to read request body as JSON.", http.StatusBadRequest)
+		return
+	}
+
+	err = utils.Validate.Struct(exchange)
+	if err != nil {
+		http.Error(w, "Invalid JSON in request body. "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	args := sqlc.AuthCodesConsumeParams{
+		CodeHash:     hashToken(*exchange.Code),
+		CreatedAfter: time.Now().Add(-authCodeLifetime).UTC().Format(time.RFC3339),
+	}
+	code, err := state.queries.AuthCodesConsume(r.Context(), args)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Invalid or expired code.", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Println("Error: Failed to read auth code.", "error:", err)
+		http.Error(w, "Failed to exchange code.", http.StatusInternalServerError)
+		return
+	}
+
+	if code.CodeChallenge.Valid && (exchange.CodeVerifier == nil || !verifyCodeChallenge(*exchange.CodeVerifier, code.CodeChallenge.String)) {
+		http.Error(w, "Invalid 'codeVerifier' for code.", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := state.queries.UsersGetById(r.Context(), code.UserID)
+	if err != nil {
+		http.Error(w, "Invalid or expired code.", http.StatusUnauthorized)
+		return
+	}
+
+	if user.IsBlocked {
+		http.Error(w, "User is blocked", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := createSession(r, user)
+	if err != nil {
+		log.Println("Error: Failed to create session.", err.Error(), "user id:", user.ID, "user email:", user.Email)
+		http.Error(w, "Failed to sign in.", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(tokens)
+	if err != nil {
+		log.Println("Error: Failed to serialize authentication tokens.", "error:", err)
+		http.Error(w, "Failed to sign in.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(resp)
+}
+
+// Creates a code the user can be signed in with. Expired codes are cleaned
+// up.
+func createAuthCode(ctx context.Context, userId string, codeChallenge sql.NullString) (string, error) {
+	err := state.queries.AuthCodesDeleteExpired(ctx, time.Now().Add(-authCodeLifetime).UTC().Format(time.RFC3339))
+	if err != nil {
+		return "", err
+	}
+
+	code := genRandBase64(32)
+	args := sqlc.AuthCodesCreateParams{
+		CodeHash:      hashToken(code),
+		UserID:        userId,
+		CodeChallenge: codeChallenge,
+	}
+	return code, state.queries.AuthCodesCreate(ctx, args)
+}
+
+// Checks the 'code_challenge' and 'code_challenge_method' parameters of a
+// login. Both are optional, an empty challenge means the code isn't bound to a
+// verifier.
+func parseCodeChallenge(r *http.Request) (sql.NullString, bool) {
+	challenge := r.FormValue("code_challenge")
+	method := r.FormValue("code_challenge_method")
+	if challenge == "" {
+		return sql.NullString{}, method == ""
+	}
+
+	if method != "" && method != codeChallengeMethod {
+		return sql.NullString{}, false
+	}
+
+	// base64url encoded SHA-256 hash without padding
+	decoded, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(decoded) != sha256.Size {
+		return sql.NullString{}, false
+	}
+
+	return sql.NullString{String: challenge, Valid: true}, true
+}
+
+func verifyCodeChallenge(verifier string, challenge string) bool {
+	hash := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(hash[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -2,16 +2,13 @@ package rest
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"ride_sharing_api/app/common"
-	"ride_sharing_api/app/sqlc"
 	"ride_sharing_api/app/utils"
-	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -33,115 +30,50 @@ var googleOauthConfig = &oauth2.Config{
 	Endpoint: google.Endpoint,
 }
 
-func authHandlersGoogle(h *http.ServeMux) {
-	h.HandleFunc("GET /auth/google/login", oauthLoginGoogle)
-	h.HandleFunc("GET /auth/google/callback", oauthCallbackGoogle)
-}
-
-func oauthLoginGoogle(w http.ResponseWriter, r *http.Request) {
-	oauthState := genRandBase64(64)
-
-	state.mutex.Lock()
-	state.oauthStates[oauthState] = time.Now()
-	state.mutex.Unlock()
+type googleAuthProvider struct{}
 
-	url := googleOauthConfig.AuthCodeURL(oauthState, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+func (googleAuthProvider) Id() string {
+	return AUTH_PROVIDER_GOOGLE
 }
 
-func oauthCallbackGoogle(w http.ResponseWriter, r *http.Request) {
-	oauthState := r.FormValue("state")
-	state.mutex.Lock()
-	createdAt, ok := state.oauthStates[oauthState]
-	if !ok || time.Now().Sub(createdAt) > time.Duration(5*time.Minute) {
-		state.mutex.Unlock()
-		http.Error(w, "Invalid 'state' parameter. Make sure authentication request are only started from '/auth/google/login'.", http.StatusBadRequest)
-		return
-	}
-	delete(state.oauthStates, oauthState)
+func (googleAuthProvider) Name() string {
+	return "Google"
+}
 
-	for oauthState, createdAt := range state.oauthStates {
-		elapsed := time.Now().Sub(createdAt)
-		if elapsed > time.Duration(5*time.Minute) {
-			delete(state.oauthStates, oauthState)
-		}
-	}
+func (googleAuthProvider) Config(ctx context.Context) (*oauth2.Config, error) {
+	return googleOauthConfig, nil
+}
 
-	state.mutex.Unlock()
+func (googleAuthProvider) AuthCodeOptions() []oauth2.AuthCodeOption {
+	return []oauth2.AuthCodeOption{oauth2.AccessTypeOffline, oauth2.ApprovalForce}
+}
 
-	profile, err := getUserProfileFromGoogle(r.Context(), r.FormValue("code"))
+func (googleAuthProvider) Identity(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*authIdentity, error) {
+	profile, err := getUserProfileFromGoogle(token)
 	if err != nil {
-		log.Println("Error: Failed to get Google user profile.", "error:", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if !*profile.VerifiedEmail {
-		http.Error(w, "Google user has an unverified email address. This is not allowed.", http.StatusBadRequest)
-		return
-	}
-
-	user, err := state.queries.UsersGetById(r.Context(), *profile.Id)
-	if err == sql.ErrNoRows {
-		user, err = state.queries.UsersCreate(r.Context(), sqlc.UsersCreateParams{ID: *profile.Id, Name: *profile.Name, Email: *profile.Email, Provider: "google"})
-		if err != nil {
-			log.Println("Error: Failed to create user after Google authentication.", err.Error(), "user email:", *profile.Email)
-			http.Error(w, "Failed to create user after Google authentication.", http.StatusInternalServerError)
-			return
-		}
-
-		tokens := GenAuthTokens(user.ID, user.Email)
-
-		args := sqlc.UsersSetTokensParams{ID: user.ID, AccessToken: sql.NullString{String: tokens.AccessToken}, RefreshToken: sql.NullString{String: tokens.RefreshToken}}
-		err = state.queries.UsersSetTokens(r.Context(), args)
-		if err != nil {
-			log.Println("Error: Failed to update user tokens.", err.Error(), "user id:", *profile.Id, "user email:", *profile.Email)
-			http.Error(w, "Failed to update user data.", http.StatusInternalServerError)
-			return
-		}
-
-		url := fmt.Sprintf("%s?accessToken=%s&refreshToken=%s", clientUrlAuth, tokens.AccessToken, tokens.RefreshToken)
-		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
-		return
-	} else if err != nil {
-		log.Println("Error: Failed to get user from database.", err.Error(), "user id:", *profile.Id, "user email:", *profile.Email)
-		http.Error(w, "Failed to get user.", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	if user.Provider != AUTH_PROVIDER_GOOGLE {
-		http.Error(w, "The user already exists but was created with a different authentication method than Google.", http.StatusBadRequest)
-		return
-	}
+	return identityFromGoogleProfile(*profile, "Google")
+}
 
-	user, err = state.queries.UsersUpdateNameAndEmail(r.Context(), sqlc.UsersUpdateNameAndEmailParams{ID: *profile.Id, Name: *profile.Name, Email: *profile.Email})
+// Validates a profile in the format of the Google user info. Other providers
+// that send the same information map it to this format to share the checks.
+func identityFromGoogleProfile(profile common.GoogleProfile, providerName string) (*authIdentity, error) {
+	err := utils.Validate.Struct(profile)
 	if err != nil {
-		log.Println("Error: Failed to update user data.", err.Error(), "user id:", *profile.Id, "user email:", *profile.Email)
-		http.Error(w, "Failed to update user data.", http.StatusInternalServerError)
-		return
+		log.Println("Error: Invalid user info received.", "provider:", providerName, "error:", err.Error())
+		return nil, fmt.Errorf("Received invalid user info. %s might have sent invalid data.", providerName)
 	}
 
-	tokens := GenAuthTokens(user.ID, user.Email)
-
-	args := sqlc.UsersSetTokensParams{ID: user.ID, AccessToken: utils.SqlNullStrWrapped(tokens.AccessToken), RefreshToken: utils.SqlNullStrWrapped(tokens.RefreshToken)}
-	err = state.queries.UsersSetTokens(r.Context(), args)
-	if err != nil {
-		log.Println("Error: Failed to update user tokens.", err.Error(), "user id:", *profile.Id, "user email:", *profile.Email)
-		http.Error(w, "Failed to update user data.", http.StatusInternalServerError)
-		return
+	if !*profile.VerifiedEmail {
+		return nil, fmt.Errorf("%s user has an unverified email address. This is not allowed.", providerName)
 	}
 
-	url := fmt.Sprintf("%s?accessToken=%s&refreshToken=%s", clientUrlAuth, tokens.AccessToken, tokens.RefreshToken)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	return &authIdentity{Subject: *profile.Id, Name: *profile.Name, Email: *profile.Email}, nil
 }
 
-func getUserProfileFromGoogle(ctx context.Context, code string) (*common.GoogleProfile, error) {
-	token, err := googleOauthConfig.Exchange(ctx, code)
-	if err != nil {
-		log.Println("Error: Failed to exchange google auth codes.", err.Error())
-		return nil, fmt.Errorf("Error during code exchange. Make sure this request was started from '/auth/google/login'.")
-	}
-
+func getUserProfileFromGoogle(token *oauth2.Token) (*common.GoogleProfile, error) {
 	url := oauthUrlAPIGoogle + token.AccessToken
 	response, err := http.Get(url)
 	if err != nil {
@@ -163,11 +95,5 @@ func getUserProfileFromGoogle(ctx context.Context, code string) (*common.GoogleP
 		return nil, fmt.Errorf("Failed to parse user info. Google might have sent invalid data.")
 	}
 
-	err = utils.Validate.Struct(profile)
-	if err != nil {
-		log.Println("Error: Invalid google user info received.", err.Error())
-		return nil, fmt.Errorf("Received invalid user info. Google might have sent invalid data.")
-	}
-
 	return &profile, nil
 }
@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"sync"
+	"time"
+)
+
+// Access tokens are sealed with the keys in the 'auth_keys' table. The newest
+// key seals new tokens, tokens of older keys stay valid until the key
+// retires. Keys are rotated with the CLI ('auth-keys rotate').
+
+const (
+	authKeySize = 32
+	// Keys are read from the database again after this, so keys rotated by
+	// the CLI are picked up without a restart.
+	authKeysReloadInterval = time.Minute
+	// Tokens with an unknown key id reload the keys at most this often, so
+	// forged tokens can't make every request wait for the database.
+	authKeysReloadMinInterval = 5 * time.Second
+	// How long tokens of the previous keys stay valid after a rotation by
+	// default. Long enough for every access token sealed before to expire.
+	AuthKeyRotationWindow = accessTokenLifetime
+)
+
+type AuthKeys struct {
+	queries *sqlc.Queries
+
+	mutex    sync.Mutex
+	keys     map[string]authKey
+	activeId string
+	loadedAt time.Time
+	// The last time the keys were read, even if that failed.
+	triedAt time.Time
+}
+
+type authKey struct {
+	secret []byte
+	// Zero if the key doesn't retire yet.
+	retiresAt time.Time
+}
+
+func NewAuthKeys(queries *sqlc.Queries) *AuthKeys {
+	return &AuthKeys{queries: queries}
+}
+
+// The key new tokens are sealed with. Creates the first key if there is none.
+// Keeps using the loaded keys if they can't be read again.
+func (k *AuthKeys) active(ctx context.Context) (string, []byte) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if time.Since(k.loadedAt) > authKeysReloadInterval {
+		err := k.load(ctx)
+		if err != nil {
+			log.Println("Error: Failed to reload auth keys.", "error:", err)
+		}
+	}
+
+	if k.activeId == "" {
+		key, err := GenerateAuthKey(ctx, k.queries)
+		assert.Nil(err, "Failed to create auth key.")
+		log.Println("Created the first auth key.", "key id:", key.ID)
+
+		secret, err := base64.StdEncoding.DecodeString(key.Secret)
+		assert.Nil(err, "Invalid secret of created auth key.", "key id:", key.ID)
+		if k.keys == nil {
+			k.keys = make(map[string]authKey)
+		}
+		k.keys[key.ID] = authKey{secret: secret}
+		k.activeId = key.ID
+	}
+
+	return k.activeId, k.keys[k.activeId].secret
+}
+
+// The key with the id `id`. Returns false if no such key exists, the key
+// retired or the keys couldn't be read.
+func (k *AuthKeys) get(ctx context.Context, id string) ([]byte, bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	key, ok := k.keys[id]
+	unknown := !ok && time.Since(k.triedAt) > authKeysReloadMinInterval
+	if unknown || time.Since(k.loadedAt) > authKeysReloadInterval {
+		err := k.load(ctx)
+		if err != nil {
+			log.Println("Error: Failed to reload auth keys.", "error:", err)
+			return nil, false
+		}
+		key, ok = k.keys[id]
+	}
+
+	if !ok || (!key.retiresAt.IsZero() && time.Now().After(key.retiresAt)) {
+		return nil, false
+	}
+
+	return key.secret, true
+}
+
+// Has to be called with the mutex locked. The loaded keys are kept if reading
+// them fails.
+func (k *AuthKeys) load(ctx context.Context) error {
+	k.triedAt = time.Now()
+
+	rows, err := k.queries.AuthKeysGetValid(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]authKey, len(rows))
+	activeId := ""
+	for _, row := range rows {
+		secret, err := base64.StdEncoding.DecodeString(row.Secret)
+		if err != nil || len(secret) != authKeySize {
+			log.Println("Error: Skipping invalid auth key.", "key id:", row.ID)
+			continue
+		}
+
+		key := authKey{secret: secret}
+		if row.RetiresAt.Valid {
+			key.retiresAt, err = time.Parse(time.RFC3339, row.RetiresAt.String)
+			if err != nil {
+				log.Println("Error: Skipping auth key with invalid retire time.", "key id:", row.ID)
+				continue
+			}
+		}
+		keys[row.ID] = key
+
+		// rows are ordered from newest to oldest
+		if activeId == "" && key.retiresAt.IsZero() {
+			activeId = row.ID
+		}
+	}
+
+	k.keys = keys
+	k.activeId = activeId
+	k.loadedAt = time.Now()
+	return nil
+}
+
+// Creates a new key. Tokens are sealed with the new key from now on, older
+// keys aren't retired.
+func GenerateAuthKey(ctx context.Context, queries *sqlc.Queries) (sqlc.AuthKey, error) {
+	id := make([]byte, 8)
+	rand.Read(id)
+	secret := make([]byte, authKeySize)
+	rand.Read(secret)
+
+	args := sqlc.AuthKeysCreateParams{
+		ID:     hex.EncodeToString(id),
+		Secret: base64.StdEncoding.EncodeToString(secret),
+	}
+	return queries.AuthKeysCreate(ctx, args)
+}
+
+// Creates a new key and retires all other keys after `window`. Keys that
+// already retired are deleted.
+func RotateAuthKeys(ctx context.Context, queries *sqlc.Queries, window time.Duration) (sqlc.AuthKey, error) {
+	key, err := GenerateAuthKey(ctx, queries)
+	if err != nil {
+		return sqlc.AuthKey{}, err
+	}
+
+	args := sqlc.AuthKeysRetireParams{
+		RetiresAt: sql.NullString{String: time.Now().Add(window).UTC().Format(time.RFC3339), Valid: true},
+		ActiveID:  key.ID,
+	}
+	err = queries.AuthKeysRetire(ctx, args)
+	if err != nil {
+		return sqlc.AuthKey{}, err
+	}
+
+	_, err = queries.AuthKeysDeleteRetired(ctx)
+	return key, err
+}
@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"context"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestAuthKeysReload(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0035-auth-keys-reload.sql"))
+	queries := sqlc.New(db)
+	keys := NewAuthKeys(queries)
+
+	_, ok := keys.get(context.Background(), "test-key-01")
+	assert.True(ok)
+	loadedAt := keys.loadedAt
+
+	// Unknown key ids don't reload the keys again right away
+	_, ok = keys.get(context.Background(), "unknown")
+	assert.True(!ok)
+	assert.Eq(keys.loadedAt, loadedAt)
+
+	// Failing to read the keys rejects the token instead of panicking
+	keys.loadedAt = loadedAt.Add(-authKeysReloadInterval)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok = keys.get(canceled, "test-key-01")
+	assert.True(!ok)
+
+	_, ok = keys.get(context.Background(), "test-key-01")
+	assert.True(ok)
+}
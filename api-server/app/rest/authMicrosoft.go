@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/common"
+	"ride_sharing_api/app/utils"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// Microsoft Entra ID config. Accounts of any tenant can sign in unless
+// 'RS_MICROSOFT_TENANT' is set to a single tenant (e.g. the school's domain).
+// The endpoints can be overwritten to test against a local identity provider.
+
+const (
+	oauthUrlLoginMicrosoft   = "https://login.microsoftonline.com/"
+	oauthUrlAPIMicrosoft     = "https://graph.microsoft.com/v1.0/me"
+	oauthTenantMicrosoftAny  = "common"
+	oauthScopeMicrosoftGraph = "https://graph.microsoft.com/User.Read"
+)
+
+// The config is read on every login. Logins with Microsoft are only available
+// if the client is configured.
+func microsoftOauthConfig() (*oauth2.Config, error) {
+	clientId := utils.GetEnvOrDefault(common.ENV_MICROSOFT_CLIENT_ID, "")
+	if clientId == "" {
+		return nil, errAuthProviderNotConfigured
+	}
+
+	tenant := utils.GetEnvOrDefault(common.ENV_MICROSOFT_TENANT, oauthTenantMicrosoftAny)
+	return &oauth2.Config{
+		RedirectURL:  utils.GetEnvOrDefault(common.ENV_MICROSOFT_REDIRECT_URL, ""),
+		ClientID:     clientId,
+		ClientSecret: utils.GetEnvOrDefault(common.ENV_MICROSOFT_CLIENT_SECRET, ""),
+		Scopes: []string{
+			"openid",
+			"profile",
+			"email",
+			oauthScopeMicrosoftGraph,
+		},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   utils.GetEnvOrDefault(common.ENV_MICROSOFT_AUTH_URL, oauthUrlLoginMicrosoft+tenant+"/oauth2/v2.0/authorize"),
+			TokenURL:  utils.GetEnvOrDefault(common.ENV_MICROSOFT_TOKEN_URL, oauthUrlLoginMicrosoft+tenant+"/oauth2/v2.0/token"),
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}, nil
+}
+
+type microsoftAuthProvider struct{}
+
+func (microsoftAuthProvider) Id() string {
+	return AUTH_PROVIDER_MICROSOFT
+}
+
+func (microsoftAuthProvider) Name() string {
+	return "Microsoft"
+}
+
+func (microsoftAuthProvider) Config(ctx context.Context) (*oauth2.Config, error) {
+	return microsoftOauthConfig()
+}
+
+func (microsoftAuthProvider) AuthCodeOptions() []oauth2.AuthCodeOption {
+	return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("prompt", "select_account")}
+}
+
+func (microsoftAuthProvider) Identity(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*authIdentity, error) {
+	profile, err := getUserProfileFromMicrosoft(ctx, config, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guests of a tenant get a user principal name like
+	// 'alice_gmail.com#EXT#@tenant.onmicrosoft.com', which isn't their email
+	if strings.Contains(*profile.UserPrincipalName, "#EXT#") {
+		return nil, fmt.Errorf("Guest accounts can't sign in with Microsoft. Sign in with the account of your own organization instead.")
+	}
+
+	// The domain of the user principal name has to be verified by the tenant,
+	// 'mail' can be set to any address by the admins of any tenant
+	return &authIdentity{Subject: *profile.Id, Name: *profile.DisplayName, Email: *profile.UserPrincipalName}, nil
+}
+
+func getUserProfileFromMicrosoft(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*common.MicrosoftProfile, error) {
+	url := utils.GetEnvOrDefault(common.ENV_MICROSOFT_USER_INFO_URL, oauthUrlAPIMicrosoft)
+	response, err := config.Client(ctx, token).Get(url)
+	if err != nil {
+		log.Println("Error: Failed to get microsoft user info.", err.Error(), "url:", url)
+		return nil, fmt.Errorf("Failed to get user info. This might be an issue with the Microsoft Oauth configuration.")
+	}
+
+	defer response.Body.Close()
+	contents, err := io.ReadAll(response.Body)
+	if err != nil {
+		log.Println("Error: Failed to read microsoft user info.", err.Error())
+		return nil, fmt.Errorf("Failed to read user info. Microsoft might have send invalid data.")
+	}
+
+	if response.StatusCode != http.StatusOK {
+		log.Println("Error: Failed to get microsoft user info.", "status:", response.StatusCode, "contents:", string(contents))
+		return nil, fmt.Errorf("Failed to get user info. This might be an issue with the Microsoft Oauth configuration.")
+	}
+
+	var profile common.MicrosoftProfile
+	err = json.Unmarshal(contents, &profile)
+	if err != nil {
+		log.Println("Error: Failed to parse microsoft user info.", err.Error(), "contents:", string(contents))
+		return nil, fmt.Errorf("Failed to parse user info. Microsoft might have sent invalid data.")
+	}
+
+	err = utils.Validate.Struct(profile)
+	if err != nil {
+		log.Println("Error: Invalid microsoft user info received.", err.Error())
+		return nil, fmt.Errorf("Received invalid user info. Microsoft might have sent invalid data.")
+	}
+
+	return &profile, nil
+}
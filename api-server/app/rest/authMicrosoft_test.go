@@ -0,0 +1,308 @@
+package rest_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/common"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Starts a fake Microsoft identity provider and configures the login to use
+// it. Every code is exchanged for a token of the profile with the same key.
+func useFakeMicrosoftIdp(t *testing.T, apiUrl string, profiles map[string]string) string {
+	idp := http.NewServeMux()
+	idp.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(r.ParseForm())
+		assert.Eq(r.PostForm.Get("client_id"), "client")
+		assert.Eq(r.PostForm.Get("client_secret"), "secret")
+
+		code := r.PostForm.Get("code")
+		if _, ok := profiles[code]; !ok {
+			http.Error(w, `{ "error": "invalid_grant" }`, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{ "access_token": "` + code + `", "token_type": "Bearer", "expires_in": 3600 }`))
+	})
+	idp.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {
+		profile, ok := profiles[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(profile))
+	})
+	idpServer := httptest.NewServer(idp)
+	t.Cleanup(idpServer.Close)
+
+	t.Setenv(common.ENV_MICROSOFT_CLIENT_ID, "client")
+	t.Setenv(common.ENV_MICROSOFT_CLIENT_SECRET, "secret")
+	t.Setenv(common.ENV_MICROSOFT_REDIRECT_URL, apiUrl+"/auth/microsoft/callback")
+	t.Setenv(common.ENV_MICROSOFT_AUTH_URL, idpServer.URL+"/authorize")
+	t.Setenv(common.ENV_MICROSOFT_TOKEN_URL, idpServer.URL+"/token")
+	t.Setenv(common.ENV_MICROSOFT_USER_INFO_URL, idpServer.URL+"/me")
+
+	return idpServer.URL
+}
+
+// Client that doesn't follow redirects.
+func noRedirectClient(api *httptest.Server) *http.Client {
+	client := api.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client
+}
+
+func getUserMe(client *http.Client, apiUrl string, token string) sqlc.User {
+	req, err := http.NewRequest("GET", apiUrl+"/users/me", nil)
+	assert.Nil(err)
+	req.Header.Add("Authorization", token)
+	resp, err := client.Do(req)
+	assert.Nil(err)
+	assert.Eq(resp.StatusCode, 200)
+	var user sqlc.User
+	err = json.NewDecoder(resp.Body).Decode(&user)
+	assert.Nil(err)
+	return user
+}
+
+func TestHandleAuthMicrosoft(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0018-auth-microsoft.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	client := noRedirectClient(api)
+	get := func(endpoint string) *http.Response {
+		resp, err := client.Get(api.URL + endpoint)
+		assert.Nil(err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Not configured
+	resp := get("/auth/microsoft/login")
+	assert.Eq(resp.StatusCode, 404)
+
+	idpUrl := useFakeMicrosoftIdp(t, api.URL, map[string]string{
+		"new":        `{ "id": "entra-user-1", "displayName": "Alice", "mail": null, "userPrincipalName": "alice@school.example.com" }`,
+		"renamed":    `{ "id": "entra-user-1", "displayName": "Alice", "mail": "bob@example.com", "userPrincipalName": "alice@example.com" }`,
+		"no-profile": `{ "id": "entra-user-2" }`,
+		"no-email":   `{ "id": "entra-user-2", "displayName": "Bob", "mail": null, "userPrincipalName": "bob" }`,
+		"guest":      `{ "id": "entra-user-2", "displayName": "Bob", "mail": "bob@gmail.com", "userPrincipalName": "bob_gmail.com#EXT#@school.onmicrosoft.com" }`,
+		"existing":   `{ "id": "entra-user-3", "displayName": "Test", "mail": null, "userPrincipalName": "Test@example.com" }`,
+	})
+
+	login := func() string {
+		resp := get("/auth/microsoft/login")
+		assert.Eq(resp.StatusCode, 307)
+		location, err := url.Parse(resp.Header.Get("Location"))
+		assert.Nil(err)
+		assert.Eq(location.Scheme+"://"+location.Host+location.Path, idpUrl+"/authorize")
+		assert.Eq(location.Query().Get("client_id"), "client")
+		assert.Eq(location.Query().Get("redirect_uri"), api.URL+"/auth/microsoft/callback")
+		return location.Query().Get("state")
+	}
+
+	callback := func(state string, code string) *http.Response {
+		return get("/auth/microsoft/callback?" + url.Values{"state": {state}, "code": {code}}.Encode())
+	}
+
+	accessToken := func(resp *http.Response) string {
+		tokens, status := exchangeAuthCode(client, api.URL, authCodeFromRedirect(resp), "", "")
+		assert.Eq(status, 200)
+		return tokens.AccessToken
+	}
+
+	// Unknown state
+	resp = callback("invalid", "new")
+	assert.Eq(resp.StatusCode, 400)
+
+	// New users are created and signed in
+	state := login()
+	user := getUserMe(client, api.URL, accessToken(callback(state, "new")))
+	assert.Eq(user.Email, "alice@school.example.com")
+	assert.Eq(user.Provider, "microsoft")
+
+	// A state can only be used once
+	resp = callback(state, "new")
+	assert.Eq(resp.StatusCode, 400)
+
+	// Signing in with a changed email keeps the email of the user, 'mail' isn't
+	// verified and is ignored
+	token := accessToken(callback(login(), "renamed"))
+	again := getUserMe(client, api.URL, token)
+	assert.Eq(again.ID, user.ID)
+	assert.Eq(again.Email, "alice@school.example.com")
+
+	req, err := http.NewRequest("GET", api.URL+"/auth/identities", nil)
+	assert.Nil(err)
+	req.Header.Add("Authorization", token)
+	resp, err = client.Do(req)
+	assert.Nil(err)
+	var identities []rest.IdentityData
+	err = json.NewDecoder(resp.Body).Decode(&identities)
+	assert.Nil(err)
+	assert.Eq(identities[0].Email, "alice@example.com")
+
+	// Accounts with the email of another user don't create a second user, they
+	// have to be linked to it
+	resp = callback(login(), "existing")
+	assert.Eq(resp.StatusCode, 409)
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM users WHERE lower(email) = 'test@example.com'").Scan(&count)
+	assert.Nil(err)
+	assert.Eq(count, 1)
+
+	// Failed code exchange and invalid profiles
+	resp = callback(login(), "invalid")
+	assert.Eq(resp.StatusCode, 400)
+	resp = callback(login(), "no-profile")
+	assert.Eq(resp.StatusCode, 400)
+	resp = callback(login(), "no-email")
+	assert.Eq(resp.StatusCode, 400)
+
+	// Guests of the tenant have no email as their user principal name
+	resp, err = client.Get(api.URL + "/auth/microsoft/callback?" + url.Values{"state": {login()}, "code": {"guest"}}.Encode())
+	assert.Nil(err)
+	assert.Eq(resp.StatusCode, 400)
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.True(strings.Contains(string(body), "Guest accounts"), string(body))
+}
+
+func TestHandleLinkIdentities(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0019-link-identities.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	useFakeMicrosoftIdp(t, api.URL, map[string]string{
+		"alice": `{ "id": "entra-user-1", "displayName": "Alice", "mail": "alice@school.example.com", "userPrincipalName": "alice@school.example.com" }`,
+	})
+
+	tokenUser01 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+	tokenUser02 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.w19_ESPa5AgLpH2IV9CrwSPq4XQcJJHgAqVf2bNmcOsdamLKJL_JntjmB-Er356m2bH4OlXg0hYagmU6irxwLymqB7QCGhsF1ixtMwyrGq8lFeePVwb41uD_5xRRkJxjB0GeIO_AIyCl9yCVa3djzcvx-6EBC1f_E9_t"
+
+	client := noRedirectClient(api)
+	request := func(method string, endpoint string, token string) *http.Response {
+		req, err := http.NewRequest(method, api.URL+endpoint, nil)
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := client.Do(req)
+		assert.Nil(err)
+		return resp
+	}
+
+	identities := func(token string) string {
+		resp := request("GET", "/auth/identities", token)
+		assert.Eq(resp.StatusCode, 200)
+		var identities []rest.IdentityData
+		err := json.NewDecoder(resp.Body).Decode(&identities)
+		assert.Nil(err)
+		providers := make([]string, len(identities))
+		for idx, identity := range identities {
+			providers[idx] = identity.Provider + ": " + identity.Email
+		}
+		return strings.Join(providers, ", ")
+	}
+
+	// Links are bound to the client that started them with a verifier
+	verifier := strings.Repeat("verifier-", 6)
+	hash := sha256.Sum256([]byte(verifier))
+	challenge := "?" + url.Values{"code_challenge": {base64.RawURLEncoding.EncodeToString(hash[:])}, "code_challenge_method": {"S256"}}.Encode()
+
+	// Returns the link code the callback redirected to the web app with.
+	link := func(token string, code string) string {
+		resp := request("POST", "/auth/identities/link/microsoft"+challenge, token)
+		assert.Eq(resp.StatusCode, 200)
+		var body struct {
+			Url string `json:"url"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&body)
+		assert.Nil(err)
+		location, err := url.Parse(body.Url)
+		assert.Nil(err)
+
+		resp, err = client.Get(api.URL + "/auth/microsoft/callback?" + url.Values{"state": {location.Query().Get("state")}, "code": {code}}.Encode())
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 307)
+		location, err = url.Parse(resp.Header.Get("Location"))
+		assert.Nil(err)
+		assert.True(strings.HasPrefix(location.Path, "/user/"), location.Path)
+		return location.Query().Get("link_code")
+	}
+
+	confirm := func(token string, code string, verifier string) int {
+		req, err := http.NewRequest("POST", api.URL+"/auth/identities/confirm-link", strings.NewReader(`{ "code": "`+code+`", "codeVerifier": "`+verifier+`" }`))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := client.Do(req)
+		assert.Nil(err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Eq(identities(tokenUser01), "google: test@example.com")
+
+	resp := request("POST", "/auth/identities/link/unknown", tokenUser01)
+	assert.Eq(resp.StatusCode, 404)
+
+	resp = request("POST", "/auth/identities/link/microsoft", tokenUser01)
+	assert.Eq(resp.StatusCode, 400)
+
+	// A link started by another user or client isn't confirmed
+	code := link(tokenUser01, "alice")
+	assert.Eq(confirm(tokenUser02, code, verifier), 403)
+	code = link(tokenUser01, "alice")
+	assert.Eq(confirm(tokenUser01, code, strings.Repeat("attacker-", 6)), 403)
+	assert.Eq(confirm(tokenUser01, code, verifier), 400)
+	assert.Eq(identities(tokenUser01), "google: test@example.com")
+
+	// Link a Microsoft account
+	assert.Eq(confirm(tokenUser01, link(tokenUser01, "alice"), verifier), 200)
+	assert.Eq(identities(tokenUser01), "google: test@example.com, microsoft: alice@school.example.com")
+
+	// Accounts can only be linked to one user
+	assert.Eq(confirm(tokenUser02, link(tokenUser02, "alice"), verifier), 409)
+
+	// The last account can't be unlinked
+	resp = request("POST", "/auth/identities/by-id/google-02/unlink", tokenUser02)
+	assert.Eq(resp.StatusCode, 409)
+	resp = request("POST", "/auth/identities/by-id/google-02/unlink", tokenUser01)
+	assert.Eq(resp.StatusCode, 404)
+
+	resp = request("POST", "/auth/identities/by-id/google-01/unlink", tokenUser01)
+	assert.Eq(resp.StatusCode, 200)
+	assert.Eq(identities(tokenUser01), "microsoft: alice@school.example.com")
+
+	// Signing in with the linked account signs in as the user
+	resp, err := client.Get(api.URL + "/auth/microsoft/login")
+	assert.Nil(err)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	assert.Nil(err)
+	resp, err = client.Get(api.URL + "/auth/microsoft/callback?" + url.Values{"state": {location.Query().Get("state")}, "code": {"alice"}}.Encode())
+	assert.Nil(err)
+	tokens, status := exchangeAuthCode(client, api.URL, authCodeFromRedirect(resp), "", "")
+	assert.Eq(status, 200)
+	user := getUserMe(client, api.URL, tokens.AccessToken)
+	assert.Eq(user.ID, "NnCaPHQLC9")
+}
@@ -0,0 +1,490 @@
+package rest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"ride_sharing_api/app/common"
+	"ride_sharing_api/app/utils"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// OpenID Connect providers (e.g. Keycloak or Authentik) of partner
+// organizations. Several providers can be configured at once:
+//
+//	RS_OIDC_PROVIDERS=partner-school,other
+//	RS_OIDC_PARTNER_SCHOOL_ISSUER=https://sso.partner.example.com/realms/school
+//	RS_OIDC_PARTNER_SCHOOL_CLIENT_ID=...
+//	RS_OIDC_PARTNER_SCHOOL_CLIENT_SECRET=...
+//	RS_OIDC_PARTNER_SCHOOL_REDIRECT_URL=https://.../auth/oidc/partner-school/callback
+//	RS_OIDC_PARTNER_SCHOOL_DISPLAY_NAME=Partner School (optional)
+//
+// The endpoints are read from the discovery document of the issuer.
+
+// How far the clocks of the API and a provider may drift apart.
+const oidcClockLeeway = time.Minute
+
+type oidcAuthProvider struct {
+	name         string
+	displayName  string
+	issuer       string
+	clientId     string
+	clientSecret string
+	redirectUrl  string
+
+	// Fetched on first use, see `discover`.
+	mutex     sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]crypto.PublicKey
+}
+
+// The parts of '.well-known/openid-configuration' that are used.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer" validate:"required"`
+	AuthorizationEndpoint string `json:"authorization_endpoint" validate:"required"`
+	TokenEndpoint         string `json:"token_endpoint" validate:"required"`
+	JwksUri               string `json:"jwks_uri" validate:"required"`
+}
+
+type oidcJwks struct {
+	Keys []oidcJwk `json:"keys"`
+}
+
+type oidcJwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type oidcTokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type oidcClaims struct {
+	Issuer            string        `json:"iss"`
+	Audience          oidcAudience  `json:"aud"`
+	Expiry            int64         `json:"exp"`
+	Subject           *string       `json:"sub"`
+	Email             *string       `json:"email"`
+	EmailVerified     *oidcFlexBool `json:"email_verified"`
+	Name              *string       `json:"name"`
+	PreferredUsername *string       `json:"preferred_username"`
+}
+
+// The 'aud' claim is either a single string or a list of strings.
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*a = []string{single}
+		return nil
+	}
+
+	var list []string
+	err := json.Unmarshal(data, &list)
+	*a = list
+	return err
+}
+
+// Some providers send 'email_verified' as the string "true" or "false".
+type oidcFlexBool bool
+
+func (b *oidcFlexBool) UnmarshalJSON(data []byte) error {
+	var value bool
+	if json.Unmarshal(data, &value) == nil {
+		*b = oidcFlexBool(value)
+		return nil
+	}
+
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return err
+	}
+
+	*b = oidcFlexBool(str == "true")
+	return nil
+}
+
+// Reads the providers listed in 'RS_OIDC_PROVIDERS'. Providers without an
+// issuer or client id are skipped.
+func oidcAuthProvidersFromEnv() []*oidcAuthProvider {
+	providers := make([]*oidcAuthProvider, 0)
+	for _, name := range strings.Split(utils.GetEnvOrDefault(common.ENV_OIDC_PROVIDERS, ""), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		env := func(key string) string {
+			envName := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+			return strings.TrimSpace(utils.GetEnvOrDefault(fmt.Sprintf(key, envName), ""))
+		}
+
+		provider := &oidcAuthProvider{
+			name:         name,
+			displayName:  env(common.ENV_OIDC_DISPLAY_NAME),
+			issuer:       strings.TrimSuffix(env(common.ENV_OIDC_ISSUER), "/"),
+			clientId:     env(common.ENV_OIDC_CLIENT_ID),
+			clientSecret: env(common.ENV_OIDC_CLIENT_SECRET),
+			redirectUrl:  env(common.ENV_OIDC_REDIRECT_URL),
+		}
+		if provider.issuer == "" || provider.clientId == "" {
+			log.Println("Error: OpenID Connect provider is missing an issuer or client id, skipping it.", "provider:", name)
+			continue
+		}
+		if provider.displayName == "" {
+			provider.displayName = name
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
+
+func (p *oidcAuthProvider) Id() string {
+	return AUTH_PROVIDER_OIDC_PREFIX + p.name
+}
+
+func (p *oidcAuthProvider) Name() string {
+	return p.displayName
+}
+
+func (p *oidcAuthProvider) Config(ctx context.Context) (*oauth2.Config, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Config{
+		RedirectURL:  p.redirectUrl,
+		ClientID:     p.clientId,
+		ClientSecret: p.clientSecret,
+		Scopes:       []string{"openid", "profile", "email"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}, nil
+}
+
+func (p *oidcAuthProvider) AuthCodeOptions() []oauth2.AuthCodeOption {
+	return []oauth2.AuthCodeOption{}
+}
+
+// The identity is read from the ID token, the user info endpoint isn't used.
+func (p *oidcAuthProvider) Identity(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*authIdentity, error) {
+	idToken, ok := token.Extra("id_token").(string)
+	if !ok || idToken == "" {
+		log.Println("Error: Token response has no ID token.", "provider:", p.Id())
+		return nil, fmt.Errorf("%s didn't send an ID token. This might be an issue with the OpenID Connect configuration.", p.Name())
+	}
+
+	claims, err := p.verifyIdToken(ctx, idToken)
+	if err != nil {
+		log.Println("Error: Invalid ID token received.", "provider:", p.Id(), "error:", err)
+		return nil, fmt.Errorf("Received an invalid ID token. %s might have sent invalid data.", p.Name())
+	}
+
+	profile := common.GoogleProfile{
+		Id:    claims.Subject,
+		Email: claims.Email,
+		Name:  claims.Name,
+	}
+	if profile.Name == nil {
+		profile.Name = claims.PreferredUsername
+	}
+	if claims.EmailVerified != nil {
+		verified := bool(*claims.EmailVerified)
+		profile.VerifiedEmail = &verified
+	}
+
+	return identityFromGoogleProfile(profile, p.Name())
+}
+
+// Fetches the discovery document of the issuer once. Failed fetches are
+// retried on the next login.
+func (p *oidcAuthProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	err := oidcFetchJSON(ctx, p.issuer+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	err = utils.Validate.Struct(discovery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid discovery document: %w", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("discovery document is for the issuer '%s', expected '%s'", discovery.Issuer, p.issuer)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// Gets the key the ID token was signed with. The keys are fetched again if
+// the provider rotated its keys.
+func (p *oidcAuthProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks oidcJwks
+	err = oidcFetchJSON(ctx, discovery.JwksUri, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Println("Error: Skipping invalid key of OpenID Connect provider.", "provider:", p.Id(), "kid:", jwk.Kid, "error:", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no key with the id '%s'", kid)
+	}
+
+	return key, nil
+}
+
+// Checks the signature, issuer, audience and expiry of the ID token.
+func (p *oidcAuthProvider) verifyIdToken(ctx context.Context, idToken string) (*oidcClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header oidcTokenHeader
+	err := oidcDecodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	err = oidcVerifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims oidcClaims
+	err = oidcDecodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Issuer != discovery.Issuer {
+		return nil, fmt.Errorf("token was issued by '%s'", claims.Issuer)
+	}
+
+	if !slices.Contains(claims.Audience, p.clientId) {
+		return nil, fmt.Errorf("token wasn't issued for this client, audience: %v", []string(claims.Audience))
+	}
+
+	if time.Unix(claims.Expiry, 0).Add(oidcClockLeeway).Before(time.Now()) {
+		return nil, errors.New("token expired")
+	}
+
+	return &claims, nil
+}
+
+func oidcVerifySignature(alg string, key crypto.PublicKey, signed []byte, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm '%s'", alg)
+	}
+
+	hasher := hash.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("key doesn't match the signing algorithm '%s'", alg)
+		}
+
+		return rsa.VerifyPKCS1v15(key, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("key doesn't match the signing algorithm '%s'", alg)
+		}
+
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+	default:
+		return errors.New("unsupported key type")
+	}
+}
+
+func (jwk oidcJwk) publicKey() (crypto.PublicKey, error) {
+	decode := func(value string) (*big.Int, error) {
+		data, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(data), nil
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", jwk.Crv)
+		}
+
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", jwk.Kty)
+	}
+}
+
+func oidcDecodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func oidcFetchJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get '%s': %w", url, err)
+	}
+
+	defer response.Body.Close()
+	contents, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", url, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get '%s', status: %d", url, response.StatusCode)
+	}
+
+	err = json.Unmarshal(contents, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse '%s': %w", url, err)
+	}
+
+	return nil
+}
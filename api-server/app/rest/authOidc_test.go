@@ -0,0 +1,212 @@
+package rest_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/utils"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/oauth2/jws"
+)
+
+type fakeOidcIdToken struct {
+	kid    string
+	key    *rsa.PrivateKey
+	claims jws.ClaimSet
+}
+
+// Starts a fake OpenID Connect provider. Every code is exchanged for the ID
+// token with the same key. Only the keys in `publish` are listed in the JWKS.
+type fakeOidcIdp struct {
+	url     string
+	mutex   sync.Mutex
+	publish map[string]*rsa.PrivateKey
+	tokens  map[string]fakeOidcIdToken
+}
+
+func newFakeOidcIdp(t *testing.T) *fakeOidcIdp {
+	fake := &fakeOidcIdp{publish: make(map[string]*rsa.PrivateKey), tokens: make(map[string]fakeOidcIdToken)}
+
+	idp := http.NewServeMux()
+	idp.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"issuer": "` + fake.url + `",
+			"authorization_endpoint": "` + fake.url + `/authorize",
+			"token_endpoint": "` + fake.url + `/token",
+			"jwks_uri": "` + fake.url + `/jwks"
+		}`))
+	})
+	idp.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+
+		keys := make([]map[string]string, 0)
+		for kid, key := range fake.publish {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		resp, err := json.Marshal(map[string]any{"keys": keys})
+		assert.Nil(err)
+		w.Write(resp)
+	})
+	idp.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(r.ParseForm())
+
+		fake.mutex.Lock()
+		token, ok := fake.tokens[r.PostForm.Get("code")]
+		fake.mutex.Unlock()
+		if !ok {
+			http.Error(w, `{ "error": "invalid_grant" }`, http.StatusBadRequest)
+			return
+		}
+
+		idToken, err := jws.Encode(&jws.Header{Algorithm: "RS256", Typ: "JWT", KeyID: token.kid}, &token.claims, token.key)
+		assert.Nil(err)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{ "access_token": "access", "token_type": "Bearer", "expires_in": 3600, "id_token": "` + idToken + `" }`))
+	})
+	idpServer := httptest.NewServer(idp)
+	t.Cleanup(idpServer.Close)
+
+	fake.url = idpServer.URL
+	return fake
+}
+
+func TestHandleAuthOidc(t *testing.T) {
+	idp := newFakeOidcIdp(t)
+
+	t.Setenv("RS_OIDC_PROVIDERS", "partner-school, broken")
+	t.Setenv("RS_OIDC_PARTNER_SCHOOL_ISSUER", idp.url)
+	t.Setenv("RS_OIDC_PARTNER_SCHOOL_CLIENT_ID", "client")
+	t.Setenv("RS_OIDC_PARTNER_SCHOOL_CLIENT_SECRET", "secret")
+	t.Setenv("RS_OIDC_PARTNER_SCHOOL_REDIRECT_URL", "http://localhost/auth/oidc/partner-school/callback")
+	t.Setenv("RS_OIDC_PARTNER_SCHOOL_DISPLAY_NAME", "Partner School")
+	t.Setenv("RS_OIDC_BROKEN_ISSUER", idp.url+"/missing")
+	t.Setenv("RS_OIDC_BROKEN_CLIENT_ID", "client")
+
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0020-auth-oidc.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	client := noRedirectClient(api)
+	get := func(endpoint string) *http.Response {
+		resp, err := client.Get(api.URL + endpoint)
+		assert.Nil(err)
+		return resp
+	}
+
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(err)
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(err)
+	idp.publish["key-1"] = key1
+
+	claims := func(sub string, audience string, verified any, expiry time.Time) jws.ClaimSet {
+		return jws.ClaimSet{
+			Iss: idp.url,
+			Aud: audience,
+			Sub: sub,
+			Exp: expiry.Unix(),
+			PrivateClaims: map[string]any{
+				"email":          sub + "@partner.example.com",
+				"email_verified": verified,
+				"name":           "User " + sub,
+			},
+		}
+	}
+	valid := time.Now().Add(time.Hour)
+	idp.tokens["alice"] = fakeOidcIdToken{kid: "key-1", key: key1, claims: claims("alice", "client", true, valid)}
+	idp.tokens["verified-str"] = fakeOidcIdToken{kid: "key-1", key: key1, claims: claims("bob", "client", "true", valid)}
+	idp.tokens["unverified"] = fakeOidcIdToken{kid: "key-1", key: key1, claims: claims("carol", "client", false, valid)}
+	idp.tokens["wrong-aud"] = fakeOidcIdToken{kid: "key-1", key: key1, claims: claims("alice", "other", true, valid)}
+	idp.tokens["expired"] = fakeOidcIdToken{kid: "key-1", key: key1, claims: claims("alice", "client", true, time.Now().Add(-time.Hour))}
+	idp.tokens["bad-signature"] = fakeOidcIdToken{kid: "key-1", key: key2, claims: claims("alice", "client", true, valid)}
+	idp.tokens["rotated"] = fakeOidcIdToken{kid: "key-2", key: key2, claims: claims("alice", "client", true, valid)}
+
+	// Only reachable providers are listed
+	resp := get("/auth/providers")
+	assert.Eq(resp.StatusCode, 200)
+	var providers []rest.AuthProviderData
+	err = json.NewDecoder(resp.Body).Decode(&providers)
+	assert.Nil(err)
+	assert.Eq(providers[len(providers)-1], rest.AuthProviderData{Id: "oidc/partner-school", Name: "Partner School"})
+	for _, provider := range providers {
+		assert.Neq(provider.Id, "oidc/broken")
+	}
+
+	resp = get("/auth/oidc/broken/login")
+	assert.Eq(resp.StatusCode, 502)
+	resp = get("/auth/oidc/unknown/login")
+	assert.Eq(resp.StatusCode, 404)
+
+	login := func() string {
+		resp := get("/auth/oidc/partner-school/login")
+		assert.Eq(resp.StatusCode, 307)
+		location, err := url.Parse(resp.Header.Get("Location"))
+		assert.Nil(err)
+		assert.Eq(location.Scheme+"://"+location.Host+location.Path, idp.url+"/authorize")
+		assert.Eq(location.Query().Get("client_id"), "client")
+		assert.Eq(location.Query().Get("scope"), "openid profile email")
+		return location.Query().Get("state")
+	}
+
+	callback := func(code string) *http.Response {
+		return get("/auth/oidc/partner-school/callback?" + url.Values{"state": {login()}, "code": {code}}.Encode())
+	}
+
+	accessToken := func(resp *http.Response) string {
+		tokens, status := exchangeAuthCode(client, api.URL, authCodeFromRedirect(resp), "", "")
+		assert.Eq(status, 200)
+		return tokens.AccessToken
+	}
+
+	// Claims are mapped to the user
+	user := getUserMe(client, api.URL, accessToken(callback("alice")))
+	assert.Eq(user.Name, "User alice")
+	assert.Eq(user.Email, "alice@partner.example.com")
+	assert.Eq(user.Provider, "oidc/partner-school")
+
+	user = getUserMe(client, api.URL, accessToken(callback("verified-str")))
+	assert.Eq(user.Email, "bob@partner.example.com")
+
+	// Invalid tokens and profiles
+	resp = callback("unverified")
+	assert.Eq(resp.StatusCode, 400)
+	resp = callback("wrong-aud")
+	assert.Eq(resp.StatusCode, 400)
+	resp = callback("expired")
+	assert.Eq(resp.StatusCode, 400)
+	resp = callback("bad-signature")
+	assert.Eq(resp.StatusCode, 400)
+
+	// Keys the provider rotated to are fetched again
+	resp = callback("rotated")
+	assert.Eq(resp.StatusCode, 400)
+
+	idp.mutex.Lock()
+	idp.publish["key-2"] = key2
+	idp.mutex.Unlock()
+
+	again := getUserMe(client, api.URL, accessToken(callback("rotated")))
+	assert.Eq(again.ID, getUserMe(client, api.URL, accessToken(callback("alice"))).ID)
+}
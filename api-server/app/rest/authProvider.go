@@ -0,0 +1,538 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/common"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// How long a login started at one of the providers stays valid.
+const oauthStateLifetime = 5 * time.Minute
+
+// An OAuth provider users can sign in with. Every provider gets the routes
+// '/auth/{id}/login' and '/auth/{id}/callback'.
+type AuthProvider interface {
+	// Stored with the identities of the provider and used in routes.
+	Id() string
+	// Shown to users.
+	Name() string
+	// Returns `errAuthProviderNotConfigured` if the provider isn't configured.
+	// Other errors mean the provider is currently unavailable.
+	Config(ctx context.Context) (*oauth2.Config, error)
+	AuthCodeOptions() []oauth2.AuthCodeOption
+	// Gets the identity of the user the token was issued to. Errors are sent
+	// to the client as is.
+	Identity(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*authIdentity, error)
+}
+
+// A user as identified by an auth provider. The subject is unique per provider.
+type authIdentity struct {
+	Subject string
+	Name    string
+	Email   string
+}
+
+var errAuthProviderNotConfigured = errors.New("Auth provider isn't configured.")
+
+// The providers users can sign in with. The OpenID Connect providers are read
+// from the environment.
+func newAuthProviders() []AuthProvider {
+	providers := []AuthProvider{googleAuthProvider{}, microsoftAuthProvider{}}
+	for _, provider := range oidcAuthProvidersFromEnv() {
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
+
+func authProviderHandlers(h *http.ServeMux) {
+	for _, provider := range state.authProviders {
+		h.HandleFunc(fmt.Sprintf("GET /auth/%s/login", provider.Id()), oauthLogin(provider))
+		h.HandleFunc(fmt.Sprintf("GET /auth/%s/callback", provider.Id()), oauthCallback(provider))
+	}
+
+	h.HandleFunc("GET /auth/providers", getAuthProviders)
+	h.HandleFunc("GET /auth/identities", handle(getIdentities).with(bearerAuth(false)).build())
+	h.HandleFunc("POST /auth/identities/link/{provider...}", handle(linkIdentity).with(bearerAuth(false)).build())
+	h.HandleFunc("POST /auth/identities/confirm-link", handle(confirmIdentityLink).with(bearerAuth(false)).build())
+	h.HandleFunc("POST /auth/identities/by-id/{id}/unlink", handle(unlinkIdentity).with(bearerAuth(false)).build())
+}
+
+type AuthProviderData struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type IdentityData struct {
+	IdentityId string `json:"identityId"`
+	Provider   string `json:"provider"`
+	Email      string `json:"email"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+type linkIdentityResponse struct {
+	Url string `json:"url"`
+}
+
+type confirmIdentityLinkParams struct {
+	Code         *string `json:"code" validate:"required"`
+	CodeVerifier *string `json:"codeVerifier" validate:"required,min=43,max=128"`
+}
+
+func authProviderById(id string) (AuthProvider, bool) {
+	for _, provider := range state.authProviders {
+		if provider.Id() == id {
+			return provider, true
+		}
+	}
+
+	return nil, false
+}
+
+// Status and message for an error returned by `AuthProvider.Config`.
+func authProviderConfigErr(provider AuthProvider, err error) (int, string) {
+	if errors.Is(err, errAuthProviderNotConfigured) {
+		return http.StatusNotFound, fmt.Sprintf("Login with %s isn't configured.", provider.Name())
+	}
+
+	log.Println("Error: Auth provider is unavailable.", "provider:", provider.Id(), "error:", err)
+	return http.StatusBadGateway, fmt.Sprintf("Login with %s is currently unavailable.", provider.Name())
+}
+
+func oauthLogin(provider AuthProvider) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config, err := provider.Config(r.Context())
+		if err != nil {
+			status, msg := authProviderConfigErr(provider, err)
+			http.Error(w, msg, status)
+			return
+		}
+
+		codeChallenge, ok := parseCodeChallenge(r)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Invalid 'code_challenge' parameter. Only the method '%s' is supported.", codeChallengeMethod), http.StatusBadRequest)
+			return
+		}
+
+		oauthState, err := newOauthState(r.Context(), sql.NullString{}, codeChallenge)
+		if err != nil {
+			log.Println("Error: Failed to store login state.", "provider:", provider.Id(), "error:", err)
+			http.Error(w, "Failed to start login.", http.StatusInternalServerError)
+			return
+		}
+
+		url := config.AuthCodeURL(oauthState, provider.AuthCodeOptions()...)
+		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	}
+}
+
+func oauthCallback(provider AuthProvider) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config, err := provider.Config(r.Context())
+		if err != nil {
+			status, msg := authProviderConfigErr(provider, err)
+			http.Error(w, msg, status)
+			return
+		}
+
+		request, ok := consumeOauthState(r.Context(), r.FormValue("state"))
+		if !ok {
+			http.Error(w, fmt.Sprintf("Invalid 'state' parameter. Make sure authentication request are only started from '/auth/%s/login'.", provider.Id()), http.StatusBadRequest)
+			return
+		}
+
+		// the user declined or the provider doesn't allow the app
+		if errCode := r.FormValue("error"); errCode != "" {
+			log.Println("Error: Authentication at provider failed.", "provider:", provider.Id(), "error:", errCode, "description:", r.FormValue("error_description"))
+			http.Error(w, fmt.Sprintf("Authentication with %s failed.", provider.Name()), http.StatusBadRequest)
+			return
+		}
+
+		token, err := config.Exchange(r.Context(), r.FormValue("code"))
+		if err != nil {
+			log.Println("Error: Failed to exchange auth codes.", "provider:", provider.Id(), "error:", err)
+			http.Error(w, fmt.Sprintf("Error during code exchange. Make sure this request was started from '/auth/%s/login'.", provider.Id()), http.StatusBadRequest)
+			return
+		}
+
+		identity, err := provider.Identity(r.Context(), config, token)
+		if err != nil {
+			log.Println("Error: Failed to get user identity.", "provider:", provider.Id(), "error:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if request.LinkUserID.Valid {
+			oauthLink(w, r, provider, *identity, request)
+			return
+		}
+
+		oauthSignIn(w, r, provider, *identity, request)
+	}
+}
+
+// Signs in the user the identity belongs to. Identities that aren't linked
+// to a user yet create a new user, unless a user with the same email exists.
+// They have to sign in with their existing account and link this one instead.
+// Only the email of the identity is updated, not the one of the user.
+// Redirects to the web app with a code the web app exchanges for tokens, see
+// `exchangeAuthCode`.
+func oauthSignIn(w http.ResponseWriter, r *http.Request, provider AuthProvider, identity authIdentity, request sqlc.OauthState) {
+	argsIdentity := sqlc.UsersGetIdentityParams{
+		Provider: provider.Id(),
+		Subject:  identity.Subject,
+	}
+	existing, err := state.queries.UsersGetIdentity(r.Context(), argsIdentity)
+
+	var user sqlc.User
+	if errors.Is(err, sql.ErrNoRows) {
+		count, err := state.queries.UsersCountByEmail(r.Context(), identity.Email)
+		if err != nil {
+			log.Println("Error: Failed to get users by email.", err.Error(), "user email:", identity.Email)
+			http.Error(w, "Failed to get user.", http.StatusInternalServerError)
+			return
+		}
+
+		if count > 0 {
+			http.Error(w, fmt.Sprintf("A user with the email '%s' already exists. Sign in with the account you used before and link your %s account from your profile.", identity.Email, provider.Name()), http.StatusConflict)
+			return
+		}
+
+		user, err = createUserWithIdentity(r.Context(), provider, identity)
+		if err != nil {
+			log.Println(fmt.Sprintf("Error: Failed to create user after %s authentication.", provider.Name()), err.Error(), "user email:", identity.Email)
+			http.Error(w, fmt.Sprintf("Failed to create user after %s authentication.", provider.Name()), http.StatusInternalServerError)
+			return
+		}
+	} else if err != nil {
+		log.Println("Error: Failed to get user identity from database.", err.Error(), "provider:", provider.Id(), "subject:", identity.Subject)
+		http.Error(w, "Failed to get user.", http.StatusInternalServerError)
+		return
+	} else {
+		// the user keeps their name and primary email, whichever identity
+		// they sign in with
+		err = state.queries.UsersUpdateIdentityEmail(r.Context(), sqlc.UsersUpdateIdentityEmailParams{Email: identity.Email, ID: existing.ID})
+		if err == nil {
+			user, err = state.queries.UsersGetById(r.Context(), existing.UserID)
+		}
+		if err != nil {
+			log.Println("Error: Failed to update user identity.", err.Error(), "user id:", existing.UserID, "identity email:", identity.Email)
+			http.Error(w, "Failed to update user data.", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	code, err := createAuthCode(r.Context(), user.ID, request.CodeChallenge)
+	if err != nil {
+		log.Println("Error: Failed to create auth code.", err.Error(), "user id:", user.ID, "user email:", user.Email)
+		http.Error(w, "Failed to sign in.", http.StatusInternalServerError)
+		return
+	}
+
+	url := fmt.Sprintf("%s?%s", clientUrlAuth, url.Values{"code": {code}}.Encode())
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+}
+
+// Redirects to the profile of the user who started the link with a one-time
+// code. The identity is only linked once the web app that started the link
+// confirms the code with its verifier, see `confirmIdentityLink`, so a link
+// started by someone else can't add an account to their user.
+func oauthLink(w http.ResponseWriter, r *http.Request, provider AuthProvider, identity authIdentity, request sqlc.OauthState) {
+	if !request.CodeChallenge.Valid {
+		http.Error(w, "Linking an account requires a code challenge. Start linking the account again.", http.StatusBadRequest)
+		return
+	}
+
+	err := state.queries.IdentityLinkCodesDeleteExpired(r.Context(), time.Now().Add(-authCodeLifetime).UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Println("Error: Failed to delete expired identity link codes.", "error:", err)
+	}
+
+	code := genRandBase64(32)
+	args := sqlc.IdentityLinkCodesCreateParams{
+		CodeHash:      hashToken(code),
+		UserID:        request.LinkUserID.String,
+		Provider:      provider.Id(),
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		CodeChallenge: request.CodeChallenge.String,
+	}
+	err = state.queries.IdentityLinkCodesCreate(r.Context(), args)
+	if err != nil {
+		log.Println("Error: Failed to store identity link code.", err.Error(), "provider:", provider.Id(), "user id:", request.LinkUserID.String)
+		http.Error(w, "Failed to link account.", http.StatusInternalServerError)
+		return
+	}
+
+	url := fmt.Sprintf("%s/user/%s?%s", utils.GetEnvRequired(common.ENV_WEB_APP_URL), request.LinkUserID.String, url.Values{"link_code": {code}}.Encode())
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+}
+
+func createUserWithIdentity(ctx context.Context, provider AuthProvider, identity authIdentity) (sqlc.User, error) {
+	tx, err := state.getDBTx(ctx)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	defer tx.Rollback()
+
+	queriesTx := state.queries.WithTx(tx)
+
+	user, err := queriesTx.UsersCreate(ctx, sqlc.UsersCreateParams{ID: genUserId(), Name: identity.Name, Email: identity.Email, Provider: provider.Id()})
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	argsIdentity := sqlc.UsersCreateIdentityParams{
+		UserID:   user.ID,
+		Provider: provider.Id(),
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	_, err = queriesTx.UsersCreateIdentity(ctx, argsIdentity)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+// Lists the providers users can currently sign in with.
+func getAuthProviders(w http.ResponseWriter, r *http.Request) {
+	providers := make([]AuthProviderData, 0, len(state.authProviders))
+	for _, provider := range state.authProviders {
+		_, err := provider.Config(r.Context())
+		if err != nil {
+			continue
+		}
+
+		providers = append(providers, AuthProviderData{Id: provider.Id(), Name: provider.Name()})
+	}
+
+	resp, err := json.Marshal(providers)
+	assert.Nil(err, "Failed to serialize auth providers.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+func getIdentities(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	rows, err := state.queries.UsersGetIdentities(r.Context(), user.ID)
+	assert.Nil(err)
+
+	identities := make([]IdentityData, len(rows))
+	for idx, row := range rows {
+		identities[idx] = IdentityData{
+			IdentityId: row.ID,
+			Provider:   row.Provider,
+			Email:      row.Email,
+			CreatedAt:  row.CreatedAt,
+		}
+	}
+
+	resp, err := json.Marshal(identities)
+	assert.Nil(err, "Failed to serialize identities.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+// Starts linking an account of a provider to the user. The client has to send
+// the 'code_challenge' of a verifier and open the returned URL, the provider
+// redirects back to '/auth/{provider}/callback'.
+func linkIdentity(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	provider, ok := authProviderById(r.PathValue("provider"))
+	if !ok {
+		httpWriteErr(w, http.StatusNotFound, "No login provider exists with 'provider'.")
+		return
+	}
+
+	codeChallenge, ok := parseCodeChallenge(r)
+	if !ok || !codeChallenge.Valid {
+		httpWriteErr(w, http.StatusBadRequest, fmt.Sprintf("Missing or invalid 'code_challenge' parameter. Only the method '%s' is supported.", codeChallengeMethod))
+		return
+	}
+
+	config, err := provider.Config(r.Context())
+	if err != nil {
+		status, msg := authProviderConfigErr(provider, err)
+		httpWriteErr(w, status, msg)
+		return
+	}
+
+	oauthState, err := newOauthState(r.Context(), sql.NullString{String: user.ID, Valid: true}, codeChallenge)
+	if err != nil {
+		log.Println("Error: Failed to store login state.", "provider:", provider.Id(), "error:", err)
+		httpWriteErr(w, http.StatusInternalServerError, "Failed to start linking account.")
+		return
+	}
+
+	url := config.AuthCodeURL(oauthState, provider.AuthCodeOptions()...)
+
+	resp, err := json.Marshal(linkIdentityResponse{Url: url})
+	assert.Nil(err, "Failed to serialize link identity response.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+// Links the identity of a code from `oauthLink` to the user. Only the user who
+// started the link can confirm it, with the verifier of the code challenge.
+func confirmIdentityLink(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error: Invalid request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+
+	var params confirmIdentityLinkParams
+	err = json.Unmarshal(data, &params)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
+		return
+	}
+
+	err = utils.Validate.Struct(params)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", err.Error())
+		return
+	}
+
+	args := sqlc.IdentityLinkCodesConsumeParams{
+		CodeHash:     hashToken(*params.Code),
+		CreatedAfter: time.Now().Add(-authCodeLifetime).UTC().Format(time.RFC3339),
+	}
+	code, err := state.queries.IdentityLinkCodesConsume(r.Context(), args)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpWriteErr(w, http.StatusBadRequest, "Invalid or expired code.")
+		return
+	}
+	assert.Nil(err)
+
+	if code.UserID != user.ID || !verifyCodeChallenge(*params.CodeVerifier, code.CodeChallenge) {
+		httpWriteErr(w, http.StatusForbidden, "The link was started by another user or client.")
+		return
+	}
+
+	provider, ok := authProviderById(code.Provider)
+	assert.True(ok, "Unknown auth provider of identity link code.", "provider:", code.Provider)
+
+	argsIdentity := sqlc.UsersGetIdentityParams{
+		Provider: code.Provider,
+		Subject:  code.Subject,
+	}
+	existing, err := state.queries.UsersGetIdentity(r.Context(), argsIdentity)
+	if err == nil && existing.UserID != user.ID {
+		httpWriteErr(w, http.StatusConflict, fmt.Sprintf("This %s account is already linked to another user.", provider.Name()))
+		return
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		argsCreate := sqlc.UsersCreateIdentityParams{
+			UserID:   user.ID,
+			Provider: code.Provider,
+			Subject:  code.Subject,
+			Email:    code.Email,
+		}
+		_, err = state.queries.UsersCreateIdentity(r.Context(), argsCreate)
+	}
+	if err != nil {
+		log.Println("Error: Failed to link user identity.", err.Error(), "provider:", code.Provider, "user id:", user.ID)
+		httpWriteErr(w, http.StatusInternalServerError, "Failed to link account.")
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func unlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	// Locking so two requests can't remove the last two identities at once.
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	count, err := state.queries.UsersCountIdentities(r.Context(), user.ID)
+	assert.Nil(err)
+
+	if count <= 1 {
+		httpWriteErr(w, http.StatusConflict, "Can't unlink the last account you can sign in with.")
+		return
+	}
+
+	args := sqlc.UsersDeleteIdentityParams{
+		ID:     r.PathValue("id"),
+		UserID: user.ID,
+	}
+	deleted, err := state.queries.UsersDeleteIdentity(r.Context(), args)
+	assert.Nil(err)
+
+	if deleted == 0 {
+		httpWriteErr(w, http.StatusNotFound, "No linked account exists with 'id'.")
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+// Creates the 'state' parameter for a login at an auth provider. The
+// callback only accepts states created here, see `consumeOauthState`. Expired
+// states are cleaned up.
+func newOauthState(ctx context.Context, linkUserId sql.NullString, codeChallenge sql.NullString) (string, error) {
+	err := state.queries.OauthStatesDeleteExpired(ctx, time.Now().Add(-oauthStateLifetime).UTC().Format(time.RFC3339))
+	if err != nil {
+		return "", err
+	}
+
+	oauthState := genRandBase64(64)
+	args := sqlc.OauthStatesCreateParams{
+		State:         oauthState,
+		LinkUserID:    linkUserId,
+		CodeChallenge: codeChallenge,
+	}
+	return oauthState, state.queries.OauthStatesCreate(ctx, args)
+}
+
+// Checks if `oauthState` was created by `newOauthState` and hasn't expired
+// yet. A state can only be used once.
+func consumeOauthState(ctx context.Context, oauthState string) (sqlc.OauthState, bool) {
+	args := sqlc.OauthStatesConsumeParams{
+		State:        oauthState,
+		CreatedAfter: time.Now().Add(-oauthStateLifetime).UTC().Format(time.RFC3339),
+	}
+	request, err := state.queries.OauthStatesConsume(ctx, args)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.OauthState{}, false
+	}
+	assert.Nil(err)
+
+	return request, true
+}
+
+// Ids of users are no longer the ids of their accounts at a provider, a user
+// can have accounts at several providers.
+func genUserId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
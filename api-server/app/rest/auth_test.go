@@ -0,0 +1,338 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type testAuthTokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Signs in with the fake Microsoft identity provider, see `useFakeMicrosoftIdp`.
+func signInMicrosoft(client *http.Client, apiUrl string, code string) testAuthTokens {
+	resp, err := client.Get(apiUrl + "/auth/microsoft/login")
+	assert.Nil(err)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	assert.Nil(err)
+
+	resp, err = client.Get(apiUrl + "/auth/microsoft/callback?" + url.Values{"state": {location.Query().Get("state")}, "code": {code}}.Encode())
+	assert.Nil(err)
+	assert.Eq(resp.StatusCode, 307)
+
+	tokens, status := exchangeAuthCode(client, apiUrl, authCodeFromRedirect(resp), "", "")
+	assert.Eq(status, 200)
+	return tokens
+}
+
+// The one-time code the web app is redirected to with after a login.
+func authCodeFromRedirect(resp *http.Response) string {
+	assert.Eq(resp.StatusCode, 307)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	assert.Nil(err)
+	assert.Eq(location.Path, "/authenticate")
+	assert.Eq(location.Query().Get("accessToken"), "")
+	assert.Eq(location.Query().Get("refreshToken"), "")
+	code := location.Query().Get("code")
+	assert.Neq(code, "")
+	return code
+}
+
+func exchangeAuthCode(client *http.Client, apiUrl string, code string, codeVerifier string, userAgent string) (testAuthTokens, int) {
+	body, err := json.Marshal(struct {
+		Code         string `json:"code"`
+		CodeVerifier string `json:"codeVerifier,omitempty"`
+	}{code, codeVerifier})
+	assert.Nil(err)
+
+	req, err := http.NewRequest("POST", apiUrl+"/auth/exchange", bytes.NewReader(body))
+	assert.Nil(err)
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := client.Do(req)
+	assert.Nil(err)
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return testAuthTokens{}, resp.StatusCode
+	}
+
+	var tokens testAuthTokens
+	assert.Nil(json.NewDecoder(resp.Body).Decode(&tokens))
+	return tokens, resp.StatusCode
+}
+
+func accessTokenKeyId(token string) string {
+	header, _, _ := strings.Cut(token, ".")
+	data, err := base64.RawURLEncoding.DecodeString(header)
+	assert.Nil(err)
+	var parsed struct {
+		Kid string `json:"kid"`
+	}
+	assert.Nil(json.Unmarshal(data, &parsed))
+	return parsed.Kid
+}
+
+func TestAccessTokens(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0022-auth-tokens.sql"))
+
+	var api *httptest.Server
+	// Restarting the API forgets the cached keys
+	start := func() {
+		if api != nil {
+			api.Close()
+		}
+		api = httptest.NewServer(rest.NewRESTApi(db))
+	}
+	start()
+	defer func() { api.Close() }()
+
+	tokenUser01 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+
+	status := func(token string) int {
+		req, err := http.NewRequest("GET", api.URL+"/users/me", nil)
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		return resp.StatusCode
+	}
+
+	assert.Eq(status(tokenUser01), 200)
+	assert.Eq(accessTokenKeyId(tokenUser01), "test-key-01")
+
+	// Changed tokens are rejected
+	header, sealed, _ := strings.Cut(tokenUser01, ".")
+	flipped := []byte(sealed)
+	flipped[len(flipped)/2] ^= 1
+	assert.Eq(status(header+"."+string(flipped)), 401)
+
+	otherHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"A256GCM","kid":"other-key"}`))
+	assert.Eq(status(otherHeader+"."+sealed), 401)
+	assert.Eq(status("not-a-token"), 401)
+
+	// Tokens of the previous key stay valid during the rotation window
+	queries := sqlc.New(db)
+	key, err := rest.RotateAuthKeys(context.Background(), queries, time.Hour)
+	assert.Nil(err)
+	start()
+
+	useFakeMicrosoftIdp(t, api.URL, map[string]string{
+		"alice": `{ "id": "entra-user-1", "displayName": "Alice", "mail": "alice@example.com", "userPrincipalName": "alice@example.com" }`,
+	})
+	client := noRedirectClient(api)
+
+	alice := signInMicrosoft(client, api.URL, "alice")
+	assert.Eq(accessTokenKeyId(alice.AccessToken), key.ID)
+	assert.Eq(status(alice.AccessToken), 200)
+	assert.Eq(status(tokenUser01), 200)
+
+	// Retired keys can't be used anymore
+	key, err = rest.RotateAuthKeys(context.Background(), queries, 0)
+	assert.Nil(err)
+	start()
+	useFakeMicrosoftIdp(t, api.URL, map[string]string{
+		"alice": `{ "id": "entra-user-1", "displayName": "Alice", "mail": "alice@example.com", "userPrincipalName": "alice@example.com" }`,
+	})
+	client = noRedirectClient(api)
+
+	assert.Eq(status(tokenUser01), 401)
+	assert.Eq(status(alice.AccessToken), 401)
+
+	alice = signInMicrosoft(client, api.URL, "alice")
+	assert.Eq(accessTokenKeyId(alice.AccessToken), key.ID)
+	assert.Eq(status(alice.AccessToken), 200)
+
+	keys, err := queries.AuthKeysGetValid(context.Background())
+	assert.Nil(err)
+	assert.Eq(len(keys), 1)
+}
+
+func TestRefreshTokenReuse(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0023-refresh-token-reuse.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	useFakeMicrosoftIdp(t, api.URL, map[string]string{
+		"alice": `{ "id": "entra-user-1", "displayName": "Alice", "mail": "alice@example.com", "userPrincipalName": "alice@example.com" }`,
+	})
+	client := noRedirectClient(api)
+
+	refresh := func(refreshToken string) (testAuthTokens, int) {
+		resp, err := client.Post(api.URL+"/auth/refresh", "application/json", strings.NewReader(`{ "refreshToken": "`+refreshToken+`" }`))
+		assert.Nil(err)
+		if resp.StatusCode != 200 {
+			return testAuthTokens{}, resp.StatusCode
+		}
+
+		var tokens testAuthTokens
+		assert.Nil(json.NewDecoder(resp.Body).Decode(&tokens))
+		return tokens, resp.StatusCode
+	}
+
+	status := func(token string) int {
+		req, err := http.NewRequest("GET", api.URL+"/users/me", nil)
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := client.Do(req)
+		assert.Nil(err)
+		return resp.StatusCode
+	}
+
+	first := signInMicrosoft(client, api.URL, "alice")
+	other := signInMicrosoft(client, api.URL, "alice")
+
+	// Refresh tokens are rotated
+	second, code := refresh(first.RefreshToken)
+	assert.Eq(code, 200)
+	assert.Neq(second.RefreshToken, first.RefreshToken)
+	assert.Eq(status(first.AccessToken), 401)
+	assert.Eq(status(second.AccessToken), 200)
+
+	_, code = refresh("unknown")
+	assert.Eq(code, 401)
+
+	// Clients that refresh twice at once aren't signed out
+	_, code = refresh(first.RefreshToken)
+	assert.Eq(code, 401)
+	assert.Eq(status(second.AccessToken), 200)
+
+	// Reusing a refresh token later signs out the session
+	_, err := db.Exec("UPDATE used_refresh_tokens SET used_at = ?", time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))
+	assert.Nil(err)
+	_, code = refresh(first.RefreshToken)
+	assert.Eq(code, 401)
+	assert.Eq(status(second.AccessToken), 401)
+	_, code = refresh(second.RefreshToken)
+	assert.Eq(code, 401)
+
+	usedCount := func() int {
+		var count int
+		assert.Nil(db.QueryRow("SELECT COUNT(*) FROM used_refresh_tokens").Scan(&count))
+		return count
+	}
+
+	// The used refresh tokens of signed out sessions are deleted
+	assert.Eq(usedCount(), 0)
+
+	// Other sessions aren't affected
+	assert.Eq(status(other.AccessToken), 200)
+
+	// Used refresh tokens are forgotten once they would have expired anyway
+	expired := time.Now().Add(-31 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	_, err = db.Exec("INSERT INTO used_refresh_tokens (token_hash, session_id, used_at) VALUES ('expired', 'signed-out', ?)", expired)
+	assert.Nil(err)
+	third, code := refresh(other.RefreshToken)
+	assert.Eq(code, 200)
+	assert.Eq(usedCount(), 1)
+
+	// Refresh tokens expire
+	_, err = db.Exec("UPDATE sessions SET refreshed_at = ?", expired)
+	assert.Nil(err)
+	_, code = refresh(third.RefreshToken)
+	assert.Eq(code, 401)
+}
+
+func TestHandleAuthCodeExchange(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0025-auth-codes.sql"))
+
+	api := httptest.NewServer(rest.NewRESTApi(db))
+	defer api.Close()
+
+	useFakeMicrosoftIdp(t, api.URL, map[string]string{
+		"alice": `{ "id": "entra-user-1", "displayName": "Alice", "mail": "alice@example.com", "userPrincipalName": "alice@example.com" }`,
+	})
+	client := noRedirectClient(api)
+
+	login := func(apiUrl string, query string) string {
+		resp, err := client.Get(apiUrl + "/auth/microsoft/login" + query)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 307)
+		location, err := url.Parse(resp.Header.Get("Location"))
+		assert.Nil(err)
+		return location.Query().Get("state")
+	}
+
+	callback := func(apiUrl string, state string) string {
+		resp, err := client.Get(apiUrl + "/auth/microsoft/callback?" + url.Values{"state": {state}, "code": {"alice"}}.Encode())
+		assert.Nil(err)
+		return authCodeFromRedirect(resp)
+	}
+
+	// Codes can only be exchanged once
+	code := callback(api.URL, login(api.URL, ""))
+	tokens, status := exchangeAuthCode(client, api.URL, code, "", "")
+	assert.Eq(status, 200)
+	assert.Eq(getUserMe(client, api.URL, tokens.AccessToken).Email, "alice@example.com")
+	_, status = exchangeAuthCode(client, api.URL, code, "", "")
+	assert.Eq(status, 401)
+	_, status = exchangeAuthCode(client, api.URL, "unknown", "", "")
+	assert.Eq(status, 401)
+
+	// Expired codes are rejected
+	code = callback(api.URL, login(api.URL, ""))
+	_, err := db.Exec("UPDATE auth_codes SET created_at = ?", time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))
+	assert.Nil(err)
+	_, status = exchangeAuthCode(client, api.URL, code, "", "")
+	assert.Eq(status, 401)
+
+	// Codes bound to a verifier can only be exchanged with the verifier
+	verifier := strings.Repeat("verifier-", 6)
+	hash := sha256.Sum256([]byte(verifier))
+	challenge := "?" + url.Values{"code_challenge": {base64.RawURLEncoding.EncodeToString(hash[:])}, "code_challenge_method": {"S256"}}.Encode()
+
+	code = callback(api.URL, login(api.URL, challenge))
+	_, status = exchangeAuthCode(client, api.URL, code, "", "")
+	assert.Eq(status, 401)
+	code = callback(api.URL, login(api.URL, challenge))
+	_, status = exchangeAuthCode(client, api.URL, code, strings.Repeat("attacker-", 6), "")
+	assert.Eq(status, 401)
+	_, status = exchangeAuthCode(client, api.URL, code, verifier, "")
+	assert.Eq(status, 401)
+	code = callback(api.URL, login(api.URL, challenge))
+	_, status = exchangeAuthCode(client, api.URL, code, verifier, "")
+	assert.Eq(status, 200)
+
+	for _, query := range []string{"?code_challenge=invalid", "?code_challenge_method=plain&code_challenge=" + verifier, "?code_challenge_method=S256"} {
+		resp, err := client.Get(api.URL + "/auth/microsoft/login" + query)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 400, query)
+	}
+
+	// Logins and codes survive a restart
+	state := login(api.URL, "")
+	restarted := httptest.NewServer(rest.NewRESTApi(db))
+	defer func() { restarted.Close() }()
+	code = callback(restarted.URL, state)
+
+	restarted.Close()
+	restarted = httptest.NewServer(rest.NewRESTApi(db))
+	_, status = exchangeAuthCode(client, restarted.URL, code, "", "")
+	assert.Eq(status, 200)
+
+	// Blocked users can't exchange codes
+	code = callback(restarted.URL, login(restarted.URL, ""))
+	_, err = db.Exec("UPDATE users SET is_blocked = TRUE WHERE email = 'alice@example.com'")
+	assert.Nil(err)
+	_, status = exchangeAuthCode(client, restarted.URL, code, "", "")
+	assert.Eq(status, 401)
+}
@@ -0,0 +1,200 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"slices"
+	"strings"
+)
+
+// Roles a user can have in relation to a resource. Admins have every role.
+const (
+	ROLE_ADMIN           = "admin"
+	ROLE_GROUP_OWNER     = "group owner"
+	ROLE_GROUP_MODERATOR = "group moderator"
+	ROLE_GROUP_MEMBER    = "group member"
+	ROLE_RIDE_OWNER      = "ride owner"
+	ROLE_RIDE_DRIVER     = "ride driver"
+	// Has a seat in the ride event, users on the waitlist don't.
+	ROLE_RIDE_PARTICIPANT = "ride participant"
+)
+
+// Where the id of the resource a role is checked against comes from.
+type resourceId struct {
+	name string
+	get  func(w http.ResponseWriter, r *http.Request) (string, bool)
+}
+
+// The id is the path parameter `name`.
+func fromPath(name string) resourceId {
+	return resourceId{name: name, get: func(w http.ResponseWriter, r *http.Request) (string, bool) {
+		id := r.PathValue(name)
+		if id == "" {
+			httpWriteErr(w, http.StatusBadRequest, fmt.Sprintf("Must provide '%s' path parameter.", name))
+			return "", false
+		}
+
+		return id, true
+	}}
+}
+
+// The id is the string field `field` of the JSON request body. The body is
+// left intact for the handler.
+func fromBody(field string) resourceId {
+	return resourceId{name: field, get: func(w http.ResponseWriter, r *http.Request) (string, bool) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Println("Error: Invalid request body.", "error:", err)
+			httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
+			return "", false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+
+		var body map[string]any
+		err = json.Unmarshal(data, &body)
+		if err != nil {
+			log.Println("Error: Invalid JSON in request body.", "error:", err)
+			httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
+			return "", false
+		}
+
+		id, ok := body[field].(string)
+		if !ok {
+			httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", fmt.Sprintf("Field '%s' is required.", field))
+			return "", false
+		}
+
+		return id, true
+	}}
+}
+
+// Stops the request with 403 unless the user has at least one of `roles` for
+// the resource identified by `id`. All roles have to belong to the same kind
+// of resource. Has to run after `bearerAuth`.
+func requireRole(id resourceId, roles ...string) func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+	assert.True(len(roles) > 0, "Must require at least one role.")
+	for _, role := range roles {
+		assert.True(roleResource(role) == roleResource(roles[0]), "Required roles must belong to the same kind of resource.")
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+		if user.IsAdmin {
+			return false, nil
+		}
+
+		value, ok := id.get(w, r)
+		if !ok {
+			return true, nil
+		}
+
+		for _, role := range roles {
+			has, err := hasRole(r.Context(), user, role, value)
+			if errors.Is(err, sql.ErrNoRows) {
+				httpWriteErr(w, http.StatusNotFound, fmt.Sprintf("No %s exists with '%s'.", roleResource(role), id.name))
+				return true, nil
+			}
+			assert.Nil(err)
+
+			if has {
+				return false, nil
+			}
+		}
+
+		httpWriteErr(w, http.StatusForbidden, "You do not have the permission to do this.", fmt.Sprintf("Requires one of the roles: %s.", strings.Join(roles, ", ")))
+		return true, nil
+	}
+}
+
+// Stops the request with 403 unless the user is an admin. Has to run after
+// `bearerAuth`.
+func requireAdmin() func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+	return func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+		if !user.IsAdmin {
+			httpWriteErr(w, http.StatusForbidden, "You do not have the permission to do this.", fmt.Sprintf("Requires the role: %s.", ROLE_ADMIN))
+			return true, nil
+		}
+
+		return false, nil
+	}
+}
+
+// Checks if the user has `role` for the group or ride event with the id `id`.
+// Returns sql.ErrNoRows if the resource doesn't exist.
+func hasRole(ctx context.Context, user sqlc.User, role string, id string) (bool, error) {
+	if user.IsAdmin {
+		return true, nil
+	}
+
+	switch roleResource(role) {
+	case "group":
+		group, err := state.queries.GroupsGetById(ctx, id)
+		if err != nil {
+			return false, err
+		}
+
+		if role == ROLE_GROUP_OWNER {
+			return group.CreatedBy == user.ID, nil
+		}
+
+		args := sqlc.GroupsMembersGetStatusParams{
+			GroupID: group.ID,
+			UserID:  user.ID,
+		}
+		member, err := state.queries.GroupsMembersGetStatus(ctx, args)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		isMember := member.JoinStatus == GROUP_JOIN_STATUS_MEMBER
+		if role == ROLE_GROUP_MODERATOR {
+			return isMember && member.IsModerator, nil
+		}
+		return isMember, nil
+	case "ride event":
+		event, err := state.queries.RidesGetEvent(ctx, id)
+		if err != nil {
+			return false, err
+		}
+
+		if role == ROLE_RIDE_OWNER {
+			return event.CreatedBy == user.ID, nil
+		}
+		if role == ROLE_RIDE_PARTICIPANT {
+			participants, err := state.queries.RidesGetParticipants(ctx, event.RideEventID)
+			if err != nil {
+				return false, err
+			}
+
+			return slices.ContainsFunc(participants, func(p sqlc.RidesGetParticipantsRow) bool { return p.ID == user.ID }), nil
+		}
+		return event.Driver == user.ID, nil
+	default:
+		return false, nil
+	}
+}
+
+// The kind of resource a role belongs to.
+func roleResource(role string) string {
+	switch role {
+	case ROLE_GROUP_OWNER, ROLE_GROUP_MODERATOR, ROLE_GROUP_MEMBER:
+		return "group"
+	case ROLE_RIDE_OWNER, ROLE_RIDE_DRIVER, ROLE_RIDE_PARTICIPANT:
+		return "ride event"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,129 @@
+package rest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/utils"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandleAuthorization(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0017-authorization.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenAdmin := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+	tokenOwner := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.w19_ESPa5AgLpH2IV9CrwSPq4XQcJJHgAqVf2bNmcOsdamLKJL_JntjmB-Er356m2bH4OlXg0hYagmU6irxwLymqB7QCGhsF1ixtMwyrGq8lFeePVwb41uD_5xRRkJxjB0GeIO_AIyCl9yCVa3djzcvx-6EBC1f_E9_t"
+	tokenMember := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.cevnH03gVtsxMU7jy3vx4c7caO9X1tQuTTTyHR6a4ZAmqi3Icut3oi9UPfToSx7hnzvBk265_VIt7W5u-Tu2s7SXG6RPEBWlHzQ6DFEZjDsXLd1eqVONAPgWifhpD3UrooBLYzUyJ0lDh05O5gOZxaKYB1o"
+
+	request := func(method string, endpoint string, token string, body string) int {
+		req, err := http.NewRequest(method, api.URL+endpoint, bytes.NewReader([]byte(body)))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		_, err = io.ReadAll(resp.Body)
+		assert.Nil(err)
+		return resp.StatusCode
+	}
+
+	moderators := func() map[string]bool {
+		req, err := http.NewRequest("GET", api.URL+"/groups/by-id/g", nil)
+		assert.Nil(err)
+		req.Header.Add("Authorization", tokenOwner)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 200)
+		var group rest.GroupData
+		err = json.NewDecoder(resp.Body).Decode(&group)
+		assert.Nil(err)
+		moderators := make(map[string]bool)
+		for _, member := range group.Members {
+			moderators[member.UserId] = member.IsModerator
+		}
+		return moderators
+	}
+
+	// Only admins can block users
+	status := request("POST", "/users/by-id/m6SYNABgAw/ban-status", tokenOwner, `{ "isBanned": true }`)
+	assert.Eq(status, 403)
+	status = request("POST", "/users/by-id/NnCaPHQLC9/ban-status", tokenMember, `{ "isBanned": true }`)
+	assert.Eq(status, 403)
+
+	// Only the owner can update a group
+	status = request("POST", "/groups/update", tokenMember, `{ "groupId": "g", "name": "G2" }`)
+	assert.Eq(status, 403)
+	status = request("POST", "/groups/update", tokenMember, `{ "groupId": "missing", "name": "G2" }`)
+	assert.Eq(status, 404)
+	status = request("POST", "/groups/update", tokenMember, `{ "name": "G2" }`)
+	assert.Eq(status, 400)
+	status = request("POST", "/groups/update", tokenOwner, `{ "groupId": "g", "name": "G2" }`)
+	assert.Eq(status, 200)
+
+	// Only the owner or driver can update a ride
+	status = request("POST", "/rides/update", tokenMember, `{ "rideEventId": "r-event", "transportLimit": 3 }`)
+	assert.Eq(status, 403)
+	status = request("POST", "/rides/update", tokenMember, `{ "rideEventId": "missing", "transportLimit": 3 }`)
+	assert.Eq(status, 404)
+
+	// Admins can do anything
+	status = request("POST", "/groups/update", tokenAdmin, `{ "groupId": "g", "name": "G3" }`)
+	assert.Eq(status, 200)
+	status = request("POST", "/rides/update", tokenAdmin, `{ "rideEventId": "r-event", "locationTo": "Linz" }`)
+	assert.Eq(status, 200)
+
+	// Only the owner can appoint moderators, members can't ban
+	status = request("POST", "/groups/by-id/g/members/ban", tokenMember, `{ "userId": "NnCaPHQLC9" }`)
+	assert.Eq(status, 403)
+	status = request("POST", "/groups/by-id/g/moderators/add", tokenMember, `{ "userId": "m6SYNABgAw" }`)
+	assert.Eq(status, 403)
+	status = request("POST", "/groups/by-id/g/moderators/add", tokenOwner, `{ "userId": "NnCaPHQLC9" }`)
+	assert.Eq(status, 409)
+	status = request("POST", "/groups/by-id/g/moderators/add", tokenOwner, `{ "userId": "m6SYNABgAw" }`)
+	assert.Eq(status, 200)
+	assert.Eq(moderators()["m6SYNABgAw"], true)
+
+	// Moderators can approve and ban members, but not the owner or other moderators
+	status = request("POST", "/groups/by-id/g/members/approve", tokenMember, `{ "userId": "NnCaPHQLC9" }`)
+	assert.Eq(status, 200)
+	status = request("POST", "/groups/by-id/g/members/ban", tokenMember, `{ "userId": "nmBSHcxyvn" }`)
+	assert.Eq(status, 403)
+
+	status = request("POST", "/groups/by-id/g/moderators/add", tokenOwner, `{ "userId": "NnCaPHQLC9" }`)
+	assert.Eq(status, 200)
+	status = request("POST", "/groups/by-id/g/members/ban", tokenMember, `{ "userId": "NnCaPHQLC9" }`)
+	assert.Eq(status, 403)
+
+	status = request("POST", "/groups/by-id/g/moderators/remove", tokenOwner, `{ "userId": "NnCaPHQLC9" }`)
+	assert.Eq(status, 200)
+	assert.Eq(moderators()["NnCaPHQLC9"], false)
+	status = request("POST", "/groups/by-id/g/members/ban", tokenMember, `{ "userId": "NnCaPHQLC9" }`)
+	assert.Eq(status, 200)
+
+	// Only members can send messages
+	status = request("POST", "/groups/by-id/g/send-message", tokenMember, `{ "groupId": "g", "content": "Hi" }`)
+	assert.Eq(status, 201)
+	status = request("POST", "/groups/by-id/g/send-message", tokenOwner, `{ "groupId": "other", "content": "Hi" }`)
+	assert.Eq(status, 400)
+
+	status = request("POST", "/groups/by-id/g/members/ban", tokenOwner, `{ "userId": "m6SYNABgAw" }`)
+	assert.Eq(status, 200)
+	status = request("POST", "/groups/by-id/g/send-message", tokenMember, `{ "groupId": "g", "content": "Hi" }`)
+	assert.Eq(status, 403)
+
+	// Blocked users can't do anything
+	status = request("POST", "/users/by-id/m6SYNABgAw/ban-status", tokenAdmin, `{ "isBanned": true }`)
+	assert.Eq(status, 200)
+	status = request("GET", "/users/me", tokenMember, "")
+	assert.Eq(status, 401)
+}
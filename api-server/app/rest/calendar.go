@@ -0,0 +1,316 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ICAL_PRODUCT_ID = "-//ride_sharing//rides//EN"
+	ICAL_UID_DOMAIN = "ride-sharing"
+	// Lines longer than this many bytes are folded (RFC 5545 section 3.1).
+	ICAL_LINE_LENGTH_MAX = 75
+)
+
+func calendarHandlers(h *http.ServeMux) {
+	h.HandleFunc("POST /users/me/calendar-token", handle(createCalendarToken).with(bearerAuth(false)).build())
+	h.HandleFunc("POST /users/me/calendar-token/revoke", handle(revokeCalendarToken).with(bearerAuth(false)).build())
+	h.HandleFunc("GET /users/me/calendar.ics", handle(getUserCalendar).with(calendarTokenAuth()).build())
+	// '/rides/{rideId}/calendar.ics' would conflict with '/rides/by-id/{id}', so
+	// the file name is matched separately.
+	h.HandleFunc("GET /rides/{rideId}/{file}", handle(getRideCalendar).with(pathValueEquals("file", "calendar.ics")).with(calendarTokenAuth()).build())
+}
+
+type calendarTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// Creates a new calendar token for the user. Calendar apps can't send bearer
+// tokens, so the feeds are authenticated with this token as the query
+// parameter 'token'. Any previous token of the user stops working. Only the
+// hash of the token is stored, so a leaked database can't be used to read the
+// feeds.
+func createCalendarToken(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	token := genCalendarToken()
+	args := sqlc.UsersSetCalendarTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+	}
+
+	err := state.queries.UsersSetCalendarToken(r.Context(), args)
+	assert.Nil(err)
+
+	resp, err := json.Marshal(calendarTokenResponse{Token: token})
+	assert.Nil(err, "Failed to serialize calendar token response.")
+	w.WriteHeader(201)
+	w.Write(resp)
+}
+
+func revokeCalendarToken(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	err := state.queries.UsersDropCalendarToken(r.Context(), user.ID)
+	assert.Nil(err)
+	w.WriteHeader(200)
+}
+
+// Feed of all rides the user created, drives or takes part in.
+func getUserCalendar(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	rideIds, err := state.queries.RidesGetCalendarRideIds(r.Context(), user.ID)
+	assert.Nil(err)
+
+	events, err := state.queries.RidesGetCalendarEvents(r.Context(), rideIds)
+	assert.Nil(err)
+
+	writeCalendar(w, r, "Rides", events)
+}
+
+// Feed of a single ride series.
+func getRideCalendar(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	rideId := r.PathValue("rideId")
+	events, err := state.queries.RidesGetCalendarEvents(r.Context(), []string{rideId})
+	assert.Nil(err)
+
+	if len(events) == 0 || !canSeeRide(r.Context(), state.queries, events[0].GroupID, user.ID) {
+		httpWriteErr(w, http.StatusNotFound, "No ride exists with 'rideId'.")
+		return
+	}
+
+	writeCalendar(w, r, fmt.Sprintf("Ride from %s to %s", events[0].LocationFrom, events[0].LocationTo), events)
+}
+
+func writeCalendar(w http.ResponseWriter, r *http.Request, name string, events []sqlc.RidesGetCalendarEventsRow) {
+	rideIds := make([]string, 0)
+	for _, event := range events {
+		if len(rideIds) == 0 || rideIds[len(rideIds)-1] != event.RideID {
+			rideIds = append(rideIds, event.RideID)
+		}
+	}
+
+	participants, err := state.queries.RidesGetCalendarParticipants(r.Context(), rideIds)
+	assert.Nil(err)
+
+	participantsByEvent := make(map[string][]sqlc.RidesGetCalendarParticipantsRow)
+	for _, participant := range participants {
+		participantsByEvent[participant.RideEventID] = append(participantsByEvent[participant.RideEventID], participant)
+	}
+
+	cal := icalWriter{stamp: time.Now()}
+	cal.prop("BEGIN", "VCALENDAR")
+	cal.prop("VERSION", "2.0")
+	cal.prop("PRODID", ICAL_PRODUCT_ID)
+	cal.prop("CALSCALE", "GREGORIAN")
+	cal.prop("METHOD", "PUBLISH")
+	cal.prop("X-WR-CALNAME", icalEscape(name))
+
+	// events are ordered by ride, so every series is a consecutive run
+	for start := 0; start < len(events); {
+		end := start + 1
+		for end < len(events) && events[end].RideID == events[start].RideID {
+			end++
+		}
+
+		cal.series(r.Context(), events[start:end], participantsByEvent)
+		start = end
+	}
+
+	cal.prop("END", "VCALENDAR")
+
+	w.Header().Add("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(200)
+	w.Write([]byte(cal.builder.String()))
+}
+
+type icalWriter struct {
+	builder strings.Builder
+	stamp   time.Time
+}
+
+// Writes the events of one ride series. A recurring series is written as one
+// event with an RRULE, the events that already exist override single
+// occurrences of it.
+func (c *icalWriter) series(ctx context.Context, events []sqlc.RidesGetCalendarEventsRow, participants map[string][]sqlc.RidesGetCalendarParticipantsRow) {
+	first := events[0]
+	uid := first.RideID + "@" + ICAL_UID_DOMAIN
+
+	if first.RideScheduleID.Valid {
+		rule, err := calendarRecurrence(ctx, first)
+		if err == nil {
+			c.prop("BEGIN", "VEVENT")
+			c.prop("UID", uid)
+			c.time("DTSTART", rule.anchor)
+			c.prop("RRULE", rule.rule.format())
+			for _, exclusion := range rule.exclusions() {
+				c.time("EXDATE", exclusion.In(rule.anchor.Location()))
+			}
+			c.details(first, "upcoming")
+			c.prop("END", "VEVENT")
+
+			for _, event := range events {
+				c.event(uid, event, participants[event.RideEventID], &rule)
+			}
+			return
+		}
+
+		log.Println("Error: Failed to build recurrence of ride for calendar.", "ride:", first.RideID, "error:", err)
+	}
+
+	for _, event := range events {
+		c.event(event.RideEventID+"@"+ICAL_UID_DOMAIN, event, participants[event.RideEventID], nil)
+	}
+}
+
+// Writes a single ride event. Events of a recurring series override the
+// occurrence they were created for, even if they were moved to another time.
+func (c *icalWriter) event(uid string, event sqlc.RidesGetCalendarEventsRow, participants []sqlc.RidesGetCalendarParticipantsRow, rule *recurrence) {
+	tackingPlaceAt, err := time.Parse(time.RFC3339, event.TackingPlaceAt)
+	assert.Nil(err)
+
+	c.prop("BEGIN", "VEVENT")
+	c.prop("UID", uid)
+	if rule != nil {
+		occurrence, err := time.Parse(time.RFC3339, event.OccurrenceAt.String)
+		assert.Nil(err)
+
+		tackingPlaceAt = tackingPlaceAt.In(rule.anchor.Location())
+		c.time("RECURRENCE-ID", occurrence.In(rule.anchor.Location()))
+	}
+	c.time("DTSTART", tackingPlaceAt)
+	c.details(event, event.Status)
+
+	for _, participant := range participants {
+		c.prop(fmt.Sprintf("ATTENDEE;CN=%s;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED", icalParam(participant.Name)), "mailto:"+participant.Email)
+	}
+
+	c.prop("END", "VEVENT")
+}
+
+func (c *icalWriter) details(event sqlc.RidesGetCalendarEventsRow, status string) {
+	c.time("DTSTAMP", c.stamp.UTC())
+	c.prop("SUMMARY", icalEscape(fmt.Sprintf("Ride from %s to %s", event.LocationFrom, event.LocationTo)))
+	c.prop("LOCATION", icalEscape(fmt.Sprintf("%s → %s", event.LocationFrom, event.LocationTo)))
+	c.prop("DESCRIPTION", icalEscape(fmt.Sprintf("From: %s\nTo: %s\nDriver: %s", event.LocationFrom, event.LocationTo, event.DriverEmail)))
+	c.prop(fmt.Sprintf("ORGANIZER;CN=%s", icalParam(event.DriverName)), "mailto:"+event.DriverEmail)
+
+	switch status {
+	case RIDE_STATUS_CANCELED:
+		c.prop("STATUS", "CANCELLED")
+	default:
+		c.prop("STATUS", "CONFIRMED")
+	}
+}
+
+// Writes a date-time property. Times in UTC use the UTC form, all other times
+// are written as local time with the IANA name of their time zone. Calendar
+// apps resolve these names without a VTIMEZONE component (RFC 7809).
+func (c *icalWriter) time(name string, t time.Time) {
+	if t.Location() == time.UTC {
+		c.prop(name, t.Format("20060102T150405Z"))
+		return
+	}
+
+	c.prop(name+";TZID="+t.Location().String(), t.Format("20060102T150405"))
+}
+
+// Writes a content line, folded after every 75 bytes without splitting
+// UTF-8 characters.
+func (c *icalWriter) prop(name string, value string) {
+	line := name + ":" + value
+	for len(line) > ICAL_LINE_LENGTH_MAX {
+		cut := ICAL_LINE_LENGTH_MAX
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		c.builder.WriteString(line[:cut] + "\r\n")
+		line = " " + line[cut:]
+	}
+
+	c.builder.WriteString(line + "\r\n")
+}
+
+func calendarRecurrence(ctx context.Context, event sqlc.RidesGetCalendarEventsRow) (recurrence, error) {
+	anchor, err := time.Parse(time.RFC3339, event.SeriesStart)
+	if err != nil {
+		return recurrence{}, err
+	}
+
+	schedule := sqlc.RideSchedule{
+		ID:               event.RideScheduleID.String,
+		RideID:           event.RideID,
+		ScheduleInterval: event.RideScheduleInterval.Int64,
+		Unit:             event.RideScheduleUnit.String,
+		Timezone:         event.RideScheduleTimezone.String,
+		Rrule:            event.RideScheduleRrule,
+	}
+
+	return loadRecurrence(ctx, state.queries, schedule, anchor)
+}
+
+// Escapes a TEXT value (RFC 5545 section 3.3.11).
+func icalEscape(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// Quotes a parameter value. Parameter values can't contain double quotes.
+func icalParam(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "'") + `"`
+}
+
+func calendarTokenAuth() func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+	return func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			http.Error(w, "Missing query parameter 'token'.", http.StatusBadRequest)
+			return true, nil
+		}
+
+		user, err := state.queries.UsersGetByCalendarToken(r.Context(), hashToken(token))
+		if err != nil {
+			http.Error(w, "Invalid calendar token in query parameter 'token'.", http.StatusUnauthorized)
+			return true, nil
+		}
+
+		if user.IsBlocked {
+			http.Error(w, "User is blocked", http.StatusUnauthorized)
+			return true, nil
+		}
+
+		return false, &middlewareData{key: "user", value: user}
+	}
+}
+
+// Responds with 404 unless the path parameter `name` equals `value`.
+func pathValueEquals(name string, value string) func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+	return func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+		if r.PathValue(name) != value {
+			http.NotFound(w, r)
+			return true, nil
+		}
+
+		return false, nil
+	}
+}
+
+func genCalendarToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,122 @@
+package rest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandleCalendarFeeds(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0013-calendar-feeds.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	testAuth(api, "/users/me/calendar-token", "POST")
+
+	createToken := func() string {
+		req, err := http.NewRequest("POST", api.URL+"/users/me/calendar-token", bytes.NewReader([]byte{}))
+		assert.Nil(err)
+		req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 201)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		var body map[string]string
+		err = json.Unmarshal(data, &body)
+		assert.Nil(err)
+		assert.Neq(body["token"], "")
+		return body["token"]
+	}
+
+	getFeed := func(url string) (int, string) {
+		resp, err := api.Client().Get(api.URL + url)
+		assert.Nil(err)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		if resp.StatusCode == 200 {
+			assert.Eq(resp.Header.Get("Content-Type"), "text/calendar; charset=utf-8")
+		}
+		return resp.StatusCode, string(data)
+	}
+
+	// Missing and invalid token
+	status, _ := getFeed("/users/me/calendar.ics")
+	assert.Eq(status, 400)
+	status, _ = getFeed("/users/me/calendar.ics?token=invalid")
+	assert.Eq(status, 401)
+
+	token := createToken()
+
+	status, feed := getFeed("/users/me/calendar.ics?token=" + token)
+	assert.Eq(status, 200)
+	assert.True(strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\n"), feed)
+	assert.True(strings.HasSuffix(feed, "END:VCALENDAR\r\n"), feed)
+	assert.Eq(strings.Count(feed, "BEGIN:VEVENT"), 4, feed)
+	assert.True(strings.Contains(feed, "UID:weekly@ride-sharing\r\nDTSTART;TZID=Europe/Vienna:20441101T073000\r\nRRULE:FREQ=WEEKLY;BYDAY=TU\r\nEXDATE;TZID=Europe/Vienna:20441122T073000\r\n"), feed)
+	assert.True(strings.Contains(feed, "RECURRENCE-ID;TZID=Europe/Vienna:20441108T073000\r\nDTSTART;TZID=Europe/Vienna:20441108T073000\r\n"), feed)
+	assert.True(strings.Contains(feed, "STATUS:CANCELLED"), feed)
+	assert.True(strings.Contains(feed, "LOCATION:Graz → Wien"), feed)
+	assert.True(strings.Contains(feed, `ORGANIZER;CN="test-user-03":mailto:KluwXy24KzJnN@proton.me`), feed)
+	// long lines are folded
+	assert.True(strings.Contains(feed, "ATTENDEE;CN=\"test-user-02\";ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED:mailto:WD\r\n ZHw/GNwrQ5vhtWojbR@gmail.com\r\n"), feed)
+	// rides the user isn't part of are not included
+	assert.False(strings.Contains(feed, "Tokyo"), feed)
+
+	// Feed of a single ride
+	status, feed = getFeed("/rides/once/calendar.ics?token=" + token)
+	assert.Eq(status, 200)
+	assert.Eq(strings.Count(feed, "BEGIN:VEVENT"), 1, feed)
+	assert.True(strings.Contains(feed, "DTSTART:20441120T100000Z\r\n"), feed)
+	assert.True(strings.Contains(feed, "SUMMARY:Ride from Tokyo to NYC\r\n"), feed)
+	assert.False(strings.Contains(feed, "RRULE"), feed)
+
+	status, _ = getFeed("/rides/missing/calendar.ics?token=" + token)
+	assert.Eq(status, 404)
+	status, _ = getFeed("/rides/once/other.ics?token=" + token)
+	assert.Eq(status, 404)
+	status, _ = getFeed("/rides/once/calendar.ics?token=invalid")
+	assert.Eq(status, 401)
+
+	// Moved events still override the occurrence they were created for
+	req, err := http.NewRequest("POST", api.URL+"/rides/update", strings.NewReader(`{ "rideEventId": "weekly-3", "tackingPlaceAt": "2044-11-16T07:00:00Z" }`))
+	assert.Nil(err)
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+	resp, err := api.Client().Do(req)
+	assert.Nil(err)
+	assert.Eq(resp.StatusCode, 200)
+
+	status, feed = getFeed("/rides/weekly/calendar.ics?token=" + token)
+	assert.Eq(status, 200)
+	assert.True(strings.Contains(feed, "RECURRENCE-ID;TZID=Europe/Vienna:20441115T073000\r\nDTSTART;TZID=Europe/Vienna:20441116T080000\r\n"), feed)
+
+	// Creating a new token invalidates the old one
+	newToken := createToken()
+	status, _ = getFeed("/users/me/calendar.ics?token=" + token)
+	assert.Eq(status, 401)
+	status, _ = getFeed("/users/me/calendar.ics?token=" + newToken)
+	assert.Eq(status, 200)
+
+	// Revoked tokens can't be used anymore
+	req, err = http.NewRequest("POST", api.URL+"/users/me/calendar-token/revoke", bytes.NewReader([]byte{}))
+	assert.Nil(err)
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+	resp, err = api.Client().Do(req)
+	assert.Nil(err)
+	assert.Eq(resp.StatusCode, 200)
+
+	status, _ = getFeed("/users/me/calendar.ics?token=" + newToken)
+	assert.Eq(status, 401)
+}
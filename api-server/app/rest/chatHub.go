@@ -0,0 +1,587 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/common"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"sync"
+	"time"
+
+	websocket "golang.org/x/net/websocket"
+)
+
+// Group chats, the threads of ride events and conversations between two users
+// are WebSockets that send and receive JSON frames. Browsers can't send the
+// 'Authorization' header when opening a WebSocket, so members get a
+// short-lived ticket from the 'chat-ticket' route of the chat first, e.g.
+// '/groups/by-id/{id}/chat-ticket', and pass it in the 'ticket' query
+// parameter.
+//
+// Only the latest messages are sent when a chat is opened, older ones are
+// loaded from the 'messages' route of the chat, e.g.
+// '/groups/by-id/{id}/messages'. Clients that reconnect pass the id of the
+// last message they received in the 'since' query parameter and get the
+// messages they missed, after the current state of the older messages that
+// changed in the meantime.
+//
+// Every client has its own queue of frames, a client that doesn't keep up
+// with its queue is disconnected instead of slowing down the others.
+
+// Types of chat frames.
+const (
+	// Sent and received, a new message.
+	CHAT_FRAME_MESSAGE = "message"
+	// Sent with the message after the change.
+	CHAT_FRAME_MESSAGE_EDITED  = "message.edited"
+	CHAT_FRAME_MESSAGE_DELETED = "message.deleted"
+	// Sent to reconnecting clients with the current state of a message they
+	// received before that changed while they were away.
+	CHAT_FRAME_MESSAGE_CHANGED = "message.changed"
+	// Sent with the message after the change and the reaction that changed.
+	CHAT_FRAME_REACTION_ADDED   = "reaction.added"
+	CHAT_FRAME_REACTION_REMOVED = "reaction.removed"
+	// Sent every `pingInterval`, the client has to answer with 'pong'.
+	CHAT_FRAME_PING = "ping"
+	CHAT_FRAME_PONG = "pong"
+	// Sent if a received frame couldn't be handled.
+	CHAT_FRAME_ERROR = "error"
+	// Sent before the replayed messages if the client missed more messages
+	// or changes than are replayed. The client has to drop the messages it
+	// has, the ones before `before` have to be loaded from the REST API.
+	CHAT_FRAME_GAP = "gap"
+)
+
+const (
+	chatTicketLifetime = 30 * time.Second
+	// Frames a client can fall behind before it is disconnected.
+	chatQueueSize = 64
+	chatWriteWait = 10 * time.Second
+	// Messages sent when a chat is opened.
+	chatReplaySize = 50
+)
+
+type chatHub struct {
+	mutex   sync.Mutex
+	rooms   map[chatChannel]map[*chatClient]struct{}
+	tickets map[string]chatTicket
+	// Clients that don't send any frame, including pongs, for `pongWait`
+	// are disconnected.
+	pingInterval time.Duration
+	pongWait     time.Duration
+}
+
+// The chat of a group, the thread of a ride event or a conversation, only one
+// of the ids is set.
+type chatChannel struct {
+	groupId        string
+	rideEventId    string
+	conversationId string
+}
+
+type chatTicket struct {
+	userId    string
+	channel   chatChannel
+	expiresAt time.Time
+}
+
+type chatClient struct {
+	conn    *websocket.Conn
+	user    sqlc.User
+	channel chatChannel
+	queue   chan chatFrame
+	// Closed when the client was removed from the hub.
+	removed chan struct{}
+}
+
+type chatFrame struct {
+	Type     string            `json:"type"`
+	Message  *GroupMessageData `json:"message,omitempty"`
+	Reaction *chatReaction     `json:"reaction,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Before   string            `json:"before,omitempty"`
+}
+
+type chatReaction struct {
+	Emoji  string `json:"emoji"`
+	UserId string `json:"userId"`
+}
+
+type chatInboundFrame struct {
+	Type      *string `json:"type" validate:"required,oneof=message pong"`
+	Content   *string `json:"content" validate:"required_if=Type message"`
+	RepliesTo *string `json:"repliesTo"`
+}
+
+type chatTicketResponse struct {
+	Ticket string `json:"ticket"`
+}
+
+func newChatHub() *chatHub {
+	return &chatHub{
+		rooms:        make(map[chatChannel]map[*chatClient]struct{}),
+		tickets:      make(map[string]chatTicket),
+		pingInterval: 30 * time.Second,
+		pongWait:     60 * time.Second,
+	}
+}
+
+// Roles that can use the thread of a ride event.
+var rideThreadRoles = []string{ROLE_RIDE_OWNER, ROLE_RIDE_DRIVER, ROLE_RIDE_PARTICIPANT}
+
+func groupChat(groupId string) chatChannel {
+	return chatChannel{groupId: groupId}
+}
+
+func rideThread(rideEventId string) chatChannel {
+	return chatChannel{rideEventId: rideEventId}
+}
+
+func conversationChat(conversationId string) chatChannel {
+	return chatChannel{conversationId: conversationId}
+}
+
+func groupChatFromPath(r *http.Request) chatChannel {
+	return groupChat(r.PathValue("id"))
+}
+
+func rideThreadFromPath(r *http.Request) chatChannel {
+	return rideThread(r.PathValue("id"))
+}
+
+func conversationChatFromPath(r *http.Request) chatChannel {
+	return conversationChat(r.PathValue("id"))
+}
+
+func messageChannel(groupId sql.NullString, rideEventId sql.NullString) chatChannel {
+	return chatChannel{groupId: groupId.String, rideEventId: rideEventId.String}
+}
+
+// The ids as query parameters, ids that aren't set are NULL.
+func (c chatChannel) sqlIds() (sql.NullString, sql.NullString) {
+	return sql.NullString{String: c.groupId, Valid: c.groupId != ""}, sql.NullString{String: c.rideEventId, Valid: c.rideEventId != ""}
+}
+
+func (c chatChannel) String() string {
+	if c.groupId != "" {
+		return "group " + c.groupId
+	}
+	if c.conversationId != "" {
+		return "conversation " + c.conversationId
+	}
+	return "ride event " + c.rideEventId
+}
+
+// Members can use the chat of their group. The creator, the driver and the
+// participants of a ride event can use its thread. Conversations can only be
+// used by their two users, not even by admins.
+func canUseChat(ctx context.Context, user sqlc.User, channel chatChannel) (bool, error) {
+	if channel.groupId != "" {
+		return hasRole(ctx, user, ROLE_GROUP_MEMBER, channel.groupId)
+	}
+	if channel.conversationId != "" {
+		conversation, err := state.queries.ConversationsGetById(ctx, channel.conversationId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return err == nil && isConversationMember(user, conversation), err
+	}
+
+	for _, role := range rideThreadRoles {
+		has, err := hasRole(ctx, user, role, channel.rideEventId)
+		if err != nil || has {
+			return has, err
+		}
+	}
+	return false, nil
+}
+
+// The owner of a group or the creator of a ride event can delete the messages
+// of others. Nobody moderates conversations.
+func canModerateChat(ctx context.Context, user sqlc.User, channel chatChannel) (bool, error) {
+	if channel.groupId != "" {
+		return hasRole(ctx, user, ROLE_GROUP_OWNER, channel.groupId)
+	}
+	if channel.conversationId != "" {
+		return false, nil
+	}
+	return hasRole(ctx, user, ROLE_RIDE_OWNER, channel.rideEventId)
+}
+
+func (h *chatHub) issueTicket(userId string, channel chatChannel) string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	ticket := base64.RawURLEncoding.EncodeToString(b)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := time.Now()
+	for key, t := range h.tickets {
+		if now.After(t.expiresAt) {
+			delete(h.tickets, key)
+		}
+	}
+	h.tickets[ticket] = chatTicket{userId: userId, channel: channel, expiresAt: now.Add(chatTicketLifetime)}
+
+	return ticket
+}
+
+// Tickets can only be used once, for the chat they were issued for.
+func (h *chatHub) consumeTicket(ticket string, channel chatChannel) (string, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	t, ok := h.tickets[ticket]
+	delete(h.tickets, ticket)
+
+	if !ok || t.channel != channel || time.Now().After(t.expiresAt) {
+		return "", false
+	}
+
+	return t.userId, true
+}
+
+func (h *chatHub) join(client *chatClient) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	room, ok := h.rooms[client.channel]
+	if !ok {
+		room = make(map[*chatClient]struct{})
+		h.rooms[client.channel] = room
+	}
+	room[client] = struct{}{}
+}
+
+func (h *chatHub) leave(client *chatClient) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.remove(client)
+}
+
+// Has to be called with the mutex locked.
+func (h *chatHub) remove(client *chatClient) {
+	room, ok := h.rooms[client.channel]
+	if !ok {
+		return
+	}
+
+	if _, ok := room[client]; !ok {
+		return
+	}
+
+	delete(room, client)
+	if len(room) == 0 {
+		delete(h.rooms, client.channel)
+	}
+	close(client.removed)
+}
+
+// Has to be called with the mutex locked.
+func (h *chatHub) enqueue(client *chatClient, frame chatFrame) {
+	select {
+	case client.queue <- frame:
+	default:
+		log.Println("Disconnecting chat client that fell behind.", "chat:", client.channel, "user id:", client.user.ID)
+		h.remove(client)
+	}
+}
+
+func (h *chatHub) send(client *chatClient, frame chatFrame) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.enqueue(client, frame)
+}
+
+func (h *chatHub) broadcast(channel chatChannel, frame chatFrame) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for client := range h.rooms[channel] {
+		h.enqueue(client, frame)
+	}
+}
+
+// Disconnects the clients of a user that lost access to a chat.
+func (h *chatHub) disconnectMember(channel chatChannel, userId string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for client := range h.rooms[channel] {
+		if client.user.ID == userId {
+			h.remove(client)
+		}
+	}
+}
+
+// Disconnects the clients of a user from all chats.
+func (h *chatHub) disconnectUser(userId string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, room := range h.rooms {
+		for client := range room {
+			if client.user.ID == userId {
+				h.remove(client)
+			}
+		}
+	}
+}
+
+func (h *chatHub) clientCount(channel chatChannel) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.rooms[channel])
+}
+
+func createChatTicket(chatOf func(r *http.Request) chatChannel) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+
+		ticket := state.chat.issueTicket(user.ID, chatOf(r))
+
+		resp, err := json.Marshal(chatTicketResponse{Ticket: ticket})
+		assert.Nil(err, "Failed to serialize chat ticket.")
+		w.WriteHeader(200)
+		w.Write(resp)
+	}
+}
+
+// Authenticates the opening of a chat with a ticket. Access is checked again,
+// the user might have been banned since the ticket was issued.
+func chatTicketAuth(chatOf func(r *http.Request) chatChannel) func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+	return func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+		channel := chatOf(r)
+
+		userId, ok := state.chat.consumeTicket(r.URL.Query().Get("ticket"), channel)
+		if !ok {
+			http.Error(w, "Invalid or expired chat ticket in 'ticket' query parameter.", http.StatusUnauthorized)
+			return true, nil
+		}
+
+		user, err := state.queries.UsersGetById(r.Context(), userId)
+		if err != nil || user.IsBlocked {
+			http.Error(w, "Invalid or expired chat ticket in 'ticket' query parameter.", http.StatusUnauthorized)
+			return true, nil
+		}
+
+		canUse, err := canUseChat(r.Context(), user, channel)
+		if err != nil || !canUse {
+			httpWriteErr(w, http.StatusForbidden, "You do not have the permission to open this chat.")
+			return true, nil
+		}
+
+		return false, &middlewareData{key: "user", value: user}
+	}
+}
+
+// Only the web-app may open chats from a browser, so other sites can't open
+// them with a ticket of a signed in user.
+func checkChatOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := url.Parse(strings.TrimSuffix(utils.GetEnvRequired(common.ENV_WEB_APP_URL), "/"))
+	if err != nil {
+		return err
+	}
+
+	if origin == nil || origin.Scheme != allowed.Scheme || origin.Host != allowed.Host {
+		return errors.New("Origin is not allowed to open chats.")
+	}
+
+	config.Origin = origin
+	return nil
+}
+
+func serveChat(chatOf func(r *http.Request) chatChannel) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+		channel := chatOf(r)
+
+		var since *string = nil
+		if sinceStr := r.FormValue("since"); sinceStr != "" {
+			if !isChatMessage(r.Context(), channel, sinceStr) {
+				httpWriteErr(w, http.StatusBadRequest, "Query parameter 'since' has to be the id of a message of the chat.")
+				return
+			}
+			since = &sinceStr
+		}
+
+		server := websocket.Server{
+			Handshake: checkChatOrigin,
+			Handler: func(conn *websocket.Conn) {
+				state.chat.serve(conn, user, channel, since)
+			},
+		}
+		server.ServeHTTP(w, r)
+	}
+}
+
+// Replays the latest messages, or the changes to older messages and the ones
+// sent after the message with the id `since`, and then relays frames until
+// the client disconnects or is removed from the hub.
+func (h *chatHub) serve(conn *websocket.Conn, user sqlc.User, channel chatChannel, since *string) {
+	ctx := conn.Request().Context()
+	client := &chatClient{
+		conn:    conn,
+		user:    user,
+		channel: channel,
+		queue:   make(chan chatFrame, chatQueueSize),
+		removed: make(chan struct{}),
+	}
+
+	// joins before loading the history, so no message is missed in between
+	h.join(client)
+	defer h.leave(client)
+
+	var msgs, changed []GroupMessageData
+	var err error
+	hasGap := false
+	if since != nil {
+		msgs, hasGap, err = loadGroupMessagesSince(ctx, channel, *since, chatReplaySize)
+		if err == nil && !hasGap {
+			changed, hasGap, err = loadGroupMessagesChangedSince(ctx, channel, *since, chatReplaySize)
+		}
+	}
+	if err == nil && (since == nil || hasGap) {
+		changed = nil
+		msgs, _, err = loadGroupMessagesBefore(ctx, channel, nil, chatReplaySize)
+	}
+	if err != nil {
+		log.Println("Error: Failed to load chat history.", "chat:", channel, "error:", err)
+		return
+	}
+
+	if hasGap {
+		err = writeChatFrame(conn, chatFrame{Type: CHAT_FRAME_GAP, Before: msgs[0].MessageId})
+		if err != nil {
+			return
+		}
+	}
+
+	for _, msg := range changed {
+		err = writeChatFrame(conn, chatFrame{Type: CHAT_FRAME_MESSAGE_CHANGED, Message: &msg})
+		if err != nil {
+			return
+		}
+	}
+
+	sent := make(map[string]struct{}, len(msgs))
+	for _, msg := range msgs {
+		err = writeChatFrame(conn, chatFrame{Type: CHAT_FRAME_MESSAGE, Message: &msg})
+		if err != nil {
+			return
+		}
+		sent[msg.MessageId] = struct{}{}
+	}
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		h.writeFrames(client, sent)
+	}()
+
+	h.readFrames(client)
+	h.leave(client)
+	<-writerDone
+}
+
+func (h *chatHub) writeFrames(client *chatClient, sent map[string]struct{}) {
+	// unblocks the reader once the client was removed
+	defer client.conn.Close()
+
+	ping := time.NewTicker(h.pingInterval)
+	defer ping.Stop()
+
+	for {
+		var frame chatFrame
+		select {
+		case <-client.removed:
+			return
+		case <-ping.C:
+			frame = chatFrame{Type: CHAT_FRAME_PING}
+		case frame = <-client.queue:
+			// the history might already contain messages that were sent while it was loading
+			if frame.Type == CHAT_FRAME_MESSAGE {
+				if _, ok := sent[frame.Message.MessageId]; ok {
+					continue
+				}
+			}
+		}
+
+		err := writeChatFrame(client.conn, frame)
+		if err != nil {
+			h.leave(client)
+			return
+		}
+	}
+}
+
+func (h *chatHub) readFrames(client *chatClient) {
+	ctx := client.conn.Request().Context()
+
+	for {
+		client.conn.SetReadDeadline(time.Now().Add(h.pongWait))
+
+		var data []byte
+		err := websocket.Message.Receive(client.conn, &data)
+		if err != nil {
+			return
+		}
+
+		var frame chatInboundFrame
+		err = json.Unmarshal(data, &frame)
+		if err == nil {
+			err = utils.Validate.Struct(frame)
+		}
+		if err != nil {
+			h.send(client, chatFrame{Type: CHAT_FRAME_ERROR, Error: "Invalid frame. " + err.Error()})
+			continue
+		}
+
+		if *frame.Type != CHAT_FRAME_MESSAGE {
+			continue
+		}
+
+		canUse, err := canUseChat(ctx, client.user, client.channel)
+		if err != nil || !canUse {
+			return
+		}
+
+		_, err = createGroupMessage(ctx, client.user, client.channel, *frame.Content, frame.RepliesTo)
+		if errors.Is(err, errInvalidReply) || errors.Is(err, errMessagingBlocked) {
+			h.send(client, chatFrame{Type: CHAT_FRAME_ERROR, Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			h.send(client, chatFrame{Type: CHAT_FRAME_ERROR, Error: "Failed to send message."})
+		}
+	}
+}
+
+func writeChatFrame(conn *websocket.Conn, frame chatFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(chatWriteWait))
+	return websocket.Message.Send(conn, string(data))
+}
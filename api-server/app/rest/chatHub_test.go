@@ -0,0 +1,681 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/common"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	websocket "golang.org/x/net/websocket"
+)
+
+func TestGroupChat(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0028-group-chat.sql"))
+	handler := NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenOwner := utils.TEST_TOKEN_USER_01
+	tokenMember := utils.TEST_TOKEN_USER_02
+	tokenPending := utils.TEST_TOKEN_USER_03
+
+	ticket := func(token string, groupId string) string {
+		code, body := utils.TestRequest(api, "POST", "/groups/by-id/"+groupId+"/chat-ticket", token, "")
+		assert.Eq(code, 200)
+		var resp chatTicketResponse
+		err := json.Unmarshal([]byte(body), &resp)
+		assert.Nil(err)
+		return resp.Ticket
+	}
+
+	chatUrl := func(groupId string, ticket string) string {
+		return "ws" + strings.TrimPrefix(api.URL, "http") + "/groups/messages/" + groupId + "?ticket=" + ticket
+	}
+
+	dial := func(groupId string, ticket string, origin string) (*websocket.Conn, error) {
+		config, err := websocket.NewConfig(chatUrl(groupId, ticket), origin)
+		assert.Nil(err)
+		return websocket.DialConfig(config)
+	}
+
+	webAppUrl := utils.GetEnvRequired(common.ENV_WEB_APP_URL)
+	connect := func(token string) *websocket.Conn {
+		conn, err := dial("g", ticket(token, "g"), webAppUrl)
+		assert.Nil(err)
+		return conn
+	}
+
+	receive := func(conn *websocket.Conn) (chatFrame, error) {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var frame chatFrame
+		err := websocket.JSON.Receive(conn, &frame)
+		return frame, err
+	}
+
+	receiveMessage := func(conn *websocket.Conn) GroupMessageData {
+		frame, err := receive(conn)
+		assert.Nil(err)
+		assert.Eq(frame.Type, CHAT_FRAME_MESSAGE, frame.Error)
+		return *frame.Message
+	}
+
+	// Only members get tickets
+	code, _ := utils.TestRequest(api, "POST", "/groups/by-id/g/chat-ticket", tokenPending, "")
+	assert.Eq(code, 403)
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/other/chat-ticket", tokenMember, "")
+	assert.Eq(code, 403)
+
+	// Chats can't be opened without a valid ticket
+	for _, url := range []string{"/groups/messages/g", "/groups/messages/g?ticket=invalid"} {
+		resp, err := api.Client().Get(api.URL + url)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 401, url)
+	}
+
+	_, err := dial("other", ticket(tokenMember, "g"), webAppUrl)
+	assert.Neq(err, nil)
+
+	// Tickets only work once
+	memberTicket := ticket(tokenMember, "g")
+	member, err := dial("g", memberTicket, webAppUrl)
+	assert.Nil(err)
+	defer member.Close()
+	_, err = dial("g", memberTicket, webAppUrl)
+	assert.Neq(err, nil)
+
+	// Other sites can't open chats
+	_, err = dial("g", ticket(tokenOwner, "g"), "https://evil.example.com")
+	assert.Neq(err, nil)
+
+	// Membership is checked again when the ticket is used
+	pendingTicket := state.chat.issueTicket("m6SYNABgAw", groupChat("g"))
+	_, err = dial("g", pendingTicket, webAppUrl)
+	assert.Neq(err, nil)
+
+	owner := connect(tokenOwner)
+	defer owner.Close()
+
+	// The history is sent first
+	assert.Eq(receiveMessage(member).MessageId, "history-01")
+	assert.Eq(receiveMessage(owner).MessageId, "history-01")
+
+	// Messages sent over the socket or the REST API reach everyone in the chat
+	err = websocket.JSON.Send(owner, map[string]any{"type": "message", "content": "Hello", "repliesTo": "history-01"})
+	assert.Nil(err)
+	for _, conn := range []*websocket.Conn{owner, member} {
+		msg := receiveMessage(conn)
+		assert.Eq(msg.Content, "Hello")
+		assert.Eq(msg.SentBy, "NnCaPHQLC9")
+		assert.Eq(*msg.RepliesTo, "history-01")
+	}
+
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/send-message", tokenMember, `{ "groupId": "g", "content": "Hi" }`)
+	assert.Eq(code, 201)
+	for _, conn := range []*websocket.Conn{owner, member} {
+		msg := receiveMessage(conn)
+		assert.Eq(msg.Content, "Hi")
+		assert.Eq(msg.SentBy, "nmBSHcxyvn")
+	}
+
+	// Invalid frames are answered with an error
+	err = websocket.Message.Send(member, `{ "type": "message" }`)
+	assert.Nil(err)
+	frame, err := receive(member)
+	assert.Nil(err)
+	assert.Eq(frame.Type, CHAT_FRAME_ERROR)
+
+	// Banned members are disconnected
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/members/ban", tokenOwner, `{ "userId": "nmBSHcxyvn" }`)
+	assert.Eq(code, 200)
+	_, err = receive(member)
+	assert.Neq(err, nil)
+	assert.Eq(state.chat.clientCount(groupChat("g")), 1)
+
+	// Clients that disconnect are removed
+	owner.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for state.chat.clientCount(groupChat("g")) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Eq(state.chat.clientCount(groupChat("g")), 0)
+}
+
+func TestGroupChatConcurrentClients(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0029-group-chat-concurrent.sql"))
+	handler := NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	// Short intervals, so clients that don't answer pings are dropped quickly
+	state.chat.pingInterval = 20 * time.Millisecond
+	state.chat.pongWait = 200 * time.Millisecond
+
+	webAppUrl := utils.GetEnvRequired(common.ENV_WEB_APP_URL)
+	connect := func(userId string) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(api.URL, "http") + "/groups/messages/g?ticket=" + state.chat.issueTicket(userId, groupChat("g"))
+		conn, err := websocket.Dial(url, "", webAppUrl)
+		assert.Nil(err)
+		return conn
+	}
+
+	const clients = 24
+	const messagesPerClient = 2
+	users := []string{"NnCaPHQLC9", "nmBSHcxyvn"}
+
+	conns := make([]*websocket.Conn, clients)
+	for idx := range conns {
+		conns[idx] = connect(users[idx%len(users)])
+		defer conns[idx].Close()
+	}
+
+	// A client that never answers pings
+	silent := connect(users[0])
+	defer silent.Close()
+
+	// Clients keep answering pings after they received all messages
+	var wg sync.WaitGroup
+	for idx, conn := range conns {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			for range messagesPerClient {
+				err := websocket.JSON.Send(conn, map[string]any{"type": "message", "content": fmt.Sprintf("from %d", idx)})
+				assert.Nil(err)
+			}
+		}()
+
+		go func() {
+			received := make(map[string]bool)
+			conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+			for {
+				var frame chatFrame
+				err := websocket.JSON.Receive(conn, &frame)
+				if err != nil {
+					assert.Eq(len(received), clients*messagesPerClient, "Client disconnected before receiving all messages.", err)
+					return
+				}
+
+				switch frame.Type {
+				case CHAT_FRAME_PING:
+					// fails once the connection was closed, which the next receive catches
+					websocket.JSON.Send(conn, map[string]any{"type": "pong"})
+				case CHAT_FRAME_MESSAGE:
+					assert.False(received[frame.Message.MessageId], "Received message twice.")
+					received[frame.Message.MessageId] = true
+					if len(received) == clients*messagesPerClient {
+						wg.Done()
+					}
+				}
+			}
+		}()
+	}
+
+	// Every client gets every message once, and the silent client is dropped
+	wg.Wait()
+
+	silent.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var frame chatFrame
+		err := websocket.JSON.Receive(silent, &frame)
+		if err != nil {
+			break
+		}
+	}
+	assert.Eq(state.chat.clientCount(groupChat("g")), clients)
+}
+
+func TestGroupChatHistory(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0030-group-chat-history.sql"))
+	handler := NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenMember := utils.TEST_TOKEN_USER_02
+	tokenPending := utils.TEST_TOKEN_USER_03
+
+	messages := func(query string) Page[GroupMessageData] {
+		code, body := utils.TestRequest(api, "GET", "/groups/by-id/g/messages"+query, tokenMember, "")
+		assert.Eq(code, 200, body)
+		var page Page[GroupMessageData]
+		err := json.Unmarshal([]byte(body), &page)
+		assert.Nil(err)
+		return page
+	}
+
+	send := func(content string) string {
+		code, body := utils.TestRequest(api, "POST", "/groups/by-id/g/send-message", tokenMember, `{ "groupId": "g", "content": "`+content+`" }`)
+		assert.Eq(code, 201)
+		var msg GroupMessageData
+		err := json.Unmarshal([]byte(body), &msg)
+		assert.Nil(err)
+		return msg.MessageId
+	}
+
+	webAppUrl := utils.GetEnvRequired(common.ENV_WEB_APP_URL)
+	connect := func(since string) (*websocket.Conn, error) {
+		url := "ws" + strings.TrimPrefix(api.URL, "http") + "/groups/messages/g?ticket=" + state.chat.issueTicket("nmBSHcxyvn", groupChat("g"))
+		if since != "" {
+			url += "&since=" + since
+		}
+		return websocket.Dial(url, "", webAppUrl)
+	}
+
+	receive := func(conn *websocket.Conn) chatFrame {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var frame chatFrame
+		err := websocket.JSON.Receive(conn, &frame)
+		assert.Nil(err)
+		return frame
+	}
+
+	receiveMessages := func(conn *websocket.Conn, count int) []string {
+		ids := make([]string, 0, count)
+		for len(ids) < count {
+			frame := receive(conn)
+			if frame.Type == CHAT_FRAME_PING {
+				continue
+			}
+			assert.Eq(frame.Type, CHAT_FRAME_MESSAGE, frame.Error)
+			ids = append(ids, frame.Message.MessageId)
+		}
+		return ids
+	}
+
+	// Only members can read the messages
+	code, _ := utils.TestRequest(api, "GET", "/groups/by-id/g/messages", tokenPending, "")
+	assert.Eq(code, 403)
+	code, _ = utils.TestRequest(api, "GET", "/groups/by-id/other/messages", tokenMember, "")
+	assert.Eq(code, 403)
+
+	// Pages are loaded from the newest to the oldest, messages within a page are oldest first
+	page := messages("")
+	assert.Eq(page.Total, int64(60))
+	assert.Eq(len(page.Items), PAGE_SIZE_DEFAULT)
+	assert.Eq(page.Items[0].MessageId, "msg-11")
+	assert.Eq(page.Items[len(page.Items)-1].MessageId, "msg-60")
+	assert.Eq(*page.NextCursor, "msg-11")
+
+	page = messages("?limit=6&before=msg-11")
+	assert.Eq(len(page.Items), 6)
+	assert.Eq(page.Items[0].MessageId, "msg-05")
+	assert.Eq(page.Items[5].MessageId, "msg-10")
+	assert.Eq(*page.NextCursor, "msg-05")
+
+	page = messages("?limit=6&before=msg-05")
+	assert.Eq(len(page.Items), 4)
+	assert.Eq(page.Items[0].MessageId, "msg-01")
+	assert.True(page.NextCursor == nil)
+
+	for _, query := range []string{"?limit=0", "?before=unknown", "?before=other-01"} {
+		code, _ = utils.TestRequest(api, "GET", "/groups/by-id/g/messages"+query, tokenMember, "")
+		assert.Eq(code, 400, query)
+	}
+
+	// Opening a chat only replays the latest messages
+	conn, err := connect("")
+	assert.Nil(err)
+	ids := receiveMessages(conn, chatReplaySize)
+	assert.Eq(ids[0], "msg-11")
+	assert.Eq(ids[len(ids)-1], "msg-60")
+	conn.Close()
+
+	// Reconnecting replays the changes to received messages and the messages
+	// that were missed, followed by new ones
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/messages/by-id/msg-60/edit", tokenMember, `{ "content": "Edited" }`)
+	assert.Eq(code, 200)
+	missed := []string{send("Missed 1"), send("Missed 2")}
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/messages/by-id/msg-58/reactions/add", tokenMember, `{ "emoji": "👍" }`)
+	assert.Eq(code, 200)
+	conn, err = connect(ids[len(ids)-1])
+	assert.Nil(err)
+	defer conn.Close()
+	frame := receive(conn)
+	assert.Eq(frame.Type, CHAT_FRAME_MESSAGE_CHANGED)
+	assert.Eq(frame.Message.MessageId, "msg-58")
+	assert.Eq(frame.Message.Reactions[0].Emoji, "👍")
+	frame = receive(conn)
+	assert.Eq(frame.Type, CHAT_FRAME_MESSAGE_CHANGED)
+	assert.Eq(frame.Message.Content, "Edited")
+	assert.True(slices.Equal(receiveMessages(conn, 2), missed))
+	live := send("Live")
+	assert.Eq(receiveMessages(conn, 1)[0], live)
+
+	// Clients that missed too many messages are told where the gap is
+	gapped, err := connect("msg-01")
+	assert.Nil(err)
+	defer gapped.Close()
+	frame = receive(gapped)
+	assert.Eq(frame.Type, CHAT_FRAME_GAP)
+	ids = receiveMessages(gapped, chatReplaySize)
+	assert.Eq(frame.Before, ids[0])
+	assert.Eq(ids[len(ids)-1], live)
+	page = messages("?before=" + frame.Before)
+	assert.Eq(page.Items[0].MessageId, "msg-01")
+
+	// Only messages of the group can be used to reconnect
+	for _, since := range []string{"unknown", "other-01"} {
+		_, err = connect(since)
+		assert.Neq(err, nil, since)
+	}
+}
+
+func TestGroupMessageEdits(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0031-group-message-edits.sql"))
+	handler := NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenOwner := utils.TEST_TOKEN_USER_01
+	tokenAuthor := utils.TEST_TOKEN_USER_02
+	tokenMember := utils.TEST_TOKEN_USER_03
+
+	change := func(messageId string, action string, token string, body string) (int, GroupMessageData) {
+		code, resp := utils.TestRequest(api, "POST", "/groups/by-id/g/messages/by-id/"+messageId+"/"+action, token, body)
+		var msg GroupMessageData
+		if code == 200 {
+			err := json.Unmarshal([]byte(resp), &msg)
+			assert.Nil(err)
+		}
+		return code, msg
+	}
+
+	webAppUrl := utils.GetEnvRequired(common.ENV_WEB_APP_URL)
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(api.URL, "http")+"/groups/messages/g?ticket="+state.chat.issueTicket("NnCaPHQLC9", groupChat("g")), "", webAppUrl)
+	assert.Nil(err)
+	defer conn.Close()
+
+	receive := func() chatFrame {
+		for {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var frame chatFrame
+			err := websocket.JSON.Receive(conn, &frame)
+			assert.Nil(err)
+			if frame.Type != CHAT_FRAME_PING {
+				return frame
+			}
+		}
+	}
+
+	assert.Eq(receive().Message.MessageId, "msg-01")
+	assert.Eq(receive().Message.MessageId, "msg-02")
+
+	// Only the author can edit a message, the previous content is kept
+	code, _ := change("msg-01", "edit", tokenMember, `{ "content": "Changed" }`)
+	assert.Eq(code, 403)
+	code, msg := change("msg-01", "edit", tokenAuthor, `{ "content": "Edited" }`)
+	assert.Eq(code, 200)
+	assert.Eq(msg.Content, "Edited")
+	assert.True(msg.EditedAt != nil)
+
+	frame := receive()
+	assert.Eq(frame.Type, CHAT_FRAME_MESSAGE_EDITED)
+	assert.Eq(frame.Message.Content, "Edited")
+
+	code, body := utils.TestRequest(api, "GET", "/groups/by-id/g/messages/by-id/msg-01/history", tokenMember, "")
+	assert.Eq(code, 200)
+	var edits []groupMessageEditData
+	err = json.Unmarshal([]byte(body), &edits)
+	assert.Nil(err)
+	assert.Eq(len(edits), 1)
+	assert.Eq(edits[0].Content, "Original")
+
+	// Messages have to belong to the group in the path
+	code, _ = change("other-01", "edit", tokenMember, `{ "content": "Changed" }`)
+	assert.Eq(code, 404)
+	code, _ = change("unknown", "reactions/add", tokenMember, `{ "emoji": "👍" }`)
+	assert.Eq(code, 404)
+
+	// Reactions are grouped by emoji, adding one twice doesn't change anything
+	code, msg = change("msg-01", "reactions/add", tokenMember, `{ "emoji": "👍" }`)
+	assert.Eq(code, 200)
+	frame = receive()
+	assert.Eq(frame.Type, CHAT_FRAME_REACTION_ADDED)
+	assert.Eq(*frame.Reaction, chatReaction{Emoji: "👍", UserId: "m6SYNABgAw"})
+	code, _ = change("msg-01", "reactions/add", tokenMember, `{ "emoji": "👍" }`)
+	assert.Eq(code, 200)
+
+	for _, emoji := range []string{"👍🏽", "🇦🇹", "👨‍👩‍👧"} {
+		code, msg = change("msg-01", "reactions/add", tokenAuthor, `{ "emoji": "`+emoji+`" }`)
+		assert.Eq(code, 200, emoji)
+		assert.Eq(receive().Reaction.Emoji, emoji)
+	}
+	code, msg = change("msg-01", "reactions/add", tokenAuthor, `{ "emoji": "👍" }`)
+	assert.Eq(code, 200)
+	assert.Eq(receive().Type, CHAT_FRAME_REACTION_ADDED)
+	assert.Eq(len(msg.Reactions), 4)
+	assert.Eq(msg.Reactions[0].Emoji, "👍")
+	assert.True(slices.Equal(msg.Reactions[0].UserIds, []string{"m6SYNABgAw", "nmBSHcxyvn"}))
+
+	for _, emoji := range []string{"", "a", "👍 a", "1", strings.Repeat("👍", 10)} {
+		code, _ = change("msg-01", "reactions/add", tokenMember, `{ "emoji": "`+emoji+`" }`)
+		assert.Eq(code, 400, emoji)
+	}
+
+	code, msg = change("msg-01", "reactions/remove", tokenMember, `{ "emoji": "👍" }`)
+	assert.Eq(code, 200)
+	assert.True(slices.Equal(msg.Reactions[0].UserIds, []string{"nmBSHcxyvn"}))
+	frame = receive()
+	assert.Eq(frame.Type, CHAT_FRAME_REACTION_REMOVED)
+	assert.Eq(frame.Reaction.UserId, "m6SYNABgAw")
+
+	// Replies have to be to messages of the same group
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/send-message", tokenMember, `{ "groupId": "g", "content": "Reply", "repliesTo": "other-01" }`)
+	assert.Eq(code, 400)
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/send-message", tokenMember, `{ "groupId": "g", "content": "Reply", "repliesTo": "msg-02" }`)
+	assert.Eq(code, 201)
+	assert.Eq(*receive().Message.RepliesTo, "msg-02")
+
+	// Messages are deleted by their author or the owner of the group
+	code, _ = change("msg-01", "delete", tokenMember, "")
+	assert.Eq(code, 403)
+	code, msg = change("msg-02", "delete", tokenOwner, "")
+	assert.Eq(code, 200)
+	assert.Eq(msg.Content, "")
+	assert.True(msg.DeletedAt != nil)
+	frame = receive()
+	assert.Eq(frame.Type, CHAT_FRAME_MESSAGE_DELETED)
+	assert.Eq(frame.Message.MessageId, "msg-02")
+	assert.Eq(frame.Message.Content, "")
+
+	code, _ = change("msg-01", "delete", tokenAuthor, "")
+	assert.Eq(code, 200)
+	assert.Eq(receive().Type, CHAT_FRAME_MESSAGE_DELETED)
+
+	// Deleted messages can't be changed or replied to
+	code, _ = change("msg-01", "edit", tokenAuthor, `{ "content": "Again" }`)
+	assert.Eq(code, 409)
+	code, _ = change("msg-01", "reactions/add", tokenAuthor, `{ "emoji": "👍" }`)
+	assert.Eq(code, 409)
+	code, _ = change("msg-01", "delete", tokenAuthor, "")
+	assert.Eq(code, 409)
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/send-message", tokenMember, `{ "groupId": "g", "content": "Reply", "repliesTo": "msg-01" }`)
+	assert.Eq(code, 400)
+
+	// The history only contains deleted messages without content
+	code, body = utils.TestRequest(api, "GET", "/groups/by-id/g/messages", tokenMember, "")
+	assert.Eq(code, 200)
+	var page Page[GroupMessageData]
+	err = json.Unmarshal([]byte(body), &page)
+	assert.Nil(err)
+	assert.Eq(page.Items[0].Content, "")
+	assert.Eq(len(page.Items[0].Reactions), 0)
+	assert.Eq(page.Items[2].Content, "Reply")
+}
+
+func TestChatHubBackpressure(t *testing.T) {
+	hub := newChatHub()
+
+	newClient := func(userId string) *chatClient {
+		return &chatClient{
+			user:    sqlc.User{ID: userId},
+			channel: groupChat("g"),
+			queue:   make(chan chatFrame, chatQueueSize),
+			removed: make(chan struct{}),
+		}
+	}
+
+	slow := newClient("slow")
+	// can hold all frames, like a client that keeps up
+	fast := newClient("fast")
+	fast.queue = make(chan chatFrame, chatQueueSize*4)
+	other := newClient("other")
+	other.channel = groupChat("other")
+	hub.join(slow)
+	hub.join(fast)
+	hub.join(other)
+
+	// The slow client is dropped once its queue is full, without blocking the others
+	for idx := range chatQueueSize * 4 {
+		hub.broadcast(groupChat("g"), chatFrame{Type: CHAT_FRAME_MESSAGE, Message: &GroupMessageData{MessageId: fmt.Sprint(idx)}})
+	}
+
+	select {
+	case <-slow.removed:
+	default:
+		t.Fatal("Slow client wasn't removed.")
+	}
+	assert.Eq(len(slow.queue), chatQueueSize)
+	assert.Eq(len(fast.queue), chatQueueSize*4)
+	assert.Eq(len(other.queue), 0)
+	assert.Eq(hub.clientCount(groupChat("g")), 1)
+
+	// Removing a client twice is fine
+	hub.leave(slow)
+	hub.disconnectMember(groupChat("g"), "fast")
+	<-fast.removed
+	assert.Eq(hub.clientCount(groupChat("g")), 0)
+	assert.Eq(hub.clientCount(groupChat("other")), 1)
+}
+
+func TestRideThread(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0032-ride-threads.sql"))
+	handler := NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenOwner := utils.TEST_TOKEN_USER_01
+	tokenDriver := utils.TEST_TOKEN_USER_02
+	tokenPassenger := utils.TEST_TOKEN_USER_03
+
+	getRide := func(token string) RideEventData {
+		code, body := utils.TestRequest(api, "GET", "/rides/by-id/ride-event", token, "")
+		assert.Eq(code, 200)
+		var ride RideEventData
+		err := json.Unmarshal([]byte(body), &ride)
+		assert.Nil(err)
+		return ride
+	}
+
+	webAppUrl := utils.GetEnvRequired(common.ENV_WEB_APP_URL)
+	connect := func(token string) *websocket.Conn {
+		code, body := utils.TestRequest(api, "POST", "/rides/by-id/ride-event/chat-ticket", token, "")
+		assert.Eq(code, 200)
+		var resp chatTicketResponse
+		err := json.Unmarshal([]byte(body), &resp)
+		assert.Nil(err)
+
+		conn, err := websocket.Dial("ws"+strings.TrimPrefix(api.URL, "http")+"/rides/messages/ride-event?ticket="+resp.Ticket, "", webAppUrl)
+		assert.Nil(err)
+		return conn
+	}
+
+	receive := func(conn *websocket.Conn) (chatFrame, error) {
+		for {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var frame chatFrame
+			err := websocket.JSON.Receive(conn, &frame)
+			if err != nil || frame.Type != CHAT_FRAME_PING {
+				return frame, err
+			}
+		}
+	}
+
+	receiveMessage := func(conn *websocket.Conn, frameType string) GroupMessageData {
+		frame, err := receive(conn)
+		assert.Nil(err)
+		assert.Eq(frame.Type, frameType, frame.Error)
+		return *frame.Message
+	}
+
+	// Only the creator, the driver and the participants can use the thread
+	code, _ := utils.TestRequest(api, "POST", "/rides/by-id/ride-event/chat-ticket", tokenPassenger, "")
+	assert.Eq(code, 403)
+	code, _ = utils.TestRequest(api, "GET", "/rides/by-id/ride-event/messages", tokenPassenger, "")
+	assert.Eq(code, 403)
+	assert.True(getRide(tokenPassenger).Thread == nil)
+
+	thread := getRide(tokenDriver).Thread
+	assert.Eq(thread.Total, int64(1))
+	assert.Eq(thread.Items[0].MessageId, "thread-01")
+	assert.Eq(*thread.Items[0].RideEventId, "ride-event")
+	assert.True(thread.Items[0].GroupId == nil)
+
+	// Messages of group chats aren't part of the thread
+	code, _ = utils.TestRequest(api, "POST", "/rides/by-id/ride-event/messages/by-id/group-01/edit", tokenDriver, `{ "content": "Changed" }`)
+	assert.Eq(code, 404)
+	code, _ = utils.TestRequest(api, "POST", "/rides/by-id/ride-event/send-message", tokenDriver, `{ "content": "Reply", "repliesTo": "group-01" }`)
+	assert.Eq(code, 400)
+
+	driver := connect(tokenDriver)
+	defer driver.Close()
+	assert.Eq(receiveMessage(driver, CHAT_FRAME_MESSAGE).MessageId, "thread-01")
+
+	// Joining the ride gives access to the thread
+	code, _ = utils.TestRequest(api, "POST", "/rides/join", tokenPassenger, `{ "rideEventId": "ride-event" }`)
+	assert.Eq(code, 200)
+	passenger := connect(tokenPassenger)
+	defer passenger.Close()
+	assert.Eq(receiveMessage(passenger, CHAT_FRAME_MESSAGE).MessageId, "thread-01")
+
+	code, _ = utils.TestRequest(api, "POST", "/rides/by-id/ride-event/send-message", tokenPassenger, `{ "content": "At the station", "repliesTo": "thread-01" }`)
+	assert.Eq(code, 201)
+	var sent GroupMessageData
+	for _, conn := range []*websocket.Conn{driver, passenger} {
+		sent = receiveMessage(conn, CHAT_FRAME_MESSAGE)
+		assert.Eq(sent.Content, "At the station")
+		assert.Eq(*sent.RideEventId, "ride-event")
+	}
+	assert.Eq(getRide(tokenPassenger).Thread.Total, int64(2))
+
+	// The creator of the ride can delete messages of others
+	code, _ = utils.TestRequest(api, "POST", "/rides/by-id/ride-event/messages/by-id/"+sent.MessageId+"/delete", tokenDriver, "")
+	assert.Eq(code, 403)
+	code, _ = utils.TestRequest(api, "POST", "/rides/by-id/ride-event/messages/by-id/"+sent.MessageId+"/delete", tokenOwner, "")
+	assert.Eq(code, 200)
+	for _, conn := range []*websocket.Conn{driver, passenger} {
+		assert.Eq(receiveMessage(conn, CHAT_FRAME_MESSAGE_DELETED).MessageId, sent.MessageId)
+	}
+
+	// Leaving the ride closes the thread
+	code, _ = utils.TestRequest(api, "POST", "/rides/leave", tokenPassenger, `{ "rideEventId": "ride-event" }`)
+	assert.Eq(code, 200)
+	_, err := receive(passenger)
+	assert.Neq(err, nil)
+	assert.Eq(state.chat.clientCount(rideThread("ride-event")), 1)
+	assert.True(getRide(tokenPassenger).Thread == nil)
+	code, _ = utils.TestRequest(api, "POST", "/rides/by-id/ride-event/send-message", tokenPassenger, `{ "content": "Bye" }`)
+	assert.Eq(code, 403)
+
+	// A replaced driver loses access to the thread
+	code, _ = utils.TestRequest(api, "POST", "/rides/update", tokenOwner, `{ "rideEventId": "ride-event", "driver": "NnCaPHQLC9" }`)
+	assert.Eq(code, 200)
+	_, err = receive(driver)
+	assert.Neq(err, nil)
+	assert.Eq(state.chat.clientCount(rideThread("ride-event")), 0)
+}
@@ -9,7 +9,7 @@ import (
 func WithCors(mux *http.ServeMux) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header()["Access-Control-Allow-Origin"] = []string{utils.GetEnvRequired(common.ENV_WEB_APP_URL)}
-		w.Header()["Access-Control-Allow-Methods"] = []string{"GET", "POST", "OPTIONS"}
+		w.Header()["Access-Control-Allow-Methods"] = []string{"GET", "POST", "DELETE", "OPTIONS"}
 		w.Header()["Access-Control-Allow-Headers"] = []string{"*"}
 
 		if r.Method == http.MethodOptions {
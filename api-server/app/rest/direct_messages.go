@@ -0,0 +1,294 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+)
+
+// Direct messages are sent in a conversation between two users. They use the
+// chat hub and the message handlers of group chats, but can't be edited or
+// reacted to. Users who blocked each other can still read their conversation,
+// but can't send messages anymore.
+
+func directMessageHandlers(h *http.ServeMux) {
+	h.HandleFunc("GET /users/me/conversations", handle(getConversations).with(bearerAuth(false, SCOPE_CHAT)).build())
+	h.HandleFunc("POST /users/by-id/{id}/conversation", handle(openConversation).with(bearerAuth(false, SCOPE_CHAT)).build())
+
+	h.HandleFunc("POST /conversations/by-id/{id}/send-message", handle(groupMessageCreate(conversationChatFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireConversationMember()).build())
+	h.HandleFunc("GET /conversations/by-id/{id}/messages", handle(getGroupMessages(conversationChatFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireConversationMember()).build())
+	h.HandleFunc("POST /conversations/by-id/{id}/read", handle(markConversationRead).with(bearerAuth(false, SCOPE_CHAT)).with(requireConversationMember()).build())
+	h.HandleFunc("POST /conversations/by-id/{id}/chat-ticket", handle(createChatTicket(conversationChatFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireConversationMember()).build())
+	h.HandleFunc("GET /conversations/messages/{id}", handle(serveChat(conversationChatFromPath)).with(chatTicketAuth(conversationChatFromPath)).build())
+}
+
+type ConversationData struct {
+	ConversationId string `json:"conversationId"`
+	// The other user of the conversation.
+	UserId    string `json:"userId"`
+	UserEmail string `json:"userEmail"`
+	CreatedAt string `json:"createdAt"`
+	// Messages of the other user that weren't read yet.
+	UnreadCount int64 `json:"unreadCount"`
+	// Either user blocked the other, no messages can be sent.
+	IsBlocked   bool                     `json:"isBlocked"`
+	LastMessage *conversationLastMessage `json:"lastMessage"`
+}
+
+type conversationLastMessage struct {
+	MessageId string `json:"messageId"`
+	Content   string `json:"content"`
+	SentBy    string `json:"sentBy"`
+	CreatedAt string `json:"createdAt"`
+}
+
+var errMessagingBlocked = errors.New("You can't send messages to this user, one of you blocked the other.")
+
+// Stops the request with 404 unless the user is one of the two users of the
+// conversation in the 'id' path parameter. Has to run after `bearerAuth`.
+func requireConversationMember() func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+	return func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+
+		conversation, err := state.queries.ConversationsGetById(r.Context(), r.PathValue("id"))
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && !isConversationMember(user, conversation)) {
+			httpWriteErr(w, http.StatusNotFound, "No conversation exists with 'id'.")
+			return true, nil
+		}
+		assert.Nil(err)
+
+		return false, &middlewareData{key: "conversation", value: conversation}
+	}
+}
+
+func isConversationMember(user sqlc.User, conversation sqlc.Conversation) bool {
+	return conversation.UserA == user.ID || conversation.UserB == user.ID
+}
+
+func getConversations(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	conversations, err := loadConversations(r.Context(), user, nil)
+	assert.Nil(err)
+
+	resp, err := json.Marshal(conversations)
+	assert.Nil(err, "Failed to serialize conversations.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+// Returns the conversation with the user in the 'id' path parameter, it is
+// created if the users never had one.
+func openConversation(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	other, err := state.queries.UsersGetById(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		httpWriteErr(w, http.StatusNotFound, "No user exists with 'id'.")
+		return
+	}
+	assert.Nil(err)
+
+	if other.ID == user.ID {
+		httpWriteErr(w, http.StatusBadRequest, "You can't start a conversation with yourself.")
+		return
+	}
+
+	blocked, err := state.queries.UserBlocksBetween(r.Context(), sqlc.UserBlocksBetweenParams{UserA: user.ID, UserB: other.ID})
+	assert.Nil(err)
+	if blocked != 0 {
+		httpWriteErr(w, http.StatusForbidden, errMessagingBlocked.Error())
+		return
+	}
+
+	args := sqlc.ConversationsCreateParams{UserA: min(user.ID, other.ID), UserB: max(user.ID, other.ID)}
+	err = state.queries.ConversationsCreate(r.Context(), args)
+	assert.Nil(err)
+
+	conversation, err := state.queries.ConversationsGetByUsers(r.Context(), sqlc.ConversationsGetByUsersParams(args))
+	assert.Nil(err)
+
+	writeConversation(w, r, user, conversation.ID)
+}
+
+// Marks the messages of the other user as read.
+func markConversationRead(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+	conversation := getMiddlewareData[sqlc.Conversation](r, "conversation")
+
+	_, err := state.queries.DirectMessagesMarkRead(r.Context(), sqlc.DirectMessagesMarkReadParams{ConversationID: conversation.ID, SentBy: user.ID})
+	if err != nil {
+		log.Println("Error: Failed to mark messages as read.", "conversation id:", conversation.ID, "error:", err)
+		httpWriteErr(w, http.StatusInternalServerError, "Failed to mark messages as read.")
+		return
+	}
+
+	writeConversation(w, r, user, conversation.ID)
+}
+
+func writeConversation(w http.ResponseWriter, r *http.Request, user sqlc.User, conversationId string) {
+	conversations, err := loadConversations(r.Context(), user, &conversationId)
+	assert.Nil(err)
+	assert.True(len(conversations) == 1, "Conversation of user not found.", "conversation id:", conversationId)
+
+	resp, err := json.Marshal(conversations[0])
+	assert.Nil(err, "Failed to serialize conversation.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+// Loads the conversations of a user, or only the one with the id
+// `conversationId` if it isn't `nil`.
+func loadConversations(ctx context.Context, user sqlc.User, conversationId *string) ([]ConversationData, error) {
+	rows, err := state.queries.ConversationsGetForUser(ctx, sqlc.ConversationsGetForUserParams{UserID: user.ID, ConversationID: utils.SqlNullStr(conversationId)})
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := make([]ConversationData, len(rows))
+	for idx, row := range rows {
+		conversations[idx] = ConversationData{
+			ConversationId: row.ID,
+			UserId:         row.OtherUserID,
+			UserEmail:      row.OtherUserEmail,
+			CreatedAt:      row.CreatedAt,
+			UnreadCount:    row.UnreadCount,
+			IsBlocked:      row.IsBlocked != 0,
+		}
+
+		if row.LastMessageID.Valid {
+			conversations[idx].LastMessage = &conversationLastMessage{
+				MessageId: row.LastMessageID.String,
+				Content:   row.LastMessageContent.String,
+				SentBy:    row.LastMessageSentBy.String,
+				CreatedAt: row.LastMessageCreatedAt.String,
+			}
+		}
+	}
+
+	return conversations, nil
+}
+
+// Stores a direct message and sends it to the conversation, unless one of
+// the users blocked the other.
+func createDirectMessage(ctx context.Context, user sqlc.User, conversationId string, content string, repliesTo *string) (GroupMessageData, error) {
+	conversation, err := state.queries.ConversationsGetById(ctx, conversationId)
+	if err != nil {
+		return GroupMessageData{}, err
+	}
+
+	blocked, err := state.queries.UserBlocksBetween(ctx, sqlc.UserBlocksBetweenParams{UserA: conversation.UserA, UserB: conversation.UserB})
+	if err != nil {
+		return GroupMessageData{}, err
+	}
+	if blocked != 0 {
+		return GroupMessageData{}, errMessagingBlocked
+	}
+
+	if repliesTo != nil {
+		reply, err := state.queries.DirectMessagesGetById(ctx, *repliesTo)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && reply.ConversationID != conversationId) {
+			return GroupMessageData{}, errInvalidReply
+		}
+		if err != nil {
+			return GroupMessageData{}, err
+		}
+	}
+
+	argsCreateMessage := sqlc.DirectMessagesCreateParams{
+		ConversationID: conversationId,
+		Content:        content,
+		SentBy:         user.ID,
+		RepliesTo:      utils.SqlNullStr(repliesTo),
+	}
+
+	msg, err := state.queries.DirectMessagesCreate(ctx, argsCreateMessage)
+	if err != nil {
+		return GroupMessageData{}, err
+	}
+
+	msgData := directMessageRowToData(sqlc.DirectMessagesGetByIdRow{
+		ID:             msg.ID,
+		ConversationID: msg.ConversationID,
+		Content:        msg.Content,
+		SentBy:         msg.SentBy,
+		SentByEmail:    user.Email,
+		CreatedAt:      msg.CreatedAt,
+		RepliesTo:      msg.RepliesTo,
+		ReadAt:         msg.ReadAt,
+	})
+
+	state.chat.broadcast(conversationChat(conversationId), chatFrame{Type: CHAT_FRAME_MESSAGE, Message: &msgData})
+
+	n := notification{Type: NOTIFICATION_DIRECT_MESSAGE, Actor: user, ConversationId: conversationId, MessageId: msg.ID}
+	notify(ctx, n, conversation.UserA, conversation.UserB)
+
+	return msgData, nil
+}
+
+// See `loadGroupMessagesBefore`.
+func loadDirectMessagesBefore(ctx context.Context, conversationId string, before *string, size int64) (msgs []GroupMessageData, hasMore bool, err error) {
+	rows, err := state.queries.DirectMessagesGetPage(ctx, sqlc.DirectMessagesGetPageParams{
+		ConversationID: conversationId,
+		BeforeID:       utils.SqlNullStr(before),
+		PageSize:       size + 1,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore = int64(len(rows)) > size
+	if hasMore {
+		rows = rows[:size]
+	}
+
+	msgs = make([]GroupMessageData, len(rows))
+	for idx, row := range rows {
+		msgs[len(rows)-1-idx] = directMessageRowToData(sqlc.DirectMessagesGetByIdRow(row))
+	}
+
+	return msgs, hasMore, nil
+}
+
+// See `loadGroupMessagesSince`.
+func loadDirectMessagesSince(ctx context.Context, conversationId string, since string, size int64) (msgs []GroupMessageData, hasMore bool, err error) {
+	rows, err := state.queries.DirectMessagesGetSince(ctx, sqlc.DirectMessagesGetSinceParams{
+		ConversationID: conversationId,
+		SinceID:        since,
+		PageSize:       size + 1,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore = int64(len(rows)) > size
+	if hasMore {
+		rows = rows[:size]
+	}
+
+	msgs = make([]GroupMessageData, len(rows))
+	for idx, row := range rows {
+		msgs[idx] = directMessageRowToData(sqlc.DirectMessagesGetByIdRow(row))
+	}
+
+	return msgs, hasMore, nil
+}
+
+func directMessageRowToData(msg sqlc.DirectMessagesGetByIdRow) GroupMessageData {
+	return GroupMessageData{
+		MessageId:      msg.ID,
+		ConversationId: &msg.ConversationID,
+		Content:        msg.Content,
+		SentBy:         msg.SentBy,
+		SentByEmail:    msg.SentByEmail,
+		RepliesTo:      utils.SqlNullStrToPtr(msg.RepliesTo),
+		CreatedAt:      msg.CreatedAt,
+		Reactions:      make([]GroupMessageReaction, 0),
+	}
+}
@@ -0,0 +1,184 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/common"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	websocket "golang.org/x/net/websocket"
+)
+
+func TestDirectMessages(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0033-direct-messages.sql"))
+	handler := NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenAdmin := utils.TEST_TOKEN_USER_01
+	tokenUser02 := utils.TEST_TOKEN_USER_02
+	tokenUser03 := utils.TEST_TOKEN_USER_03
+
+	conversation := func(method string, endpoint string, token string) ConversationData {
+		code, body := utils.TestRequest(api, method, endpoint, token, "")
+		assert.Eq(code, 200, body)
+		var data ConversationData
+		err := json.Unmarshal([]byte(body), &data)
+		assert.Nil(err)
+		return data
+	}
+
+	conversations := func(token string) []ConversationData {
+		code, body := utils.TestRequest(api, "GET", "/users/me/conversations", token, "")
+		assert.Eq(code, 200)
+		var data []ConversationData
+		err := json.Unmarshal([]byte(body), &data)
+		assert.Nil(err)
+		return data
+	}
+
+	webAppUrl := utils.GetEnvRequired(common.ENV_WEB_APP_URL)
+	connect := func(token string, conversationId string) *websocket.Conn {
+		code, body := utils.TestRequest(api, "POST", "/conversations/by-id/"+conversationId+"/chat-ticket", token, "")
+		assert.Eq(code, 200)
+		var resp chatTicketResponse
+		err := json.Unmarshal([]byte(body), &resp)
+		assert.Nil(err)
+
+		conn, err := websocket.Dial("ws"+strings.TrimPrefix(api.URL, "http")+"/conversations/messages/"+conversationId+"?ticket="+resp.Ticket, "", webAppUrl)
+		assert.Nil(err)
+		return conn
+	}
+
+	receive := func(conn *websocket.Conn) chatFrame {
+		for {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var frame chatFrame
+			err := websocket.JSON.Receive(conn, &frame)
+			assert.Nil(err)
+			if frame.Type != CHAT_FRAME_PING {
+				return frame
+			}
+		}
+	}
+
+	// There is only one conversation for two users
+	code, _ := utils.TestRequest(api, "POST", "/users/by-id/NnCaPHQLC9/conversation", tokenAdmin, "")
+	assert.Eq(code, 400)
+	code, _ = utils.TestRequest(api, "POST", "/users/by-id/unknown/conversation", tokenAdmin, "")
+	assert.Eq(code, 404)
+
+	opened := conversation("POST", "/users/by-id/nmBSHcxyvn/conversation", tokenAdmin)
+	assert.Eq(opened.UserId, "nmBSHcxyvn")
+	assert.True(opened.LastMessage == nil)
+	assert.Eq(conversation("POST", "/users/by-id/nmBSHcxyvn/conversation", tokenAdmin).ConversationId, opened.ConversationId)
+	assert.Eq(conversation("POST", "/users/by-id/NnCaPHQLC9/conversation", tokenUser02).ConversationId, opened.ConversationId)
+	id := opened.ConversationId
+
+	// Only the two users can use a conversation, not even admins
+	code, _ = utils.TestRequest(api, "GET", "/conversations/by-id/"+id+"/messages", tokenUser03, "")
+	assert.Eq(code, 404)
+	code, _ = utils.TestRequest(api, "POST", "/conversations/by-id/"+id+"/chat-ticket", tokenUser03, "")
+	assert.Eq(code, 404)
+	code, _ = utils.TestRequest(api, "GET", "/conversations/by-id/private/messages", tokenAdmin, "")
+	assert.Eq(code, 404)
+	code, _ = utils.TestRequest(api, "POST", "/conversations/by-id/private/send-message", tokenAdmin, `{ "content": "Hi" }`)
+	assert.Eq(code, 404)
+	_, err := websocket.Dial("ws"+strings.TrimPrefix(api.URL, "http")+"/conversations/messages/private?ticket="+state.chat.issueTicket("NnCaPHQLC9", conversationChat("private")), "", webAppUrl)
+	assert.Neq(err, nil)
+
+	// Messages are delivered to both users
+	admin := connect(tokenAdmin, id)
+	defer admin.Close()
+	user02 := connect(tokenUser02, id)
+	defer user02.Close()
+
+	err = websocket.JSON.Send(admin, map[string]any{"type": "message", "content": "Are there seats left?"})
+	assert.Nil(err)
+	var question GroupMessageData
+	for _, conn := range []*websocket.Conn{admin, user02} {
+		frame := receive(conn)
+		assert.Eq(frame.Type, CHAT_FRAME_MESSAGE, frame.Error)
+		question = *frame.Message
+		assert.Eq(question.Content, "Are there seats left?")
+		assert.Eq(*question.ConversationId, id)
+		assert.True(question.GroupId == nil)
+	}
+
+	code, _ = utils.TestRequest(api, "POST", "/conversations/by-id/"+id+"/send-message", tokenUser02, `{ "content": "Two", "repliesTo": "`+question.MessageId+`" }`)
+	assert.Eq(code, 201)
+	for _, conn := range []*websocket.Conn{admin, user02} {
+		assert.Eq(*receive(conn).Message.RepliesTo, question.MessageId)
+	}
+
+	code, _ = utils.TestRequest(api, "POST", "/conversations/by-id/"+id+"/send-message", tokenUser02, `{ "content": "Hi", "repliesTo": "private-01" }`)
+	assert.Eq(code, 400)
+
+	// Unread messages are counted until they are read
+	list := conversations(tokenUser02)
+	assert.Eq(len(list), 2)
+	assert.Eq(list[0].ConversationId, id)
+	assert.Eq(list[0].UserEmail, "test@example.com")
+	assert.Eq(list[0].UnreadCount, int64(1))
+	assert.Eq(list[0].LastMessage.Content, "Two")
+	assert.Eq(list[1].ConversationId, "private")
+	assert.Eq(list[1].UnreadCount, int64(1))
+
+	assert.Eq(conversation("POST", "/conversations/by-id/"+id+"/read", tokenUser02).UnreadCount, int64(0))
+	assert.Eq(conversations(tokenUser02)[0].UnreadCount, int64(0))
+	assert.Eq(conversations(tokenAdmin)[0].UnreadCount, int64(1))
+
+	code, body := utils.TestRequest(api, "GET", "/conversations/by-id/"+id+"/messages", tokenAdmin, "")
+	assert.Eq(code, 200)
+	var page Page[GroupMessageData]
+	err = json.Unmarshal([]byte(body), &page)
+	assert.Nil(err)
+	assert.Eq(page.Total, int64(2))
+	assert.Eq(page.Items[0].MessageId, question.MessageId)
+
+	// Users who blocked each other can't message one another
+	code, _ = utils.TestRequest(api, "POST", "/users/by-id/nmBSHcxyvn/block", tokenUser02, "")
+	assert.Eq(code, 400)
+	code, _ = utils.TestRequest(api, "POST", "/users/by-id/NnCaPHQLC9/block", tokenUser02, "")
+	assert.Eq(code, 200)
+	code, body = utils.TestRequest(api, "GET", "/users/me/blocks", tokenUser02, "")
+	assert.Eq(code, 200)
+	var blocks []BlockedUserData
+	err = json.Unmarshal([]byte(body), &blocks)
+	assert.Nil(err)
+	assert.Eq(len(blocks), 1)
+	assert.Eq(blocks[0].UserId, "NnCaPHQLC9")
+
+	for _, token := range []string{tokenAdmin, tokenUser02} {
+		code, _ = utils.TestRequest(api, "POST", "/conversations/by-id/"+id+"/send-message", token, `{ "content": "Hello?" }`)
+		assert.Eq(code, 403)
+		assert.True(conversations(token)[0].IsBlocked)
+	}
+	code, _ = utils.TestRequest(api, "POST", "/users/by-id/nmBSHcxyvn/conversation", tokenAdmin, "")
+	assert.Eq(code, 403)
+
+	err = websocket.JSON.Send(admin, map[string]any{"type": "message", "content": "Hello?"})
+	assert.Nil(err)
+	frame := receive(admin)
+	assert.Eq(frame.Type, CHAT_FRAME_ERROR)
+	assert.Eq(frame.Error, errMessagingBlocked.Error())
+
+	// The conversation can still be read
+	code, _ = utils.TestRequest(api, "GET", "/conversations/by-id/"+id+"/messages", tokenAdmin, "")
+	assert.Eq(code, 200)
+
+	code, _ = utils.TestRequest(api, "POST", "/users/by-id/NnCaPHQLC9/unblock", tokenUser02, "")
+	assert.Eq(code, 200)
+	code, _ = utils.TestRequest(api, "POST", "/conversations/by-id/"+id+"/send-message", tokenAdmin, `{ "content": "Hello?" }`)
+	assert.Eq(code, 201)
+	for _, conn := range []*websocket.Conn{admin, user02} {
+		assert.Eq(receive(conn).Message.Content, "Hello?")
+	}
+}
@@ -0,0 +1,399 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Changes to rides are published on an event bus after they were committed.
+// Clients subscribe to them at '/rides/events', a stream of Server-Sent
+// Events that only contains the rides the user can see or takes part in.
+//
+// Streams are opened with the 'Authorization' header or, since EventSource
+// can't send it, with a short-lived ticket from the 'ticket' route of the
+// stream, e.g. '/rides/events/ticket', in the 'ticket' query parameter. The
+// token the stream was opened with is checked again with every keep-alive,
+// the stream ends once it expired or was revoked.
+
+const (
+	RIDE_EVENT_CREATED  = "ride.created"
+	RIDE_EVENT_JOINED   = "ride.joined"
+	RIDE_EVENT_LEFT     = "ride.left"
+	RIDE_EVENT_UPDATED  = "ride.updated"
+	RIDE_EVENT_CANCELED = "ride.canceled"
+)
+
+const (
+	// Events a subscriber can fall behind before it is dropped. Publishing
+	// never waits for slow subscribers.
+	eventBusQueueSize = 64
+	// Comments are sent this often so proxies don't close idle streams.
+	eventStreamKeepAlive = 30 * time.Second
+	streamTicketLifetime = 30 * time.Second
+)
+
+// Delivers published values to every subscriber, in the order they were
+// published.
+type eventBus[T any] struct {
+	mutex       sync.Mutex
+	subscribers map[*subscription[T]]struct{}
+	keepAlive   time.Duration
+}
+
+type subscription[T any] struct {
+	userId string
+	events chan T
+	// Closed when the subscriber fell too far behind and was dropped.
+	dropped chan struct{}
+	// Closed when the user was blocked.
+	disconnected chan struct{}
+}
+
+// The token an event stream was opened with.
+type streamCredentials struct {
+	userId string
+	// The access token of a session or a personal access token.
+	token string
+	// Only set for access tokens, personal access tokens are looked up again.
+	expiresAt time.Time
+}
+
+type streamTickets struct {
+	mutex   sync.Mutex
+	tickets map[string]streamTicket
+}
+
+type streamTicket struct {
+	path        string
+	credentials streamCredentials
+	expiresAt   time.Time
+}
+
+type streamTicketResponse struct {
+	Ticket string `json:"ticket"`
+}
+
+// A change to a ride event, sent with the state of the ride event after the
+// change.
+type RideUpdate struct {
+	Type string `json:"type"`
+	// The user whose action caused the change. Empty for changes made by the
+	// scheduler.
+	UserId string         `json:"userId"`
+	Ride   *RideEventData `json:"ride"`
+}
+
+func newEventBus[T any]() *eventBus[T] {
+	return &eventBus[T]{subscribers: make(map[*subscription[T]]struct{}), keepAlive: eventStreamKeepAlive}
+}
+
+func (b *eventBus[T]) subscribe(userId string) *subscription[T] {
+	sub := &subscription[T]{
+		userId:       userId,
+		events:       make(chan T, eventBusQueueSize),
+		dropped:      make(chan struct{}),
+		disconnected: make(chan struct{}),
+	}
+
+	b.mutex.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mutex.Unlock()
+
+	return sub
+}
+
+func (b *eventBus[T]) unsubscribe(sub *subscription[T]) {
+	b.mutex.Lock()
+	delete(b.subscribers, sub)
+	b.mutex.Unlock()
+}
+
+func (b *eventBus[T]) publish(event T) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.dropped)
+		}
+	}
+}
+
+// Ends the streams of the user, e.g. after they were blocked.
+func (b *eventBus[T]) disconnectUser(userId string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for sub := range b.subscribers {
+		if sub.userId == userId {
+			delete(b.subscribers, sub)
+			close(sub.disconnected)
+		}
+	}
+}
+
+func rideEventHandlers(h *http.ServeMux) {
+	h.HandleFunc("GET /rides/events", handle(streamRideUpdates).with(streamAuth(SCOPE_RIDES_READ)).build())
+	h.HandleFunc("POST /rides/events/ticket", handle(createStreamTicket("/rides/events")).with(bearerAuth(false, SCOPE_RIDES_READ)).build())
+}
+
+// Publishes a change to the ride event with the id `rideEventId`. Has to be
+// called after the change was committed, so subscribers never see changes
+// that were rolled back.
+func publishRideUpdate(ctx context.Context, queries *sqlc.Queries, updateType string, userId string, rideEventId string) {
+	// the scheduler can run without the API
+	if state == nil {
+		return
+	}
+
+	event, err := queries.RidesGetEvent(ctx, rideEventId)
+	if err != nil {
+		log.Println("Error: Failed to load ride event for update.", "ride event id:", rideEventId, "error:", err)
+		return
+	}
+
+	ride, err := loadRideEventData(ctx, queries, eventToRideRow(event))
+	if err != nil {
+		log.Println("Error: Failed to load ride event for update.", "ride event id:", rideEventId, "error:", err)
+		return
+	}
+
+	state.rideUpdates.publish(RideUpdate{Type: updateType, UserId: userId, Ride: ride})
+}
+
+// Users get updates of rides they can see, take part in or are waiting for,
+// and of their own actions.
+func canSeeRideUpdate(ctx context.Context, update RideUpdate, user sqlc.User) bool {
+	if update.UserId == user.ID {
+		return true
+	}
+
+	isUser := func(p rideParticipant) bool { return p.UserId == user.ID }
+	if slices.ContainsFunc(update.Ride.Participants, isUser) || slices.ContainsFunc(update.Ride.Waitlist, isUser) {
+		return true
+	}
+
+	return canSeeRide(ctx, state.queries, utils.SqlNullStr(update.Ride.GroupId), user.ID)
+}
+
+// Streams the changes to rides as Server-Sent Events until the client
+// disconnects. The event name is the type of the change, the data is a
+// `RideUpdate`.
+func streamRideUpdates(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	streamEvents(w, r, state.rideUpdates, func(update RideUpdate) (string, bool) {
+		return update.Type, canSeeRideUpdate(r.Context(), update, user)
+	})
+}
+
+// Streams the values published on `bus` as Server-Sent Events until the
+// client disconnects. `eventOf` returns the event name of a value and if it is
+// sent to the user at all, the data is the value itself.
+func streamEvents[T any](w http.ResponseWriter, r *http.Request, bus *eventBus[T], eventOf func(value T) (string, bool)) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	flusher, ok := w.(http.Flusher)
+	assert.True(ok, "Response writer doesn't support streaming.")
+
+	credentials := getMiddlewareData[streamCredentials](r, "streamCredentials")
+
+	sub := bus.subscribe(user.ID)
+	defer bus.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(bus.keepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.dropped:
+			log.Println("Dropped event stream subscriber that fell behind.", "path:", r.URL.Path, "user id:", user.ID)
+			return
+		case <-sub.disconnected:
+			return
+		case <-keepAlive.C:
+			if _, ok := credentials.check(r.Context()); !ok {
+				return
+			}
+
+			_, err := fmt.Fprint(w, ": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+		case value := <-sub.events:
+			name, ok := eventOf(value)
+			if !ok {
+				continue
+			}
+
+			data, err := json.Marshal(value)
+			assert.Nil(err, "Failed to serialize event.", "event:", name)
+
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+			if err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+func newStreamTickets() *streamTickets {
+	return &streamTickets{tickets: make(map[string]streamTicket)}
+}
+
+// Authenticates an event stream like `bearerAuth`, or with a ticket in the
+// 'ticket' query parameter. Provides the `streamCredentials` of the stream.
+func streamAuth(scopes ...string) func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+	bearer := bearerAuth(false, scopes...)
+
+	return func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+		var credentials streamCredentials
+		var user sqlc.User
+		if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+			var ok bool
+			credentials, ok = state.streamTickets.consume(ticket, r.URL.Path)
+			if ok {
+				user, ok = credentials.check(r.Context())
+			}
+			if !ok {
+				http.Error(w, "Invalid or expired ticket in 'ticket' query parameter.", http.StatusUnauthorized)
+				return true, nil
+			}
+		} else {
+			stop, data := bearer(w, r)
+			if stop {
+				return true, nil
+			}
+
+			user = data.value.(sqlc.User)
+			var err error
+			credentials, err = newStreamCredentials(r.Context(), user.ID, r.Header.Get("Authorization"))
+			if err != nil {
+				http.Error(w, "Invalid access token in 'Authorization' header.", http.StatusUnauthorized)
+				return true, nil
+			}
+		}
+
+		setMiddlewareData(r, "streamCredentials", credentials)
+		return false, &middlewareData{key: "user", value: user}
+	}
+}
+
+func newStreamCredentials(ctx context.Context, userId string, token string) (streamCredentials, error) {
+	credentials := streamCredentials{userId: userId, token: token}
+	if isPersonalToken(token) {
+		return credentials, nil
+	}
+
+	decoded, err := decodeAccessToken(ctx, state.authKeys, token)
+	if err != nil {
+		return streamCredentials{}, err
+	}
+
+	credentials.expiresAt = decoded.ExpiresAt
+	return credentials, nil
+}
+
+// Returns the user if the token is still valid and the user isn't blocked.
+// Access tokens are revoked when their session is refreshed or deleted.
+func (c streamCredentials) check(ctx context.Context) (sqlc.User, bool) {
+	user, err := state.queries.UsersGetById(ctx, c.userId)
+	if err != nil || user.IsBlocked {
+		return sqlc.User{}, false
+	}
+
+	if isPersonalToken(c.token) {
+		token, err := state.queries.PersonalTokensGetByHash(ctx, hashToken(c.token))
+		if err != nil || token.UserID != c.userId {
+			return sqlc.User{}, false
+		}
+
+		expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt)
+		return user, err == nil && time.Now().Before(expiresAt)
+	}
+
+	if time.Now().After(c.expiresAt) {
+		return sqlc.User{}, false
+	}
+
+	session, err := state.queries.SessionsGetByAccessTokenHash(ctx, hashToken(c.token))
+	return user, err == nil && session.UserID == c.userId
+}
+
+// Issues a ticket to open the stream at `path` with the token of the request.
+func createStreamTicket(path string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+
+		credentials, err := newStreamCredentials(r.Context(), user.ID, r.Header.Get("Authorization"))
+		if err != nil {
+			http.Error(w, "Invalid access token in 'Authorization' header.", http.StatusUnauthorized)
+			return
+		}
+
+		ticket := state.streamTickets.issue(path, credentials)
+
+		resp, err := json.Marshal(streamTicketResponse{Ticket: ticket})
+		assert.Nil(err, "Failed to serialize stream ticket.")
+		w.WriteHeader(200)
+		w.Write(resp)
+	}
+}
+
+func (t *streamTickets) issue(path string, credentials streamCredentials) string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	ticket := base64.RawURLEncoding.EncodeToString(b)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	for key, issued := range t.tickets {
+		if now.After(issued.expiresAt) {
+			delete(t.tickets, key)
+		}
+	}
+	t.tickets[ticket] = streamTicket{path: path, credentials: credentials, expiresAt: now.Add(streamTicketLifetime)}
+
+	return ticket
+}
+
+// Tickets can only be used once, for the stream they were issued for.
+func (t *streamTickets) consume(ticket string, path string) (streamCredentials, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	issued, ok := t.tickets[ticket]
+	delete(t.tickets, ticket)
+
+	if !ok || issued.path != path || time.Now().After(issued.expiresAt) {
+		return streamCredentials{}, false
+	}
+
+	return issued.credentials, true
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestEventStreamAuth(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0036-event-stream-auth.sql"))
+	handler := NewRESTApi(db)
+	// the ride updates only end early if the user is disconnected
+	state.rideUpdates.keepAlive = time.Hour
+	state.notifications.keepAlive = 50 * time.Millisecond
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenUser01 := utils.TEST_TOKEN_USER_01
+	tokenUser03 := utils.TEST_TOKEN_USER_03
+
+	ticket := func(stream string, token string) string {
+		code, body := utils.TestRequest(api, "POST", stream+"/ticket", token, "")
+		assert.Eq(code, 200, body)
+		var resp streamTicketResponse
+		err := json.Unmarshal([]byte(body), &resp)
+		assert.Nil(err)
+		return resp.Ticket
+	}
+
+	// Returns a channel that is closed once the server ended the stream.
+	open := func(endpoint string, token string) chan struct{} {
+		req, err := http.NewRequest("GET", api.URL+endpoint, nil)
+		assert.Nil(err)
+		if token != "" {
+			req.Header.Add("Authorization", token)
+		}
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 200)
+
+		ended := make(chan struct{})
+		go func() {
+			defer close(ended)
+			defer resp.Body.Close()
+			io.Copy(io.Discard, resp.Body)
+		}()
+		return ended
+	}
+
+	assertEnds := func(ended chan struct{}) {
+		select {
+		case <-ended:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the stream to end.")
+		}
+	}
+
+	// Tickets open the stream they were issued for, once
+	notificationsTicket := ticket("/users/me/notifications/events", tokenUser01)
+	code, _ := utils.TestRequest(api, "GET", "/rides/events?ticket="+notificationsTicket, "", "")
+	assert.Eq(code, 401)
+	notificationsTicket = ticket("/users/me/notifications/events", tokenUser01)
+	notifications := open("/users/me/notifications/events?ticket="+notificationsTicket, "")
+	code, _ = utils.TestRequest(api, "GET", "/users/me/notifications/events?ticket="+notificationsTicket, "", "")
+	assert.Eq(code, 401)
+
+	// Streams end when the session they were opened with is gone
+	code, _ = utils.TestRequest(api, "POST", "/auth/logout", tokenUser01, "")
+	assert.Eq(code, 200)
+	assertEnds(notifications)
+
+	// ... or the personal access token was deleted
+	created, secret, err := CreatePersonalToken(context.Background(), state.queries, "nmBSHcxyvn", "Stream", []string{SCOPE_RIDES_READ}, time.Now().Add(time.Hour))
+	assert.Nil(err)
+	notifications = open("/users/me/notifications/events", secret)
+	_, err = state.queries.PersonalTokensDelete(context.Background(), sqlc.PersonalTokensDeleteParams{ID: created.ID, UserID: "nmBSHcxyvn"})
+	assert.Nil(err)
+	assertEnds(notifications)
+
+	// Blocked users are disconnected right away
+	rides := open("/rides/events?ticket="+ticket("/rides/events", tokenUser03), "")
+	err = setUserBlocked(context.Background(), sqlc.UsersSetBlockedParams{ID: "m6SYNABgAw", IsBlocked: true})
+	assert.Nil(err)
+	assertEnds(rides)
+}
@@ -0,0 +1,147 @@
+package rest_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandleRideUpdates(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0027-ride-updates.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	testAuth(api, "/rides/events", "GET")
+	testAuth(api, "/rides/events/ticket", "POST")
+
+	tokenUser01 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+	tokenUser02 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.w19_ESPa5AgLpH2IV9CrwSPq4XQcJJHgAqVf2bNmcOsdamLKJL_JntjmB-Er356m2bH4OlXg0hYagmU6irxwLymqB7QCGhsF1ixtMwyrGq8lFeePVwb41uD_5xRRkJxjB0GeIO_AIyCl9yCVa3djzcvx-6EBC1f_E9_t"
+	tokenUser03 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.cevnH03gVtsxMU7jy3vx4c7caO9X1tQuTTTyHR6a4ZAmqi3Icut3oi9UPfToSx7hnzvBk265_VIt7W5u-Tu2s7SXG6RPEBWlHzQ6DFEZjDsXLd1eqVONAPgWifhpD3UrooBLYzUyJ0lDh05O5gOZxaKYB1o"
+
+	post := func(endpoint string, token string, body string) int {
+		req, err := http.NewRequest("POST", api.URL+endpoint, strings.NewReader(body))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	type streamedUpdate struct {
+		event  string
+		update rest.RideUpdate
+	}
+
+	// Reads the stream in the background, the subscription exists once the
+	// response headers were received.
+	subscribe := func(token string) (chan streamedUpdate, func()) {
+		req, err := http.NewRequest("GET", api.URL+"/rides/events", nil)
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 200)
+		assert.Eq(resp.Header.Get("Content-Type"), "text/event-stream")
+
+		updates := make(chan streamedUpdate, 16)
+		go func() {
+			defer close(updates)
+
+			var streamed streamedUpdate
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				line := scanner.Text()
+				switch {
+				case strings.HasPrefix(line, "event: "):
+					streamed.event = strings.TrimPrefix(line, "event: ")
+				case strings.HasPrefix(line, "data: "):
+					err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &streamed.update)
+					assert.Nil(err)
+				case line == "" && streamed.event != "":
+					updates <- streamed
+					streamed = streamedUpdate{}
+				}
+			}
+		}()
+
+		return updates, func() { resp.Body.Close() }
+	}
+
+	next := func(updates chan streamedUpdate) streamedUpdate {
+		select {
+		case streamed, ok := <-updates:
+			assert.True(ok, "Stream of ride updates closed.")
+			return streamed
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for ride update.")
+			return streamedUpdate{}
+		}
+	}
+
+	updatesUser02, closeUser02 := subscribe(tokenUser02)
+	defer closeUser02()
+	updatesUser03, closeUser03 := subscribe(tokenUser03)
+	defer closeUser03()
+
+	// Rides of a group are only sent to its members
+	code := post("/rides", tokenUser01, `{ "locationFrom": "Graz", "locationTo": "Salzburg", "tackingPlaceAt": "2044-12-01T08:00:00Z", "driver": "NnCaPHQLC9", "transportLimit": 3, "groupId": "g" }`)
+	assert.Eq(code, 201)
+	streamed := next(updatesUser02)
+	assert.Eq(streamed.event, rest.RIDE_EVENT_CREATED)
+	assert.Eq(streamed.update.Type, rest.RIDE_EVENT_CREATED)
+	assert.Eq(streamed.update.UserId, "NnCaPHQLC9")
+	assert.Eq(streamed.update.Ride.LocationTo, "Salzburg")
+	assert.Eq(len(streamed.update.Ride.Participants), 1)
+
+	// Public rides are sent to everyone
+	assert.Eq(post("/rides/join", tokenUser02, `{ "rideEventId": "public-event" }`), 200)
+	for _, updates := range []chan streamedUpdate{updatesUser02, updatesUser03} {
+		streamed = next(updates)
+		assert.Eq(streamed.event, rest.RIDE_EVENT_JOINED)
+		assert.Eq(streamed.update.UserId, "nmBSHcxyvn")
+		assert.Eq(streamed.update.Ride.RideEventId, "public-event")
+		assert.Eq(len(streamed.update.Ride.Participants), 1)
+	}
+
+	assert.Eq(post("/rides/update", tokenUser01, `{ "rideEventId": "private-event", "locationTo": "Villach" }`), 200)
+	streamed = next(updatesUser02)
+	assert.Eq(streamed.event, rest.RIDE_EVENT_UPDATED)
+	assert.Eq(streamed.update.Ride.RideEventId, "private-event")
+	assert.Eq(streamed.update.Ride.LocationTo, "Villach")
+
+	assert.Eq(post("/rides/update", tokenUser01, `{ "rideEventId": "private-event", "status": "canceled" }`), 200)
+	streamed = next(updatesUser02)
+	assert.Eq(streamed.event, rest.RIDE_EVENT_CANCELED)
+	assert.Eq(streamed.update.Ride.Status, "canceled")
+
+	assert.Eq(post("/rides/leave", tokenUser02, `{ "rideEventId": "public-event" }`), 200)
+	for _, updates := range []chan streamedUpdate{updatesUser02, updatesUser03} {
+		streamed = next(updates)
+		assert.Eq(streamed.event, rest.RIDE_EVENT_LEFT)
+		assert.Eq(streamed.update.Ride.RideEventId, "public-event")
+		assert.Eq(len(streamed.update.Ride.Participants), 0)
+	}
+
+	// Failed changes aren't published
+	assert.Eq(post("/rides/leave", tokenUser02, `{ "rideEventId": "public-event" }`), 409)
+	select {
+	case streamed := <-updatesUser02:
+		t.Fatal("Received update of failed change.", streamed.event)
+	case streamed := <-updatesUser03:
+		t.Fatal("Received update of failed change.", streamed.event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
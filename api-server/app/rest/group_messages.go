@@ -1,217 +1,389 @@
 package rest
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
 	"ride_sharing_api/app/assert"
 	"ride_sharing_api/app/sqlc"
 	"ride_sharing_api/app/utils"
-	"sync"
-
-	websocket "golang.org/x/net/websocket"
+	"slices"
 )
 
+// Messages are sent in the chat of a group or in the thread of a ride event.
+// Both use the same routes, below '/groups/by-id/{id}' and
+// '/rides/by-id/{id}'. Direct messages are stored separately but are sent
+// through the same handlers, see 'direct_messages.go'.
 func groupMessageHandlers(h *http.ServeMux) {
-	chat := groupChatHandler{
-		chats: make(map[string]*groupChatWs),
-	}
-
-	wsChatServer := websocket.Server{
-		Handler: chat.handleWs,
-		Config: websocket.Config{
-			Origin: nil,
-		},
-	}
-
-	h.HandleFunc("POST /groups/by-id/{id}/send-message", handle(chat.groupMessageCreate).with(bearerAuth(false)).build())
-	h.Handle("/groups/messages/{groupId}", wsChatServer)
+	h.HandleFunc("POST /groups/by-id/{id}/send-message", handle(groupMessageCreate(groupChatFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), ROLE_GROUP_MEMBER)).build())
+	h.HandleFunc("GET /groups/by-id/{id}/messages", handle(getGroupMessages(groupChatFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), ROLE_GROUP_MEMBER)).build())
+	h.HandleFunc("POST /groups/by-id/{id}/chat-ticket", handle(createChatTicket(groupChatFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), ROLE_GROUP_MEMBER)).build())
+	h.HandleFunc("GET /groups/messages/{id}", handle(serveChat(groupChatFromPath)).with(chatTicketAuth(groupChatFromPath)).build())
+
+	h.HandleFunc("POST /rides/by-id/{id}/send-message", handle(groupMessageCreate(rideThreadFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), rideThreadRoles...)).build())
+	h.HandleFunc("GET /rides/by-id/{id}/messages", handle(getGroupMessages(rideThreadFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), rideThreadRoles...)).build())
+	h.HandleFunc("POST /rides/by-id/{id}/chat-ticket", handle(createChatTicket(rideThreadFromPath)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), rideThreadRoles...)).build())
+	h.HandleFunc("GET /rides/messages/{id}", handle(serveChat(rideThreadFromPath)).with(chatTicketAuth(rideThreadFromPath)).build())
 }
 
-type groupChatHandler struct {
-	mutex sync.Mutex
-	chats map[string]*groupChatWs
+type GroupMessageData struct {
+	MessageId string `json:"messageId"`
+	// Only one of `GroupId`, `RideEventId` and `ConversationId` is set.
+	GroupId        *string `json:"groupId"`
+	RideEventId    *string `json:"rideEventId"`
+	ConversationId *string `json:"conversationId"`
+	// Empty if the message was deleted.
+	Content     string                 `json:"content"`
+	SentBy      string                 `json:"sentBy"`
+	SentByEmail string                 `json:"sentByEmail"`
+	RepliesTo   *string                `json:"repliesTo"`
+	CreatedAt   string                 `json:"createdAt"`
+	EditedAt    *string                `json:"editedAt"`
+	DeletedAt   *string                `json:"deletedAt"`
+	Reactions   []GroupMessageReaction `json:"reactions"`
 }
 
-type GroupMessageData struct {
-	MessageId   string  `json:"messageId"`
-	GroupId     string  `json:"groupId"`
-	Content     string  `json:"content"`
-	SentBy      string  `json:"sentBy"`
-	SentByEmail string  `json:"sentByEmail"`
-	RepliesTo   *string `json:"repliesTo"`
-	CreatedAt   string  `json:"createdAt"`
+// The users that reacted with an emoji, ordered by the first reaction.
+type GroupMessageReaction struct {
+	Emoji   string   `json:"emoji"`
+	UserIds []string `json:"userIds"`
 }
 
 type createGroupMessageData struct {
-	GroupId   *string `json:"groupId" validate:"required"`
+	// Required in group chats.
+	GroupId   *string `json:"groupId"`
 	Content   *string `json:"content" validate:"required"`
 	RepliesTo *string `json:"repliesTo"`
 }
 
-type groupChatWs struct {
-	clients []*groupChatClient
-}
+// Returns the messages of a chat, newest page first and oldest message first
+// within a page. Pass `nextCursor` as the `before` query parameter to get the
+// page of older messages.
+func getGroupMessages(chatOf func(r *http.Request) chatChannel) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		channel := chatOf(r)
+
+		size, err := parsePageSize(r)
+		if err != nil {
+			httpWriteErr(w, http.StatusBadRequest, "Invalid pagination parameters.", err.Error())
+			return
+		}
 
-type groupChatClient struct {
-	conn *websocket.Conn
-	done chan struct{}
-}
+		var before *string = nil
+		if beforeStr := r.FormValue("before"); beforeStr != "" {
+			if !isChatMessage(r.Context(), channel, beforeStr) {
+				httpWriteErr(w, http.StatusBadRequest, "Query parameter 'before' has to be the id of a message of the chat.")
+				return
+			}
+			before = &beforeStr
+		}
 
-func (c *groupChatHandler) groupMessageCreate(w http.ResponseWriter, r *http.Request) {
-	user := getMiddlewareData[sqlc.User](r, "user")
+		page, err := loadChatPage(r.Context(), channel, before, size)
+		if err != nil {
+			log.Println("Error: Failed to get messages.", "chat:", channel, "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to get messages.")
+			return
+		}
 
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Println("Error: Invalid request body.", "error:", err)
-		httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
-		return
+		resp, err := json.Marshal(page)
+		assert.Nil(err, "Failed to serialize messages.")
+		w.WriteHeader(200)
+		w.Write(resp)
 	}
+}
 
-	var createParams createGroupMessageData
-	err = json.Unmarshal(data, &createParams)
+// Loads a page of up to `size` messages sent before the message with the id
+// `before`, or of the latest messages if it is `nil`.
+func loadChatPage(ctx context.Context, channel chatChannel, before *string, size int64) (Page[GroupMessageData], error) {
+	msgs, hasMore, err := loadGroupMessagesBefore(ctx, channel, before, size)
 	if err != nil {
-		log.Println("Error: Invalid JSON in request body.", "error:", err)
-		httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
-		return
+		return Page[GroupMessageData]{}, err
 	}
 
-	err = utils.Validate.Struct(createParams)
+	var total int64
+	if channel.conversationId != "" {
+		total, err = state.queries.DirectMessagesCount(ctx, channel.conversationId)
+	} else {
+		groupId, rideEventId := channel.sqlIds()
+		total, err = state.queries.GroupMessagesCount(ctx, sqlc.GroupMessagesCountParams{GroupID: groupId, RideEventID: rideEventId})
+	}
 	if err != nil {
-		log.Println("Error: Invalid JSON in request body.", "error:", err)
-		httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", err.Error())
-		return
+		return Page[GroupMessageData]{}, err
+	}
+
+	page := Page[GroupMessageData]{Items: msgs, Total: total}
+	if hasMore {
+		page.NextCursor = &msgs[0].MessageId
+	}
+
+	return page, nil
+}
+
+func groupMessageCreate(chatOf func(r *http.Request) chatChannel) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+		channel := chatOf(r)
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Println("Error: Invalid request body.", "error:", err)
+			httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
+			return
+		}
+
+		var createParams createGroupMessageData
+		err = json.Unmarshal(data, &createParams)
+		if err != nil {
+			log.Println("Error: Invalid JSON in request body.", "error:", err)
+			httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
+			return
+		}
+
+		err = utils.Validate.Struct(createParams)
+		if err != nil {
+			log.Println("Error: Invalid JSON in request body.", "error:", err)
+			httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", err.Error())
+			return
+		}
+
+		// membership was checked for the group in the path
+		if channel.groupId != "" && (createParams.GroupId == nil || *createParams.GroupId != channel.groupId) {
+			httpWriteErr(w, http.StatusBadRequest, "Field 'groupId' has to match the 'id' path parameter.")
+			return
+		}
+
+		msgData, err := createGroupMessage(r.Context(), user, channel, *createParams.Content, createParams.RepliesTo)
+		if errors.Is(err, errInvalidReply) {
+			httpWriteErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, errMessagingBlocked) {
+			httpWriteErr(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err != nil {
+			log.Println("Error: Failed to create message.", "error:", err)
+			httpWriteErr(w, http.StatusBadRequest, "Failed to send message. This might be due to invalid data or because of an internal server error.")
+			return
+		}
+
+		resp, err := json.Marshal(msgData)
+		assert.Nil(err, "Failed to serialize message.")
+		w.WriteHeader(201)
+		w.Write(resp)
+	}
+}
+
+var errInvalidReply = errors.New("Field 'repliesTo' has to be the id of a message of the chat that wasn't deleted.")
+
+// Stores a message and sends it to everyone in the chat.
+func createGroupMessage(ctx context.Context, user sqlc.User, channel chatChannel, content string, repliesTo *string) (GroupMessageData, error) {
+	if channel.conversationId != "" {
+		return createDirectMessage(ctx, user, channel.conversationId, content, repliesTo)
 	}
 
-	argsCreateGroup := sqlc.GroupMessagesCreateParams{
-		Content:   *createParams.Content,
-		GroupID:   *createParams.GroupId,
-		SentBy:    user.ID,
-		RepliesTo: utils.SqlNullStr(createParams.RepliesTo),
+	var repliedTo sqlc.GroupMessagesGetByIdRow
+	if repliesTo != nil {
+		reply, err := state.queries.GroupMessagesGetById(ctx, *repliesTo)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && (messageChannel(reply.GroupID, reply.RideEventID) != channel || reply.DeletedAt.Valid)) {
+			return GroupMessageData{}, errInvalidReply
+		}
+		if err != nil {
+			return GroupMessageData{}, err
+		}
+		repliedTo = reply
 	}
 
-	msg, err := state.queries.GroupMessagesCreate(r.Context(), argsCreateGroup)
-	assert.Nil(err)
+	groupId, rideEventId := channel.sqlIds()
+	argsCreateMessage := sqlc.GroupMessagesCreateParams{
+		Content:     content,
+		GroupID:     groupId,
+		RideEventID: rideEventId,
+		SentBy:      user.ID,
+		RepliesTo:   utils.SqlNullStr(repliesTo),
+	}
 
-	var repliesTo *string = nil
-	if msg.RepliesTo.Valid {
-		repliesTo = &msg.RepliesTo.String
+	msg, err := state.queries.GroupMessagesCreate(ctx, argsCreateMessage)
+	if err != nil {
+		return GroupMessageData{}, err
 	}
 
 	msgData := GroupMessageData{
 		MessageId:   msg.ID,
-		GroupId:     msg.GroupID,
+		GroupId:     utils.SqlNullStrToPtr(msg.GroupID),
+		RideEventId: utils.SqlNullStrToPtr(msg.RideEventID),
 		Content:     msg.Content,
 		SentBy:      user.ID,
 		SentByEmail: user.Email,
-		RepliesTo:   repliesTo,
+		RepliesTo:   utils.SqlNullStrToPtr(msg.RepliesTo),
 		CreatedAt:   msg.CreatedAt,
+		Reactions:   make([]GroupMessageReaction, 0),
 	}
 
-	resp, err := json.Marshal(msgData)
-
-	chat, exists := c.chats[msg.GroupID]
-	if exists {
-		for _, client := range chat.clients {
-			if client.conn == nil {
-				continue
-			}
+	state.chat.broadcast(channel, chatFrame{Type: CHAT_FRAME_MESSAGE, Message: &msgData})
 
-			client.Write(resp)
-		}
+	if repliesTo != nil {
+		n := notification{Type: NOTIFICATION_MESSAGE_REPLY, Actor: user, GroupId: msg.GroupID.String, RideEventId: msg.RideEventID.String, MessageId: msg.ID}
+		notify(ctx, n, repliedTo.SentBy)
 	}
 
-	assert.Nil(err, "Failed to serialize message.")
-	w.WriteHeader(201)
-	w.Write(resp)
+	return msgData, nil
 }
 
-func (c *groupChatHandler) handleWs(conn *websocket.Conn) {
-	r := conn.Request()
-	if r == nil {
-		conn.Write([]byte("Missing required HTTP request."))
-		conn.WriteClose(http.StatusBadRequest)
-		return
+func isChatMessage(ctx context.Context, channel chatChannel, messageId string) bool {
+	if channel.conversationId != "" {
+		msg, err := state.queries.DirectMessagesGetById(ctx, messageId)
+		return err == nil && msg.ConversationID == channel.conversationId
 	}
 
-	groupId := r.PathValue("groupId")
-	if groupId == "" {
-		conn.Write([]byte("Missing 'groupId' parameter in route."))
-		conn.WriteClose(http.StatusBadRequest)
-		return
+	msg, err := state.queries.GroupMessagesGetById(ctx, messageId)
+	return err == nil && messageChannel(msg.GroupID, msg.RideEventID) == channel
+}
+
+// Loads up to `size` of the newest messages of a chat that were sent before
+// the message with the id `before`, or of all messages if it is `nil`. The
+// messages are returned oldest first, `hasMore` is set if there are older ones.
+func loadGroupMessagesBefore(ctx context.Context, channel chatChannel, before *string, size int64) (msgs []GroupMessageData, hasMore bool, err error) {
+	if channel.conversationId != "" {
+		return loadDirectMessagesBefore(ctx, channel.conversationId, before, size)
 	}
 
-	locked := true
-	c.mutex.Lock()
-	defer func() {
-		if locked {
-			c.mutex.Unlock()
-		}
-	}()
+	groupId, rideEventId := channel.sqlIds()
+	rows, err := state.queries.GroupMessagesGetPage(ctx, sqlc.GroupMessagesGetPageParams{
+		GroupID:     groupId,
+		RideEventID: rideEventId,
+		BeforeID:    utils.SqlNullStr(before),
+		PageSize:    size + 1,
+	})
+	if err != nil {
+		return nil, false, err
+	}
 
-	done := make(chan struct{})
-	client := groupChatClient{
-		conn: conn,
-		done: done,
+	hasMore = int64(len(rows)) > size
+	if hasMore {
+		rows = rows[:size]
 	}
 
-	chat, exists := c.chats[groupId]
-	if exists {
-		chat.clients = append(chat.clients, &client)
-	} else {
-		chat = &groupChatWs{
-			clients: []*groupChatClient{&client},
+	msgs = make([]GroupMessageData, len(rows))
+	for idx, row := range rows {
+		msgs[len(rows)-1-idx], err = groupMessageRowToData(ctx, sqlc.GroupMessagesGetByIdRow(row))
+		if err != nil {
+			return nil, false, err
 		}
+	}
+
+	return msgs, hasMore, nil
+}
+
+// Loads up to `size` of the oldest messages of a chat that were sent after
+// the message with the id `since`. `hasMore` is set if there are newer ones.
+func loadGroupMessagesSince(ctx context.Context, channel chatChannel, since string, size int64) (msgs []GroupMessageData, hasMore bool, err error) {
+	if channel.conversationId != "" {
+		return loadDirectMessagesSince(ctx, channel.conversationId, since, size)
+	}
 
-		c.chats[groupId] = chat
+	groupId, rideEventId := channel.sqlIds()
+	rows, err := state.queries.GroupMessagesGetSince(ctx, sqlc.GroupMessagesGetSinceParams{
+		GroupID:     groupId,
+		RideEventID: rideEventId,
+		SinceID:     since,
+		PageSize:    size + 1,
+	})
+	if err != nil {
+		return nil, false, err
 	}
 
-	msgs, err := state.queries.GroupMessagesGetMany(r.Context(), groupId)
-	assert.Nil(err)
+	hasMore = int64(len(rows)) > size
+	if hasMore {
+		rows = rows[:size]
+	}
 
-	for _, msg := range msgs {
-		var repliesTo *string = nil
-		if msg.RepliesTo.Valid {
-			repliesTo = &msg.RepliesTo.String
-		}
-		msgData := GroupMessageData{
-			MessageId:   msg.ID,
-			GroupId:     msg.GroupID,
-			Content:     msg.Content,
-			SentBy:      msg.SentBy,
-			SentByEmail: msg.SentByEmail,
-			RepliesTo:   repliesTo,
-			CreatedAt:   msg.CreatedAt,
+	msgs = make([]GroupMessageData, len(rows))
+	for idx, row := range rows {
+		msgs[idx], err = groupMessageRowToData(ctx, sqlc.GroupMessagesGetByIdRow(row))
+		if err != nil {
+			return nil, false, err
 		}
+	}
 
-		resp, err := json.Marshal(msgData)
-		assert.Nil(err)
+	return msgs, hasMore, nil
+}
+
+// Loads up to `size` of the messages of a chat up to the message with the id
+// `since` that were edited, deleted or reacted to after it was sent, oldest
+// first. `hasMore` is set if more of them changed. Direct messages can't be
+// changed.
+func loadGroupMessagesChangedSince(ctx context.Context, channel chatChannel, since string, size int64) (msgs []GroupMessageData, hasMore bool, err error) {
+	if channel.conversationId != "" {
+		return nil, false, nil
+	}
+
+	groupId, rideEventId := channel.sqlIds()
+	rows, err := state.queries.GroupMessagesGetChangedSince(ctx, sqlc.GroupMessagesGetChangedSinceParams{
+		SinceID:     since,
+		GroupID:     groupId,
+		RideEventID: rideEventId,
+		PageSize:    size + 1,
+	})
+	if err != nil {
+		return nil, false, err
+	}
 
-		_, err = client.Write(resp)
-		assert.Nil(err)
+	hasMore = int64(len(rows)) > size
+	if hasMore {
+		rows = rows[:size]
 	}
 
-	locked = false
-	c.mutex.Unlock()
-	<-done // wait for websocket to close
-	client.conn.WriteClose(http.StatusOK)
-	client.conn = nil
+	msgs = make([]GroupMessageData, len(rows))
+	for idx, row := range rows {
+		msgs[idx], err = groupMessageRowToData(ctx, sqlc.GroupMessagesGetByIdRow(row))
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return msgs, hasMore, nil
 }
 
-func (ws *groupChatClient) Write(p []byte) (int, error) {
-	if ws.conn == nil {
-		return 0, nil
+// Converts a message and loads its reactions. The content and reactions of
+// deleted messages are left out.
+func groupMessageRowToData(ctx context.Context, msg sqlc.GroupMessagesGetByIdRow) (GroupMessageData, error) {
+	data := GroupMessageData{
+		MessageId:   msg.ID,
+		GroupId:     utils.SqlNullStrToPtr(msg.GroupID),
+		RideEventId: utils.SqlNullStrToPtr(msg.RideEventID),
+		Content:     msg.Content,
+		SentBy:      msg.SentBy,
+		SentByEmail: msg.SentByEmail,
+		RepliesTo:   utils.SqlNullStrToPtr(msg.RepliesTo),
+		CreatedAt:   msg.CreatedAt,
+		EditedAt:    utils.SqlNullStrToPtr(msg.EditedAt),
+		DeletedAt:   utils.SqlNullStrToPtr(msg.DeletedAt),
+		Reactions:   make([]GroupMessageReaction, 0),
+	}
+
+	if msg.DeletedAt.Valid {
+		data.Content = ""
+		return data, nil
 	}
 
-	n, err := ws.conn.Write(p)
+	reactions, err := state.queries.GroupMessageReactionsGet(ctx, msg.ID)
 	if err != nil {
-		return n, err
+		return GroupMessageData{}, err
 	}
 
-	if n != len(p) {
-		return n, io.ErrShortWrite
+	for _, reaction := range reactions {
+		idx := slices.IndexFunc(data.Reactions, func(r GroupMessageReaction) bool { return r.Emoji == reaction.Emoji })
+		if idx == -1 {
+			data.Reactions = append(data.Reactions, GroupMessageReaction{Emoji: reaction.Emoji})
+			idx = len(data.Reactions) - 1
+		}
+		data.Reactions[idx].UserIds = append(data.Reactions[idx].UserIds, reaction.UserID)
 	}
 
-	return n, nil
+	return data, nil
 }
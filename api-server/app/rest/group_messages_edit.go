@@ -0,0 +1,311 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Messages can be edited by their author and deleted by their author or the
+// owner of the group or ride event. Deleted messages keep their row, so replies
+// to them still resolve, but their content isn't sent anymore. Every change is
+// sent to the chat as a typed frame.
+
+// Longest accepted reaction, long enough for emoji sequences like flags and
+// families.
+const reactionMaxBytes = 32
+
+type editGroupMessageData struct {
+	Content *string `json:"content" validate:"required"`
+}
+
+type groupMessageReactionData struct {
+	Emoji *string `json:"emoji" validate:"required"`
+}
+
+type groupMessageEditData struct {
+	// The content before the edit.
+	Content  string `json:"content"`
+	EditedAt string `json:"editedAt"`
+}
+
+func groupMessageEditHandlers(h *http.ServeMux) {
+	for _, chat := range []struct {
+		prefix string
+		chatOf func(r *http.Request) chatChannel
+		roles  []string
+	}{
+		{"/groups/by-id/{id}", groupChatFromPath, []string{ROLE_GROUP_MEMBER}},
+		{"/rides/by-id/{id}", rideThreadFromPath, rideThreadRoles},
+	} {
+		h.HandleFunc("POST "+chat.prefix+"/messages/by-id/{messageId}/edit", handle(groupMessageEdit(chat.chatOf)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), chat.roles...)).build())
+		h.HandleFunc("POST "+chat.prefix+"/messages/by-id/{messageId}/delete", handle(groupMessageDelete(chat.chatOf)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), chat.roles...)).build())
+		h.HandleFunc("GET "+chat.prefix+"/messages/by-id/{messageId}/history", handle(getGroupMessageHistory(chat.chatOf)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), chat.roles...)).build())
+		h.HandleFunc("POST "+chat.prefix+"/messages/by-id/{messageId}/reactions/add", handle(groupMessageReact(chat.chatOf, true)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), chat.roles...)).build())
+		h.HandleFunc("POST "+chat.prefix+"/messages/by-id/{messageId}/reactions/remove", handle(groupMessageReact(chat.chatOf, false)).with(bearerAuth(false, SCOPE_CHAT)).with(requireRole(fromPath("id"), chat.roles...)).build())
+	}
+}
+
+func groupMessageEdit(chatOf func(r *http.Request) chatChannel) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+		channel := chatOf(r)
+
+		msg, ok := getPathGroupMessage(w, r, channel)
+		if !ok {
+			return
+		}
+
+		if msg.SentBy != user.ID {
+			httpWriteErr(w, http.StatusForbidden, "Only the author of a message can edit it.")
+			return
+		}
+
+		var params editGroupMessageData
+		if !readGroupMessageBody(w, r, &params) {
+			return
+		}
+
+		if *params.Content == msg.Content {
+			writeGroupMessage(w, r, msg.ID)
+			return
+		}
+
+		err := editGroupMessage(r.Context(), msg, *params.Content)
+		if err != nil {
+			log.Println("Error: Failed to edit message.", "message id:", msg.ID, "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to edit message.")
+			return
+		}
+
+		broadcastGroupMessageChange(w, r, channel, CHAT_FRAME_MESSAGE_EDITED, msg.ID, nil)
+	}
+}
+
+// Keeps the previous content in the history of the message.
+func editGroupMessage(ctx context.Context, msg sqlc.GroupMessagesGetByIdRow, content string) error {
+	tx, err := state.getDBTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queriesTx := state.queries.WithTx(tx)
+
+	err = queriesTx.GroupMessageEditsCreate(ctx, sqlc.GroupMessageEditsCreateParams{MessageID: msg.ID, Content: msg.Content})
+	if err != nil {
+		return err
+	}
+
+	err = queriesTx.GroupMessagesUpdateContent(ctx, sqlc.GroupMessagesUpdateContentParams{Content: content, ID: msg.ID})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func groupMessageDelete(chatOf func(r *http.Request) chatChannel) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+		channel := chatOf(r)
+
+		msg, ok := getPathGroupMessage(w, r, channel)
+		if !ok {
+			return
+		}
+
+		if msg.SentBy != user.ID {
+			isOwner, err := canModerateChat(r.Context(), user, channel)
+			assert.Nil(err)
+
+			if !isOwner {
+				httpWriteErr(w, http.StatusForbidden, "Only the author of a message or the owner of the group or ride can delete it.")
+				return
+			}
+		}
+
+		err := state.queries.GroupMessagesDelete(r.Context(), sqlc.GroupMessagesDeleteParams{DeletedBy: utils.SqlNullStrWrapped(user.ID), ID: msg.ID})
+		if err != nil {
+			log.Println("Error: Failed to delete message.", "message id:", msg.ID, "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to delete message.")
+			return
+		}
+
+		broadcastGroupMessageChange(w, r, channel, CHAT_FRAME_MESSAGE_DELETED, msg.ID, nil)
+	}
+}
+
+// Returns the previous contents of a message, oldest first.
+func getGroupMessageHistory(chatOf func(r *http.Request) chatChannel) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		msg, ok := getPathGroupMessage(w, r, chatOf(r))
+		if !ok {
+			return
+		}
+
+		rows, err := state.queries.GroupMessageEditsGet(r.Context(), msg.ID)
+		assert.Nil(err)
+
+		edits := make([]groupMessageEditData, len(rows))
+		for idx, row := range rows {
+			edits[idx] = groupMessageEditData{Content: row.Content, EditedAt: row.EditedAt}
+		}
+
+		resp, err := json.Marshal(edits)
+		assert.Nil(err, "Failed to serialize message history.")
+		w.WriteHeader(200)
+		w.Write(resp)
+	}
+}
+
+// Adding a reaction that already exists and removing one that doesn't are
+// no-ops, which aren't sent to the chat.
+func groupMessageReact(chatOf func(r *http.Request) chatChannel, add bool) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+		channel := chatOf(r)
+
+		msg, ok := getPathGroupMessage(w, r, channel)
+		if !ok {
+			return
+		}
+
+		var params groupMessageReactionData
+		if !readGroupMessageBody(w, r, &params) {
+			return
+		}
+
+		if !isEmoji(*params.Emoji) {
+			httpWriteErr(w, http.StatusBadRequest, "Field 'emoji' has to be a single emoji.")
+			return
+		}
+
+		args := sqlc.GroupMessageReactionsAddParams{MessageID: msg.ID, UserID: user.ID, Emoji: *params.Emoji}
+
+		var changed int64
+		var err error
+		frameType := CHAT_FRAME_REACTION_ADDED
+		if add {
+			changed, err = state.queries.GroupMessageReactionsAdd(r.Context(), args)
+		} else {
+			frameType = CHAT_FRAME_REACTION_REMOVED
+			changed, err = state.queries.GroupMessageReactionsRemove(r.Context(), sqlc.GroupMessageReactionsRemoveParams(args))
+		}
+		if err != nil {
+			log.Println("Error: Failed to change reaction.", "message id:", msg.ID, "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to change reaction.")
+			return
+		}
+
+		if changed == 0 {
+			writeGroupMessage(w, r, msg.ID)
+			return
+		}
+
+		broadcastGroupMessageChange(w, r, channel, frameType, msg.ID, &chatReaction{Emoji: *params.Emoji, UserId: user.ID})
+	}
+}
+
+// Loads the message in the 'messageId' path parameter. Writes an error and
+// returns false if it isn't a message of the chat or was deleted.
+func getPathGroupMessage(w http.ResponseWriter, r *http.Request, channel chatChannel) (sqlc.GroupMessagesGetByIdRow, bool) {
+	msg, err := state.queries.GroupMessagesGetById(r.Context(), r.PathValue("messageId"))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && messageChannel(msg.GroupID, msg.RideEventID) != channel) {
+		httpWriteErr(w, http.StatusNotFound, "No message exists in the chat with 'messageId'.")
+		return msg, false
+	}
+	assert.Nil(err)
+
+	if msg.DeletedAt.Valid {
+		httpWriteErr(w, http.StatusConflict, "The message was deleted.")
+		return msg, false
+	}
+
+	return msg, true
+}
+
+func readGroupMessageBody(w http.ResponseWriter, r *http.Request, params any) bool {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error: Invalid request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
+		return false
+	}
+
+	err = json.Unmarshal(data, params)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
+		return false
+	}
+
+	err = utils.Validate.Struct(params)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", err.Error())
+		return false
+	}
+
+	return true
+}
+
+// Sends the message after a change to its chat and responds with it.
+func broadcastGroupMessageChange(w http.ResponseWriter, r *http.Request, channel chatChannel, frameType string, messageId string, reaction *chatReaction) {
+	msgData, ok := writeGroupMessage(w, r, messageId)
+	if !ok {
+		return
+	}
+
+	state.chat.broadcast(channel, chatFrame{Type: frameType, Message: &msgData, Reaction: reaction})
+}
+
+func writeGroupMessage(w http.ResponseWriter, r *http.Request, messageId string) (GroupMessageData, bool) {
+	msg, err := state.queries.GroupMessagesGetById(r.Context(), messageId)
+	assert.Nil(err)
+
+	msgData, err := groupMessageRowToData(r.Context(), msg)
+	if err != nil {
+		log.Println("Error: Failed to load message.", "message id:", messageId, "error:", err)
+		httpWriteErr(w, http.StatusInternalServerError, "Failed to load message.")
+		return msgData, false
+	}
+
+	resp, err := json.Marshal(msgData)
+	assert.Nil(err, "Failed to serialize message.")
+	w.WriteHeader(200)
+	w.Write(resp)
+
+	return msgData, true
+}
+
+// Accepts a single emoji, including sequences of emojis joined with
+// zero-width joiners, skin tones and flags.
+func isEmoji(str string) bool {
+	if str == "" || len(str) > reactionMaxBytes || !utf8.ValidString(str) {
+		return false
+	}
+
+	for idx, r := range str {
+		isSymbol := unicode.Is(unicode.So, r)
+		if idx == 0 && !isSymbol {
+			return false
+		}
+
+		// modifiers, variation selectors, keycaps and joiners
+		if !isSymbol && !unicode.In(r, unicode.Sk, unicode.Mn, unicode.Me, unicode.Cf) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -11,18 +11,25 @@ import (
 	"ride_sharing_api/app/sqlc"
 	"ride_sharing_api/app/utils"
 	"slices"
-	"strconv"
+)
+
+const (
+	GROUP_JOIN_STATUS_PENDING = "pending"
+	GROUP_JOIN_STATUS_MEMBER  = "member"
+	GROUP_JOIN_STATUS_BANNED  = "banned"
 )
 
 func groupHandlers(h *http.ServeMux) {
-	h.HandleFunc("POST /groups", handle(createGroup).with(bearerAuth(false)).build())
-	h.HandleFunc("POST /groups/update", handle(updateGroup).with(bearerAuth(false)).build())
-	h.HandleFunc("GET /groups/many", handle(getManyGroups).with(bearerAuth(false)).build())
-	h.HandleFunc("GET /groups/by-id/{id}", handle(getGroupById).with(bearerAuth(false)).build())
-	h.HandleFunc("POST /groups/by-id/{id}/members/join", handle(groupMemberJoin).with(bearerAuth(false)).build())
-	h.HandleFunc("POST /groups/by-id/{id}/members/leave", handle(groupMemberLeave).with(bearerAuth(false)).build())
-	h.HandleFunc("POST /groups/by-id/{id}/members/ban", handle(groupMemberOwnerSetStatus("banned")).with(bearerAuth(false)).build())
-	h.HandleFunc("POST /groups/by-id/{id}/members/approve", handle(groupMemberOwnerSetStatus("member")).with(bearerAuth(false)).build())
+	h.HandleFunc("POST /groups", handle(createGroup).with(bearerAuth(false, SCOPE_GROUPS)).build())
+	h.HandleFunc("POST /groups/update", handle(updateGroup).with(bearerAuth(false, SCOPE_GROUPS)).with(requireRole(fromBody("groupId"), ROLE_GROUP_OWNER)).build())
+	h.HandleFunc("GET /groups/many", handle(getManyGroups).with(bearerAuth(false, SCOPE_GROUPS)).build())
+	h.HandleFunc("GET /groups/by-id/{id}", handle(getGroupById).with(bearerAuth(false, SCOPE_GROUPS)).build())
+	h.HandleFunc("POST /groups/by-id/{id}/members/join", handle(groupMemberJoin).with(bearerAuth(false, SCOPE_GROUPS)).build())
+	h.HandleFunc("POST /groups/by-id/{id}/members/leave", handle(groupMemberLeave).with(bearerAuth(false, SCOPE_GROUPS)).build())
+	h.HandleFunc("POST /groups/by-id/{id}/members/ban", handle(groupMemberSetStatus(GROUP_JOIN_STATUS_BANNED)).with(bearerAuth(false, SCOPE_GROUPS)).with(requireRole(fromPath("id"), ROLE_GROUP_OWNER, ROLE_GROUP_MODERATOR)).build())
+	h.HandleFunc("POST /groups/by-id/{id}/members/approve", handle(groupMemberSetStatus(GROUP_JOIN_STATUS_MEMBER)).with(bearerAuth(false, SCOPE_GROUPS)).with(requireRole(fromPath("id"), ROLE_GROUP_OWNER, ROLE_GROUP_MODERATOR)).build())
+	h.HandleFunc("POST /groups/by-id/{id}/moderators/add", handle(groupSetModerator(true)).with(bearerAuth(false, SCOPE_GROUPS)).with(requireRole(fromPath("id"), ROLE_GROUP_OWNER)).build())
+	h.HandleFunc("POST /groups/by-id/{id}/moderators/remove", handle(groupSetModerator(false)).with(bearerAuth(false, SCOPE_GROUPS)).with(requireRole(fromPath("id"), ROLE_GROUP_OWNER)).build())
 }
 
 type GroupData struct {
@@ -31,12 +38,15 @@ type GroupData struct {
 	Description *string       `json:"description"`
 	CreatedBy   string        `json:"createdBy"`
 	Members     []GroupMember `json:"members"`
+	// Upcoming rides of the group. Empty unless the user is a member.
+	Rides []*RideEventData `json:"rides"`
 }
 
 type GroupMember struct {
-	UserId     string `json:"userId"`
-	Email      string `json:"email"`
-	JoinStatus string `json:"joinStatus"`
+	UserId      string `json:"userId"`
+	Email       string `json:"email"`
+	JoinStatus  string `json:"joinStatus"`
+	IsModerator bool   `json:"isModerator"`
 }
 
 type createGroupParams struct {
@@ -50,7 +60,7 @@ type updateGroupParams struct {
 	Description *string `json:"description"`
 }
 
-type groupMemberSetStatusParams struct {
+type groupMemberParams struct {
 	UserId *string `json:"userId" validate:"required"`
 }
 
@@ -105,9 +115,23 @@ func createGroup(w http.ResponseWriter, r *http.Request) {
 	membersData := make([]GroupMember, len(members))
 	for idx, member := range members {
 		membersData[idx] = GroupMember{
-			UserId:     member.UserID,
-			Email:      member.Email,
-			JoinStatus: member.JoinStatus,
+			UserId:      member.UserID,
+			Email:       member.Email,
+			JoinStatus:  member.JoinStatus,
+			IsModerator: member.IsModerator,
+		}
+	}
+
+	ridesData := make([]*RideEventData, 0)
+	groupId := sql.NullString{String: group.ID, Valid: true}
+	if canSeeRide(r.Context(), state.queries, groupId, user.ID) {
+		rides, err := state.queries.RidesGetByGroup(r.Context(), groupId)
+		assert.Nil(err)
+
+		for _, ride := range rides {
+			rideData, err := loadRideEventData(r.Context(), state.queries, eventToRideRow(sqlc.RidesGetEventRow(ride)))
+			assert.Nil(err)
+			ridesData = append(ridesData, rideData)
 		}
 	}
 
@@ -117,6 +141,7 @@ func createGroup(w http.ResponseWriter, r *http.Request) {
 		Description: dataDesc,
 		CreatedBy:   group.CreatedBy,
 		Members:     membersData,
+		Rides:       ridesData,
 	}
 
 	resp, err := json.Marshal(groupData)
@@ -126,8 +151,6 @@ func createGroup(w http.ResponseWriter, r *http.Request) {
 }
 
 func updateGroup(w http.ResponseWriter, r *http.Request) {
-	user := getMiddlewareData[sqlc.User](r, "user")
-
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Println("Error: Invalid request body.", "error:", err)
@@ -150,17 +173,6 @@ func updateGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group, err := state.queries.GroupsGetById(r.Context(), *updateParams.GroupId)
-	if errors.Is(err, sql.ErrNoRows) {
-		httpWriteErr(w, http.StatusNotFound, "No group exists with 'id'.")
-		return
-	}
-
-	if group.CreatedBy != user.ID {
-		httpWriteErr(w, http.StatusBadRequest, "You are not the owner of this group.")
-		return
-	}
-
 	if updateParams.Name != nil {
 		argsUpdateName := sqlc.GroupsUpdateNameParams{
 			Name: *updateParams.Name,
@@ -183,6 +195,8 @@ func updateGroup(w http.ResponseWriter, r *http.Request) {
 }
 
 func getGroupById(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
 	id := r.PathValue("id")
 	if id == "" {
 		httpWriteErr(w, http.StatusBadRequest, "Must provide 'id' path parameter.")
@@ -206,9 +220,23 @@ func getGroupById(w http.ResponseWriter, r *http.Request) {
 	membersData := make([]GroupMember, len(members))
 	for idx, member := range members {
 		membersData[idx] = GroupMember{
-			UserId:     member.UserID,
-			Email:      member.Email,
-			JoinStatus: member.JoinStatus,
+			UserId:      member.UserID,
+			Email:       member.Email,
+			JoinStatus:  member.JoinStatus,
+			IsModerator: member.IsModerator,
+		}
+	}
+
+	ridesData := make([]*RideEventData, 0)
+	groupId := sql.NullString{String: group.ID, Valid: true}
+	if canSeeRide(r.Context(), state.queries, groupId, user.ID) {
+		rides, err := state.queries.RidesGetByGroup(r.Context(), groupId)
+		assert.Nil(err)
+
+		for _, ride := range rides {
+			rideData, err := loadRideEventData(r.Context(), state.queries, eventToRideRow(sqlc.RidesGetEventRow(ride)))
+			assert.Nil(err)
+			ridesData = append(ridesData, rideData)
 		}
 	}
 
@@ -218,6 +246,7 @@ func getGroupById(w http.ResponseWriter, r *http.Request) {
 		Description: dataDesc,
 		CreatedBy:   group.CreatedBy,
 		Members:     membersData,
+		Rides:       ridesData,
 	}
 
 	var resp []byte
@@ -227,23 +256,40 @@ func getGroupById(w http.ResponseWriter, r *http.Request) {
 	w.Write(resp)
 }
 
+type groupCursor struct {
+	Name    *string `json:"name" validate:"required"`
+	GroupId *string `json:"groupId" validate:"required"`
+}
+
 func getManyGroups(w http.ResponseWriter, r *http.Request) {
-	var offset int64 = 0
-	offsetStr := r.FormValue("offset")
-	if parsed, err := strconv.ParseInt(offsetStr, 10, 64); err == nil && parsed > 0 {
-		offset = parsed
+	pageParams, err := parsePageParams[groupCursor](r)
+	if err != nil {
+		httpWriteErr(w, http.StatusBadRequest, "Invalid pagination parameters.", err.Error())
+		return
+	}
+
+	args := sqlc.GroupsGetManyParams{PageSize: pageParams.Size + 1}
+	if pageParams.Cursor != nil {
+		args.CursorName = utils.SqlNullStr(pageParams.Cursor.Name)
+		args.CursorID = utils.SqlNullStr(pageParams.Cursor.GroupId)
 	}
 
-	rows, err := state.queries.GroupsGetMany(r.Context(), offset)
+	rows, err := state.queries.GroupsGetMany(r.Context(), args)
 	if err != nil {
 		log.Println("Error: Failed to get groups.", "error:", err)
 		httpWriteErr(w, http.StatusInternalServerError, "Failed to get groups.")
 		return
 	}
 
-	groups := make([]GroupData, len(rows))
-	for idx, row := range rows {
+	total, err := state.queries.GroupsCount(r.Context())
+	assert.Nil(err)
+
+	page := buildPage(rows, pageParams.Size, total, func(last sqlc.GroupsGetManyRow) groupCursor {
+		return groupCursor{Name: &last.Name, GroupId: &last.ID}
+	})
 
+	groups := make([]GroupData, len(page.Items))
+	for idx, row := range page.Items {
 		var desc *string = nil
 		if row.Description.Valid {
 			desc = &row.Description.String
@@ -255,9 +301,10 @@ func getManyGroups(w http.ResponseWriter, r *http.Request) {
 		membersData := make([]GroupMember, len(members))
 		for idx, member := range members {
 			membersData[idx] = GroupMember{
-				UserId:     member.UserID,
-				Email:      member.Email,
-				JoinStatus: member.JoinStatus,
+				UserId:      member.UserID,
+				Email:       member.Email,
+				JoinStatus:  member.JoinStatus,
+				IsModerator: member.IsModerator,
 			}
 		}
 
@@ -270,13 +317,11 @@ func getManyGroups(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var resp []byte
-	if len(groups) == 0 {
-		resp = []byte("[]")
-	} else {
-		resp, err = json.Marshal(groups)
-	}
-
+	resp, err := json.Marshal(Page[GroupData]{
+		Items:      groups,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
 	assert.Nil(err, "Failed to serialize groups.")
 	w.WriteHeader(200)
 	w.Write(resp)
@@ -291,6 +336,13 @@ func groupMemberJoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	group, err := state.queries.GroupsGetById(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpWriteErr(w, http.StatusNotFound, "No group exists with 'id'.")
+		return
+	}
+	assert.Nil(err)
+
 	members, err := state.queries.GroupsMembersGet(r.Context(), id)
 	assert.Nil(err)
 
@@ -309,6 +361,15 @@ func groupMemberJoin(w http.ResponseWriter, r *http.Request) {
 	}
 	err = state.queries.GroupsMembersJoin(r.Context(), argsJoin)
 	assert.Nil(err)
+
+	approvers := []string{group.CreatedBy}
+	for _, member := range members {
+		if member.IsModerator && member.JoinStatus == GROUP_JOIN_STATUS_MEMBER {
+			approvers = append(approvers, member.UserID)
+		}
+	}
+
+	notify(r.Context(), notification{Type: NOTIFICATION_GROUP_JOIN_REQUESTED, Actor: user, GroupId: id}, approvers...)
 }
 
 func groupMemberLeave(w http.ResponseWriter, r *http.Request) {
@@ -332,66 +393,125 @@ func groupMemberLeave(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	argsJoin := sqlc.GroupsMembersLeaveParams {
+	argsJoin := sqlc.GroupsMembersLeaveParams{
 		GroupID: id,
 		UserID:  user.ID,
 	}
 
 	err = state.queries.GroupsMembersLeave(r.Context(), argsJoin)
 	assert.Nil(err)
+
+	state.chat.disconnectMember(groupChat(id), user.ID)
 }
 
-func groupMemberOwnerSetStatus(status string) func(w http.ResponseWriter, r *http.Request) {
+// Changes the status of a group member. Moderators can only change the
+// status of members that are neither the owner nor moderators themselves.
+func groupMemberSetStatus(status string) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := getMiddlewareData[sqlc.User](r, "user")
 
 		id := r.PathValue("id")
-		if id == "" {
-			httpWriteErr(w, http.StatusBadRequest, "Must provide 'id' path parameter.")
+		params, ok := parseGroupMemberParams(w, r)
+		if !ok {
 			return
 		}
 
-		data, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Println("Error: Invalid request body.", "error:", err)
-			httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
+		if user.ID == *params.UserId {
+			httpWriteErr(w, http.StatusForbidden, "Not allowed to change your own status.")
 			return
 		}
 
-		var setStatusParams groupMemberSetStatusParams
-		err = json.Unmarshal(data, &setStatusParams)
-		if err != nil {
-			log.Println("Error: Invalid JSON in request body.", "error:", err)
-			httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
-			return
-		}
+		group, err := state.queries.GroupsGetById(r.Context(), id)
+		assert.Nil(err)
 
-		err = utils.Validate.Struct(setStatusParams)
-		if err != nil {
-			log.Println("Error: Invalid JSON in request body.", "error:", err)
-			httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", err.Error())
+		if *params.UserId == group.CreatedBy {
+			httpWriteErr(w, http.StatusForbidden, "Not allowed to change the status of the group owner.")
 			return
 		}
 
-		if user.ID == *setStatusParams.UserId {
-			httpWriteErr(w, http.StatusForbidden, "Not allowed to change your own status.")
-			return
+		argsTarget := sqlc.GroupsMembersGetStatusParams{
+			GroupID: id,
+			UserID:  *params.UserId,
+		}
+		target, err := state.queries.GroupsMembersGetStatus(r.Context(), argsTarget)
+		if !errors.Is(err, sql.ErrNoRows) {
+			assert.Nil(err)
 		}
 
-		group, err := state.queries.GroupsGetById(r.Context(), id)
-		assert.Nil(err)
-
-		if user.ID != group.CreatedBy {
-			httpWriteErr(w, http.StatusForbidden, "You do not have the permission to change the status of a group member.")
+		if user.ID != group.CreatedBy && !user.IsAdmin && err == nil && target.IsModerator {
+			httpWriteErr(w, http.StatusForbidden, "Only the group owner can change the status of a moderator.")
 			return
 		}
 
 		argsSetStatus := sqlc.GroupsMembersSetStatusParams{
 			JoinStatus: status,
 			GroupID:    id,
-			UserID:     *setStatusParams.UserId,
+			UserID:     *params.UserId,
 		}
 		err = state.queries.GroupsMembersSetStatus(r.Context(), argsSetStatus)
 		assert.Nil(err) // TODO: handle member not in pending state
+
+		if status != GROUP_JOIN_STATUS_MEMBER {
+			state.chat.disconnectMember(groupChat(id), *params.UserId)
+		} else if target.JoinStatus == GROUP_JOIN_STATUS_PENDING {
+			notify(r.Context(), notification{Type: NOTIFICATION_GROUP_JOIN_APPROVED, Actor: user, GroupId: id}, *params.UserId)
+		}
 	}
 }
+
+// Grants or revokes the moderator role of a group member. Moderators can
+// approve and ban members.
+func groupSetModerator(isModerator bool) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		params, ok := parseGroupMemberParams(w, r)
+		if !ok {
+			return
+		}
+
+		argsMember := sqlc.GroupsMembersGetStatusParams{
+			GroupID: id,
+			UserID:  *params.UserId,
+		}
+		member, err := state.queries.GroupsMembersGetStatus(r.Context(), argsMember)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && member.JoinStatus != GROUP_JOIN_STATUS_MEMBER) {
+			httpWriteErr(w, http.StatusConflict, "User with 'userId' is not a member of this group.")
+			return
+		}
+		assert.Nil(err)
+
+		argsSetModerator := sqlc.GroupsMembersSetModeratorParams{
+			IsModerator: isModerator,
+			GroupID:     id,
+			UserID:      *params.UserId,
+		}
+		err = state.queries.GroupsMembersSetModerator(r.Context(), argsSetModerator)
+		assert.Nil(err)
+	}
+}
+
+func parseGroupMemberParams(w http.ResponseWriter, r *http.Request) (*groupMemberParams, bool) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error: Invalid request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
+		return nil, false
+	}
+
+	var params groupMemberParams
+	err = json.Unmarshal(data, &params)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
+		return nil, false
+	}
+
+	err = utils.Validate.Struct(params)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", err.Error())
+		return nil, false
+	}
+
+	return &params, true
+}
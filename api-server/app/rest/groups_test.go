@@ -34,7 +34,7 @@ func TestHandleCreateGroup(t *testing.T) {
 	// Missing request body
 	req, err := http.NewRequest("POST", api.URL+"/rides", bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err = api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 400)
@@ -45,7 +45,7 @@ func TestHandleCreateGroup(t *testing.T) {
 	// Missing fields in request body
 	req, err = http.NewRequest("POST", api.URL+"/groups", bytes.NewReader([]byte(`{}`)))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err = api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 400)
@@ -58,7 +58,7 @@ func TestHandleCreateGroup(t *testing.T) {
 		"name": "G1"
 	}`)))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err = api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 201)
@@ -81,15 +81,18 @@ func TestHandleGetManyGroups(t *testing.T) {
 
 	req, err := http.NewRequest("GET", api.URL+"/groups/many", bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err := api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 200)
 	data, err := io.ReadAll(resp.Body)
 	assert.Nil(err)
-	var groups []rest.GroupData
-	err = json.Unmarshal(data, &groups)
+	var page rest.Page[rest.GroupData]
+	err = json.Unmarshal(data, &page)
 	assert.Nil(err)
+	assert.Eq(page.Total, int64(3))
+	assert.True(page.NextCursor == nil)
+	groups := page.Items
 	assert.Eq(len(groups), 3)
 	assert.Eq(groups[0].GroupId, "abc")
 	assert.Eq(groups[0].Name, "G1")
@@ -108,13 +111,153 @@ func TestHandleGetManyGroupsEmpty(t *testing.T) {
 
 	req, err := http.NewRequest("GET", api.URL+"/groups/many", bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err := api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 200)
 	data, err := io.ReadAll(resp.Body)
 	assert.Nil(err)
-	var groups []rest.GroupData
-	err = json.Unmarshal(data, &groups)
-	assert.Eq(len(groups), 0)
+	var page rest.Page[rest.GroupData]
+	err = json.Unmarshal(data, &page)
+	assert.Nil(err)
+	assert.Eq(page.Total, int64(0))
+	assert.Eq(len(page.Items), 0)
+}
+
+func TestHandleGetManyGroupsPaginated(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0010-handle-get-many-groups-paginated.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	groupNames := make([]string, 0)
+	cursor := ""
+	for {
+		req, err := http.NewRequest("GET", api.URL+"/groups/many?limit=1&cursor="+cursor, bytes.NewReader([]byte{}))
+		assert.Nil(err)
+		req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 200)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		var page rest.Page[rest.GroupData]
+		err = json.Unmarshal(data, &page)
+		assert.Nil(err)
+		assert.Eq(page.Total, int64(3))
+
+		for _, group := range page.Items {
+			groupNames = append(groupNames, group.Name)
+		}
+
+		if page.NextCursor == nil {
+			break
+		}
+
+		cursor = *page.NextCursor
+	}
+	assert.Eq(len(groupNames), 3)
+	assert.Eq(groupNames[0], "G1")
+	assert.Eq(groupNames[1], "G2")
+}
+
+func TestHandleGroupRides(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0016-handle-group-rides.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenMember := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.w19_ESPa5AgLpH2IV9CrwSPq4XQcJJHgAqVf2bNmcOsdamLKJL_JntjmB-Er356m2bH4OlXg0hYagmU6irxwLymqB7QCGhsF1ixtMwyrGq8lFeePVwb41uD_5xRRkJxjB0GeIO_AIyCl9yCVa3djzcvx-6EBC1f_E9_t"
+	tokenBanned := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.cevnH03gVtsxMU7jy3vx4c7caO9X1tQuTTTyHR6a4ZAmqi3Icut3oi9UPfToSx7hnzvBk265_VIt7W5u-Tu2s7SXG6RPEBWlHzQ6DFEZjDsXLd1eqVONAPgWifhpD3UrooBLYzUyJ0lDh05O5gOZxaKYB1o"
+
+	request := func(method string, endpoint string, token string, body string) (int, []byte) {
+		req, err := http.NewRequest(method, api.URL+endpoint, bytes.NewReader([]byte(body)))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		return resp.StatusCode, data
+	}
+
+	rideIds := func(endpoint string, token string) (string, int64) {
+		status, data := request("GET", endpoint, token, "")
+		assert.Eq(status, 200)
+		var page rest.Page[rest.RideEventData]
+		err := json.Unmarshal(data, &page)
+		assert.Nil(err)
+		ids := make([]string, len(page.Items))
+		for idx, ride := range page.Items {
+			ids[idx] = ride.RideId
+		}
+		return strings.Join(ids, ", "), page.Total
+	}
+
+	// Members see the rides of their group
+	ids, total := rideIds("/rides/many", tokenMember)
+	assert.Eq(ids, "private, public")
+	assert.Eq(total, int64(2))
+	ids, total = rideIds("/rides/many?from=Graz", tokenMember)
+	assert.Eq(ids, "private, public")
+	assert.Eq(total, int64(2))
+
+	status, data := request("GET", "/rides/by-id/private-event", tokenMember, "")
+	assert.Eq(status, 200)
+	var ride rest.RideEventData
+	err := json.Unmarshal(data, &ride)
+	assert.Nil(err)
+	assert.Eq(*ride.GroupId, "g")
+
+	// Banned members don't
+	ids, total = rideIds("/rides/many", tokenBanned)
+	assert.Eq(ids, "public")
+	assert.Eq(total, int64(1))
+	ids, total = rideIds("/rides/many?from=Graz", tokenBanned)
+	assert.Eq(ids, "public")
+	assert.Eq(total, int64(1))
+
+	status, _ = request("GET", "/rides/by-id/private-event", tokenBanned, "")
+	assert.Eq(status, 404)
+	status, _ = request("GET", "/rides/upcoming/by-id/private", tokenBanned, "")
+	assert.Eq(status, 404)
+	status, _ = request("POST", "/rides/join", tokenBanned, `{ "rideEventId": "private-event" }`)
+	assert.Eq(status, 404)
+	status, _ = request("POST", "/rides/join", tokenMember, `{ "rideEventId": "private-event" }`)
+	assert.Eq(status, 200)
+
+	// Group rides are listed on the group
+	getGroup := func(token string) rest.GroupData {
+		status, data := request("GET", "/groups/by-id/g", token, "")
+		assert.Eq(status, 200)
+		var group rest.GroupData
+		err := json.Unmarshal(data, &group)
+		assert.Nil(err)
+		return group
+	}
+
+	group := getGroup(tokenMember)
+	assert.Eq(len(group.Rides), 1)
+	assert.Eq(group.Rides[0].RideEventId, "private-event")
+	assert.Eq(len(group.Rides[0].Participants), 1)
+	assert.Eq(len(getGroup(tokenBanned).Rides), 0)
+
+	// Only members can create rides for a group
+	create := `{
+		"locationFrom": "Graz",
+		"locationTo": "Salzburg",
+		"tackingPlaceAt": "2044-11-28T15:00:00Z",
+		"driver": "m6SYNABgAw",
+		"transportLimit": 3,
+		"groupId": "%s"
+	}`
+	status, _ = request("POST", "/rides", tokenBanned, fmt.Sprintf(create, "g"))
+	assert.Eq(status, 403)
+	status, _ = request("POST", "/rides", tokenMember, fmt.Sprintf(create, "missing"))
+	assert.Eq(status, 404)
+	status, _ = request("POST", "/rides", tokenMember, fmt.Sprintf(create, "g"))
+	assert.Eq(status, 201)
+	assert.Eq(len(getGroup(tokenMember).Rides), 2)
 }
@@ -12,10 +12,20 @@ import (
 var state *apiState
 
 type apiState struct {
-	queries     *sqlc.Queries
-	mutex       sync.Mutex
-	oauthStates map[string]time.Time
-	getDBTx     func(ctx context.Context) (*sql.Tx, error)
+	queries *sqlc.Queries
+	mutex   sync.Mutex
+	// Set up once, providers are configured through the environment.
+	authProviders []AuthProvider
+	// Refreshes of different sessions don't block each other.
+	sessionLocks keyedMutex
+	authKeys     *AuthKeys
+	// Changes to rides, published after they were committed.
+	rideUpdates *eventBus[RideUpdate]
+	// New notifications of all users, published after they were stored.
+	notifications *eventBus[NotificationData]
+	streamTickets *streamTickets
+	chat          *chatHub
+	getDBTx       func(ctx context.Context) (*sql.Tx, error)
 }
 
 const middlewareKey = "middleware"
@@ -23,7 +33,6 @@ const middlewareKey = "middleware"
 type handleFuncBuilder struct {
 	handler    func(w http.ResponseWriter, r *http.Request)
 	middleware [](func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData))
-	data       map[string]any
 }
 
 type middlewareData struct {
@@ -32,30 +41,42 @@ type middlewareData struct {
 }
 
 func NewRESTApi(db *sql.DB) http.Handler {
-	state = &apiState{oauthStates: make(map[string]time.Time), queries: sqlc.New(db), getDBTx: func(ctx context.Context) (*sql.Tx, error) {
+	state = &apiState{authProviders: newAuthProviders(), authKeys: NewAuthKeys(sqlc.New(db)), queries: sqlc.New(db), rideUpdates: newEventBus[RideUpdate](), notifications: newEventBus[NotificationData](), streamTickets: newStreamTickets(), chat: newChatHub(), getDBTx: func(ctx context.Context) (*sql.Tx, error) {
 		return db.BeginTx(ctx, &sql.TxOptions{})
 	}}
 
 	mux := http.NewServeMux()
 
 	authHandlers(mux)
-	authHandlersGoogle(mux)
+	authProviderHandlers(mux)
+	sessionHandlers(mux)
+	personalTokenHandlers(mux)
 	userHandlers(mux)
 	rideHandlers(mux)
+	rideEventHandlers(mux)
 	groupHandlers(mux)
 	groupMessageHandlers(mux)
+	groupMessageEditHandlers(mux)
+	directMessageHandlers(mux)
+	notificationHandlers(mux)
+	calendarHandlers(mux)
 
 	return WithCors(mux)
 }
 
 func handle(handler func(w http.ResponseWriter, r *http.Request)) *handleFuncBuilder {
-	return &handleFuncBuilder{handler: handler, middleware: make([]func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData), 0), data: make(map[string]any)}
+	return &handleFuncBuilder{handler: handler, middleware: make([]func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData), 0)}
 }
 
 func getMiddlewareData[T any](r *http.Request, key string) T {
 	return r.Context().Value(middlewareKey).(map[string]any)[key].(T)
 }
 
+// For middleware that provides more than the one value it returns.
+func setMiddlewareData(r *http.Request, key string, value any) {
+	r.Context().Value(middlewareKey).(map[string]any)[key] = value
+}
+
 func (b *handleFuncBuilder) with(middleware func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData)) *handleFuncBuilder {
 	b.middleware = append(b.middleware, middleware)
 	return b
@@ -63,23 +84,30 @@ func (b *handleFuncBuilder) with(middleware func(w http.ResponseWriter, r *http.
 
 func (b *handleFuncBuilder) build() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// The data is set up before running the middleware, so middleware can
+		// use the data of the middleware that ran before it.
+		data := make(map[string]any)
+		r = r.WithContext(context.WithValue(r.Context(), middlewareKey, data))
+
 		for _, handler := range b.middleware {
-			stop, data := handler(w, r)
+			stop, value := handler(w, r)
 			if stop {
 				return
 			}
 
-			if data != nil {
-				b.data[data.key] = data.value
+			if value != nil {
+				data[value.key] = value.value
 			}
 		}
 
-		ctx := context.WithValue(r.Context(), middlewareKey, b.data)
-		b.handler(w, r.WithContext(ctx))
+		b.handler(w, r)
 	}
 }
 
-func bearerAuth(ignoreExpired bool) func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
+// Authenticates the user with the access token of a session or a personal
+// access token. Personal access tokens need one of `scopes`, they are
+// rejected if no scopes are given.
+func bearerAuth(ignoreExpired bool, scopes ...string) func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
 	return func(w http.ResponseWriter, r *http.Request) (bool, *middlewareData) {
 		token := r.Header.Get("Authorization")
 		if token == "" {
@@ -87,7 +115,11 @@ func bearerAuth(ignoreExpired bool) func(w http.ResponseWriter, r *http.Request)
 			return true, nil
 		}
 
-		tokens, err := decodeAccessToken([]byte(token))
+		if isPersonalToken(token) {
+			return personalTokenAuth(w, r, token, scopes)
+		}
+
+		tokens, err := decodeAccessToken(r.Context(), state.authKeys, token)
 		if err != nil || (!ignoreExpired && time.Now().After(tokens.ExpiresAt)) {
 			http.Error(w, "Invalid access token in 'Authorization' header.", http.StatusUnauthorized)
 			return true, nil
@@ -104,11 +136,14 @@ func bearerAuth(ignoreExpired bool) func(w http.ResponseWriter, r *http.Request)
 			return true, nil
 		}
 
-		if !user.AccessToken.Valid || user.AccessToken.String != token {
+		session, ok := sessionByAccessToken(r.Context(), token, user.ID)
+		if !ok {
 			http.Error(w, "Invalid access token in 'Authorization' header.", http.StatusUnauthorized)
 			return true, nil
 		}
 
+		touchSession(r, session)
+		setMiddlewareData(r, "session", session)
 		return false, &middlewareData{key: "user", value: user}
 	}
 }
@@ -0,0 +1,252 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"strconv"
+)
+
+// Notifications tell users about the actions of others that concern them.
+// Handlers create them with `notify` after their change was committed, they
+// are stored for the notification center and pushed to the users' clients
+// connected to '/users/me/notifications/events'.
+
+const (
+	// Sent to the owner and the driver of the ride, not if the user only
+	// joined the waitlist.
+	NOTIFICATION_RIDE_JOINED = "ride.joined"
+	// Sent to users who got a seat of a ride they were waiting for.
+	NOTIFICATION_RIDE_SEAT_ASSIGNED = "ride.seat_assigned"
+	// Sent to the participants and the waitlist of the ride.
+	NOTIFICATION_RIDE_CANCELED = "ride.canceled"
+	// Sent to the participants and the waitlist of every ride event whose
+	// details were changed.
+	NOTIFICATION_RIDE_CHANGED = "ride.changed"
+	// Sent to the owner and the moderators of the group.
+	NOTIFICATION_GROUP_JOIN_REQUESTED = "group.join_requested"
+	// Sent to the user who was approved.
+	NOTIFICATION_GROUP_JOIN_APPROVED = "group.join_approved"
+	// Sent to the author of the message that was replied to.
+	NOTIFICATION_MESSAGE_REPLY = "message.reply"
+	// Sent to the other user of the conversation.
+	NOTIFICATION_DIRECT_MESSAGE = "message.direct"
+)
+
+// What happened and what it happened to, ids that don't apply to the type of
+// the notification are left empty.
+type notification struct {
+	Type string
+	// The user whose action caused the notification, who is never notified
+	// about it.
+	Actor          sqlc.User
+	RideEventId    string
+	GroupId        string
+	ConversationId string
+	MessageId      string
+}
+
+type NotificationData struct {
+	NotificationId string `json:"notificationId"`
+	// The user who received the notification.
+	UserId         string  `json:"userId"`
+	Type           string  `json:"type"`
+	ActorId        *string `json:"actorId"`
+	ActorEmail     *string `json:"actorEmail"`
+	RideEventId    *string `json:"rideEventId"`
+	GroupId        *string `json:"groupId"`
+	ConversationId *string `json:"conversationId"`
+	MessageId      *string `json:"messageId"`
+	CreatedAt      string  `json:"createdAt"`
+	ReadAt         *string `json:"readAt"`
+}
+
+type notificationCursor struct {
+	NotificationId *string `json:"notificationId" validate:"required"`
+}
+
+func notificationHandlers(h *http.ServeMux) {
+	h.HandleFunc("GET /users/me/notifications", handle(getNotifications).with(bearerAuth(false, allTokenScopes...)).build())
+	h.HandleFunc("GET /users/me/notifications/events", handle(streamNotifications).with(streamAuth(allTokenScopes...)).build())
+	h.HandleFunc("POST /users/me/notifications/events/ticket", handle(createStreamTicket("/users/me/notifications/events")).with(bearerAuth(false, allTokenScopes...)).build())
+	h.HandleFunc("POST /users/me/notifications/by-id/{id}/read", handle(markNotificationRead).with(bearerAuth(false, allTokenScopes...)).build())
+	h.HandleFunc("POST /users/me/notifications/read-all", handle(markAllNotificationsRead).with(bearerAuth(false, allTokenScopes...)).build())
+}
+
+// Stores the notification for each of `userIds` and pushes it to their
+// connected clients. The actor, duplicates and empty ids are skipped. Has to
+// be called after the change was committed. Failing to notify doesn't fail
+// the change, errors are only logged.
+func notify(ctx context.Context, n notification, userIds ...string) {
+	// the scheduler can run without the API
+	if state == nil {
+		return
+	}
+
+	notified := make(map[string]bool)
+	for _, userId := range userIds {
+		if userId == "" || userId == n.Actor.ID || notified[userId] {
+			continue
+		}
+		notified[userId] = true
+
+		args := sqlc.NotificationsCreateParams{
+			UserID:         userId,
+			Type:           n.Type,
+			ActorID:        utils.SqlNullStrWrapped(n.Actor.ID),
+			RideEventID:    nullIfEmpty(n.RideEventId),
+			GroupID:        nullIfEmpty(n.GroupId),
+			ConversationID: nullIfEmpty(n.ConversationId),
+			MessageID:      nullIfEmpty(n.MessageId),
+		}
+		created, err := state.queries.NotificationsCreate(ctx, args)
+		if err != nil {
+			log.Println("Error: Failed to create notification.", "type:", n.Type, "user id:", userId, "error:", err)
+			continue
+		}
+
+		state.notifications.publish(notificationRowToData(sqlc.NotificationsGetByIdRow{
+			ID:             created.ID,
+			UserID:         created.UserID,
+			Type:           created.Type,
+			ActorID:        created.ActorID,
+			ActorEmail:     utils.SqlNullStrWrapped(n.Actor.Email),
+			RideEventID:    created.RideEventID,
+			GroupID:        created.GroupID,
+			ConversationID: created.ConversationID,
+			MessageID:      created.MessageID,
+			CreatedAt:      created.CreatedAt,
+			ReadAt:         created.ReadAt,
+		}))
+	}
+}
+
+func nullIfEmpty(str string) sql.NullString {
+	return sql.NullString{String: str, Valid: str != ""}
+}
+
+// Returns the notifications of the user, newest first. Only unread
+// notifications are returned if the query parameter 'unread' is true, `Total`
+// is then the number of unread notifications.
+func getNotifications(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	pageParams, err := parsePageParams[notificationCursor](r)
+	if err != nil {
+		httpWriteErr(w, http.StatusBadRequest, "Invalid pagination parameters.", err.Error())
+		return
+	}
+
+	unreadOnly := false
+	if unreadStr := r.FormValue("unread"); unreadStr != "" {
+		unreadOnly, err = strconv.ParseBool(unreadStr)
+		if err != nil {
+			httpWriteErr(w, http.StatusBadRequest, "Query parameter 'unread' must be 'true' or 'false'.")
+			return
+		}
+	}
+
+	args := sqlc.NotificationsGetPageParams{UserID: user.ID, UnreadOnly: unreadOnly, PageSize: pageParams.Size + 1}
+	if pageParams.Cursor != nil {
+		args.CursorID = utils.SqlNullStr(pageParams.Cursor.NotificationId)
+	}
+
+	rows, err := state.queries.NotificationsGetPage(r.Context(), args)
+	if err != nil {
+		log.Println("Error: Failed to get notifications.", "user id:", user.ID, "error:", err)
+		httpWriteErr(w, http.StatusInternalServerError, "Failed to get notifications.")
+		return
+	}
+
+	total, err := state.queries.NotificationsCount(r.Context(), sqlc.NotificationsCountParams{UserID: user.ID, UnreadOnly: unreadOnly})
+	assert.Nil(err)
+
+	notifications := make([]NotificationData, len(rows))
+	for idx, row := range rows {
+		notifications[idx] = notificationRowToData(sqlc.NotificationsGetByIdRow(row))
+	}
+
+	page := buildPage(notifications, pageParams.Size, total, func(last NotificationData) notificationCursor {
+		return notificationCursor{NotificationId: &last.NotificationId}
+	})
+
+	resp, err := json.Marshal(page)
+	assert.Nil(err, "Failed to serialize notifications.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+// Streams the new notifications of the user as Server-Sent Events until the
+// client disconnects. The event name is the type of the notification, the
+// data is a `NotificationData`.
+func streamNotifications(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	streamEvents(w, r, state.notifications, func(n NotificationData) (string, bool) {
+		return n.Type, n.UserId == user.ID
+	})
+}
+
+// Marking a notification that was already read keeps the time it was first
+// read.
+func markNotificationRead(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	id := r.PathValue("id")
+	n, err := state.queries.NotificationsGetById(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && n.UserID != user.ID) {
+		httpWriteErr(w, http.StatusNotFound, "No notification exists with 'id'.")
+		return
+	}
+	assert.Nil(err)
+
+	_, err = state.queries.NotificationsMarkRead(r.Context(), id)
+	if err != nil {
+		log.Println("Error: Failed to mark notification as read.", "notification id:", id, "error:", err)
+		httpWriteErr(w, http.StatusInternalServerError, "Failed to mark notification as read.")
+		return
+	}
+
+	n, err = state.queries.NotificationsGetById(r.Context(), id)
+	assert.Nil(err)
+
+	resp, err := json.Marshal(notificationRowToData(n))
+	assert.Nil(err, "Failed to serialize notification.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+func markAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	_, err := state.queries.NotificationsMarkAllRead(r.Context(), user.ID)
+	if err != nil {
+		log.Println("Error: Failed to mark notifications as read.", "user id:", user.ID, "error:", err)
+		httpWriteErr(w, http.StatusInternalServerError, "Failed to mark notifications as read.")
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+func notificationRowToData(n sqlc.NotificationsGetByIdRow) NotificationData {
+	return NotificationData{
+		NotificationId: n.ID,
+		UserId:         n.UserID,
+		Type:           n.Type,
+		ActorId:        utils.SqlNullStrToPtr(n.ActorID),
+		ActorEmail:     utils.SqlNullStrToPtr(n.ActorEmail),
+		RideEventId:    utils.SqlNullStrToPtr(n.RideEventID),
+		GroupId:        utils.SqlNullStrToPtr(n.GroupID),
+		ConversationId: utils.SqlNullStrToPtr(n.ConversationID),
+		MessageId:      utils.SqlNullStrToPtr(n.MessageID),
+		CreatedAt:      n.CreatedAt,
+		ReadAt:         utils.SqlNullStrToPtr(n.ReadAt),
+	}
+}
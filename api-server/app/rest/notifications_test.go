@@ -0,0 +1,198 @@
+package rest_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandleNotifications(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0034-notifications.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	testAuth(api, "/users/me/notifications", "GET")
+	testAuth(api, "/users/me/notifications/events", "GET")
+	testAuth(api, "/users/me/notifications/events/ticket", "POST")
+
+	tokenUser01 := utils.TEST_TOKEN_USER_01
+	tokenUser02 := utils.TEST_TOKEN_USER_02
+	tokenUser03 := utils.TEST_TOKEN_USER_03
+
+	notifications := func(query string, token string) rest.Page[rest.NotificationData] {
+		code, body := utils.TestRequest(api, "GET", "/users/me/notifications"+query, token, "")
+		assert.Eq(code, 200, body)
+		var page rest.Page[rest.NotificationData]
+		err := json.Unmarshal([]byte(body), &page)
+		assert.Nil(err)
+		return page
+	}
+
+	// Reads the stream in the background, the subscription exists once the
+	// response headers were received.
+	subscribe := func(token string) (chan rest.NotificationData, func()) {
+		req, err := http.NewRequest("GET", api.URL+"/users/me/notifications/events", nil)
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 200)
+
+		pushed := make(chan rest.NotificationData, 16)
+		go func() {
+			defer close(pushed)
+
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+					var n rest.NotificationData
+					err := json.Unmarshal([]byte(data), &n)
+					assert.Nil(err)
+					pushed <- n
+				}
+			}
+		}()
+
+		return pushed, func() { resp.Body.Close() }
+	}
+
+	next := func(pushed chan rest.NotificationData) rest.NotificationData {
+		select {
+		case n, ok := <-pushed:
+			assert.True(ok, "Stream of notifications closed.")
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for notification.")
+			return rest.NotificationData{}
+		}
+	}
+
+	pushedUser01, closeUser01 := subscribe(tokenUser01)
+	defer closeUser01()
+	pushedUser02, closeUser02 := subscribe(tokenUser02)
+	defer closeUser02()
+	pushedUser03, closeUser03 := subscribe(tokenUser03)
+	defer closeUser03()
+
+	// The owner and driver of a ride are told about new participants
+	code, _ := utils.TestRequest(api, "POST", "/rides/join", tokenUser02, `{ "rideEventId": "ride-event" }`)
+	assert.Eq(code, 200)
+	joined := next(pushedUser01)
+	assert.Eq(joined.Type, rest.NOTIFICATION_RIDE_JOINED)
+	assert.Eq(joined.UserId, "NnCaPHQLC9")
+	assert.Eq(*joined.ActorId, "nmBSHcxyvn")
+	assert.Eq(*joined.RideEventId, "ride-event")
+	assert.True(joined.ReadAt == nil)
+
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/unknown/members/join", tokenUser03, "")
+	assert.Eq(code, 404)
+
+	// Join requests go to the owner and moderators, approvals to the new member
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/members/join", tokenUser03, "")
+	assert.Eq(code, 200)
+	for _, pushed := range []chan rest.NotificationData{pushedUser01, pushedUser02} {
+		requested := next(pushed)
+		assert.Eq(requested.Type, rest.NOTIFICATION_GROUP_JOIN_REQUESTED)
+		assert.Eq(*requested.GroupId, "g")
+	}
+
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/members/approve", tokenUser02, `{ "userId": "m6SYNABgAw" }`)
+	assert.Eq(code, 200)
+	approved := next(pushedUser03)
+	assert.Eq(approved.Type, rest.NOTIFICATION_GROUP_JOIN_APPROVED)
+	assert.Eq(*approved.ActorEmail, "WDZHw/GNwrQ5vhtWojbR@gmail.com")
+
+	// Authors are told about replies to their messages
+	code, _ = utils.TestRequest(api, "POST", "/groups/by-id/g/send-message", tokenUser03, `{ "groupId": "g", "content": "Hi", "repliesTo": "group-01" }`)
+	assert.Eq(code, 201)
+	reply := next(pushedUser02)
+	assert.Eq(reply.Type, rest.NOTIFICATION_MESSAGE_REPLY)
+	assert.True(reply.MessageId != nil)
+
+	// Everyone taking part is told about canceled rides, but not the user who
+	// canceled it
+	code, _ = utils.TestRequest(api, "POST", "/rides/update", tokenUser01, `{ "rideEventId": "ride-event", "status": "canceled" }`)
+	assert.Eq(code, 200)
+	canceled := next(pushedUser02)
+	assert.Eq(canceled.Type, rest.NOTIFICATION_RIDE_CANCELED)
+	assert.Eq(*canceled.RideEventId, "ride-event")
+
+	select {
+	case n := <-pushedUser01:
+		t.Fatalf("Unexpected notification '%s' for user01.", n.Type)
+	default:
+	}
+
+	// Notifications are listed newest first and can be filtered by unread
+	page := notifications("", tokenUser01)
+	assert.Eq(page.Total, int64(2))
+	assert.Eq(page.Items[0].Type, rest.NOTIFICATION_GROUP_JOIN_REQUESTED)
+	assert.Eq(page.Items[1].NotificationId, joined.NotificationId)
+
+	page = notifications("?limit=1", tokenUser02)
+	assert.Eq(page.Total, int64(3))
+	assert.Eq(page.Items[0].NotificationId, canceled.NotificationId)
+	page = notifications("?limit=1&cursor="+*page.NextCursor, tokenUser02)
+	assert.Eq(page.Items[0].NotificationId, reply.NotificationId)
+
+	code, _ = utils.TestRequest(api, "GET", "/users/me/notifications?unread=maybe", tokenUser01, "")
+	assert.Eq(code, 400)
+
+	// Only the user who received a notification can read it
+	code, _ = utils.TestRequest(api, "POST", "/users/me/notifications/by-id/"+joined.NotificationId+"/read", tokenUser02, "")
+	assert.Eq(code, 404)
+
+	code, body := utils.TestRequest(api, "POST", "/users/me/notifications/by-id/"+joined.NotificationId+"/read", tokenUser01, "")
+	assert.Eq(code, 200)
+	var read rest.NotificationData
+	err := json.Unmarshal([]byte(body), &read)
+	assert.Nil(err)
+	assert.True(read.ReadAt != nil)
+
+	page = notifications("?unread=true", tokenUser01)
+	assert.Eq(page.Total, int64(1))
+	assert.Eq(page.Items[0].Type, rest.NOTIFICATION_GROUP_JOIN_REQUESTED)
+
+	code, _ = utils.TestRequest(api, "POST", "/users/me/notifications/read-all", tokenUser02, "")
+	assert.Eq(code, 200)
+	assert.Eq(notifications("?unread=true", tokenUser02).Total, int64(0))
+	assert.Eq(notifications("", tokenUser02).Total, int64(3))
+	assert.Eq(notifications("?unread=true", tokenUser01).Total, int64(1))
+
+	// Joining the waitlist doesn't notify anyone, getting a seat from it does
+	code, _ = utils.TestRequest(api, "POST", "/rides/join", tokenUser02, `{ "rideEventId": "small-event" }`)
+	assert.Eq(code, 200)
+	assert.Eq(next(pushedUser01).Type, rest.NOTIFICATION_RIDE_JOINED)
+	code, _ = utils.TestRequest(api, "POST", "/rides/join", tokenUser03, `{ "rideEventId": "small-event" }`)
+	assert.Eq(code, 202)
+
+	code, _ = utils.TestRequest(api, "POST", "/rides/leave", tokenUser02, `{ "rideEventId": "small-event" }`)
+	assert.Eq(code, 200)
+	seat := next(pushedUser03)
+	assert.Eq(seat.Type, rest.NOTIFICATION_RIDE_SEAT_ASSIGNED)
+	assert.Eq(*seat.RideEventId, "small-event")
+	assert.Eq(notifications("?unread=true", tokenUser01).Total, int64(2))
+
+	// The participants and the waitlist are told about changed details
+	code, _ = utils.TestRequest(api, "POST", "/rides/join", tokenUser02, `{ "rideEventId": "small-event" }`)
+	assert.Eq(code, 202)
+	code, _ = utils.TestRequest(api, "POST", "/rides/update", tokenUser01, `{ "rideEventId": "small-event", "locationTo": "Wien" }`)
+	assert.Eq(code, 200)
+	for _, pushed := range []chan rest.NotificationData{pushedUser02, pushedUser03} {
+		changed := next(pushed)
+		assert.Eq(changed.Type, rest.NOTIFICATION_RIDE_CHANGED)
+		assert.Eq(*changed.RideEventId, "small-event")
+	}
+}
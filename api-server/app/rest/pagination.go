@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/utils"
+	"strconv"
+)
+
+const (
+	PAGE_SIZE_DEFAULT = 50
+	PAGE_SIZE_MAX     = 100
+)
+
+// Response envelope of paginated endpoints. `NextCursor` is `nil` if there are
+// no more items. Pass it as the `cursor` query parameter to get the next page.
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"nextCursor"`
+	Total      int64   `json:"total"`
+}
+
+type pageParams[C any] struct {
+	// `nil` when requesting the first page.
+	Cursor *C
+	Size   int64
+}
+
+// Reads the `cursor` and `limit` query parameters of a request. A `limit`
+// above `PAGE_SIZE_MAX` is capped.
+func parsePageParams[C any](r *http.Request) (pageParams[C], error) {
+	size, err := parsePageSize(r)
+	params := pageParams[C]{Size: size}
+	if err != nil {
+		return params, err
+	}
+
+	cursorStr := r.FormValue("cursor")
+	if cursorStr != "" {
+		cursor, err := decodeCursor[C](cursorStr)
+		if err != nil {
+			return params, errors.New("Query parameter 'cursor' is invalid. Only use cursors returned as 'nextCursor'.")
+		}
+
+		params.Cursor = cursor
+	}
+
+	return params, nil
+}
+
+// Reads the `limit` query parameter of a request. A `limit` above
+// `PAGE_SIZE_MAX` is capped.
+func parsePageSize(r *http.Request) (int64, error) {
+	limitStr := r.FormValue("limit")
+	if limitStr == "" {
+		return PAGE_SIZE_DEFAULT, nil
+	}
+
+	limit, err := strconv.ParseInt(limitStr, 10, 64)
+	if err != nil || limit <= 0 {
+		return PAGE_SIZE_DEFAULT, errors.New("Query parameter 'limit' must be a positive integer.")
+	}
+
+	return min(limit, PAGE_SIZE_MAX), nil
+}
+
+// Builds a page from `items`, which must hold up to one item more than the page
+// size. The extra item is only used to detect if there is a next page.
+func buildPage[T any, C any](items []T, size int64, total int64, cursorOf func(last T) C) Page[T] {
+	page := Page[T]{Items: items, Total: total}
+	if int64(len(items)) <= size {
+		if page.Items == nil {
+			page.Items = make([]T, 0)
+		}
+
+		return page
+	}
+
+	page.Items = items[:size]
+	cursor := encodeCursor(cursorOf(page.Items[size-1]))
+	page.NextCursor = &cursor
+
+	return page
+}
+
+func encodeCursor(cursor any) string {
+	bytes, err := json.Marshal(cursor)
+	assert.Nil(err, "Failed to serialize pagination cursor.", cursor)
+
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func decodeCursor[C any](raw string) (*C, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor C
+	err = json.Unmarshal(bytes, &cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	err = utils.Validate.Struct(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,247 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Personal access tokens let scripts and integrations use the API without a
+// session. They are sent in the 'Authorization' header like access tokens,
+// but only work for routes that allow one of their scopes, see `bearerAuth`.
+
+// What a personal access token can be used for.
+const (
+	SCOPE_RIDES_READ  = "rides:read"
+	SCOPE_RIDES_WRITE = "rides:write"
+	SCOPE_GROUPS      = "groups"
+	SCOPE_CHAT        = "chat"
+)
+
+const (
+	// Tells personal access tokens apart from access tokens of sessions.
+	personalTokenPrefix      = "rs_pat_"
+	personalTokenMaxLifetime = 365 * 24 * time.Hour
+)
+
+var allTokenScopes = []string{SCOPE_RIDES_READ, SCOPE_RIDES_WRITE, SCOPE_GROUPS, SCOPE_CHAT}
+
+func personalTokenHandlers(h *http.ServeMux) {
+	h.HandleFunc("GET /users/me/tokens", handle(getPersonalTokens).with(bearerAuth(false)).build())
+	h.HandleFunc("POST /users/me/tokens", handle(createPersonalToken).with(bearerAuth(false)).build())
+	h.HandleFunc("DELETE /users/me/tokens/{id}", handle(deletePersonalToken).with(bearerAuth(false)).build())
+}
+
+type PersonalTokenData struct {
+	TokenId   string   `json:"tokenId"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"createdAt"`
+	ExpiresAt string   `json:"expiresAt"`
+	// Null if the token was never used.
+	LastUsedAt *string `json:"lastUsedAt"`
+}
+
+// Only returned once when the token is created.
+type CreatedPersonalTokenData struct {
+	PersonalTokenData
+	Token string `json:"token"`
+}
+
+type createPersonalTokenParams struct {
+	Name      *string    `json:"name" validate:"required,min=1,max=64"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=rides:read rides:write groups chat"`
+	ExpiresAt *time.Time `json:"expiresAt" validate:"required"`
+}
+
+func personalTokenToData(token sqlc.PersonalToken) PersonalTokenData {
+	data := PersonalTokenData{
+		TokenId:   token.ID,
+		Name:      token.Name,
+		Scopes:    strings.Fields(token.Scopes),
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+	}
+	if token.LastUsedAt.Valid {
+		data.LastUsedAt = &token.LastUsedAt.String
+	}
+
+	return data
+}
+
+func getPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	rows, err := state.queries.PersonalTokensGetByUser(r.Context(), user.ID)
+	assert.Nil(err)
+
+	tokens := make([]PersonalTokenData, len(rows))
+	for idx, row := range rows {
+		tokens[idx] = personalTokenToData(row)
+	}
+
+	resp, err := json.Marshal(tokens)
+	assert.Nil(err, "Failed to serialize personal tokens.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+func createPersonalToken(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error: Invalid request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+
+	var params createPersonalTokenParams
+	err = json.Unmarshal(data, &params)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
+		return
+	}
+
+	err = utils.Validate.Struct(params)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", err.Error())
+		return
+	}
+
+	token, secret, err := CreatePersonalToken(r.Context(), state.queries, user.ID, *params.Name, params.Scopes, *params.ExpiresAt)
+	if err != nil {
+		httpWriteErr(w, http.StatusBadRequest, "Invalid personal access token.", err.Error())
+		return
+	}
+
+	resp, err := json.Marshal(CreatedPersonalTokenData{PersonalTokenData: personalTokenToData(token), Token: secret})
+	assert.Nil(err, "Failed to serialize personal token.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+func deletePersonalToken(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	id := r.PathValue("id")
+	if id == "" {
+		httpWriteErr(w, http.StatusBadRequest, "Must provide 'id' path parameter.")
+		return
+	}
+
+	args := sqlc.PersonalTokensDeleteParams{
+		ID:     id,
+		UserID: user.ID,
+	}
+	deleted, err := state.queries.PersonalTokensDelete(r.Context(), args)
+	assert.Nil(err)
+
+	if deleted == 0 {
+		httpWriteErr(w, http.StatusNotFound, "No personal access token exists with 'id'.")
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+// Creates a personal access token for the user. Returns the stored token and
+// the secret that is sent in the 'Authorization' header, only the hash of the
+// secret is stored.
+func CreatePersonalToken(ctx context.Context, queries *sqlc.Queries, userId string, name string, scopes []string, expiresAt time.Time) (sqlc.PersonalToken, string, error) {
+	if len(scopes) == 0 {
+		return sqlc.PersonalToken{}, "", errors.New("A token needs at least one scope.")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allTokenScopes, scope) {
+			return sqlc.PersonalToken{}, "", fmt.Errorf("Unknown scope '%s'. Valid scopes are: %s.", scope, strings.Join(allTokenScopes, ", "))
+		}
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return sqlc.PersonalToken{}, "", errors.New("A token can't expire in the past.")
+	}
+	if expiresAt.After(time.Now().Add(personalTokenMaxLifetime)) {
+		return sqlc.PersonalToken{}, "", fmt.Errorf("A token can't be valid for longer than %d days.", personalTokenMaxLifetime/(24*time.Hour))
+	}
+
+	b := make([]byte, 32)
+	rand.Read(b)
+	secret := personalTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	args := sqlc.PersonalTokensCreateParams{
+		UserID:    userId,
+		Name:      name,
+		TokenHash: hashToken(secret),
+		Scopes:    strings.Join(slices.Compact(slices.Sorted(slices.Values(scopes))), " "),
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+	}
+	token, err := queries.PersonalTokensCreate(ctx, args)
+	return token, secret, err
+}
+
+func isPersonalToken(token string) bool {
+	return strings.HasPrefix(token, personalTokenPrefix)
+}
+
+// Authenticates a request made with a personal access token. The token needs
+// one of `scopes`, routes without scopes can't be used with personal access
+// tokens.
+func personalTokenAuth(w http.ResponseWriter, r *http.Request, secret string, scopes []string) (bool, *middlewareData) {
+	token, err := state.queries.PersonalTokensGetByHash(r.Context(), hashToken(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Invalid personal access token in 'Authorization' header.", http.StatusUnauthorized)
+		return true, nil
+	}
+	assert.Nil(err)
+
+	expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt)
+	assert.Nil(err, "Invalid expiry of personal access token.", "token id:", token.ID)
+	if time.Now().After(expiresAt) {
+		http.Error(w, "Personal access token in 'Authorization' header expired.", http.StatusUnauthorized)
+		return true, nil
+	}
+
+	user, err := state.queries.UsersGetById(r.Context(), token.UserID)
+	if err != nil {
+		http.Error(w, "Invalid personal access token in 'Authorization' header.", http.StatusUnauthorized)
+		return true, nil
+	}
+
+	if user.IsBlocked {
+		http.Error(w, "User is blocked", http.StatusUnauthorized)
+		return true, nil
+	}
+
+	granted := strings.Fields(token.Scopes)
+	if !slices.ContainsFunc(scopes, func(scope string) bool { return slices.Contains(granted, scope) }) {
+		if len(scopes) == 0 {
+			httpWriteErr(w, http.StatusForbidden, "Personal access tokens can't be used for this.")
+		} else {
+			httpWriteErr(w, http.StatusForbidden, "Your personal access token does not have the permission to do this.", fmt.Sprintf("Requires one of the scopes: %s.", strings.Join(scopes, ", ")))
+		}
+		return true, nil
+	}
+
+	err = state.queries.PersonalTokensTouch(r.Context(), token.ID)
+	if err != nil {
+		log.Println("Error: Failed to update personal access token.", "token id:", token.ID, "error:", err)
+	}
+
+	return false, &middlewareData{key: "user", value: user}
+}
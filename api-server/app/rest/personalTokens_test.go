@@ -0,0 +1,127 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandlePersonalTokens(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0026-personal-tokens.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tokenUser01 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+	tokenUser02 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.w19_ESPa5AgLpH2IV9CrwSPq4XQcJJHgAqVf2bNmcOsdamLKJL_JntjmB-Er356m2bH4OlXg0hYagmU6irxwLymqB7QCGhsF1ixtMwyrGq8lFeePVwb41uD_5xRRkJxjB0GeIO_AIyCl9yCVa3djzcvx-6EBC1f_E9_t"
+
+	request := func(method string, endpoint string, token string, body string) *http.Response {
+		req, err := http.NewRequest(method, api.URL+endpoint, strings.NewReader(body))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		return resp
+	}
+
+	status := func(method string, endpoint string, token string) int {
+		resp := request(method, endpoint, token, "{}")
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	create := func(body string) (rest.CreatedPersonalTokenData, int) {
+		resp := request("POST", "/users/me/tokens", tokenUser01, body)
+		defer resp.Body.Close()
+		var created rest.CreatedPersonalTokenData
+		if resp.StatusCode == 200 {
+			assert.Nil(json.NewDecoder(resp.Body).Decode(&created))
+		}
+		return created, resp.StatusCode
+	}
+
+	tokens := func() []rest.PersonalTokenData {
+		resp := request("GET", "/users/me/tokens", tokenUser01, "")
+		assert.Eq(resp.StatusCode, 200)
+		var tokens []rest.PersonalTokenData
+		assert.Nil(json.NewDecoder(resp.Body).Decode(&tokens))
+		return tokens
+	}
+
+	expiresAt := time.Now().Add(30 * 24 * time.Hour).UTC().Format(time.RFC3339)
+
+	// Invalid tokens
+	_, code := create(`{ "name": "sheet", "scopes": ["rides:read", "admin"], "expiresAt": "` + expiresAt + `" }`)
+	assert.Eq(code, 400)
+	_, code = create(`{ "name": "sheet", "scopes": [], "expiresAt": "` + expiresAt + `" }`)
+	assert.Eq(code, 400)
+	_, code = create(`{ "name": "sheet", "scopes": ["rides:read"], "expiresAt": "2000-01-01T00:00:00Z" }`)
+	assert.Eq(code, 400)
+	_, code = create(`{ "name": "sheet", "scopes": ["rides:read"], "expiresAt": "` + time.Now().Add(2*365*24*time.Hour).UTC().Format(time.RFC3339) + `" }`)
+	assert.Eq(code, 400)
+
+	// Only the hash of a token is stored
+	sheet, code := create(`{ "name": "sheet", "scopes": ["rides:write", "rides:read"], "expiresAt": "` + expiresAt + `" }`)
+	assert.Eq(code, 200)
+	assert.True(strings.HasPrefix(sheet.Token, "rs_pat_"), sheet.Token)
+	assert.Eq(strings.Join(sheet.Scopes, " "), "rides:read rides:write")
+	assert.True(sheet.LastUsedAt == nil)
+	var stored int64
+	assert.Nil(db.QueryRow("SELECT COUNT(*) FROM personal_tokens WHERE token_hash = ?", sheet.Token).Scan(&stored))
+	assert.Eq(stored, int64(0))
+
+	list := tokens()
+	assert.Eq(len(list), 1)
+	assert.Eq(list[0].Name, "sheet")
+
+	// Tokens only work for routes that allow one of their scopes
+	assert.Eq(getUserMe(api.Client(), api.URL, sheet.Token).ID, "NnCaPHQLC9")
+	assert.Eq(status("GET", "/rides/many", sheet.Token), 200)
+	assert.Eq(status("POST", "/rides", sheet.Token), 400)
+	assert.Eq(status("GET", "/groups/many", sheet.Token), 403)
+	assert.Eq(status("POST", "/groups/by-id/g/send-message", sheet.Token), 403)
+
+	// Tokens can't be used to manage the account
+	assert.Eq(status("GET", "/users/me/tokens", sheet.Token), 403)
+	assert.Eq(status("POST", "/users/me/tokens", sheet.Token), 403)
+	assert.Eq(status("GET", "/auth/sessions", sheet.Token), 403)
+	assert.Eq(status("POST", "/auth/logout", sheet.Token), 403)
+	assert.Eq(status("POST", "/users/me/calendar-token", sheet.Token), 403)
+
+	// Using a token records when it was last used
+	list = tokens()
+	assert.True(list[0].LastUsedAt != nil)
+
+	// Tokens created with the CLI
+	chat, secret, err := rest.CreatePersonalToken(context.Background(), sqlc.New(db), "NnCaPHQLC9", "bot", []string{"chat", "groups"}, time.Now().Add(time.Hour))
+	assert.Nil(err)
+	assert.Eq(chat.Scopes, "chat groups")
+	assert.Eq(status("GET", "/groups/many", secret), 200)
+	assert.Eq(status("GET", "/rides/many", secret), 403)
+	assert.Eq(len(tokens()), 2)
+
+	// Expired tokens
+	_, err = db.Exec("UPDATE personal_tokens SET expires_at = '2000-01-01T00:00:00Z' WHERE id = ?", chat.ID)
+	assert.Nil(err)
+	assert.Eq(status("GET", "/groups/many", secret), 401)
+	assert.Eq(status("GET", "/users/me", "rs_pat_unknown"), 401)
+
+	// Revoking tokens
+	assert.Eq(status("DELETE", "/users/me/tokens/"+sheet.TokenId, tokenUser02), 404)
+	assert.Eq(status("GET", "/rides/many", sheet.Token), 200)
+	assert.Eq(status("DELETE", "/users/me/tokens/"+sheet.TokenId, tokenUser01), 200)
+	assert.Eq(status("GET", "/rides/many", sheet.Token), 401)
+	assert.Eq(status("DELETE", "/users/me/tokens/"+sheet.TokenId, tokenUser01), 404)
+}
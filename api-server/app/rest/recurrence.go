@@ -0,0 +1,391 @@
+package rest
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	// Schedules are expanded in the time zone of their creator. Embed the
+	// time zone database so this works on hosts without one.
+	_ "time/tzdata"
+)
+
+const (
+	SCHEDULE_UNIT_DAYS     = "days"
+	SCHEDULE_UNIT_WEEKS    = "weeks"
+	SCHEDULE_UNIT_MONTHS   = "months"
+	SCHEDULE_UNIT_YEARS    = "years"
+	SCHEDULE_UNIT_WEEKDAYS = "weekdays"
+	// Number of consecutive periods without an occurrence after which a series
+	// is considered to have ended. Rules like 'FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29'
+	// may go almost 8 years without an occurrence.
+	RECURRENCE_EMPTY_PERIODS_MAX = 3000
+)
+
+// The recurrence rule of a ride series. Occurrences are always computed from
+// the anchor, the time of the first event of the series, and not from the
+// previous occurrence. This keeps the time of day stable across DST changes
+// and keeps month-end dates from drifting (Jan 31 -> Feb 28 -> Mar 31).
+type recurrence struct {
+	anchor time.Time
+	rule   rrule
+}
+
+// Creates the recurrence of a series whose first event takes place at `anchor`
+// from a recurrence rule (see `parseRRule`). Wall clock times are kept in the
+// time zone `timezone`, which has to be an IANA time zone name (e.g.
+// 'Europe/Vienna').
+func newRecurrence(anchor time.Time, rule string, timezone string) (recurrence, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return recurrence{}, fmt.Errorf("Invalid time zone '%s'.", timezone)
+	}
+
+	parsed, err := parseRRule(rule, location)
+	if err != nil {
+		return recurrence{}, err
+	}
+
+	return recurrence{anchor: anchor.In(location), rule: parsed}, nil
+}
+
+// Creates the recurrence of a series that was scheduled with one of the basic
+// schedule units.
+func newRecurrenceFromUnit(anchor time.Time, unit string, interval int64, weekdays []string, timezone string) (recurrence, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return recurrence{}, fmt.Errorf("Invalid time zone '%s'.", timezone)
+	}
+
+	rule, err := basicScheduleToRRule(anchor.In(location), unit, interval, weekdays)
+	if err != nil {
+		return recurrence{}, err
+	}
+
+	return newRecurrence(anchor, rule, timezone)
+}
+
+// Returns the first occurrence that takes place strictly after `after`. The
+// anchor itself is the first occurrence of the series. Returns false if the
+// series has no more occurrences. Use `occurrencesAfter` to walk through
+// several occurrences.
+func (r recurrence) next(after time.Time) (time.Time, bool) {
+	return r.occurrencesAfter(after).next()
+}
+
+// Iterates over the occurrences of a recurrence in order. Continuing from the
+// previous occurrence saves expanding the periods before it again, which
+// rules with COUNT would otherwise need for every occurrence.
+type recurrenceIter struct {
+	r     recurrence
+	after time.Time
+	// The next period to expand and the occurrences of the last one that
+	// weren't looked at yet.
+	period       int
+	pending      []time.Time
+	emptyPeriods int
+	// Number of occurrences counted towards COUNT, including the anchor.
+	seen  int
+	ended bool
+}
+
+// Returns an iterator over the occurrences that take place strictly after
+// `after`.
+func (r recurrence) occurrencesAfter(after time.Time) *recurrenceIter {
+	// COUNT includes every occurrence of the series, so we have to start
+	// from the beginning. Otherwise start a bit before the expected period so
+	// DST offsets can't make us skip one.
+	period := 0
+	if r.rule.count == 0 {
+		period = max(r.estimatePeriods(after)-1, 0)
+	}
+
+	return &recurrenceIter{r: r, after: after, period: period, seen: 1}
+}
+
+// Returns the next occurrence or false if the series has no more occurrences.
+func (it *recurrenceIter) next() (time.Time, bool) {
+	r := it.r
+	if it.after.Before(r.anchor) {
+		it.after = r.anchor
+		return r.anchor, true
+	}
+
+	for !it.ended {
+		if len(it.pending) == 0 {
+			if it.emptyPeriods >= RECURRENCE_EMPTY_PERIODS_MAX {
+				it.ended = true
+				break
+			}
+
+			it.pending = r.expand(it.period)
+			it.period++
+			if len(it.pending) == 0 {
+				it.emptyPeriods++
+				continue
+			}
+			it.emptyPeriods = 0
+		}
+
+		occurrence := it.pending[0]
+		it.pending = it.pending[1:]
+		if !occurrence.After(r.anchor) {
+			continue
+		}
+
+		if r.rule.until != nil && occurrence.After(*r.rule.until) {
+			it.ended = true
+			break
+		}
+
+		// Excluded occurrences still count towards COUNT.
+		if r.rule.count != 0 {
+			if it.seen >= r.rule.count {
+				it.ended = true
+				break
+			}
+			it.seen++
+		}
+
+		if occurrence.After(it.after) && !r.excluded(occurrence) {
+			it.after = occurrence
+			return occurrence, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Reports whether the rule matches any day after the anchor, ignoring COUNT,
+// UNTIL and EXDATEs. Rules like 'FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30' never do
+// and would have the scheduler search through every empty period on each tick.
+func (r recurrence) recurs() bool {
+	emptyPeriods := 0
+	for period := 0; emptyPeriods < RECURRENCE_EMPTY_PERIODS_MAX; period++ {
+		occurrences := r.expand(period)
+		if slices.ContainsFunc(occurrences, r.anchor.Before) {
+			return true
+		}
+
+		emptyPeriods++
+		if len(occurrences) > 0 {
+			emptyPeriods = 0
+		}
+	}
+
+	return false
+}
+
+// Returns the occurrences excluded by EXDATEs. Excluded dates are returned at
+// the time of day of the anchor.
+func (r recurrence) exclusions() []time.Time {
+	exclusions := make([]time.Time, len(r.rule.exDates))
+	for idx, exDate := range r.rule.exDates {
+		exclusions[idx] = exDate.at
+		if exDate.dateOnly {
+			exclusions[idx] = r.at(exDate.at.Year(), exDate.at.Month(), exDate.at.Day())
+		}
+	}
+
+	return exclusions
+}
+
+// Returns the sorted occurrences of the `period`-th period of the series. The
+// 0th period contains the anchor.
+func (r recurrence) expand(period int) []time.Time {
+	a := r.anchor
+	n := period * r.rule.interval
+	dates := make([]time.Time, 0)
+
+	switch r.rule.freq {
+	case RRULE_FREQ_DAILY:
+		day := r.at(a.Year(), a.Month(), a.Day()+n)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			dates = append(dates, day)
+		}
+	case RRULE_FREQ_WEEKLY:
+		monday := a.Day() - daysSinceMonday(a.Weekday()) + n*7
+		if len(r.rule.byDay) == 0 {
+			dates = append(dates, r.at(a.Year(), a.Month(), monday+daysSinceMonday(a.Weekday())))
+		}
+		for _, day := range r.rule.byDay {
+			dates = append(dates, r.at(a.Year(), a.Month(), monday+daysSinceMonday(day.weekday)))
+		}
+		dates = slices.DeleteFunc(dates, func(date time.Time) bool { return !r.matchesMonth(date) })
+	case RRULE_FREQ_MONTHLY:
+		firstOfMonth := time.Date(a.Year(), a.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(firstOfMonth) {
+			dates = r.expandMonth(firstOfMonth.Year(), firstOfMonth.Month())
+		}
+	case RRULE_FREQ_YEARLY:
+		months := r.rule.byMonth
+		if len(months) == 0 && len(r.rule.byMonthDay) > 0 {
+			months = []time.Month{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+		} else if len(months) == 0 {
+			months = []time.Month{a.Month()}
+		}
+
+		for _, month := range months {
+			dates = append(dates, r.expandMonth(a.Year()+n, month)...)
+		}
+	}
+
+	slices.SortFunc(dates, func(a time.Time, b time.Time) int { return a.Compare(b) })
+	dates = slices.CompactFunc(dates, func(a time.Time, b time.Time) bool { return a.Equal(b) })
+
+	return r.applySetPos(dates)
+}
+
+// Returns the days of a month matched by BYMONTHDAY and BYDAY. Without either
+// of them only the day of the anchor matches, if the month has it.
+func (r recurrence) expandMonth(year int, month time.Month) []time.Time {
+	length := daysInMonth(year, month)
+	dates := make([]time.Time, 0)
+
+	for day := 1; day <= length; day++ {
+		date := r.at(year, month, day)
+
+		matches := r.matchesMonthDay(date) && r.matchesWeekday(date)
+		if len(r.rule.byMonthDay) == 0 && len(r.rule.byDay) == 0 {
+			matches = day == r.anchor.Day()
+		}
+
+		if matches {
+			dates = append(dates, date)
+		}
+	}
+
+	return dates
+}
+
+func (r recurrence) applySetPos(dates []time.Time) []time.Time {
+	if len(r.rule.bySetPos) == 0 {
+		return dates
+	}
+
+	selected := make([]time.Time, 0, len(r.rule.bySetPos))
+	for _, pos := range r.rule.bySetPos {
+		idx := pos - 1
+		if pos < 0 {
+			idx = len(dates) + pos
+		}
+
+		if idx >= 0 && idx < len(dates) && !slices.ContainsFunc(selected, dates[idx].Equal) {
+			selected = append(selected, dates[idx])
+		}
+	}
+
+	slices.SortFunc(selected, func(a time.Time, b time.Time) int { return a.Compare(b) })
+	return selected
+}
+
+// Returns the given day at the time of day of the anchor.
+func (r recurrence) at(year int, month time.Month, day int) time.Time {
+	a := r.anchor
+	return time.Date(year, month, day, a.Hour(), a.Minute(), a.Second(), 0, a.Location())
+}
+
+func (r recurrence) matchesMonth(date time.Time) bool {
+	return len(r.rule.byMonth) == 0 || slices.Contains(r.rule.byMonth, date.Month())
+}
+
+func (r recurrence) matchesMonthDay(date time.Time) bool {
+	if len(r.rule.byMonthDay) == 0 {
+		return true
+	}
+
+	length := daysInMonth(date.Year(), date.Month())
+	for _, day := range r.rule.byMonthDay {
+		if day == date.Day() || length+day+1 == date.Day() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r recurrence) matchesWeekday(date time.Time) bool {
+	if len(r.rule.byDay) == 0 {
+		return true
+	}
+
+	length := daysInMonth(date.Year(), date.Month())
+	for _, day := range r.rule.byDay {
+		if day.weekday != date.Weekday() {
+			continue
+		}
+
+		nth := (date.Day()-1)/7 + 1
+		nthFromEnd := -((length-date.Day())/7 + 1)
+		if day.ordinal == 0 || day.ordinal == nth || day.ordinal == nthFromEnd {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r recurrence) excluded(occurrence time.Time) bool {
+	for _, exDate := range r.rule.exDates {
+		if !exDate.dateOnly && exDate.at.Equal(occurrence) {
+			return true
+		}
+
+		local := occurrence.In(exDate.at.Location())
+		if exDate.dateOnly && local.Year() == exDate.at.Year() && local.YearDay() == exDate.at.YearDay() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Rough number of periods between the anchor and `t`. Never larger than the
+// real number.
+func (r recurrence) estimatePeriods(t time.Time) int {
+	t = t.In(r.anchor.Location())
+	if !t.After(r.anchor) {
+		return 0
+	}
+
+	switch r.rule.freq {
+	case RRULE_FREQ_DAILY:
+		return int(t.Sub(r.anchor).Hours()/24) / r.rule.interval
+	case RRULE_FREQ_WEEKLY:
+		return int(t.Sub(r.anchor).Hours()/(24*7)) / r.rule.interval
+	case RRULE_FREQ_MONTHLY:
+		return monthsBetween(r.anchor, t) / r.rule.interval
+	case RRULE_FREQ_YEARLY:
+		return (t.Year() - r.anchor.Year()) / r.rule.interval
+	default:
+		return 0
+	}
+}
+
+// Returns the seconds between the wall clock times of `from` and `to` in
+// `location`. Unlike `to.Sub(from)` it doesn't include the hour gained or lost
+// when DST changes in between.
+func wallClockOffset(from time.Time, to time.Time, location *time.Location) int64 {
+	from, to = from.In(location), to.In(location)
+	fromWall := time.Date(from.Year(), from.Month(), from.Day(), from.Hour(), from.Minute(), from.Second(), 0, time.UTC)
+	toWall := time.Date(to.Year(), to.Month(), to.Day(), to.Hour(), to.Minute(), to.Second(), 0, time.UTC)
+	return int64(toWall.Sub(fromWall) / time.Second)
+}
+
+// Moves `occurrence` by `offset` seconds on the wall clock of `location`.
+func shiftOccurrence(occurrence time.Time, offset int64, location *time.Location) time.Time {
+	occurrence = occurrence.In(location)
+	return time.Date(occurrence.Year(), occurrence.Month(), occurrence.Day(), occurrence.Hour(), occurrence.Minute(), occurrence.Second()+int(offset), 0, location)
+}
+
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func monthsBetween(from time.Time, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+func daysSinceMonday(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
@@ -0,0 +1,426 @@
+package rest
+
+import (
+	"ride_sharing_api/app/assert"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		anchor   string
+		timezone string
+		unit     string
+		interval int64
+		weekdays []string
+		// defaults to the anchor
+		after    string
+		expected []string
+	}{
+		{
+			name:     "daily across spring DST change",
+			anchor:   "2025-03-29T07:30:00+01:00",
+			timezone: "Europe/Vienna",
+			unit:     "days",
+			interval: 1,
+			expected: []string{"2025-03-30T07:30:00+02:00", "2025-03-31T07:30:00+02:00"},
+		},
+		{
+			name:     "daily across fall DST change",
+			anchor:   "2025-10-25T07:30:00+02:00",
+			timezone: "Europe/Vienna",
+			unit:     "days",
+			interval: 1,
+			expected: []string{"2025-10-26T07:30:00+01:00", "2025-10-27T07:30:00+01:00"},
+		},
+		{
+			name:     "daily inside DST gap keeps time of day afterwards",
+			anchor:   "2025-03-29T02:30:00+01:00",
+			timezone: "Europe/Vienna",
+			unit:     "days",
+			interval: 1,
+			expected: []string{"2025-03-30T03:30:00+02:00", "2025-03-31T02:30:00+02:00"},
+		},
+		{
+			name:     "weekly across US DST change",
+			anchor:   "2025-03-03T18:00:00-05:00",
+			timezone: "America/New_York",
+			unit:     "weeks",
+			interval: 1,
+			expected: []string{"2025-03-10T18:00:00-04:00", "2025-03-17T18:00:00-04:00"},
+		},
+		{
+			name:     "UTC schedule shifts local time across DST change",
+			anchor:   "2025-03-29T06:30:00Z",
+			timezone: "UTC",
+			unit:     "days",
+			interval: 1,
+			expected: []string{"2025-03-30T06:30:00Z"},
+		},
+		{
+			name:     "daily far after anchor",
+			anchor:   "2025-01-01T07:30:00Z",
+			timezone: "UTC",
+			unit:     "days",
+			interval: 3,
+			after:    "2025-06-15T12:00:00Z",
+			expected: []string{"2025-06-18T07:30:00Z", "2025-06-21T07:30:00Z"},
+		},
+		{
+			name:     "monthly from month end",
+			anchor:   "2025-01-31T09:00:00Z",
+			timezone: "UTC",
+			unit:     "months",
+			interval: 1,
+			expected: []string{"2025-02-28T09:00:00Z", "2025-03-31T09:00:00Z", "2025-04-30T09:00:00Z", "2025-05-31T09:00:00Z"},
+		},
+		{
+			name:     "monthly from month end in leap year",
+			anchor:   "2024-01-31T09:00:00Z",
+			timezone: "UTC",
+			unit:     "months",
+			interval: 1,
+			expected: []string{"2024-02-29T09:00:00Z", "2024-03-31T09:00:00Z"},
+		},
+		{
+			name:     "monthly from month end after clamped occurrence",
+			anchor:   "2025-01-31T09:00:00Z",
+			timezone: "UTC",
+			unit:     "months",
+			interval: 1,
+			after:    "2025-03-01T00:00:00Z",
+			expected: []string{"2025-03-31T09:00:00Z"},
+		},
+		{
+			name:     "every two months across year end",
+			anchor:   "2025-12-30T09:00:00Z",
+			timezone: "UTC",
+			unit:     "months",
+			interval: 2,
+			expected: []string{"2026-02-28T09:00:00Z", "2026-04-30T09:00:00Z"},
+		},
+		{
+			name:     "monthly month end in local time zone",
+			anchor:   "2025-01-31T23:30:00+01:00",
+			timezone: "Europe/Vienna",
+			unit:     "months",
+			interval: 1,
+			expected: []string{"2025-02-28T23:30:00+01:00", "2025-03-31T23:30:00+02:00"},
+		},
+		{
+			name:     "yearly from leap day",
+			anchor:   "2024-02-29T12:00:00Z",
+			timezone: "UTC",
+			unit:     "years",
+			interval: 1,
+			expected: []string{"2025-02-28T12:00:00Z", "2026-02-28T12:00:00Z", "2027-02-28T12:00:00Z", "2028-02-29T12:00:00Z"},
+		},
+		{
+			name:     "weekdays",
+			anchor:   "2044-11-25T08:00:00Z",
+			timezone: "UTC",
+			unit:     "weekdays",
+			interval: 1,
+			weekdays: []string{"wednesday", "monday"},
+			expected: []string{"2044-11-28T08:00:00Z", "2044-11-30T08:00:00Z", "2044-12-05T08:00:00Z"},
+		},
+		{
+			name:     "weekdays every second week",
+			anchor:   "2025-01-06T08:00:00Z",
+			timezone: "UTC",
+			unit:     "weekdays",
+			interval: 2,
+			weekdays: []string{"friday", "monday"},
+			expected: []string{"2025-01-10T08:00:00Z", "2025-01-20T08:00:00Z", "2025-01-24T08:00:00Z", "2025-02-03T08:00:00Z"},
+		},
+		{
+			name:     "weekdays every second week far after anchor",
+			anchor:   "2025-01-06T08:00:00Z",
+			timezone: "UTC",
+			unit:     "weekdays",
+			interval: 2,
+			weekdays: []string{"sunday"},
+			after:    "2025-03-01T00:00:00Z",
+			expected: []string{"2025-03-09T08:00:00Z", "2025-03-23T08:00:00Z"},
+		},
+		{
+			name:     "weekdays across DST change",
+			anchor:   "2025-03-28T07:30:00+01:00",
+			timezone: "Europe/Vienna",
+			unit:     "weekdays",
+			interval: 1,
+			weekdays: []string{"monday"},
+			expected: []string{"2025-03-31T07:30:00+02:00"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			anchor, err := time.Parse(time.RFC3339, test.anchor)
+			assert.Nil(err)
+
+			rule, err := newRecurrenceFromUnit(anchor, test.unit, test.interval, test.weekdays, test.timezone)
+			assert.Nil(err)
+
+			assertOccurrences(rule, anchor, test.after, test.expected, false)
+		})
+	}
+}
+
+func TestRecurrenceNextRRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		anchor   string
+		timezone string
+		rule     string
+		// defaults to the anchor
+		after    string
+		expected []string
+		// the series has no more occurrences after the expected ones
+		ends bool
+	}{
+		{
+			name:     "every other tuesday and thursday",
+			anchor:   "2025-01-07T07:30:00+01:00",
+			timezone: "Europe/Vienna",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			expected: []string{"2025-01-09T07:30:00+01:00", "2025-01-21T07:30:00+01:00", "2025-01-23T07:30:00+01:00", "2025-02-04T07:30:00+01:00"},
+		},
+		{
+			name:     "first monday of the month",
+			anchor:   "2025-01-06T18:00:00Z",
+			timezone: "UTC",
+			rule:     "RRULE:FREQ=MONTHLY;BYDAY=1MO",
+			expected: []string{"2025-02-03T18:00:00Z", "2025-03-03T18:00:00Z", "2025-04-07T18:00:00Z"},
+		},
+		{
+			name:     "last friday of the month",
+			anchor:   "2025-01-31T18:00:00Z",
+			timezone: "UTC",
+			rule:     "FREQ=MONTHLY;BYDAY=-1FR",
+			expected: []string{"2025-02-28T18:00:00Z", "2025-03-28T18:00:00Z"},
+		},
+		{
+			name:     "last day of the month",
+			anchor:   "2025-01-31T09:00:00Z",
+			timezone: "UTC",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=-1",
+			expected: []string{"2025-02-28T09:00:00Z", "2025-03-31T09:00:00Z", "2025-04-30T09:00:00Z"},
+		},
+		{
+			name:     "last workday of the month",
+			anchor:   "2025-01-31T09:00:00Z",
+			timezone: "UTC",
+			rule:     "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			after:    "2025-04-30T10:00:00Z",
+			expected: []string{"2025-05-30T09:00:00Z", "2025-06-30T09:00:00Z", "2025-07-31T09:00:00Z"},
+		},
+		{
+			name:     "count includes the anchor",
+			anchor:   "2025-01-01T09:00:00Z",
+			timezone: "UTC",
+			rule:     "FREQ=DAILY;COUNT=3",
+			expected: []string{"2025-01-02T09:00:00Z", "2025-01-03T09:00:00Z"},
+			ends:     true,
+		},
+		{
+			name:     "until is inclusive",
+			anchor:   "2025-01-01T09:00:00Z",
+			timezone: "UTC",
+			rule:     "FREQ=WEEKLY;UNTIL=20250115T090000Z",
+			expected: []string{"2025-01-08T09:00:00Z", "2025-01-15T09:00:00Z"},
+			ends:     true,
+		},
+		{
+			name:     "until date in local time zone",
+			anchor:   "2025-01-01T23:30:00+01:00",
+			timezone: "Europe/Vienna",
+			rule:     "FREQ=DAILY;UNTIL=20250102",
+			expected: []string{"2025-01-02T23:30:00+01:00"},
+			ends:     true,
+		},
+		{
+			name:     "excluded dates are skipped",
+			anchor:   "2025-04-01T07:30:00+02:00",
+			timezone: "Europe/Vienna",
+			rule:     "RRULE:FREQ=WEEKLY;BYDAY=TU,TH\nEXDATE:20250403T053000Z\nEXDATE;VALUE=DATE:20250408",
+			expected: []string{"2025-04-10T07:30:00+02:00", "2025-04-15T07:30:00+02:00"},
+		},
+		{
+			name:     "excluded dates count towards count",
+			anchor:   "2025-01-01T09:00:00Z",
+			timezone: "UTC",
+			rule:     "FREQ=DAILY;COUNT=3 EXDATE:20250102T090000Z",
+			expected: []string{"2025-01-03T09:00:00Z"},
+			ends:     true,
+		},
+		{
+			name:     "yearly on a weekday of a month",
+			anchor:   "2025-11-27T12:00:00-05:00",
+			timezone: "America/New_York",
+			rule:     "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+			expected: []string{"2026-11-26T12:00:00-05:00", "2027-11-25T12:00:00-05:00"},
+		},
+		{
+			name:     "far after anchor",
+			anchor:   "2025-01-07T07:30:00Z",
+			timezone: "UTC",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			after:    "2026-01-01T00:00:00Z",
+			expected: []string{"2026-01-06T07:30:00Z", "2026-01-08T07:30:00Z"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			anchor, err := time.Parse(time.RFC3339, test.anchor)
+			assert.Nil(err)
+
+			rule, err := newRecurrence(anchor, test.rule, test.timezone)
+			assert.Nil(err)
+
+			assertOccurrences(rule, anchor, test.after, test.expected, test.ends)
+		})
+	}
+}
+
+func assertOccurrences(rule recurrence, anchor time.Time, afterStr string, expectedStrs []string, ends bool) {
+	after := anchor
+	if afterStr != "" {
+		var err error
+		after, err = time.Parse(time.RFC3339, afterStr)
+		assert.Nil(err)
+	}
+
+	for _, expectedStr := range expectedStrs {
+		expected, err := time.Parse(time.RFC3339, expectedStr)
+		assert.Nil(err)
+
+		next, ok := rule.next(after)
+		assert.True(ok, "expected:", expected, "received: end of series")
+		assert.True(next.Equal(expected), "expected:", expected, "received:", next)
+		after = next
+	}
+
+	if ends {
+		next, ok := rule.next(after)
+		assert.True(!ok, "expected: end of series", "received:", next)
+	}
+}
+
+func TestRecurrenceNextBeforeAnchor(t *testing.T) {
+	anchor, err := time.Parse(time.RFC3339, "2025-01-06T08:00:00Z")
+	assert.Nil(err)
+
+	rule, err := newRecurrenceFromUnit(anchor, "weekdays", 1, []string{"friday"}, "UTC")
+	assert.Nil(err)
+
+	next, ok := rule.next(anchor.Add(-time.Hour))
+	assert.True(ok)
+	assert.True(next.Equal(anchor))
+}
+
+func TestRecurrenceOccurrencesAfter(t *testing.T) {
+	anchor, err := time.Parse(time.RFC3339, "2025-01-06T08:00:00Z")
+	assert.Nil(err)
+
+	rule, err := newRecurrence(anchor, "RRULE:FREQ=WEEKLY;BYDAY=MO,TH;COUNT=200\nEXDATE:20250109T080000Z", "UTC")
+	assert.Nil(err)
+
+	// Walking the series gives the same occurrences as starting over each time
+	occurrences := rule.occurrencesAfter(anchor)
+	after := anchor
+	for {
+		expected, expectedOk := rule.next(after)
+		next, ok := occurrences.next()
+		assert.Eq(ok, expectedOk)
+		if !ok {
+			break
+		}
+
+		assert.True(next.Equal(expected), "expected:", expected, "received:", next)
+		after = next
+	}
+
+	// COUNT includes the anchor and the excluded occurrence
+	last, err := time.Parse(time.RFC3339, "2026-12-03T08:00:00Z")
+	assert.Nil(err)
+	assert.True(after.Equal(last), "expected:", last, "received:", after)
+}
+
+func TestRecurrenceRecurs(t *testing.T) {
+	anchor, err := time.Parse(time.RFC3339, "2025-01-30T08:00:00Z")
+	assert.Nil(err)
+
+	tests := []struct {
+		rule   string
+		recurs bool
+	}{
+		{rule: "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29", recurs: true},
+		{rule: "FREQ=MONTHLY;COUNT=1", recurs: true},
+		{rule: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", recurs: false},
+		{rule: "FREQ=YEARLY;BYMONTH=2", recurs: false},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=31;BYMONTH=4,6,9,11", recurs: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			rule, err := newRecurrence(anchor, test.rule, "UTC")
+			assert.Nil(err)
+			assert.Eq(rule.recurs(), test.recurs)
+		})
+	}
+}
+
+func TestRecurrenceInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		unit     string
+		interval int64
+		weekdays []string
+		timezone string
+	}{
+		{name: "unknown unit", unit: "hours", interval: 1, timezone: "UTC"},
+		{name: "zero interval", unit: "days", interval: 0, timezone: "UTC"},
+		{name: "unknown time zone", unit: "days", interval: 1, timezone: "Mars/Olympus_Mons"},
+		{name: "no weekdays", unit: "weekdays", interval: 1, timezone: "UTC"},
+		{name: "unknown weekday", unit: "weekdays", interval: 1, weekdays: []string{"Monday"}, timezone: "UTC"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newRecurrenceFromUnit(time.Now(), test.unit, test.interval, test.weekdays, test.timezone)
+			assert.True(err != nil)
+		})
+	}
+}
+
+func TestRRuleInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{name: "empty", rule: ""},
+		{name: "missing freq", rule: "INTERVAL=2"},
+		{name: "unsupported freq", rule: "FREQ=HOURLY"},
+		{name: "unsupported part", rule: "FREQ=DAILY;BYHOUR=8"},
+		{name: "zero interval", rule: "FREQ=DAILY;INTERVAL=0"},
+		{name: "count and until", rule: "FREQ=DAILY;COUNT=2;UNTIL=20250101T000000Z"},
+		{name: "invalid weekday", rule: "FREQ=WEEKLY;BYDAY=XX"},
+		{name: "ordinal in weekly rule", rule: "FREQ=WEEKLY;BYDAY=1MO"},
+		{name: "yearly weekday without month", rule: "FREQ=YEARLY;BYDAY=MO"},
+		{name: "invalid month day", rule: "FREQ=MONTHLY;BYMONTHDAY=32"},
+		{name: "set position alone", rule: "FREQ=MONTHLY;BYSETPOS=1"},
+		{name: "two rules", rule: "RRULE:FREQ=DAILY RRULE:FREQ=WEEKLY"},
+		{name: "unsupported property", rule: "RRULE:FREQ=DAILY RDATE:20250101T000000Z"},
+		{name: "invalid exdate", rule: "RRULE:FREQ=DAILY EXDATE:2025-01-01"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newRecurrence(time.Now(), test.rule, "UTC")
+			assert.True(err != nil)
+		})
+	}
+}
@@ -13,7 +13,7 @@ import (
 	"ride_sharing_api/app/sqlc"
 	"ride_sharing_api/app/utils"
 	"slices"
-	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,13 +23,20 @@ const (
 	RIDE_STATUS_UPCOMING = "upcoming"
 )
 
+const (
+	JOIN_STATUS_JOINED     = "joined"
+	JOIN_STATUS_WAITLISTED = "waitlisted"
+)
+
 func rideHandlers(h *http.ServeMux) {
-	h.HandleFunc("POST /rides", handle(createRide).with(bearerAuth(false)).build())
-	h.HandleFunc("POST /rides/update", handle(updateRide).with(bearerAuth(false)).build())
-	h.HandleFunc("POST /rides/join", handle(joinRide).with(bearerAuth(false)).build())
-	h.HandleFunc("GET /rides/many", handle(getManyRides).with(bearerAuth(false)).build())
-	h.HandleFunc("GET /rides/by-id/{id}", handle(getEventById).with(bearerAuth(false)).build())
-	h.HandleFunc("GET /rides/upcoming/by-id/{id}", handle(getUpcomingById).with(bearerAuth(false)).build())
+	h.HandleFunc("POST /rides", handle(createRide).with(bearerAuth(false, SCOPE_RIDES_WRITE)).build())
+	h.HandleFunc("POST /rides/update", handle(updateRide).with(bearerAuth(false, SCOPE_RIDES_WRITE)).with(requireRole(fromBody("rideEventId"), ROLE_RIDE_OWNER, ROLE_RIDE_DRIVER)).build())
+	h.HandleFunc("POST /rides/join", handle(joinRide).with(bearerAuth(false, SCOPE_RIDES_WRITE)).build())
+	h.HandleFunc("POST /rides/leave", handle(leaveRide).with(bearerAuth(false, SCOPE_RIDES_WRITE)).build())
+	h.HandleFunc("GET /rides/many", handle(getManyRides).with(bearerAuth(false, SCOPE_RIDES_READ)).build())
+	h.HandleFunc("GET /rides/by-id/{id}", handle(getEventById).with(bearerAuth(false, SCOPE_RIDES_READ)).build())
+	h.HandleFunc("GET /rides/by-id/{id}/changes", handle(getEventChanges).with(bearerAuth(false, SCOPE_RIDES_READ)).build())
+	h.HandleFunc("GET /rides/upcoming/by-id/{id}", handle(getUpcomingById).with(bearerAuth(false, SCOPE_RIDES_READ)).build())
 }
 
 type RideEventData struct {
@@ -46,12 +53,39 @@ type RideEventData struct {
 	TransportLimit int64             `json:"transportLimit"`
 	Schedule       *rideSchedule     `json:"schedule"`
 	Participants   []rideParticipant `json:"participants"`
+	// Users waiting for a seat, in the order they will get one.
+	Waitlist []rideParticipant `json:"waitlist"`
+	// Group the ride belongs to, only members of the group can see and join
+	// it. Not set for public rides.
+	GroupId *string `json:"groupId"`
+	// Latest messages of the thread of the ride event. Only set on
+	// '/rides/by-id/{id}' for users who can use the thread.
+	Thread *Page[GroupMessageData] `json:"thread,omitempty"`
 }
 
+// Number of messages of the thread included in a ride event.
+const rideThreadPreviewSize = 20
+
 type rideSchedule struct {
-	Unit     *string   `json:"unit" validate:"required"`
-	Interval *int64    `json:"interval" validate:"required"`
+	Unit     *string   `json:"unit" validate:"required_without=RRule"`
+	Interval *int64    `json:"interval" validate:"required_without=RRule"`
 	Weekdays *[]string `json:"weekdays"`
+	// IANA time zone name, defaults to UTC. The time of day of the ride is
+	// kept in this time zone, e.g. across DST changes.
+	Timezone *string `json:"timezone"`
+	// RFC 5545 recurrence rule, e.g. 'FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH',
+	// optionally followed by EXDATE lines. Takes precedence over `unit`,
+	// `interval` and `weekdays`, which are derived from the rule in responses.
+	RRule *string `json:"rrule"`
+}
+
+// A validated ride schedule, ready to be stored.
+type scheduleSpec struct {
+	unit     string
+	interval int64
+	weekdays []string
+	timezone string
+	rrule    sql.NullString
 }
 
 type rideParticipant struct {
@@ -66,6 +100,9 @@ type createRideParams struct {
 	Driver         *string       `json:"driver" validate:"required"`
 	TransportLimit *int64        `json:"transportLimit" validate:"required"`
 	Schedule       *rideSchedule `json:"schedule"`
+	// Makes the ride visible only to members of this group. The creator has
+	// to be a member.
+	GroupId *string `json:"groupId"`
 }
 
 type createRideResponse struct {
@@ -74,15 +111,35 @@ type createRideResponse struct {
 }
 
 type updateRideParams struct {
-	RideEventId *string       `json:"rideEventId" validate:"required"`
-	Schedule    *rideSchedule `json:"schedule"`
-	Status      *string       `json:"status"`
+	RideEventId    *string       `json:"rideEventId" validate:"required"`
+	Schedule       *rideSchedule `json:"schedule"`
+	Status         *string       `json:"status"`
+	LocationFrom   *string       `json:"locationFrom" validate:"omitempty,min=1"`
+	LocationTo     *string       `json:"locationTo" validate:"omitempty,min=1"`
+	TackingPlaceAt *time.Time    `json:"tackingPlaceAt"`
+	Driver         *string       `json:"driver"`
+	TransportLimit *int64        `json:"transportLimit" validate:"omitempty,min=1"`
+	// Either 'single' (default) to change only this ride event or 'following'
+	// to change it and all later ride events of the ride. Only applies to the
+	// ride details, not to the status.
+	Scope *string `json:"scope" validate:"omitempty,oneof=single following"`
 }
 
 type joinRideParams struct {
 	RideEventId *string `json:"rideEventId" validate:"required"`
 }
 
+type joinRideResponse struct {
+	// Either 'joined' or 'waitlisted'.
+	Status string `json:"status"`
+	// Position on the waitlist, starting at 1. Only set if waitlisted.
+	WaitlistPosition *int `json:"waitlistPosition"`
+}
+
+type leaveRideParams struct {
+	RideEventId *string `json:"rideEventId" validate:"required"`
+}
+
 func updateRide(w http.ResponseWriter, r *http.Request) {
 	user := getMiddlewareData[sqlc.User](r, "user")
 
@@ -110,13 +167,9 @@ func updateRide(w http.ResponseWriter, r *http.Request) {
 
 	tx, err := state.getDBTx(r.Context())
 	assert.Nil(err)
+	defer tx.Rollback()
 
 	queriesTx := state.queries.WithTx(tx)
-	err = markPastRideEventsAsDoneAndCreateScheduled(queriesTx, r.Context())
-	if err != nil {
-		httpWriteErr(w, http.StatusInternalServerError, err.Error())
-		return
-	}
 
 	event, err := queriesTx.RidesGetEvent(r.Context(), *updateParams.RideEventId)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -126,47 +179,54 @@ func updateRide(w http.ResponseWriter, r *http.Request) {
 
 	assert.Nil(err)
 
-	if event.CreatedBy != user.ID {
-		httpWriteErr(w, http.StatusBadRequest, "You are not the owner of this ride event.")
+	// The driver may change the number of seats, everything else is up to the owner.
+	onlyTransportLimit := updateParams.Schedule == nil && updateParams.Status == nil && updateParams.LocationFrom == nil &&
+		updateParams.LocationTo == nil && updateParams.TackingPlaceAt == nil && updateParams.Driver == nil
+	if event.CreatedBy != user.ID && !user.IsAdmin && !onlyTransportLimit {
+		httpWriteErr(w, http.StatusForbidden, "Only the owner of the ride event can change more than 'transportLimit'.")
 		return
 	}
 
 	// update schedule
 	if updateParams.Schedule != nil {
-		err := queriesTx.RidesDropScheduleWeekdays(r.Context(), event.RideScheduleID.String)
-		assert.Nil(err)
-		queriesTx.RidesDropSchedule(r.Context(), event.RideScheduleID.String)
+		anchor, err := time.Parse(time.RFC3339, event.TackingPlaceAt)
 		assert.Nil(err)
+		if updateParams.TackingPlaceAt != nil {
+			anchor = *updateParams.TackingPlaceAt
+		}
 
-		argsCreateSchedule := sqlc.RidesCreateScheduleParams{
-			RideID:           event.RideID,
-			ScheduleInterval: *updateParams.Schedule.Interval,
-			Unit:             *updateParams.Schedule.Unit,
+		spec, err := updateParams.Schedule.resolve(anchor)
+		if err != nil {
+			httpWriteErr(w, http.StatusBadRequest, "Invalid schedule.", err.Error())
+			return
 		}
 
-		scheduleId, err := queriesTx.RidesCreateSchedule(r.Context(), argsCreateSchedule)
+		err = queriesTx.RidesDropScheduleWeekdays(r.Context(), event.RideScheduleID.String)
+		assert.Nil(err)
+		err = queriesTx.RidesDropSchedule(r.Context(), event.RideScheduleID.String)
 		assert.Nil(err)
 
-		if *updateParams.Schedule.Unit == "weekdays" {
-			if updateParams.Schedule.Weekdays == nil || len(*updateParams.Schedule.Weekdays) == 0 {
-				httpWriteErr(w, http.StatusBadRequest, "Invalid schedule. Field 'unit' is 'weekdays' but the field 'weekdays' is empty or undefined.")
-				return
-			}
+		err = createSchedule(r.Context(), queriesTx, event.RideID, spec)
+		assert.Nil(err)
+	}
 
-			for _, day := range *updateParams.Schedule.Weekdays {
-				_, err = weekdayToInt(day)
-				if err != nil {
-					httpWriteErr(w, http.StatusBadRequest, "Invalid schedule weekday. Only lowercase standard english weekday names are allowed.")
-					return
-				}
+	changed := []string{event.RideEventID}
+	var edited []string
+	var promoted []waitlistPromotion
+	var replaced []replacedDriver
+	if updateParams.editsDetails() {
+		edited, promoted, replaced, err = editRideEvents(r.Context(), queriesTx, user, event, updateParams)
+		var editErr rideEditError
+		if errors.As(err, &editErr) {
+			httpWriteErr(w, editErr.status, editErr.title)
+			return
+		}
 
-				argsCreateScheduleWeekday := sqlc.RidesCreateScheduleWeekdayParams{
-					RideScheduleID: scheduleId,
-					Weekday:        day,
-				}
+		assert.Nil(err)
 
-				err := queriesTx.RidesCreateScheduleWeekday(r.Context(), argsCreateScheduleWeekday)
-				assert.Nil(err)
+		for _, id := range edited {
+			if !slices.Contains(changed, id) {
+				changed = append(changed, id)
 			}
 		}
 	}
@@ -182,6 +242,57 @@ func updateRide(w http.ResponseWriter, r *http.Request) {
 
 	err = tx.Commit()
 	assert.Nil(err)
+
+	canceled := updateParams.Status != nil && *updateParams.Status == RIDE_STATUS_CANCELED
+	for _, id := range changed {
+		updateType := RIDE_EVENT_UPDATED
+		if id == event.RideEventID && canceled {
+			updateType = RIDE_EVENT_CANCELED
+		}
+		publishRideUpdate(r.Context(), state.queries, updateType, user.ID, id)
+	}
+
+	if canceled && event.Status != RIDE_STATUS_CANCELED {
+		notifyRideMembers(r.Context(), notification{Type: NOTIFICATION_RIDE_CANCELED, Actor: user, RideEventId: event.RideEventID})
+	}
+
+	for _, id := range edited {
+		notifyRideMembers(r.Context(), notification{Type: NOTIFICATION_RIDE_CHANGED, Actor: user, RideEventId: id})
+	}
+
+	// Replaced drivers keep access to the thread only if they still take part.
+	for _, d := range replaced {
+		driver, err := state.queries.UsersGetById(r.Context(), d.UserId)
+		assert.Nil(err)
+		canUse, err := canUseChat(r.Context(), driver, rideThread(d.RideEventId))
+		assert.Nil(err)
+		if !canUse {
+			state.chat.disconnectMember(rideThread(d.RideEventId), d.UserId)
+		}
+	}
+
+	for _, p := range promoted {
+		notify(r.Context(), notification{Type: NOTIFICATION_RIDE_SEAT_ASSIGNED, Actor: user, RideEventId: p.RideEventId}, p.UserId)
+	}
+}
+
+// Notifies everyone who takes part in or waits for the ride event of the
+// notification.
+func notifyRideMembers(ctx context.Context, n notification) {
+	participants, err := state.queries.RidesGetParticipants(ctx, n.RideEventId)
+	assert.Nil(err)
+	waitlist, err := state.queries.RidesGetWaitlist(ctx, n.RideEventId)
+	assert.Nil(err)
+
+	userIds := make([]string, 0, len(participants)+len(waitlist))
+	for _, p := range participants {
+		userIds = append(userIds, p.ID)
+	}
+	for _, p := range waitlist {
+		userIds = append(userIds, p.ID)
+	}
+
+	notify(ctx, n, userIds...)
 }
 
 func joinRide(w http.ResponseWriter, r *http.Request) {
@@ -211,16 +322,12 @@ func joinRide(w http.ResponseWriter, r *http.Request) {
 
 	tx, err := state.getDBTx(r.Context())
 	assert.Nil(err)
+	defer tx.Rollback()
 
 	queriesTx := state.queries.WithTx(tx)
-	err = markPastRideEventsAsDoneAndCreateScheduled(queriesTx, r.Context())
-	if err != nil {
-		httpWriteErr(w, http.StatusInternalServerError, err.Error())
-		return
-	}
 
 	event, err := queriesTx.RidesGetEvent(r.Context(), *joinParams.RideEventId)
-	if errors.Is(err, sql.ErrNoRows) {
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !canSeeRide(r.Context(), queriesTx, event.GroupID, user.ID)) {
 		httpWriteErr(w, http.StatusNotFound, "No ride event exists for the event with 'id'.")
 		return
 	}
@@ -229,11 +336,6 @@ func joinRide(w http.ResponseWriter, r *http.Request) {
 	participants, err := queriesTx.RidesGetParticipants(r.Context(), event.RideEventID)
 	assert.Nil(err)
 
-	if int64(len(participants)) >= event.TransportLimit {
-		httpWriteErr(w, http.StatusConflict, "Ride is already full.")
-		return
-	}
-
 	alreadyJoined := slices.ContainsFunc(participants, func(p sqlc.RidesGetParticipantsRow) bool {
 		return p.ID == user.ID
 	})
@@ -243,15 +345,180 @@ func joinRide(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	joinArgs := sqlc.RidesJoinEventParams{
+	waitlist, err := queriesTx.RidesGetWaitlist(r.Context(), event.RideEventID)
+	assert.Nil(err)
+
+	alreadyWaiting := slices.ContainsFunc(waitlist, func(p sqlc.RidesGetWaitlistRow) bool {
+		return p.ID == user.ID
+	})
+
+	if alreadyWaiting {
+		httpWriteErr(w, http.StatusConflict, "Already on the waitlist of this ride.")
+		return
+	}
+
+	response := joinRideResponse{Status: JOIN_STATUS_JOINED}
+	status := http.StatusOK
+
+	if int64(len(participants)) >= event.TransportLimit {
+		waitlistArgs := sqlc.RidesWaitlistAddParams{
+			RideEventID: event.RideEventID,
+			UserID:      user.ID,
+		}
+		err = queriesTx.RidesWaitlistAdd(r.Context(), waitlistArgs)
+		assert.Nil(err)
+
+		position := len(waitlist) + 1
+		response = joinRideResponse{Status: JOIN_STATUS_WAITLISTED, WaitlistPosition: &position}
+		status = http.StatusAccepted
+	} else {
+		joinArgs := sqlc.RidesJoinEventParams{
+			RideEventID: event.RideEventID,
+			UserID:      user.ID,
+		}
+		err = queriesTx.RidesJoinEvent(r.Context(), joinArgs)
+		assert.Nil(err)
+	}
+
+	err = tx.Commit()
+	assert.Nil(err)
+
+	publishRideUpdate(r.Context(), state.queries, RIDE_EVENT_JOINED, user.ID, event.RideEventID)
+	if response.Status == JOIN_STATUS_JOINED {
+		notify(r.Context(), notification{Type: NOTIFICATION_RIDE_JOINED, Actor: user, RideEventId: event.RideEventID}, event.CreatedBy, event.Driver)
+	}
+
+	resp, err := json.Marshal(response)
+	assert.Nil(err, "Failed to serialize join ride response.")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
+
+// Leaves a ride or its waitlist. A seat that becomes free goes to the first
+// user on the waitlist.
+func leaveRide(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Println("Error: Invalid request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+
+	var leaveParams leaveRideParams
+	err = json.Unmarshal(data, &leaveParams)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Invalid JSON in request body.", err.Error())
+		return
+	}
+
+	err = utils.Validate.Struct(leaveParams)
+	if err != nil {
+		log.Println("Error: Invalid JSON in request body.", "error:", err)
+		httpWriteErr(w, http.StatusBadRequest, "Missing/Invalid fields in request body.", err.Error())
+		return
+	}
+
+	tx, err := state.getDBTx(r.Context())
+	assert.Nil(err)
+	defer tx.Rollback()
+
+	queriesTx := state.queries.WithTx(tx)
+
+	event, err := queriesTx.RidesGetEvent(r.Context(), *leaveParams.RideEventId)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpWriteErr(w, http.StatusNotFound, "No ride event exists for the event with 'id'.")
+		return
+	}
+	assert.Nil(err)
+
+	leaveArgs := sqlc.RidesLeaveEventParams{
 		RideEventID: event.RideEventID,
 		UserID:      user.ID,
 	}
-	err = queriesTx.RidesJoinEvent(r.Context(), joinArgs)
+	left, err := queriesTx.RidesLeaveEvent(r.Context(), leaveArgs)
 	assert.Nil(err)
 
+	promoted := make([]string, 0)
+	if left > 0 {
+		promoted, err = promoteWaitlist(r.Context(), queriesTx, event.RideEventID, event.TransportLimit)
+		assert.Nil(err)
+	} else {
+		waitlistArgs := sqlc.RidesWaitlistRemoveParams{
+			RideEventID: event.RideEventID,
+			UserID:      user.ID,
+		}
+		removed, err := queriesTx.RidesWaitlistRemove(r.Context(), waitlistArgs)
+		assert.Nil(err)
+
+		if removed == 0 {
+			httpWriteErr(w, http.StatusConflict, "Not a member of this ride.")
+			return
+		}
+	}
+
 	err = tx.Commit()
 	assert.Nil(err)
+
+	publishRideUpdate(r.Context(), state.queries, RIDE_EVENT_LEFT, user.ID, event.RideEventID)
+	notify(r.Context(), notification{Type: NOTIFICATION_RIDE_SEAT_ASSIGNED, Actor: user, RideEventId: event.RideEventID}, promoted...)
+
+	// The creator and the driver keep access to the thread.
+	canUse, err := canUseChat(r.Context(), user, rideThread(event.RideEventID))
+	assert.Nil(err)
+	if !canUse {
+		state.chat.disconnectMember(rideThread(event.RideEventID), user.ID)
+	}
+
+	w.WriteHeader(200)
+}
+
+// Moves users from the waitlist into the ride event until all seats are taken.
+// Has to run in the same transaction as the change that freed the seats, so
+// no seat can be given away twice. Returns the ids of the users who got a
+// seat.
+func promoteWaitlist(ctx context.Context, queriesTx *sqlc.Queries, rideEventId string, transportLimit int64) ([]string, error) {
+	participantsCount, err := queriesTx.RidesCountEventParticipants(ctx, rideEventId)
+	if err != nil {
+		return nil, err
+	}
+
+	waitlist, err := queriesTx.RidesGetWaitlist(ctx, rideEventId)
+	if err != nil {
+		return nil, err
+	}
+
+	promoted := make([]string, 0)
+	for _, waiting := range waitlist {
+		if participantsCount >= transportLimit {
+			break
+		}
+
+		waitlistArgs := sqlc.RidesWaitlistRemoveParams{
+			RideEventID: rideEventId,
+			UserID:      waiting.ID,
+		}
+		_, err = queriesTx.RidesWaitlistRemove(ctx, waitlistArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		joinArgs := sqlc.RidesJoinEventParams{
+			RideEventID: rideEventId,
+			UserID:      waiting.ID,
+		}
+		err = queriesTx.RidesJoinEvent(ctx, joinArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		participantsCount++
+		promoted = append(promoted, waiting.ID)
+	}
+
+	return promoted, nil
 }
 
 func createRide(w http.ResponseWriter, r *http.Request) {
@@ -279,6 +546,20 @@ func createRide(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if createParams.GroupId != nil {
+		_, err := state.queries.GroupsGetById(r.Context(), *createParams.GroupId)
+		if errors.Is(err, sql.ErrNoRows) {
+			httpWriteErr(w, http.StatusNotFound, "No group exists with 'groupId'.")
+			return
+		}
+		assert.Nil(err)
+
+		if !canSeeRide(r.Context(), state.queries, utils.SqlNullStr(createParams.GroupId), user.ID) {
+			httpWriteErr(w, http.StatusForbidden, "Only members of the group can create rides for it.")
+			return
+		}
+	}
+
 	tx, err := state.getDBTx(r.Context())
 	assert.Nil(err)
 
@@ -292,6 +573,7 @@ func createRide(w http.ResponseWriter, r *http.Request) {
 		Driver:         *createParams.Driver,
 		CreatedBy:      user.ID,
 		TransportLimit: *createParams.TransportLimit,
+		GroupID:        utils.SqlNullStr(createParams.GroupId),
 	}
 
 	rideId, err := queriesTx.RidesCreate(r.Context(), argsCreateBase)
@@ -302,37 +584,14 @@ func createRide(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if createParams.Schedule != nil {
-		argsCreateSchedule := sqlc.RidesCreateScheduleParams{
-			RideID:           rideId,
-			ScheduleInterval: *createParams.Schedule.Interval,
-			Unit:             *createParams.Schedule.Unit,
+		spec, err := createParams.Schedule.resolve(*createParams.TackingPlaceAt)
+		if err != nil {
+			httpWriteErr(w, http.StatusBadRequest, "Invalid schedule.", err.Error())
+			return
 		}
 
-		scheduleId, err := queriesTx.RidesCreateSchedule(r.Context(), argsCreateSchedule)
+		err = createSchedule(r.Context(), queriesTx, rideId, spec)
 		assert.Nil(err)
-
-		if *createParams.Schedule.Unit == "weekdays" {
-			if createParams.Schedule.Weekdays == nil || len(*createParams.Schedule.Weekdays) == 0 {
-				httpWriteErr(w, http.StatusBadRequest, "Invalid schedule. Field 'unit' is 'weekdays' but the field 'weekdays' is empty or undefined.")
-				return
-			}
-
-			for _, day := range *createParams.Schedule.Weekdays {
-				_, err = weekdayToInt(day)
-				if err != nil {
-					httpWriteErr(w, http.StatusBadRequest, "Invalid schedule weekday. Only lowercase standard english weekday names are allowed.")
-					return
-				}
-
-				argsCreateScheduleWeekday := sqlc.RidesCreateScheduleWeekdayParams{
-					RideScheduleID: scheduleId,
-					Weekday:        day,
-				}
-
-				err := queriesTx.RidesCreateScheduleWeekday(r.Context(), argsCreateScheduleWeekday)
-				assert.Nil(err)
-			}
-		}
 	}
 
 	rideLatest, err := queriesTx.RidesGetLatest(r.Context(), rideId)
@@ -349,6 +608,8 @@ func createRide(w http.ResponseWriter, r *http.Request) {
 	err = tx.Commit()
 	assert.Nil(err)
 
+	publishRideUpdate(r.Context(), state.queries, RIDE_EVENT_CREATED, user.ID, rideLatest.RideEventID)
+
 	response := createRideResponse{
 		RideId:      rideLatest.RideID,
 		RideEventId: rideLatest.RideEventID,
@@ -360,101 +621,138 @@ func createRide(w http.ResponseWriter, r *http.Request) {
 	w.Write(resp)
 }
 
-func getManyRides(w http.ResponseWriter, r *http.Request) {
-	var offset int64 = 0
-	offsetStr := r.FormValue("offset")
-	if parsed, err := strconv.ParseInt(offsetStr, 10, 64); err == nil && parsed > 0 {
-		offset = parsed
-	}
+type rideCursor struct {
+	StatusOrdering *int64  `json:"statusOrdering" validate:"required"`
+	TackingPlaceAt *string `json:"tackingPlaceAt" validate:"required"`
+	RideEventId    *string `json:"rideEventId" validate:"required"`
+}
 
-	tx, err := state.getDBTx(r.Context())
-	assert.Nil(err)
+func getManyRides(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
 
-	queriesTx := state.queries.WithTx(tx)
-	err = markPastRideEventsAsDoneAndCreateScheduled(queriesTx, r.Context())
+	pageParams, err := parsePageParams[rideCursor](r)
 	if err != nil {
-		httpWriteErr(w, http.StatusInternalServerError, err.Error())
+		httpWriteErr(w, http.StatusBadRequest, "Invalid pagination parameters.", err.Error())
 		return
 	}
 
-	err = tx.Commit()
-	assert.Nil(err)
-
-	rows, err := state.queries.RidesGetMany(r.Context(), offset)
+	filters, err := rideSearchFiltersFromRequest(r)
 	if err != nil {
-		log.Println("Error: Failed to get rides.", "error:", err)
-		httpWriteErr(w, http.StatusInternalServerError, "Failed to get rides.")
+		httpWriteErr(w, http.StatusBadRequest, "Invalid search filters.", err.Error())
 		return
 	}
 
-	rides := make([]*RideEventData, len(rows))
-	for idx, row := range rows {
-		var weekdays *[]string = nil
-		if row.RideScheduleID.Valid && row.RideScheduleUnit.String == "weekdays" {
-			days, err := state.queries.RidesGetScheduleWeekdays(r.Context(), row.RideScheduleID.String)
-			assert.Nil(err)
-			weekdays = &days
+	var cursorOrdering sql.NullInt64
+	var cursorTackingPlaceAt sql.NullString
+	var cursorId sql.NullString
+	if pageParams.Cursor != nil {
+		cursorOrdering = sql.NullInt64{Int64: *pageParams.Cursor.StatusOrdering, Valid: true}
+		cursorTackingPlaceAt = utils.SqlNullStr(pageParams.Cursor.TackingPlaceAt)
+		cursorId = utils.SqlNullStr(pageParams.Cursor.RideEventId)
+	}
+
+	var rows []rideRow
+	var total int64
+	if filters.isEmpty() {
+		argsGetMany := sqlc.RidesGetManyParams{
+			UserID:               user.ID,
+			CursorOrdering:       cursorOrdering,
+			CursorTackingPlaceAt: cursorTackingPlaceAt,
+			CursorID:             cursorId,
+			PageSize:             pageParams.Size + 1,
 		}
 
-		rideParticipants, err := state.queries.RidesGetParticipants(r.Context(), row.RideEventID)
-		assert.Nil(err)
+		many, err := state.queries.RidesGetMany(r.Context(), argsGetMany)
+		if err != nil {
+			log.Println("Error: Failed to get rides.", "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to get rides.")
+			return
+		}
+
+		for _, row := range many {
+			rows = append(rows, rideRow(row))
+		}
 
-		event, err := buildRideEventData(rideRow(row), weekdays, rideParticipants)
+		total, err = state.queries.RidesCount(r.Context(), user.ID)
 		assert.Nil(err)
+	} else {
+		argsSearch := filters.toQueryParams(user)
+		argsSearch.CursorOrdering = cursorOrdering
+		argsSearch.CursorTackingPlaceAt = cursorTackingPlaceAt
+		argsSearch.CursorID = cursorId
+		argsSearch.PageSize = pageParams.Size + 1
+
+		found, err := state.queries.RidesSearch(r.Context(), argsSearch)
+		if err != nil {
+			log.Println("Error: Failed to search rides.", "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to search rides.")
+			return
+		}
 
-		rides[idx] = event
+		for _, row := range found {
+			rows = append(rows, rideRow(row))
+		}
+
+		total, err = state.queries.RidesSearchCount(r.Context(), filters.toCountParams(user))
+		assert.Nil(err)
 	}
 
-	var resp []byte
-	if len(rides) == 0 {
-		resp = []byte("[]")
-	} else {
-		resp, err = json.Marshal(rides)
+	page := buildPage(rows, pageParams.Size, total, func(last rideRow) rideCursor {
+		return rideCursor{
+			StatusOrdering: &last.StatusOrdering,
+			TackingPlaceAt: &last.TackingPlaceAt,
+			RideEventId:    &last.RideEventID,
+		}
+	})
+
+	rides := make([]*RideEventData, len(page.Items))
+	for idx, row := range page.Items {
+		event, err := loadRideEventData(r.Context(), state.queries, row)
+		assert.Nil(err)
+
+		rides[idx] = event
 	}
 
+	resp, err := json.Marshal(Page[*RideEventData]{
+		Items:      rides,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	})
 	assert.Nil(err, "Failed to serialize rides.")
 	w.WriteHeader(200)
 	w.Write(resp)
 }
 
 func getEventById(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
 	id := r.PathValue("id")
 	if id == "" {
 		httpWriteErr(w, http.StatusBadRequest, "Must provide 'id' path parameter.")
 		return
 	}
 
-	tx, err := state.getDBTx(r.Context())
-	assert.Nil(err)
-
-	queriesTx := state.queries.WithTx(tx)
-	err = markPastRideEventsAsDoneAndCreateScheduled(queriesTx, r.Context())
-	if err != nil {
-		httpWriteErr(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	err = tx.Commit()
-	assert.Nil(err)
-
 	event, err := state.queries.RidesGetEvent(r.Context(), id)
-	if errors.Is(err, sql.ErrNoRows) {
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !canSeeRide(r.Context(), state.queries, event.GroupID, user.ID)) {
 		httpWriteErr(w, http.StatusNotFound, "No ride event exists for the event with 'id'.")
 		return
 	}
+	assert.Nil(err)
 
-	var weekdays *[]string = nil
-	if event.RideScheduleUnit.String == "weekdays" {
-		days, err := state.queries.RidesGetScheduleWeekdays(r.Context(), event.RideScheduleID.String)
-		assert.Nil(err)
-		weekdays = &days
-	}
-
-	rideParticipants, err := state.queries.RidesGetParticipants(r.Context(), event.RideEventID)
+	ride, err := loadRideEventData(r.Context(), state.queries, eventToRideRow(event))
 	assert.Nil(err)
 
-	ride, err := buildRideEventData(eventToRideRow(event), weekdays, rideParticipants)
+	canUse, err := canUseChat(r.Context(), user, rideThread(event.RideEventID))
 	assert.Nil(err)
+	if canUse {
+		thread, err := loadChatPage(r.Context(), rideThread(event.RideEventID), nil, rideThreadPreviewSize)
+		if err != nil {
+			log.Println("Error: Failed to load thread of ride event.", "ride event id:", event.RideEventID, "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to load thread of ride event.")
+			return
+		}
+		ride.Thread = &thread
+	}
 
 	var resp []byte
 	resp, err = json.Marshal(ride)
@@ -464,49 +762,73 @@ func getEventById(w http.ResponseWriter, r *http.Request) {
 }
 
 func getUpcomingById(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
 	id := r.PathValue("id")
 	if id == "" {
 		httpWriteErr(w, http.StatusBadRequest, "Must provide 'id' path parameter.")
 		return
 	}
 
-	tx, err := state.getDBTx(r.Context())
-	assert.Nil(err)
-
-	queriesTx := state.queries.WithTx(tx)
-	err = markPastRideEventsAsDoneAndCreateScheduled(queriesTx, r.Context())
-	if err != nil {
-		httpWriteErr(w, http.StatusInternalServerError, err.Error())
+	rideLatest, err := state.queries.RidesGetLatest(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !canSeeRide(r.Context(), state.queries, rideLatest.GroupID, user.ID)) {
+		httpWriteErr(w, http.StatusNotFound, "No next ride exists for the ride with 'id'.")
 		return
 	}
+	assert.Nil(err)
 
-	err = tx.Commit()
+	ride, err := loadRideEventData(r.Context(), state.queries, latestToRideRow(rideLatest))
 	assert.Nil(err)
 
-	rideLatest, err := state.queries.RidesGetLatest(r.Context(), id)
+	var resp []byte
+	resp, err = json.Marshal(ride)
+	assert.Nil(err, "Failed to serialize ride.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+// Rides of a group can only be seen by its members, pending and banned
+// members can't see them. Rides without a group can be seen by everyone.
+func canSeeRide(ctx context.Context, queries *sqlc.Queries, groupId sql.NullString, userId string) bool {
+	if !groupId.Valid {
+		return true
+	}
+
+	args := sqlc.GroupsMembersGetStatusParams{
+		GroupID: groupId.String,
+		UserID:  userId,
+	}
+	member, err := queries.GroupsMembersGetStatus(ctx, args)
 	if errors.Is(err, sql.ErrNoRows) {
-		httpWriteErr(w, http.StatusNotFound, "No next ride exists for the ride with 'id'.")
-		return
+		return false
 	}
+	assert.Nil(err)
+
+	return member.JoinStatus == GROUP_JOIN_STATUS_MEMBER
+}
 
+// Loads the schedule, participants and waitlist of a ride event.
+func loadRideEventData(ctx context.Context, queries *sqlc.Queries, row rideRow) (*RideEventData, error) {
 	var weekdays *[]string = nil
-	if rideLatest.RideScheduleUnit.String == "weekdays" {
-		days, err := state.queries.RidesGetScheduleWeekdays(r.Context(), rideLatest.RideScheduleID.String)
-		assert.Nil(err)
+	if row.RideScheduleID.Valid && row.RideScheduleUnit.String == "weekdays" {
+		days, err := queries.RidesGetScheduleWeekdays(ctx, row.RideScheduleID.String)
+		if err != nil {
+			return nil, err
+		}
 		weekdays = &days
 	}
 
-	rideParticipants, err := state.queries.RidesGetParticipants(r.Context(), rideLatest.RideEventID)
-	assert.Nil(err)
+	participants, err := queries.RidesGetParticipants(ctx, row.RideEventID)
+	if err != nil {
+		return nil, err
+	}
 
-	ride, err := buildRideEventData(latestToRideRow(rideLatest), weekdays, rideParticipants)
-	assert.Nil(err)
+	waitlist, err := queries.RidesGetWaitlist(ctx, row.RideEventID)
+	if err != nil {
+		return nil, err
+	}
 
-	var resp []byte
-	resp, err = json.Marshal(ride)
-	assert.Nil(err, "Failed to serialize ride.")
-	w.WriteHeader(200)
-	w.Write(resp)
+	return buildRideEventData(row, weekdays, participants, waitlist)
 }
 
 type rideRow struct {
@@ -524,6 +846,10 @@ type rideRow struct {
 	RideScheduleID       sql.NullString
 	RideScheduleUnit     sql.NullString
 	RideScheduleInterval sql.NullInt64
+	RideScheduleTimezone sql.NullString
+	RideScheduleRrule    sql.NullString
+	GroupID              sql.NullString
+	StatusOrdering       int64
 }
 
 func eventToRideRow(row sqlc.RidesGetEventRow) rideRow {
@@ -542,6 +868,9 @@ func eventToRideRow(row sqlc.RidesGetEventRow) rideRow {
 		RideScheduleID:       row.RideScheduleID,
 		RideScheduleUnit:     row.RideScheduleUnit,
 		RideScheduleInterval: row.RideScheduleInterval,
+		RideScheduleTimezone: row.RideScheduleTimezone,
+		RideScheduleRrule:    row.RideScheduleRrule,
+		GroupID:              row.GroupID,
 	}
 }
 
@@ -561,10 +890,13 @@ func latestToRideRow(row sqlc.RidesGetLatestRow) rideRow {
 		RideScheduleID:       row.RideScheduleID,
 		RideScheduleUnit:     row.RideScheduleUnit,
 		RideScheduleInterval: row.RideScheduleInterval,
+		RideScheduleTimezone: row.RideScheduleTimezone,
+		RideScheduleRrule:    row.RideScheduleRrule,
+		GroupID:              row.GroupID,
 	}
 }
 
-func buildRideEventData(ride rideRow, weekdays *[]string, participants []sqlc.RidesGetParticipantsRow) (*RideEventData, error) {
+func buildRideEventData(ride rideRow, weekdays *[]string, participants []sqlc.RidesGetParticipantsRow, waitlist []sqlc.RidesGetWaitlistRow) (*RideEventData, error) {
 	tackingPlaceAt, err := time.Parse(time.RFC3339, ride.TackingPlaceAt)
 	if err != nil {
 		return nil, err
@@ -576,7 +908,17 @@ func buildRideEventData(ride rideRow, weekdays *[]string, participants []sqlc.Ri
 			Unit:     &ride.RideScheduleUnit.String,
 			Interval: &ride.RideScheduleInterval.Int64,
 			Weekdays: weekdays,
+			Timezone: &ride.RideScheduleTimezone.String,
 		}
+
+		if ride.RideScheduleRrule.Valid {
+			schedule.RRule = &ride.RideScheduleRrule.String
+		}
+	}
+
+	var groupId *string = nil
+	if ride.GroupID.Valid {
+		groupId = &ride.GroupID.String
 	}
 
 	participantsMapped := make([]rideParticipant, len(participants))
@@ -587,6 +929,14 @@ func buildRideEventData(ride rideRow, weekdays *[]string, participants []sqlc.Ri
 		}
 	}
 
+	waitlistMapped := make([]rideParticipant, len(waitlist))
+	for idx, waiting := range waitlist {
+		waitlistMapped[idx] = rideParticipant{
+			UserId: waiting.ID,
+			Email:  waiting.Email,
+		}
+	}
+
 	rideEvent := RideEventData{
 		RideId:         ride.RideID,
 		RideEventId:    ride.RideEventID,
@@ -599,72 +949,122 @@ func buildRideEventData(ride rideRow, weekdays *[]string, participants []sqlc.Ri
 		DriverId:       ride.Driver,
 		DriverEmail:    ride.DriverEmail,
 		TransportLimit: ride.TransportLimit,
+		GroupId:        groupId,
 		Schedule:       schedule,
 		Participants:   participantsMapped,
+		Waitlist:       waitlistMapped,
 	}
 
 	return &rideEvent, nil
 }
 
-func nextScheduledTime(current time.Time, scheduleUnit string, scheduleInterval int64, weekdays *[]string) (time.Time, error) {
-	switch scheduleUnit {
-	case "days":
-		{
-			return current.AddDate(0, 0, int(scheduleInterval)), nil
-		}
-	case "weeks":
-		{
-			return current.AddDate(0, 0, int(scheduleInterval)*7), nil
+// Validates the schedule of a series that takes place at `anchor`. Schedules
+// with a recurrence rule store a summary of the rule as their unit, interval
+// and weekdays.
+func (s *rideSchedule) resolve(anchor time.Time) (scheduleSpec, error) {
+	timezone, err := s.timezone()
+	if err != nil {
+		return scheduleSpec{}, err
+	}
+
+	if s.RRule != nil {
+		raw := strings.TrimSpace(strings.ReplaceAll(*s.RRule, "\r\n", "\n"))
+		location, err := time.LoadLocation(timezone)
+		assert.Nil(err)
+
+		rule, err := parseRRule(raw, location)
+		if err != nil {
+			return scheduleSpec{}, err
 		}
-	case "months":
-		{
-			return current.AddDate(0, int(scheduleInterval), 0), nil
+
+		if !(recurrence{anchor: anchor.In(location), rule: rule}).recurs() {
+			return scheduleSpec{}, errors.New("Field 'rrule' doesn't match any date after the ride.")
 		}
-	case "years":
-		{
-			return current.AddDate(int(scheduleInterval), 0, 0), nil
+
+		unit, interval, weekdays := rule.basicSchedule()
+		spec := scheduleSpec{
+			unit:     unit,
+			interval: interval,
+			weekdays: weekdays,
+			timezone: timezone,
+			rrule:    sql.NullString{String: raw, Valid: true},
 		}
-	case "weekdays":
-		{
-			if weekdays == nil || len(*weekdays) == 0 {
-				return current, errors.New(fmt.Sprint("Invalid weekdays schedule, received weekdays ", weekdays))
-			}
+		return spec, nil
+	}
+
+	spec := scheduleSpec{unit: *s.Unit, interval: *s.Interval, timezone: timezone}
+	if spec.interval < 1 {
+		return scheduleSpec{}, errors.New("Field 'interval' must be at least 1.")
+	}
 
-			weekdayInts := make([]int, len(*weekdays))
-			for idx, day := range *weekdays {
-				dayInt, err := weekdayToInt(day)
-				if err != nil {
-					return current, err
-				}
+	if spec.unit == SCHEDULE_UNIT_WEEKDAYS {
+		if s.Weekdays == nil || len(*s.Weekdays) == 0 {
+			return scheduleSpec{}, errors.New("Field 'unit' is 'weekdays' but the field 'weekdays' is empty or undefined.")
+		}
 
-				weekdayInts[idx] = dayInt
+		for _, day := range *s.Weekdays {
+			_, err = weekdayToInt(day)
+			if err != nil {
+				return scheduleSpec{}, errors.New("Invalid weekday. Only lowercase standard english weekday names are allowed.")
 			}
+		}
+
+		spec.weekdays = *s.Weekdays
+	}
 
-			minDayDist := 8
-			nowDayInt := int(current.Weekday())
-			for _, dayInt := range weekdayInts {
-				var dist int
+	_, err = basicScheduleToRRule(time.Now(), spec.unit, spec.interval, spec.weekdays)
+	if err != nil {
+		return scheduleSpec{}, err
+	}
 
-				if nowDayInt < dayInt {
-					dist = dayInt - nowDayInt
-				} else {
-					dist = 7 - (nowDayInt - dayInt)
-				}
+	return spec, nil
+}
 
-				if dist < minDayDist {
-					minDayDist = dist
-				}
-			}
+func createSchedule(ctx context.Context, queriesTx *sqlc.Queries, rideId string, spec scheduleSpec) error {
+	argsCreateSchedule := sqlc.RidesCreateScheduleParams{
+		RideID:           rideId,
+		ScheduleInterval: spec.interval,
+		Unit:             spec.unit,
+		Timezone:         spec.timezone,
+		Rrule:            spec.rrule,
+	}
 
-			assert.True(minDayDist <= 7, "Invalid next weekday. Distance to now day", minDayDist)
+	scheduleId, err := queriesTx.RidesCreateSchedule(ctx, argsCreateSchedule)
+	if err != nil {
+		return err
+	}
 
-			return current.AddDate(0, 0, minDayDist), nil
+	for _, day := range spec.weekdays {
+		argsCreateScheduleWeekday := sqlc.RidesCreateScheduleWeekdayParams{
+			RideScheduleID: scheduleId,
+			Weekday:        day,
 		}
-	default:
-		{
-			return current, fmt.Errorf("Invalid schedule unit '%s'", scheduleUnit)
+
+		err = queriesTx.RidesCreateScheduleWeekday(ctx, argsCreateScheduleWeekday)
+		if err != nil {
+			return err
 		}
 	}
+
+	return nil
+}
+
+// Returns the time zone of the schedule. Defaults to UTC.
+func (s *rideSchedule) timezone() (string, error) {
+	if s.Timezone == nil {
+		return "UTC", nil
+	}
+
+	if *s.Timezone == "" || *s.Timezone == "Local" {
+		return "", fmt.Errorf("Invalid time zone '%s'.", *s.Timezone)
+	}
+
+	_, err := time.LoadLocation(*s.Timezone)
+	if err != nil {
+		return "", fmt.Errorf("Invalid time zone '%s'.", *s.Timezone)
+	}
+
+	return *s.Timezone, nil
 }
 
 func weekdayToInt(day string) (int, error) {
@@ -703,45 +1103,3 @@ func weekdayToInt(day string) (int, error) {
 		}
 	}
 }
-
-func markPastRideEventsAsDoneAndCreateScheduled(queriesTx *sqlc.Queries, ctx context.Context) error {
-	now := time.Now()
-	updated, err := queriesTx.RidesMarkPastEventsDone(ctx, now.UTC().Format(time.RFC3339))
-	if err != nil {
-		return errors.New("Failed to update rides.")
-	}
-
-	for _, id := range updated {
-		event, err := queriesTx.RidesGetEvent(ctx, id)
-		assert.Nil(err)
-
-		schedule, err := queriesTx.RidesGetSchedule(ctx, event.RideID)
-		if errors.Is(err, sql.ErrNoRows) {
-			continue
-		}
-		assert.Nil(err)
-
-		var weekdays *[]string = nil
-		if event.RideScheduleUnit.String == "weekdays" {
-			days, err := queriesTx.RidesGetScheduleWeekdays(ctx, schedule.ID)
-			assert.Nil(err)
-			weekdays = &days
-		}
-		next, err := nextScheduledTime(now, schedule.Unit, schedule.ScheduleInterval, weekdays)
-		assert.Nil(err)
-
-		argsCreateEvent := sqlc.RidesCreateEventParams{
-			RideID:         event.RideID,
-			LocationFrom:   event.LocationFrom,
-			LocationTo:     event.LocationTo,
-			TransportLimit: event.TransportLimit,
-			Driver:         event.Driver,
-			TackingPlaceAt: next.UTC().Format(time.RFC3339),
-		}
-
-		err = queriesTx.RidesCreateEvent(ctx, argsCreateEvent)
-		assert.Nil(err)
-	}
-
-	return nil
-}
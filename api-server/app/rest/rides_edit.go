@@ -0,0 +1,305 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"strconv"
+	"time"
+)
+
+const (
+	RIDE_EDIT_SCOPE_SINGLE    = "single"
+	RIDE_EDIT_SCOPE_FOLLOWING = "following"
+)
+
+// A single field of a ride event that was changed. Values are formatted as
+// strings, the driver is identified by their email.
+type rideEventFieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type rideEventChange struct {
+	Id             string                 `json:"id"`
+	ChangedBy      string                 `json:"changedBy"`
+	ChangedByEmail string                 `json:"changedByEmail"`
+	Changes        []rideEventFieldChange `json:"changes"`
+	CreatedAt      time.Time              `json:"createdAt"`
+}
+
+// Error caused by the request, the title is sent to the client as is.
+type rideEditError struct {
+	status int
+	title  string
+}
+
+// A user who got a seat of a ride event they were waiting for.
+type waitlistPromotion struct {
+	RideEventId string
+	UserId      string
+}
+
+// A driver who no longer drives a ride event.
+type replacedDriver struct {
+	RideEventId string
+	UserId      string
+}
+
+func (e rideEditError) Error() string {
+	return e.title
+}
+
+func (p *updateRideParams) editsDetails() bool {
+	return p.LocationFrom != nil || p.LocationTo != nil || p.TackingPlaceAt != nil || p.Driver != nil || p.TransportLimit != nil
+}
+
+// Applies the detail changes of `params` to `event`. With the scope
+// 'following' they are also applied to all later upcoming events of the ride
+// and to the ride itself, which future events are created from. A new time
+// moves the following events by the same offset from their occurrence, the
+// start of the series stays the same.
+//
+// Every event that changed gets a change notice, so participants can see
+// what happened to their ride. Returns the ids of the events that changed,
+// the users who got a seat from the waitlist because of more seats and the
+// drivers who were replaced.
+func editRideEvents(ctx context.Context, queriesTx *sqlc.Queries, user sqlc.User, event sqlc.RidesGetEventRow, params updateRideParams) (changed []string, promoted []waitlistPromotion, replaced []replacedDriver, err error) {
+	scope := RIDE_EDIT_SCOPE_SINGLE
+	if params.Scope != nil {
+		scope = *params.Scope
+	}
+
+	argsFollowing := sqlc.RidesGetFollowingEventsParams{
+		RideID:      event.RideID,
+		RideEventID: event.RideEventID,
+	}
+	events, err := queriesTx.RidesGetFollowingEvents(ctx, argsFollowing)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the event itself always comes first, nothing that follows it has an earlier occurrence
+	assert.True(len(events) > 0 && events[0].ID == event.RideEventID, "Edited ride event has to be the first of the following events.")
+	if scope == RIDE_EDIT_SCOPE_SINGLE {
+		events = events[:1]
+	}
+
+	driverEmail := ""
+	if params.Driver != nil {
+		driver, err := queriesTx.UsersGetById(ctx, *params.Driver)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil, rideEditError{status: http.StatusBadRequest, title: "Field 'driver' has to be the id of an existing user."}
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		driverEmail = driver.Email
+	}
+
+	if params.TransportLimit != nil {
+		for _, e := range events {
+			participantsCount, err := queriesTx.RidesCountEventParticipants(ctx, e.ID)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			if *params.TransportLimit < participantsCount {
+				return nil, nil, nil, rideEditError{status: http.StatusConflict, title: "Field 'transportLimit' can't be lower than the number of participants."}
+			}
+		}
+	}
+
+	times := make([]*time.Time, len(events))
+	var offset int64
+	if params.TackingPlaceAt != nil {
+		times, offset, err = rescheduleEvents(event, events, *params.TackingPlaceAt)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	changed = make([]string, 0, len(events))
+	for idx, e := range events {
+		args := sqlc.RidesUpdateEventDetailsParams{ID: e.ID}
+		changes := make([]rideEventFieldChange, 0)
+
+		if params.LocationFrom != nil && *params.LocationFrom != e.LocationFrom {
+			args.LocationFrom = sql.NullString{String: *params.LocationFrom, Valid: true}
+			changes = append(changes, rideEventFieldChange{Field: "locationFrom", From: e.LocationFrom, To: *params.LocationFrom})
+		}
+
+		if params.LocationTo != nil && *params.LocationTo != e.LocationTo {
+			args.LocationTo = sql.NullString{String: *params.LocationTo, Valid: true}
+			changes = append(changes, rideEventFieldChange{Field: "locationTo", From: e.LocationTo, To: *params.LocationTo})
+		}
+
+		if params.Driver != nil && *params.Driver != e.Driver {
+			args.Driver = sql.NullString{String: *params.Driver, Valid: true}
+			changes = append(changes, rideEventFieldChange{Field: "driver", From: e.DriverEmail, To: driverEmail})
+			replaced = append(replaced, replacedDriver{RideEventId: e.ID, UserId: e.Driver})
+		}
+
+		if params.TransportLimit != nil && *params.TransportLimit != e.TransportLimit {
+			args.TransportLimit = sql.NullInt64{Int64: *params.TransportLimit, Valid: true}
+			changes = append(changes, rideEventFieldChange{Field: "transportLimit", From: strconv.FormatInt(e.TransportLimit, 10), To: strconv.FormatInt(*params.TransportLimit, 10)})
+		}
+
+		if times[idx] != nil {
+			to := times[idx].UTC().Format(time.RFC3339)
+			from, err := time.Parse(time.RFC3339, e.TackingPlaceAt)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			if !from.Equal(*times[idx]) {
+				args.TackingPlaceAt = sql.NullString{String: to, Valid: true}
+				changes = append(changes, rideEventFieldChange{Field: "tackingPlaceAt", From: e.TackingPlaceAt, To: to})
+			}
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		err = queriesTx.RidesUpdateEventDetails(ctx, args)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if args.TransportLimit.Valid {
+			promotedUsers, err := promoteWaitlist(ctx, queriesTx, e.ID, args.TransportLimit.Int64)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			for _, userId := range promotedUsers {
+				promoted = append(promoted, waitlistPromotion{RideEventId: e.ID, UserId: userId})
+			}
+		}
+
+		changesJson, err := json.Marshal(changes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		argsChange := sqlc.RidesCreateEventChangeParams{
+			RideEventID: e.ID,
+			ChangedBy:   user.ID,
+			Changes:     string(changesJson),
+		}
+		err = queriesTx.RidesCreateEventChange(ctx, argsChange)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		changed = append(changed, e.ID)
+	}
+
+	if scope != RIDE_EDIT_SCOPE_FOLLOWING {
+		return changed, promoted, replaced, nil
+	}
+
+	argsBase := sqlc.RidesUpdateBaseParams{ID: event.RideID}
+	if params.LocationFrom != nil {
+		argsBase.LocationFrom = sql.NullString{String: *params.LocationFrom, Valid: true}
+	}
+	if params.LocationTo != nil {
+		argsBase.LocationTo = sql.NullString{String: *params.LocationTo, Valid: true}
+	}
+	if params.Driver != nil {
+		argsBase.Driver = sql.NullString{String: *params.Driver, Valid: true}
+	}
+	if params.TransportLimit != nil {
+		argsBase.TransportLimit = sql.NullInt64{Int64: *params.TransportLimit, Valid: true}
+	}
+	if params.TackingPlaceAt != nil {
+		argsBase.OccurrenceOffset = sql.NullInt64{Int64: offset, Valid: true}
+	}
+
+	return changed, promoted, replaced, queriesTx.RidesUpdateBase(ctx, argsBase)
+}
+
+// Returns the new times of `events` if the first of them is moved to
+// `tackingPlaceAt`. The others are moved by the same offset from their
+// occurrence, on the wall clock of the schedule's time zone. Returns the
+// offset in seconds.
+func rescheduleEvents(event sqlc.RidesGetEventRow, events []sqlc.RidesGetFollowingEventsRow, tackingPlaceAt time.Time) ([]*time.Time, int64, error) {
+	location := time.UTC
+	if event.RideScheduleTimezone.Valid {
+		var err error
+		location, err = time.LoadLocation(event.RideScheduleTimezone.String)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	first, err := time.Parse(time.RFC3339, events[0].OccurrenceAt.String)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := wallClockOffset(first, tackingPlaceAt, location)
+	times := make([]*time.Time, len(events))
+	times[0] = &tackingPlaceAt
+	for idx := 1; idx < len(events); idx++ {
+		occurrence, err := time.Parse(time.RFC3339, events[idx].OccurrenceAt.String)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		moved := shiftOccurrence(occurrence, offset, location)
+		times[idx] = &moved
+	}
+
+	return times, offset, nil
+}
+
+func getEventChanges(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	id := r.PathValue("id")
+	event, err := state.queries.RidesGetEvent(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !canSeeRide(r.Context(), state.queries, event.GroupID, user.ID)) {
+		httpWriteErr(w, http.StatusNotFound, "No ride event exists for the event with 'id'.")
+		return
+	}
+
+	assert.Nil(err)
+
+	rows, err := state.queries.RidesGetEventChanges(r.Context(), id)
+	assert.Nil(err)
+
+	changes := make([]rideEventChange, 0, len(rows))
+	for _, row := range rows {
+		change := rideEventChange{
+			Id:             row.ID,
+			ChangedBy:      row.ChangedBy,
+			ChangedByEmail: row.ChangedByEmail,
+		}
+
+		err = json.Unmarshal([]byte(row.Changes), &change.Changes)
+		if err != nil {
+			log.Println("Error: Invalid changes of ride event change.", "change:", row.ID, "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to get ride event changes.")
+			return
+		}
+
+		change.CreatedAt, err = time.Parse(time.RFC3339, row.CreatedAt)
+		assert.Nil(err)
+
+		changes = append(changes, change)
+	}
+
+	resp, err := json.Marshal(changes)
+	assert.Nil(err, "Failed to serialize ride event changes.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
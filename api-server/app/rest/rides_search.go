@@ -0,0 +1,269 @@
+package rest
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Names of the search filters. These have to match `SEARCH_FILTERS` in the
+// web app (web-app/src/lib/search.ts).
+const (
+	SEARCH_FILTER_STATUS       = "status"
+	SEARCH_FILTER_FROM         = "from"
+	SEARCH_FILTER_TO           = "to"
+	SEARCH_FILTER_BEFORE       = "before"
+	SEARCH_FILTER_AFTER        = "after"
+	SEARCH_FILTER_DRIVER       = "driver"
+	SEARCH_FILTER_OWNER        = "owner"
+	SEARCH_FILTER_PARTICIPANTS = "participants"
+)
+
+var searchFilterNames = []string{
+	SEARCH_FILTER_STATUS,
+	SEARCH_FILTER_FROM,
+	SEARCH_FILTER_TO,
+	SEARCH_FILTER_BEFORE,
+	SEARCH_FILTER_AFTER,
+	SEARCH_FILTER_DRIVER,
+	SEARCH_FILTER_OWNER,
+	SEARCH_FILTER_PARTICIPANTS,
+}
+
+// Layouts accepted for the `before` and `after` filters. Values without a
+// time zone are interpreted as UTC.
+var searchDateLayouts = []string{
+	time.RFC3339,
+	time.DateTime,
+	"2006-01-02T15:04",
+	time.DateOnly,
+	"01.02.2006",
+	"01/02/2006",
+}
+
+// Email alias that is replaced with the email of the requesting user.
+const searchEmailAliasMe = "me"
+
+type rideSearchFilters struct {
+	Status       *string
+	From         *string
+	To           *string
+	Driver       *string
+	Owner        *string
+	Before       *time.Time
+	After        *time.Time
+	Participants []string
+}
+
+func (f *rideSearchFilters) isEmpty() bool {
+	return f.Status == nil &&
+		f.From == nil &&
+		f.To == nil &&
+		f.Driver == nil &&
+		f.Owner == nil &&
+		f.Before == nil &&
+		f.After == nil &&
+		len(f.Participants) == 0
+}
+
+// Parses a ride search string the same way as `parseSearchString` in the web
+// app. Search filters are of the format `:{filter-name} {filter-value}`. A
+// filter value reaches until the start of the next filter or the end of the
+// string. Filters with invalid values are ignored.
+func parseRideSearchString(raw string) rideSearchFilters {
+	values := make(map[string]string)
+
+	var filterName string
+	var filterValue strings.Builder
+	active := false
+
+	for i := 0; i < len(raw); i++ {
+		matched := false
+
+		nextSpaceIdx := strings.IndexByte(raw[i+1:], ' ')
+		if raw[i] == ':' && nextSpaceIdx != -1 {
+			nextSpaceIdx += i + 1
+			name := raw[i+1 : nextSpaceIdx]
+
+			if slices.Contains(searchFilterNames, name) {
+				if active {
+					values[filterName] = filterValue.String()
+				}
+
+				filterName = name
+				filterValue.Reset()
+				active = true
+				matched = true
+
+				// skip over the filter name that was just scanned
+				i = nextSpaceIdx
+			}
+		}
+
+		if !matched && active {
+			filterValue.WriteByte(raw[i])
+		}
+	}
+
+	if active {
+		values[filterName] = filterValue.String()
+	}
+
+	var filters rideSearchFilters
+	for name, value := range values {
+		// Invalid values are dropped, matching the behavior of the web app.
+		_ = filters.set(name, value)
+	}
+
+	return filters
+}
+
+// Builds the search filters of a request. Filters are read from the raw search
+// string in the `q` query parameter, and from query parameters named after the
+// filters (e.g. `?from=Graz&participants=a@example.com,b@example.com`). Query
+// parameters named after a filter take precedence over the search string.
+func rideSearchFiltersFromRequest(r *http.Request) (rideSearchFilters, error) {
+	query := r.URL.Query()
+	filters := parseRideSearchString(query.Get("q"))
+
+	for _, name := range searchFilterNames {
+		if !query.Has(name) {
+			continue
+		}
+
+		err := filters.set(name, query.Get(name))
+		if err != nil {
+			return filters, err
+		}
+	}
+
+	return filters, nil
+}
+
+func (f *rideSearchFilters) set(name string, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch name {
+	case SEARCH_FILTER_STATUS:
+		f.Status = &value
+	case SEARCH_FILTER_FROM:
+		f.From = &value
+	case SEARCH_FILTER_TO:
+		f.To = &value
+	case SEARCH_FILTER_DRIVER:
+		f.Driver = &value
+	case SEARCH_FILTER_OWNER:
+		f.Owner = &value
+	case SEARCH_FILTER_PARTICIPANTS:
+		participants := make([]string, 0)
+		for _, participant := range strings.Split(value, ",") {
+			participant = strings.TrimSpace(participant)
+			if participant != "" {
+				participants = append(participants, participant)
+			}
+		}
+		f.Participants = participants
+	case SEARCH_FILTER_BEFORE:
+		date, err := parseSearchDate(value)
+		if err != nil {
+			return err
+		}
+		f.Before = &date
+	case SEARCH_FILTER_AFTER:
+		date, err := parseSearchDate(value)
+		if err != nil {
+			return err
+		}
+		f.After = &date
+	default:
+		return fmt.Errorf("Unknown search filter '%s'.", name)
+	}
+
+	return nil
+}
+
+// Converts the filters into the arguments of the `RidesSearch` query. The
+// email alias `me` is resolved to the email of `user`, only rides visible to
+// `user` are found. Pagination arguments are left empty.
+func (f *rideSearchFilters) toQueryParams(user sqlc.User) sqlc.RidesSearchParams {
+	count := f.toCountParams(user)
+
+	return sqlc.RidesSearchParams{
+		UserID:            count.UserID,
+		Status:            count.Status,
+		LocationFrom:      count.LocationFrom,
+		LocationTo:        count.LocationTo,
+		DriverEmail:       count.DriverEmail,
+		CreatedByEmail:    count.CreatedByEmail,
+		Before:            count.Before,
+		After:             count.After,
+		ParticipantsCount: count.ParticipantsCount,
+		Participants:      count.Participants,
+	}
+}
+
+// Converts the filters into the arguments of the `RidesSearchCount` query.
+// Participants are deduplicated after resolving `me`, the query compares their
+// number with the distinct participants of a ride.
+func (f *rideSearchFilters) toCountParams(user sqlc.User) sqlc.RidesSearchCountParams {
+	participants := make([]string, 0, len(f.Participants))
+	for _, participant := range f.Participants {
+		email := resolveEmailAlias(user, participant)
+		if !slices.Contains(participants, email) {
+			participants = append(participants, email)
+		}
+	}
+
+	return sqlc.RidesSearchCountParams{
+		UserID:            user.ID,
+		Status:            utils.SqlNullStr(f.Status),
+		LocationFrom:      utils.SqlNullStr(f.From),
+		LocationTo:        utils.SqlNullStr(f.To),
+		DriverEmail:       sqlNullEmail(user, f.Driver),
+		CreatedByEmail:    sqlNullEmail(user, f.Owner),
+		Before:            sqlNullTime(f.Before),
+		After:             sqlNullTime(f.After),
+		ParticipantsCount: int64(len(participants)),
+		Participants:      participants,
+	}
+}
+
+func parseSearchDate(value string) (time.Time, error) {
+	for _, layout := range searchDateLayouts {
+		date, err := time.ParseInLocation(layout, value, time.UTC)
+		if err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid date '%s'. Use a date of the format 'YYYY-MM-DD' or 'YYYY-MM-DDTHH:MM:SSZ'.", value)
+}
+
+func resolveEmailAlias(user sqlc.User, email string) string {
+	if email == searchEmailAliasMe {
+		return user.Email
+	}
+
+	return email
+}
+
+func sqlNullEmail(user sqlc.User, email *string) sql.NullString {
+	if email == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: resolveEmailAlias(user, *email), Valid: true}
+}
+
+func sqlNullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: t.UTC().Format(time.RFC3339), Valid: true}
+}
@@ -7,13 +7,15 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path"
 	"ride_sharing_api/app/assert"
 	"ride_sharing_api/app/rest"
-	"ride_sharing_api/app/sqlc"
 	"ride_sharing_api/app/utils"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -35,7 +37,7 @@ func TestHandleCreateRide(t *testing.T) {
 	// Missing request body
 	req, err := http.NewRequest("POST", api.URL+"/rides", bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err = api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 400)
@@ -46,7 +48,7 @@ func TestHandleCreateRide(t *testing.T) {
 	// Missing fields in request body
 	req, err = http.NewRequest("POST", api.URL+"/rides", bytes.NewReader([]byte(`{ "locationTo": "New York" }`)))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err = api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 400)
@@ -63,7 +65,7 @@ func TestHandleCreateRide(t *testing.T) {
 		"transportLimit": 4
 	}`))))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err = api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 201)
@@ -78,7 +80,7 @@ func TestHandleCreateRide(t *testing.T) {
 
 	req, err = http.NewRequest("GET", api.URL+"/rides/by-id/"+createRespone["rideEventId"].(string), bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err = api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 200)
@@ -102,15 +104,18 @@ func TestHandleGetManyRides(t *testing.T) {
 
 	req, err := http.NewRequest("GET", api.URL+"/rides/many", bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err := api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 200)
 	data, err := io.ReadAll(resp.Body)
 	assert.Nil(err)
-	var rides []rest.RideEventData
-	err = json.Unmarshal(data, &rides)
+	var page rest.Page[rest.RideEventData]
+	err = json.Unmarshal(data, &page)
 	assert.Nil(err)
+	assert.Eq(page.Total, int64(3))
+	assert.True(page.NextCursor == nil)
+	rides := page.Items
 	assert.Eq(len(rides), 3)
 	assert.Eq(rides[0].RideId, "222")
 	assert.Eq(rides[0].LocationFrom, "Tokyo")
@@ -130,15 +135,17 @@ func TestHandleGetManyRidesEmpty(t *testing.T) {
 
 	req, err := http.NewRequest("GET", api.URL+"/rides/many", bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err := api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 200)
 	data, err := io.ReadAll(resp.Body)
 	assert.Nil(err)
-	var rides []sqlc.RidesGetManyRow
-	err = json.Unmarshal(data, &rides)
-	assert.Eq(len(rides), 0)
+	var page rest.Page[rest.RideEventData]
+	err = json.Unmarshal(data, &page)
+	assert.Nil(err)
+	assert.Eq(page.Total, int64(0))
+	assert.Eq(len(page.Items), 0)
 }
 
 func TestHandleGetRideById(t *testing.T) {
@@ -152,7 +159,7 @@ func TestHandleGetRideById(t *testing.T) {
 
 	req, err := http.NewRequest("GET", api.URL+"/rides/upcoming/by-id/123", bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err := api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 200)
@@ -167,12 +174,402 @@ func TestHandleGetRideById(t *testing.T) {
 
 	req, err = http.NewRequest("GET", api.URL+"/rides/upcoming/by-id/nope", bytes.NewReader([]byte{}))
 	assert.Nil(err)
-	req.Header.Add("Authorization", "yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC")
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
 	resp, err = api.Client().Do(req)
 	assert.Nil(err)
 	assert.Eq(resp.StatusCode, 404)
 }
 
+func TestHandleSearchRides(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0008-handle-search-rides.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	search := func(query url.Values) (int, []rest.RideEventData) {
+		req, err := http.NewRequest("GET", api.URL+"/rides/many?"+query.Encode(), bytes.NewReader([]byte{}))
+		assert.Nil(err)
+		req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+
+		var page rest.Page[rest.RideEventData]
+		if resp.StatusCode == 200 {
+			err = json.Unmarshal(data, &page)
+			assert.Nil(err)
+			assert.Eq(page.Total, int64(len(page.Items)))
+		}
+
+		return resp.StatusCode, page.Items
+	}
+
+	// Search string
+	status, rides := search(url.Values{"q": {":from Graz"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 2)
+	assert.Eq(rides[0].RideId, "321")
+	assert.Eq(rides[1].RideId, "123")
+
+	// Filter values can contain spaces
+	status, rides = search(url.Values{"q": {":to Kaindorf an der Sulm :owner KluwXy24KzJnN@proton.me"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 1)
+	assert.Eq(rides[0].RideId, "321")
+
+	// Email alias
+	status, rides = search(url.Values{"driver": {"me"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 1)
+	assert.Eq(rides[0].RideId, "321")
+
+	// All participants have to be part of the ride
+	status, rides = search(url.Values{"participants": {"me, WDZHw/GNwrQ5vhtWojbR@gmail.com"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 1)
+	assert.Eq(rides[0].RideId, "321")
+
+	status, rides = search(url.Values{"q": {":participants WDZHw/GNwrQ5vhtWojbR@gmail.com"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 2)
+
+	// Participants given more than once only count once
+	status, rides = search(url.Values{"participants": {"me, test@example.com, WDZHw/GNwrQ5vhtWojbR@gmail.com"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 1)
+	assert.Eq(rides[0].RideId, "321")
+
+	// Dates
+	status, rides = search(url.Values{"q": {":after 2044-11-27 :before 2044-12-01"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 1)
+	assert.Eq(rides[0].RideId, "321")
+
+	// Query parameters take precedence over the search string
+	status, rides = search(url.Values{"q": {":from Graz"}, "from": {"Tokyo"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 1)
+	assert.Eq(rides[0].RideId, "222")
+
+	// Nothing matches
+	status, rides = search(url.Values{"q": {":status canceled"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(rides), 0)
+
+	// Invalid date
+	status, _ = search(url.Values{"before": {"not-a-date"}})
+	assert.Eq(status, 400)
+}
+
+func TestHandleGetManyRidesPaginated(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0009-handle-get-many-rides-paginated.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	getPage := func(query url.Values) (int, rest.Page[rest.RideEventData]) {
+		req, err := http.NewRequest("GET", api.URL+"/rides/many?"+query.Encode(), bytes.NewReader([]byte{}))
+		assert.Nil(err)
+		req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+
+		var page rest.Page[rest.RideEventData]
+		if resp.StatusCode == 200 {
+			err = json.Unmarshal(data, &page)
+			assert.Nil(err)
+		}
+
+		return resp.StatusCode, page
+	}
+
+	// Walk through all pages
+	rideIds := make([]string, 0)
+	query := url.Values{"limit": {"2"}}
+	for {
+		status, page := getPage(query)
+		assert.Eq(status, 200)
+		assert.Eq(page.Total, int64(3))
+
+		for _, ride := range page.Items {
+			rideIds = append(rideIds, ride.RideId)
+		}
+
+		if page.NextCursor == nil {
+			break
+		}
+
+		query.Set("cursor", *page.NextCursor)
+	}
+	assert.Eq(strings.Join(rideIds, ","), "222,321,123")
+
+	// Pagination also applies to searches
+	status, page := getPage(url.Values{"q": {":to Wien"}, "limit": {"1"}})
+	assert.Eq(status, 200)
+	assert.Eq(page.Total, int64(2))
+	assert.Eq(len(page.Items), 1)
+	assert.Eq(page.Items[0].RideId, "222")
+
+	status, page = getPage(url.Values{"q": {":to Wien"}, "limit": {"1"}, "cursor": {*page.NextCursor}})
+	assert.Eq(status, 200)
+	assert.Eq(len(page.Items), 1)
+	assert.Eq(page.Items[0].RideId, "123")
+	assert.True(page.NextCursor == nil)
+
+	// Page size is capped
+	status, page = getPage(url.Values{"limit": {"100000"}})
+	assert.Eq(status, 200)
+	assert.Eq(len(page.Items), 3)
+
+	// Invalid parameters
+	status, _ = getPage(url.Values{"limit": {"0"}})
+	assert.Eq(status, 400)
+	status, _ = getPage(url.Values{"cursor": {"not-a-cursor"}})
+	assert.Eq(status, 400)
+	status, _ = getPage(url.Values{"cursor": {"e30"}})
+	assert.Eq(status, 400)
+}
+
+func TestHandleJoinLeaveRide(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0014-handle-join-leave-rides.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	testAuth(api, "/rides/leave", "POST")
+
+	tokenUser01 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+	tokenUser02 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.w19_ESPa5AgLpH2IV9CrwSPq4XQcJJHgAqVf2bNmcOsdamLKJL_JntjmB-Er356m2bH4OlXg0hYagmU6irxwLymqB7QCGhsF1ixtMwyrGq8lFeePVwb41uD_5xRRkJxjB0GeIO_AIyCl9yCVa3djzcvx-6EBC1f_E9_t"
+	tokenUser03 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.cevnH03gVtsxMU7jy3vx4c7caO9X1tQuTTTyHR6a4ZAmqi3Icut3oi9UPfToSx7hnzvBk265_VIt7W5u-Tu2s7SXG6RPEBWlHzQ6DFEZjDsXLd1eqVONAPgWifhpD3UrooBLYzUyJ0lDh05O5gOZxaKYB1o"
+
+	post := func(endpoint string, token string, body string) (int, string) {
+		req, err := http.NewRequest("POST", api.URL+endpoint, bytes.NewReader([]byte(body)))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		return resp.StatusCode, string(data)
+	}
+
+	getRide := func() rest.RideEventData {
+		req, err := http.NewRequest("GET", api.URL+"/rides/by-id/full-event", bytes.NewReader([]byte{}))
+		assert.Nil(err)
+		req.Header.Add("Authorization", tokenUser01)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 200)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		var ride rest.RideEventData
+		err = json.Unmarshal(data, &ride)
+		assert.Nil(err)
+		return ride
+	}
+
+	// Returns the sorted ids of the participants and the ids of the waitlist in order.
+	userIds := func(ride rest.RideEventData) (string, string) {
+		participants := make([]string, 0)
+		for _, participant := range ride.Participants {
+			participants = append(participants, participant.UserId)
+		}
+		slices.Sort(participants)
+		waitlist := make([]string, 0)
+		for _, waiting := range ride.Waitlist {
+			waitlist = append(waitlist, waiting.UserId)
+		}
+		return strings.Join(participants, ", "), strings.Join(waitlist, ", ")
+	}
+
+	event := `{ "rideEventId": "full-event" }`
+
+	// Joining a full ride puts you on the waitlist
+	status, body := post("/rides/join", tokenUser01, event)
+	assert.Eq(status, 202)
+	assert.Eq(body, `{"status":"waitlisted","waitlistPosition":1}`)
+	status, body = post("/rides/join", tokenUser02, event)
+	assert.Eq(status, 202)
+	assert.Eq(body, `{"status":"waitlisted","waitlistPosition":2}`)
+
+	status, body = post("/rides/join", tokenUser01, event)
+	assert.Eq(status, 409)
+	assert.True(strings.Contains(body, "Already on the waitlist"), body)
+	status, body = post("/rides/join", tokenUser03, event)
+	assert.Eq(status, 409)
+	assert.True(strings.Contains(body, "Already a member"), body)
+
+	// Leaving the waitlist gives up your position
+	status, _ = post("/rides/leave", tokenUser01, event)
+	assert.Eq(status, 200)
+	status, body = post("/rides/leave", tokenUser01, event)
+	assert.Eq(status, 409)
+	assert.True(strings.Contains(body, "Not a member"), body)
+	status, body = post("/rides/join", tokenUser01, event)
+	assert.Eq(status, 202)
+	assert.Eq(body, `{"status":"waitlisted","waitlistPosition":2}`)
+
+	// A free seat goes to the first user on the waitlist
+	status, _ = post("/rides/leave", tokenUser03, event)
+	assert.Eq(status, 200)
+	participants, waitlist := userIds(getRide())
+	assert.Eq(participants, "nmBSHcxyvn")
+	assert.Eq(waitlist, "NnCaPHQLC9")
+
+	// Only the owner or driver can change the number of seats
+	status, _ = post("/rides/update", tokenUser02, `{ "rideEventId": "full-event", "transportLimit": 3 }`)
+	assert.Eq(status, 403)
+	status, _ = post("/rides/update", tokenUser03, `{ "rideEventId": "full-event", "transportLimit": 0 }`)
+	assert.Eq(status, 400)
+
+	// Raising the limit promotes waiting users
+	status, _ = post("/rides/update", tokenUser03, `{ "rideEventId": "full-event", "transportLimit": 3 }`)
+	assert.Eq(status, 200)
+	ride := getRide()
+	assert.Eq(ride.TransportLimit, int64(3))
+	participants, waitlist = userIds(ride)
+	assert.Eq(participants, "NnCaPHQLC9, nmBSHcxyvn")
+	assert.Eq(waitlist, "")
+
+	status, body = post("/rides/update", tokenUser03, `{ "rideEventId": "full-event", "transportLimit": 1 }`)
+	assert.Eq(status, 409)
+	assert.True(strings.Contains(body, "transportLimit"), body)
+
+	// Seats that are free are taken directly
+	status, body = post("/rides/join", tokenUser03, event)
+	assert.Eq(status, 200)
+	assert.Eq(body, `{"status":"joined","waitlistPosition":null}`)
+
+	status, _ = post("/rides/leave", tokenUser01, `{ "rideEventId": "missing" }`)
+	assert.Eq(status, 404)
+}
+
+func TestHandleEditRideEvents(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0015-handle-edit-ride-events.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	testAuth(api, "/rides/by-id/weekly-1/changes", "GET")
+
+	tokenUser01 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+	tokenUser03 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.cevnH03gVtsxMU7jy3vx4c7caO9X1tQuTTTyHR6a4ZAmqi3Icut3oi9UPfToSx7hnzvBk265_VIt7W5u-Tu2s7SXG6RPEBWlHzQ6DFEZjDsXLd1eqVONAPgWifhpD3UrooBLYzUyJ0lDh05O5gOZxaKYB1o"
+
+	request := func(method string, endpoint string, token string, body string) (int, []byte) {
+		req, err := http.NewRequest(method, api.URL+endpoint, bytes.NewReader([]byte(body)))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		return resp.StatusCode, data
+	}
+
+	getRide := func(id string) rest.RideEventData {
+		status, data := request("GET", "/rides/by-id/"+id, tokenUser01, "")
+		assert.Eq(status, 200)
+		var ride rest.RideEventData
+		err := json.Unmarshal(data, &ride)
+		assert.Nil(err)
+		return ride
+	}
+
+	// Returns the changes of a ride event as 'field: from -> to', newest first.
+	getChanges := func(id string) []string {
+		status, data := request("GET", "/rides/by-id/"+id+"/changes", tokenUser01, "")
+		assert.Eq(status, 200)
+		var changes []struct {
+			ChangedByEmail string `json:"changedByEmail"`
+			Changes        []struct {
+				Field string `json:"field"`
+				From  string `json:"from"`
+				To    string `json:"to"`
+			} `json:"changes"`
+		}
+		err := json.Unmarshal(data, &changes)
+		assert.Nil(err)
+
+		formatted := make([]string, 0)
+		for _, change := range changes {
+			assert.Eq(change.ChangedByEmail, "test@example.com")
+			fields := make([]string, 0)
+			for _, field := range change.Changes {
+				fields = append(fields, fmt.Sprintf("%s: %s -> %s", field.Field, field.From, field.To))
+			}
+			formatted = append(formatted, strings.Join(fields, ", "))
+		}
+		return formatted
+	}
+
+	// Only the owner can change anything but the number of seats
+	status, _ := request("POST", "/rides/update", tokenUser03, `{ "rideEventId": "weekly-1", "locationTo": "Linz" }`)
+	assert.Eq(status, 403)
+	status, _ = request("POST", "/rides/update", tokenUser01, `{ "rideEventId": "weekly-1", "locationTo": "Linz", "scope": "all" }`)
+	assert.Eq(status, 400)
+	status, _ = request("POST", "/rides/update", tokenUser01, `{ "rideEventId": "weekly-1", "driver": "missing" }`)
+	assert.Eq(status, 400)
+
+	// A single event
+	status, _ = request("POST", "/rides/update", tokenUser01, `{ "rideEventId": "weekly-1", "locationTo": "Linz" }`)
+	assert.Eq(status, 200)
+	assert.Eq(getRide("weekly-1").LocationTo, "Linz")
+	assert.Eq(getRide("weekly-2").LocationTo, "Wien")
+	assert.Eq(strings.Join(getChanges("weekly-1"), " | "), "locationTo: Wien -> Linz")
+	assert.Eq(len(getChanges("weekly-2")), 0)
+
+	// Unchanged values don't create change notices
+	status, _ = request("POST", "/rides/update", tokenUser01, `{ "rideEventId": "weekly-1", "locationTo": "Linz" }`)
+	assert.Eq(status, 200)
+	assert.Eq(len(getChanges("weekly-1")), 1)
+
+	// Every following event has to have enough seats
+	status, body := request("POST", "/rides/update", tokenUser01, `{ "rideEventId": "weekly-1", "transportLimit": 1, "scope": "following" }`)
+	assert.Eq(status, 409)
+	assert.True(strings.Contains(string(body), "transportLimit"), string(body))
+	assert.Eq(getRide("weekly-1").TransportLimit, int64(4))
+
+	// This and the following events, a new time moves the rest of the series
+	update := `{
+		"rideEventId": "weekly-2",
+		"scope": "following",
+		"locationFrom": "Leoben",
+		"driver": "nmBSHcxyvn",
+		"tackingPlaceAt": "2044-12-04T16:00:00Z"
+	}`
+	status, _ = request("POST", "/rides/update", tokenUser01, update)
+	assert.Eq(status, 200)
+
+	first := getRide("weekly-1")
+	assert.Eq(first.LocationFrom, "Graz")
+	assert.Eq(first.DriverId, "m6SYNABgAw")
+	assert.Eq(first.TackingPlaceAt.UTC().Format(time.RFC3339), "2044-11-26T15:00:00Z")
+
+	for _, id := range []string{"weekly-2", "weekly-3"} {
+		ride := getRide(id)
+		assert.Eq(ride.LocationFrom, "Leoben")
+		assert.Eq(ride.LocationTo, "Wien")
+		assert.Eq(ride.DriverId, "nmBSHcxyvn")
+		assert.Eq(ride.DriverEmail, "WDZHw/GNwrQ5vhtWojbR@gmail.com")
+	}
+	assert.Eq(getRide("weekly-2").TackingPlaceAt.UTC().Format(time.RFC3339), "2044-12-04T16:00:00Z")
+	assert.Eq(getRide("weekly-3").TackingPlaceAt.UTC().Format(time.RFC3339), "2044-12-11T16:00:00Z")
+
+	changes := getChanges("weekly-3")
+	assert.Eq(len(changes), 1)
+	assert.Eq(changes[0], "locationFrom: Graz -> Leoben, driver: KluwXy24KzJnN@proton.me -> WDZHw/GNwrQ5vhtWojbR@gmail.com, tackingPlaceAt: 2044-12-10T15:00:00Z -> 2044-12-11T16:00:00Z")
+
+	status, _ = request("GET", "/rides/by-id/missing/changes", tokenUser01, "")
+	assert.Eq(status, 404)
+}
+
 func testAuth(api *httptest.Server, endpoint string, method string) {
 	// Missing authentication token
 	req, err := http.NewRequest(method, api.URL+endpoint, bytes.NewReader([]byte{}))
@@ -0,0 +1,433 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RRULE_FREQ_DAILY   = "DAILY"
+	RRULE_FREQ_WEEKLY  = "WEEKLY"
+	RRULE_FREQ_MONTHLY = "MONTHLY"
+	RRULE_FREQ_YEARLY  = "YEARLY"
+)
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// A subset of the RFC 5545 recurrence rule. Supported are the rule parts FREQ,
+// INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS and WKST=MO,
+// plus EXDATE properties. The time of day is always taken from the first
+// event of the series.
+type rrule struct {
+	freq     string
+	interval int
+	// 0 if the series has no occurrence limit.
+	count      int
+	until      *time.Time
+	byDay      []rruleByDay
+	byMonthDay []int
+	byMonth    []time.Month
+	bySetPos   []int
+	exDates    []rruleExDate
+}
+
+type rruleByDay struct {
+	weekday time.Weekday
+	// n-th occurrence of the weekday within the month. Negative values count
+	// from the end of the month. 0 matches every occurrence.
+	ordinal int
+}
+
+type rruleExDate struct {
+	at time.Time
+	// Excludes every occurrence on the date of `at`.
+	dateOnly bool
+}
+
+// Parses a recurrence rule such as 'FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH'. The
+// rule may also be given as properties ('RRULE:...' and 'EXDATE:...')
+// separated by whitespace or line breaks. Times without a 'Z' suffix are
+// interpreted in `location`.
+func parseRRule(raw string, location *time.Location) (rrule, error) {
+	var rule rrule
+	hasRule := false
+
+	for _, property := range strings.Fields(raw) {
+		nameAndParams, value, found := strings.Cut(property, ":")
+		if !found {
+			nameAndParams, value = "RRULE", property
+		}
+
+		params := strings.Split(nameAndParams, ";")
+		switch strings.ToUpper(params[0]) {
+		case "RRULE":
+			if hasRule {
+				return rule, errors.New("Only one RRULE is allowed.")
+			}
+
+			err := rule.parseParts(value, location)
+			if err != nil {
+				return rule, err
+			}
+			hasRule = true
+		case "EXDATE":
+			exDates, err := parseRRuleExDates(params[1:], value, location)
+			if err != nil {
+				return rule, err
+			}
+			rule.exDates = append(rule.exDates, exDates...)
+		default:
+			return rule, fmt.Errorf("Unsupported property '%s'. Only RRULE and EXDATE are allowed.", params[0])
+		}
+	}
+
+	if !hasRule {
+		return rule, errors.New("Missing RRULE.")
+	}
+
+	return rule, rule.validate()
+}
+
+func (r *rrule) parseParts(value string, location *time.Location) error {
+	r.interval = 1
+
+	for _, part := range strings.Split(value, ";") {
+		key, partValue, found := strings.Cut(part, "=")
+		if !found || partValue == "" {
+			return fmt.Errorf("Invalid rule part '%s'.", part)
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(partValue)
+		case "INTERVAL":
+			r.interval, err = parseRRuleInt(partValue, 1, 1000)
+		case "COUNT":
+			r.count, err = parseRRuleInt(partValue, 1, 1000)
+		case "UNTIL":
+			var until time.Time
+			until, _, err = parseRRuleTime(partValue, location)
+			r.until = &until
+			if len(partValue) == len("20060102") {
+				// a date includes the whole day
+				endOfDay := until.AddDate(0, 0, 1).Add(-time.Second)
+				r.until = &endOfDay
+			}
+		case "BYDAY":
+			r.byDay, err = parseRRuleByDay(partValue)
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseRRuleIntList(partValue, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseRRuleIntList(partValue, 12)
+			for _, month := range months {
+				if month < 0 {
+					return fmt.Errorf("Invalid BYMONTH '%s'.", partValue)
+				}
+				r.byMonth = append(r.byMonth, time.Month(month))
+			}
+		case "BYSETPOS":
+			r.bySetPos, err = parseRRuleIntList(partValue, 366)
+		case "WKST":
+			if strings.ToUpper(partValue) != "MO" {
+				err = errors.New("Only WKST=MO is supported.")
+			}
+		default:
+			err = fmt.Errorf("Unsupported rule part '%s'.", key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *rrule) validate() error {
+	switch r.freq {
+	case RRULE_FREQ_DAILY, RRULE_FREQ_WEEKLY, RRULE_FREQ_MONTHLY, RRULE_FREQ_YEARLY:
+	case "":
+		return errors.New("Missing FREQ.")
+	default:
+		return fmt.Errorf("Unsupported FREQ '%s'.", r.freq)
+	}
+
+	if r.count != 0 && r.until != nil {
+		return errors.New("COUNT and UNTIL can't be used together.")
+	}
+
+	hasOrdinal := slices.ContainsFunc(r.byDay, func(day rruleByDay) bool {
+		return day.ordinal != 0
+	})
+	if hasOrdinal && (r.freq == RRULE_FREQ_DAILY || r.freq == RRULE_FREQ_WEEKLY) {
+		return errors.New("BYDAY with an ordinal (e.g. 1MO) requires FREQ=MONTHLY or FREQ=YEARLY.")
+	}
+
+	if len(r.byMonthDay) > 0 && r.freq == RRULE_FREQ_WEEKLY {
+		return errors.New("BYMONTHDAY can't be used with FREQ=WEEKLY.")
+	}
+
+	if len(r.byDay) > 0 && r.freq == RRULE_FREQ_YEARLY && len(r.byMonth) == 0 {
+		return errors.New("BYDAY with FREQ=YEARLY requires BYMONTH.")
+	}
+
+	if len(r.bySetPos) > 0 && len(r.byDay) == 0 && len(r.byMonthDay) == 0 && len(r.byMonth) == 0 {
+		return errors.New("BYSETPOS requires another BY rule part.")
+	}
+
+	return nil
+}
+
+// Formats the rule parts in their canonical form, e.g. for an iCalendar
+// 'RRULE' property. UNTIL is always given in UTC. EXDATEs aren't included.
+func (r *rrule) format() string {
+	parts := []string{"FREQ=" + r.freq}
+	if r.interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.interval))
+	}
+
+	if r.count != 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.count))
+	}
+
+	if r.until != nil {
+		parts = append(parts, "UNTIL="+r.until.UTC().Format("20060102T150405Z"))
+	}
+
+	if len(r.byMonth) > 0 {
+		months := make([]string, len(r.byMonth))
+		for idx, month := range r.byMonth {
+			months[idx] = strconv.Itoa(int(month))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+
+	if len(r.byMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.byMonthDay))
+	}
+
+	if len(r.byDay) > 0 {
+		days := make([]string, len(r.byDay))
+		for idx, day := range r.byDay {
+			days[idx] = strings.ToUpper(day.weekday.String()[:2])
+			if day.ordinal != 0 {
+				days[idx] = strconv.Itoa(day.ordinal) + days[idx]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if len(r.bySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.bySetPos))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Summary of the rule in terms of the basic schedule units. Used for clients
+// that don't understand recurrence rules.
+func (r *rrule) basicSchedule() (unit string, interval int64, weekdays []string) {
+	switch r.freq {
+	case RRULE_FREQ_DAILY:
+		return SCHEDULE_UNIT_DAYS, int64(r.interval), nil
+	case RRULE_FREQ_WEEKLY:
+		if len(r.byDay) == 0 {
+			return SCHEDULE_UNIT_WEEKS, int64(r.interval), nil
+		}
+
+		weekdays = make([]string, 0, len(r.byDay))
+		for _, day := range r.byDay {
+			weekdays = append(weekdays, strings.ToLower(day.weekday.String()))
+		}
+		return SCHEDULE_UNIT_WEEKDAYS, int64(r.interval), weekdays
+	case RRULE_FREQ_MONTHLY:
+		return SCHEDULE_UNIT_MONTHS, int64(r.interval), nil
+	default:
+		return SCHEDULE_UNIT_YEARS, int64(r.interval), nil
+	}
+}
+
+// Converts one of the basic schedule units into an equivalent rule for a
+// series that starts at `anchor`. Months without the day of the anchor fall
+// back to their last day, e.g. a monthly series starting on Jan 31 continues on
+// Feb 28 and Mar 31.
+func basicScheduleToRRule(anchor time.Time, unit string, interval int64, weekdays []string) (string, error) {
+	if interval <= 0 {
+		return "", fmt.Errorf("Invalid schedule interval %d.", interval)
+	}
+
+	rule := fmt.Sprintf("INTERVAL=%d", interval)
+
+	switch unit {
+	case SCHEDULE_UNIT_DAYS:
+		return "FREQ=DAILY;" + rule, nil
+	case SCHEDULE_UNIT_WEEKS:
+		return "FREQ=WEEKLY;" + rule, nil
+	case SCHEDULE_UNIT_WEEKDAYS:
+		if len(weekdays) == 0 {
+			return "", errors.New("Invalid weekdays schedule, no weekdays provided.")
+		}
+
+		days := make([]string, len(weekdays))
+		for idx, day := range weekdays {
+			dayInt, err := weekdayToInt(day)
+			if err != nil {
+				return "", err
+			}
+			days[idx] = strings.ToUpper(time.Weekday(dayInt).String()[:2])
+		}
+
+		return "FREQ=WEEKLY;" + rule + ";BYDAY=" + strings.Join(days, ","), nil
+	case SCHEDULE_UNIT_MONTHS:
+		rule = "FREQ=MONTHLY;" + rule
+		if anchor.Day() > 28 {
+			rule += ";BYMONTHDAY=" + monthDaysFrom28(anchor.Day()) + ";BYSETPOS=-1"
+		}
+		return rule, nil
+	case SCHEDULE_UNIT_YEARS:
+		rule = "FREQ=YEARLY;" + rule
+		if anchor.Month() == time.February && anchor.Day() == 29 {
+			rule += ";BYMONTH=2;BYMONTHDAY=28,29;BYSETPOS=-1"
+		}
+		return rule, nil
+	default:
+		return "", fmt.Errorf("Invalid schedule unit '%s'", unit)
+	}
+}
+
+func monthDaysFrom28(day int) string {
+	days := make([]string, 0, 4)
+	for d := 28; d <= day; d++ {
+		days = append(days, strconv.Itoa(d))
+	}
+
+	return strings.Join(days, ",")
+}
+
+func joinInts(values []int) string {
+	strs := make([]string, len(values))
+	for idx, value := range values {
+		strs[idx] = strconv.Itoa(value)
+	}
+
+	return strings.Join(strs, ",")
+}
+
+func parseRRuleInt(value string, minValue int, maxValue int) (int, error) {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < minValue || parsed > maxValue {
+		return 0, fmt.Errorf("Invalid number '%s', must be between %d and %d.", value, minValue, maxValue)
+	}
+
+	return parsed, nil
+}
+
+// Parses a comma separated list of non-zero integers within [-limit, limit].
+func parseRRuleIntList(value string, limit int) ([]int, error) {
+	values := make([]int, 0)
+	for _, item := range strings.Split(value, ",") {
+		parsed, err := strconv.Atoi(item)
+		if err != nil || parsed == 0 || parsed < -limit || parsed > limit {
+			return nil, fmt.Errorf("Invalid value '%s' in list '%s'.", item, value)
+		}
+
+		values = append(values, parsed)
+	}
+
+	return values, nil
+}
+
+// Parses values like 'MO', '1MO' or '-1FR'.
+func parseRRuleByDay(value string) ([]rruleByDay, error) {
+	days := make([]rruleByDay, 0)
+	for _, item := range strings.Split(strings.ToUpper(value), ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("Invalid BYDAY value '%s'.", item)
+		}
+
+		weekday, ok := rruleWeekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("Invalid BYDAY value '%s'.", item)
+		}
+
+		ordinal := 0
+		if len(item) > 2 {
+			var err error
+			ordinal, err = strconv.Atoi(item[:len(item)-2])
+			if err != nil || ordinal == 0 || ordinal < -5 || ordinal > 5 {
+				return nil, fmt.Errorf("Invalid BYDAY value '%s'.", item)
+			}
+		}
+
+		days = append(days, rruleByDay{weekday: weekday, ordinal: ordinal})
+	}
+
+	return days, nil
+}
+
+func parseRRuleExDates(params []string, value string, location *time.Location) ([]rruleExDate, error) {
+	for _, param := range params {
+		key, paramValue, _ := strings.Cut(param, "=")
+		switch strings.ToUpper(key) {
+		case "TZID":
+			var err error
+			location, err = time.LoadLocation(paramValue)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid EXDATE time zone '%s'.", paramValue)
+			}
+		case "VALUE":
+			// DATE and DATE-TIME are told apart by their format
+		default:
+			return nil, fmt.Errorf("Unsupported EXDATE parameter '%s'.", key)
+		}
+	}
+
+	exDates := make([]rruleExDate, 0)
+	for _, item := range strings.Split(value, ",") {
+		at, dateOnly, err := parseRRuleTime(item, location)
+		if err != nil {
+			return nil, err
+		}
+
+		exDates = append(exDates, rruleExDate{at: at, dateOnly: dateOnly})
+	}
+
+	return exDates, nil
+}
+
+// Parses the RFC 5545 forms '20060102T150405Z' (UTC), '20060102T150405' (in
+// `location`) and '20060102' (a date in `location`).
+func parseRRuleTime(value string, location *time.Location) (time.Time, bool, error) {
+	if strings.HasSuffix(value, "Z") {
+		parsed, err := time.Parse("20060102T150405Z", value)
+		if err == nil {
+			return parsed, false, nil
+		}
+	}
+
+	parsed, err := time.ParseInLocation("20060102T150405", value, location)
+	if err == nil {
+		return parsed, false, nil
+	}
+
+	parsed, err = time.ParseInLocation("20060102", value, location)
+	if err == nil {
+		return parsed, true, nil
+	}
+
+	return time.Time{}, false, fmt.Errorf("Invalid date '%s'. Use the format 'YYYYMMDD' or 'YYYYMMDDTHHMMSSZ'.", value)
+}
@@ -0,0 +1,233 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"ride_sharing_api/app/sqlc"
+	"time"
+)
+
+const (
+	SCHEDULER_TICK_DEFAULT      = time.Minute
+	SCHEDULER_LOOKAHEAD_DEFAULT = 4
+	// Maximum number of missed occurrences per ride that are backfilled in one
+	// run.
+	SCHEDULER_BACKFILL_MAX = 100
+)
+
+type SchedulerConfig struct {
+	// Time between two runs of the scheduler.
+	Tick time.Duration
+	// Number of upcoming events that are created ahead of time for every
+	// recurring ride.
+	Lookahead int64
+}
+
+// Marks past ride events as done and creates the upcoming events of recurring
+// rides in the background.
+type Scheduler struct {
+	db      *sql.DB
+	queries *sqlc.Queries
+	config  SchedulerConfig
+}
+
+func NewScheduler(db *sql.DB, config SchedulerConfig) *Scheduler {
+	return &Scheduler{db: db, queries: sqlc.New(db), config: config}
+}
+
+// Runs the scheduler once immediately and then every tick until `ctx` is
+// canceled. Blocks until the scheduler has stopped.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Tick)
+	defer ticker.Stop()
+
+	log.Println("Starting scheduler.", "tick:", s.config.Tick, "lookahead:", s.config.Lookahead)
+
+	for {
+		err := s.Tick(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Println("Error: Scheduler run failed.", "error:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping scheduler.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Marks all events before `now` as done and tops up every ride schedule to
+// `Lookahead` events after `now`. Running it more than once for the same `now`
+// doesn't create any additional events.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queriesTx := s.queries.WithTx(tx)
+	nowStr := now.UTC().Format(time.RFC3339)
+
+	err = queriesTx.RidesMarkPastEventsDone(ctx, nowStr)
+	if err != nil {
+		return fmt.Errorf("Failed to mark past ride events as done. %w", err)
+	}
+
+	schedules, err := queriesTx.RidesGetSchedulesWithLastEvent(ctx, nowStr)
+	if err != nil {
+		return fmt.Errorf("Failed to get ride schedules. %w", err)
+	}
+
+	created := make([]string, 0)
+	for _, schedule := range schedules {
+		// A broken schedule must not stop the other rides from advancing. Its
+		// events are rolled back to the savepoint, so it's never left half
+		// advanced.
+		_, err = tx.ExecContext(ctx, "SAVEPOINT schedule")
+		if err != nil {
+			return err
+		}
+
+		ids, err := s.createScheduledEvents(ctx, queriesTx, schedule, now)
+		if err != nil {
+			log.Println("Error: Failed to create scheduled ride events.", "ride:", schedule.RideID, "error:", err)
+			_, err = tx.ExecContext(ctx, "ROLLBACK TO schedule")
+			if err != nil {
+				return err
+			}
+			ids = nil
+		}
+
+		_, err = tx.ExecContext(ctx, "RELEASE schedule")
+		if err != nil {
+			return err
+		}
+		created = append(created, ids...)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range created {
+		publishRideUpdate(ctx, s.queries, RIDE_EVENT_CREATED, "", id)
+	}
+
+	return nil
+}
+
+// Returns the ids of the upcoming events that were created, events that are
+// backfilled as done aren't included.
+func (s *Scheduler) createScheduledEvents(ctx context.Context, queriesTx *sqlc.Queries, schedule sqlc.RidesGetSchedulesWithLastEventRow, now time.Time) ([]string, error) {
+	missing := s.config.Lookahead - schedule.FutureEvents
+	if missing <= 0 {
+		return nil, nil
+	}
+
+	anchor, err := time.Parse(time.RFC3339, schedule.Anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	rideSchedule := sqlc.RideSchedule{
+		ID:               schedule.ID,
+		RideID:           schedule.RideID,
+		ScheduleInterval: schedule.ScheduleInterval,
+		Unit:             schedule.Unit,
+		Timezone:         schedule.Timezone,
+		Rrule:            schedule.Rrule,
+	}
+
+	rule, err := loadRecurrence(ctx, queriesTx, rideSchedule, anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	// The series continues after the occurrence of its newest event, even if
+	// that event was moved to another time.
+	last, err := time.Parse(time.RFC3339, schedule.LastOccurrenceAt.String)
+	if err != nil {
+		return nil, err
+	}
+
+	// Events take place at their occurrence, moved by the offset of edits to
+	// the following events of the series.
+	location := rule.anchor.Location()
+	at := func(occurrence time.Time) time.Time {
+		return shiftOccurrence(occurrence, schedule.OccurrenceOffset, location)
+	}
+
+	occurrences := rule.occurrencesAfter(last)
+	next, ok := occurrences.next()
+
+	// Occurrences that were missed while the scheduler wasn't running are
+	// backfilled as done, so the history of the series has no gaps. Anything
+	// past the backfill limit is skipped.
+	backfilled := 0
+	for ok && !at(next).After(now) && backfilled < SCHEDULER_BACKFILL_MAX {
+		_, err = createEvent(ctx, queriesTx, schedule, next, at(next), RIDE_STATUS_DONE)
+		if err != nil {
+			return nil, err
+		}
+
+		backfilled++
+		next, ok = occurrences.next()
+	}
+
+	if ok && !at(next).After(now) {
+		log.Println("Skipping missed occurrences of ride past the backfill limit.", "ride:", schedule.RideID, "from:", next, "to:", now)
+		occurrences = rule.occurrencesAfter(now)
+		next, ok = occurrences.next()
+	}
+
+	// Series with COUNT or UNTIL simply run out of occurrences.
+	created := make([]string, 0, missing)
+	for i := int64(0); ok && i < missing; i++ {
+		id, err := createEvent(ctx, queriesTx, schedule, next, at(next), RIDE_STATUS_UPCOMING)
+		if err != nil {
+			return created, err
+		}
+
+		created = append(created, id)
+		next, ok = occurrences.next()
+	}
+
+	return created, nil
+}
+
+// Returns the recurrence of a ride series that starts at `anchor`. Schedules
+// created with a recurrence rule use it directly, older schedules are
+// converted from their unit and interval.
+func loadRecurrence(ctx context.Context, queries *sqlc.Queries, schedule sqlc.RideSchedule, anchor time.Time) (recurrence, error) {
+	if schedule.Rrule.Valid {
+		return newRecurrence(anchor, schedule.Rrule.String, schedule.Timezone)
+	}
+
+	weekdays, err := queries.RidesGetScheduleWeekdays(ctx, schedule.ID)
+	if err != nil {
+		return recurrence{}, err
+	}
+
+	return newRecurrenceFromUnit(anchor, schedule.Unit, schedule.ScheduleInterval, weekdays, schedule.Timezone)
+}
+
+func createEvent(ctx context.Context, queriesTx *sqlc.Queries, schedule sqlc.RidesGetSchedulesWithLastEventRow, occurrence time.Time, tackingPlaceAt time.Time, status string) (string, error) {
+	argsCreateEvent := sqlc.RidesCreateEventParams{
+		RideID:         schedule.RideID,
+		LocationFrom:   schedule.LocationFrom,
+		LocationTo:     schedule.LocationTo,
+		TransportLimit: schedule.TransportLimit,
+		Driver:         schedule.Driver,
+		Status:         status,
+		TackingPlaceAt: tackingPlaceAt.UTC().Format(time.RFC3339),
+		OccurrenceAt:   sql.NullString{String: occurrence.UTC().Format(time.RFC3339), Valid: true},
+	}
+
+	return queriesTx.RidesCreateEvent(ctx, argsCreateEvent)
+}
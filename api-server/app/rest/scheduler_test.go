@@ -0,0 +1,260 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/utils"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSchedulerCreateEvents(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0011-scheduler-create-events.sql"))
+	handler := rest.NewRESTApi(db)
+	scheduler := rest.NewScheduler(db, rest.SchedulerConfig{Tick: time.Hour, Lookahead: 3})
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	// Returns the events with `status` as '{rideId} {tackingPlaceAt}', sorted by time.
+	getEvents := func(status string) []string {
+		req, err := http.NewRequest("GET", api.URL+"/rides/many?limit=100&status="+status, bytes.NewReader([]byte{}))
+		assert.Nil(err)
+		req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 200)
+		data, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		var page rest.Page[rest.RideEventData]
+		err = json.Unmarshal(data, &page)
+		assert.Nil(err)
+
+		events := make([]string, len(page.Items))
+		for idx, ride := range page.Items {
+			events[idx] = fmt.Sprintf("%s %s", ride.RideId, ride.TackingPlaceAt.UTC().Format(time.DateTime))
+		}
+		slices.SortFunc(events, func(a string, b string) int {
+			return strings.Compare(a[strings.Index(a, " "):], b[strings.Index(b, " "):])
+		})
+
+		return events
+	}
+
+	tick := func(now string) {
+		parsed, err := time.Parse(time.RFC3339, now)
+		assert.Nil(err)
+		err = scheduler.Tick(context.Background(), parsed)
+		assert.Nil(err)
+	}
+
+	tick("2044-11-27T00:00:00Z")
+	assert.Eq(strings.Join(getEvents("done"), ", "), "once 2044-11-20 10:00:00, weekdays 2044-11-25 08:00:00, weekly 2044-11-26 15:00:00")
+	assert.Eq(strings.Join(getEvents("upcoming"), ", "), "weekdays 2044-11-28 08:00:00, weekdays 2044-11-30 08:00:00, weekly 2044-12-03 15:00:00, weekdays 2044-12-05 08:00:00, weekly 2044-12-10 15:00:00, weekly 2044-12-17 15:00:00")
+
+	// Running again for the same time doesn't change anything
+	tick("2044-11-27T00:00:00Z")
+	assert.Eq(len(getEvents("upcoming")), 6)
+
+	// Only the passed event is replaced
+	tick("2044-12-04T00:00:00Z")
+	upcoming := getEvents("upcoming")
+	assert.Eq(len(upcoming), 6)
+	assert.True(slices.Contains(upcoming, "weekly 2044-12-24 15:00:00"))
+	assert.True(slices.Contains(upcoming, "weekdays 2044-12-12 08:00:00"))
+	assert.False(slices.Contains(upcoming, "weekly 2044-12-03 15:00:00"))
+
+	// Occurrences missed while the scheduler wasn't running are backfilled as done
+	tick("2045-01-20T00:00:00Z")
+	done := getEvents("done")
+	assert.True(slices.Contains(done, "weekly 2044-12-31 15:00:00"))
+	assert.True(slices.Contains(done, "weekly 2045-01-07 15:00:00"))
+	assert.True(slices.Contains(done, "weekly 2045-01-14 15:00:00"))
+
+	upcoming = slices.DeleteFunc(getEvents("upcoming"), func(event string) bool {
+		return !strings.HasPrefix(event, "weekly ")
+	})
+	assert.Eq(strings.Join(upcoming, ", "), "weekly 2045-01-21 15:00:00, weekly 2045-01-28 15:00:00, weekly 2045-02-04 15:00:00")
+}
+
+func TestSchedulerPartialFailure(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0037-scheduler-partial-failure.sql"))
+	scheduler := rest.NewScheduler(db, rest.SchedulerConfig{Tick: time.Hour, Lookahead: 3})
+
+	countEvents := func(rideId string) int {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM ride_events WHERE ride_id = ? AND status = 'upcoming'", rideId).Scan(&count)
+		assert.Nil(err)
+		return count
+	}
+
+	now, err := time.Parse(time.RFC3339, "2044-11-27T00:00:00Z")
+	assert.Nil(err)
+
+	// The events of the failing schedule are rolled back, the other schedules advance
+	err = scheduler.Tick(context.Background(), now)
+	assert.Nil(err)
+	assert.Eq(countEvents("broken"), 0)
+	assert.Eq(countEvents("working"), 3)
+
+	_, err = db.Exec("DROP TRIGGER fail_broken_ride")
+	assert.Nil(err)
+	err = scheduler.Tick(context.Background(), now)
+	assert.Nil(err)
+	assert.Eq(countEvents("broken"), 3)
+	assert.Eq(countEvents("working"), 3)
+}
+
+func TestSchedulerMovedEvent(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0038-scheduler-moved-event.sql"))
+	handler := rest.NewRESTApi(db)
+	scheduler := rest.NewScheduler(db, rest.SchedulerConfig{Tick: time.Hour, Lookahead: 3})
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	tick := func(now string) {
+		parsed, err := time.Parse(time.RFC3339, now)
+		assert.Nil(err)
+		err = scheduler.Tick(context.Background(), parsed)
+		assert.Nil(err)
+	}
+
+	upcoming := func() []string {
+		rows, err := db.Query("SELECT tacking_place_at FROM ride_events WHERE ride_id = 'weekly' AND status = 'upcoming' ORDER BY tacking_place_at")
+		assert.Nil(err)
+		defer rows.Close()
+
+		events := make([]string, 0)
+		for rows.Next() {
+			var tackingPlaceAt string
+			err = rows.Scan(&tackingPlaceAt)
+			assert.Nil(err)
+			events = append(events, tackingPlaceAt)
+		}
+		return events
+	}
+
+	move := func(tackingPlaceAt string, to string, scope string) {
+		var eventId string
+		err := db.QueryRow("SELECT id FROM ride_events WHERE ride_id = 'weekly' AND tacking_place_at = ?", tackingPlaceAt).Scan(&eventId)
+		assert.Nil(err)
+
+		req, err := http.NewRequest("POST", api.URL+"/rides/update", strings.NewReader(fmt.Sprintf(`{ "rideEventId": "%s", "tackingPlaceAt": "%s", "scope": "%s" }`, eventId, to, scope)))
+		assert.Nil(err)
+		req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		assert.Eq(resp.StatusCode, 200)
+	}
+
+	tick("2044-11-27T00:00:00Z")
+	assert.Eq(strings.Join(upcoming(), ", "), "2044-12-03T15:00:00Z, 2044-12-10T15:00:00Z, 2044-12-17T15:00:00Z")
+
+	// Moving the newest event earlier doesn't bring back its occurrence
+	move("2044-12-17T15:00:00Z", "2044-12-16T15:00:00Z", "single")
+	tick("2044-12-04T00:00:00Z")
+	assert.Eq(strings.Join(upcoming(), ", "), "2044-12-10T15:00:00Z, 2044-12-16T15:00:00Z, 2044-12-24T15:00:00Z")
+
+	// Moving the following events moves the events created later, but the
+	// series still starts and ends with the same occurrences
+	move("2044-12-10T15:00:00Z", "2044-12-10T17:00:00Z", "following")
+	assert.Eq(strings.Join(upcoming(), ", "), "2044-12-10T17:00:00Z, 2044-12-17T17:00:00Z, 2044-12-24T17:00:00Z")
+
+	var anchor string
+	err := db.QueryRow("SELECT tacking_place_at FROM rides WHERE id = 'weekly'").Scan(&anchor)
+	assert.Nil(err)
+	assert.Eq(anchor, "2044-11-26T15:00:00Z")
+
+	tick("2044-12-11T00:00:00Z")
+	assert.Eq(strings.Join(upcoming(), ", "), "2044-12-17T17:00:00Z, 2044-12-24T17:00:00Z, 2044-12-31T17:00:00Z")
+	tick("2044-12-25T00:00:00Z")
+	assert.Eq(strings.Join(upcoming(), ", "), "2044-12-31T17:00:00Z")
+}
+
+func TestSchedulerRRule(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0012-scheduler-rrule.sql"))
+	handler := rest.NewRESTApi(db)
+	scheduler := rest.NewScheduler(db, rest.SchedulerConfig{Tick: time.Hour, Lookahead: 10})
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	createRide := func(schedule string) *http.Response {
+		req, err := http.NewRequest("POST", api.URL+"/rides", bytes.NewReader([]byte(fmt.Sprintf(`{
+			"locationTo": "Wien",
+			"locationFrom": "Graz",
+			"tackingPlaceAt": "2044-11-01T07:30:00+01:00",
+			"driver": "NnCaPHQLC9",
+			"transportLimit": 4,
+			"schedule": %s
+		}`, schedule))))
+		assert.Nil(err)
+		req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+		resp, err := api.Client().Do(req)
+		assert.Nil(err)
+		return resp
+	}
+
+	// Invalid rule
+	resp := createRide(`{ "rrule": "FREQ=HOURLY", "timezone": "Europe/Vienna" }`)
+	assert.Eq(resp.StatusCode, 400)
+	data, err := io.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.True(strings.Contains(string(data), "Unsupported FREQ"), "Invalid response body", string(data))
+
+	// Rule that never matches
+	resp = createRide(`{ "rrule": "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "timezone": "Europe/Vienna" }`)
+	assert.Eq(resp.StatusCode, 400)
+
+	// Every first tuesday of the month, four times including the first ride, except in december
+	resp = createRide(`{ "rrule": "RRULE:FREQ=MONTHLY;BYDAY=1TU;COUNT=4\nEXDATE;TZID=Europe/Vienna:20441206T073000", "timezone": "Europe/Vienna" }`)
+	assert.Eq(resp.StatusCode, 201)
+	data, err = io.ReadAll(resp.Body)
+	assert.Nil(err)
+	var created map[string]string
+	err = json.Unmarshal(data, &created)
+	assert.Nil(err)
+
+	now, err := time.Parse(time.RFC3339, "2044-11-02T00:00:00Z")
+	assert.Nil(err)
+	err = scheduler.Tick(context.Background(), now)
+	assert.Nil(err)
+
+	req, err := http.NewRequest("GET", api.URL+"/rides/many?limit=100&status=upcoming", bytes.NewReader([]byte{}))
+	assert.Nil(err)
+	req.Header.Add("Authorization", "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q")
+	resp, err = api.Client().Do(req)
+	assert.Nil(err)
+	assert.Eq(resp.StatusCode, 200)
+	data, err = io.ReadAll(resp.Body)
+	assert.Nil(err)
+	var page rest.Page[rest.RideEventData]
+	err = json.Unmarshal(data, &page)
+	assert.Nil(err)
+
+	events := make([]string, len(page.Items))
+	for idx, ride := range page.Items {
+		events[idx] = ride.TackingPlaceAt.UTC().Format(time.DateTime)
+	}
+	slices.Sort(events)
+	assert.Eq(strings.Join(events, ", "), "2045-01-03 06:30:00, 2045-02-07 06:30:00")
+
+	ride := page.Items[0]
+	assert.Eq(ride.RideId, created["rideId"])
+	assert.Eq(*ride.Schedule.Unit, "months")
+	assert.Eq(*ride.Schedule.Interval, int64(1))
+	assert.Eq(*ride.Schedule.RRule, "RRULE:FREQ=MONTHLY;BYDAY=1TU;COUNT=4\nEXDATE;TZID=Europe/Vienna:20441206T073000")
+}
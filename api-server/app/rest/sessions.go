@@ -0,0 +1,268 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/sqlc"
+	"strings"
+	"sync"
+)
+
+// Every sign in creates a session, so users can be signed in on several
+// devices at once. Each session has its own access and refresh token.
+
+func sessionHandlers(h *http.ServeMux) {
+	h.HandleFunc("POST /auth/logout", handle(logout).with(bearerAuth(true)).build())
+	h.HandleFunc("GET /auth/sessions", handle(getSessions).with(bearerAuth(false)).build())
+	h.HandleFunc("DELETE /auth/sessions", handle(deleteAllSessions).with(bearerAuth(false)).build())
+	h.HandleFunc("DELETE /auth/sessions/{id}", handle(deleteSession).with(bearerAuth(false)).build())
+}
+
+type SessionData struct {
+	SessionId  string `json:"sessionId"`
+	Device     string `json:"device"`
+	Ip         string `json:"ip"`
+	CreatedAt  string `json:"createdAt"`
+	LastUsedAt string `json:"lastUsedAt"`
+	// Set for the session the request was made with.
+	Current bool `json:"current"`
+}
+
+// Locks per key. Used to refresh the tokens of a session without blocking
+// the refreshes of other sessions.
+type keyedMutex struct {
+	mutex sync.Mutex
+	locks map[string]*keyedMutexEntry
+}
+
+type keyedMutexEntry struct {
+	mutex sync.Mutex
+	// Number of callers holding or waiting for the lock. The entry is removed
+	// once nobody needs it anymore.
+	users int
+}
+
+// Blocks until the lock for `key` is acquired. Returns the function that
+// releases it.
+func (k *keyedMutex) lock(key string) func() {
+	k.mutex.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedMutexEntry)
+	}
+	entry, ok := k.locks[key]
+	if !ok {
+		entry = &keyedMutexEntry{}
+		k.locks[key] = entry
+	}
+	entry.users++
+	k.mutex.Unlock()
+
+	entry.mutex.Lock()
+
+	return func() {
+		entry.mutex.Unlock()
+
+		k.mutex.Lock()
+		entry.users--
+		if entry.users == 0 {
+			delete(k.locks, key)
+		}
+		k.mutex.Unlock()
+	}
+}
+
+// Creates a new session for the user on the device the request was made
+// from.
+func createSession(r *http.Request, user sqlc.User) (authTokens, error) {
+	return NewSession(r.Context(), state.queries, state.authKeys, user.ID, user.Email, deviceLabel(r.UserAgent()), clientIp(r))
+}
+
+// Creates a session with new tokens for the user. Only hashes of the tokens
+// are stored, the tokens themselves are only returned.
+func NewSession(ctx context.Context, queries *sqlc.Queries, keys *AuthKeys, userId string, email string, device string, ip string) (authTokens, error) {
+	tokens := GenAuthTokens(ctx, keys, userId, email)
+
+	args := sqlc.SessionsCreateParams{
+		UserID:           userId,
+		AccessTokenHash:  hashToken(tokens.AccessToken),
+		RefreshTokenHash: hashToken(tokens.RefreshToken),
+		Device:           device,
+		Ip:               ip,
+	}
+	_, err := queries.SessionsCreate(ctx, args)
+	return tokens, err
+}
+
+func getSessions(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+	current := getMiddlewareData[sqlc.Session](r, "session")
+
+	rows, err := state.queries.SessionsGetByUser(r.Context(), user.ID)
+	assert.Nil(err)
+
+	sessions := make([]SessionData, len(rows))
+	for idx, row := range rows {
+		sessions[idx] = SessionData{
+			SessionId:  row.ID,
+			Device:     row.Device,
+			Ip:         row.Ip,
+			CreatedAt:  row.CreatedAt,
+			LastUsedAt: row.LastUsedAt,
+			Current:    row.ID == current.ID,
+		}
+	}
+
+	resp, err := json.Marshal(sessions)
+	assert.Nil(err, "Failed to serialize sessions.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+// Signs out the device of the session. Deleting the current session signs
+// out the user.
+func deleteSession(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	id := r.PathValue("id")
+	if id == "" {
+		httpWriteErr(w, http.StatusBadRequest, "Must provide 'id' path parameter.")
+		return
+	}
+
+	// Locking so a refresh that is in progress can't hand out new tokens
+	unlock := state.sessionLocks.lock(id)
+	defer unlock()
+
+	args := sqlc.SessionsDeleteParams{
+		ID:     id,
+		UserID: user.ID,
+	}
+	deleted, err := state.queries.SessionsDelete(r.Context(), args)
+	assert.Nil(err)
+
+	if deleted == 0 {
+		httpWriteErr(w, http.StatusNotFound, "No session exists with 'id'.")
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+// Signs out the device the request was made from. The access and refresh
+// token of the session can't be used anymore. Expired access tokens are
+// accepted, so clients can sign out without refreshing first.
+func logout(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+	session := getMiddlewareData[sqlc.Session](r, "session")
+
+	unlock := state.sessionLocks.lock(session.ID)
+	defer unlock()
+
+	args := sqlc.SessionsDeleteParams{
+		ID:     session.ID,
+		UserID: user.ID,
+	}
+	_, err := state.queries.SessionsDelete(r.Context(), args)
+	assert.Nil(err)
+
+	w.WriteHeader(200)
+}
+
+// Signs out the user on every device, including the current one.
+func deleteAllSessions(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	err := state.queries.SessionsDeleteByUser(r.Context(), user.ID)
+	assert.Nil(err)
+
+	w.WriteHeader(200)
+}
+
+// Looks up the session of an access token. Returns false if the token
+// doesn't belong to a session of the user.
+func sessionByAccessToken(ctx context.Context, token string, userId string) (sqlc.Session, bool) {
+	session, err := state.queries.SessionsGetByAccessTokenHash(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.Session{}, false
+	}
+	assert.Nil(err)
+
+	return session, session.UserID == userId
+}
+
+// Records that the session was just used from the IP of the request.
+func touchSession(r *http.Request, session sqlc.Session) {
+	args := sqlc.SessionsTouchParams{
+		Ip: clientIp(r),
+		ID: session.ID,
+	}
+	err := state.queries.SessionsTouch(r.Context(), args)
+	if err != nil {
+		log.Println("Error: Failed to update session.", "session id:", session.ID, "error:", err)
+	}
+}
+
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// A short name for the device a user agent belongs to, e.g. 'Firefox on
+// Linux'. Only meant to help users recognize their sessions.
+func deviceLabel(userAgent string) string {
+	browsers := []struct{ marker, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	systems := []struct{ marker, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+
+	browser := ""
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.marker) {
+			browser = b.name
+			break
+		}
+	}
+
+	system := ""
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.marker) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	case len(userAgent) > 64:
+		return userAgent[:64]
+	default:
+		return userAgent
+	}
+}
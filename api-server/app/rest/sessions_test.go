@@ -0,0 +1,296 @@
+package rest_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"ride_sharing_api/app/assert"
+	"ride_sharing_api/app/rest"
+	"ride_sharing_api/app/sqlc"
+	"ride_sharing_api/app/utils"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandleSessions(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0021-sessions.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	useFakeMicrosoftIdp(t, api.URL, map[string]string{
+		"alice": `{ "id": "entra-user-1", "displayName": "Alice", "mail": "alice@example.com", "userPrincipalName": "alice@example.com" }`,
+	})
+
+	tokenUser01 := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+
+	client := noRedirectClient(api)
+	request := func(method string, endpoint string, token string, body string) *http.Response {
+		req, err := http.NewRequest(method, api.URL+endpoint, strings.NewReader(body))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := client.Do(req)
+		assert.Nil(err)
+		return resp
+	}
+
+	type tokens struct {
+		AccessToken  string `json:"accessToken"`
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	signIn := func(userAgent string) tokens {
+		resp, err := client.Get(api.URL + "/auth/microsoft/login")
+		assert.Nil(err)
+		location, err := url.Parse(resp.Header.Get("Location"))
+		assert.Nil(err)
+
+		resp, err = client.Get(api.URL + "/auth/microsoft/callback?" + url.Values{"state": {location.Query().Get("state")}, "code": {"alice"}}.Encode())
+		assert.Nil(err)
+
+		// The session is created for the device the code is exchanged from
+		exchanged, status := exchangeAuthCode(client, api.URL, authCodeFromRedirect(resp), "", userAgent)
+		assert.Eq(status, 200)
+		return tokens(exchanged)
+	}
+
+	refresh := func(t tokens) (tokens, int) {
+		resp := request("POST", "/auth/refresh", t.AccessToken, `{ "refreshToken": "`+t.RefreshToken+`" }`)
+		if resp.StatusCode != 200 {
+			return tokens{}, resp.StatusCode
+		}
+
+		var refreshed tokens
+		err := json.NewDecoder(resp.Body).Decode(&refreshed)
+		assert.Nil(err)
+		return refreshed, resp.StatusCode
+	}
+
+	sessions := func(token string) []rest.SessionData {
+		resp := request("GET", "/auth/sessions", token, "")
+		assert.Eq(resp.StatusCode, 200)
+		var sessions []rest.SessionData
+		err := json.NewDecoder(resp.Body).Decode(&sessions)
+		assert.Nil(err)
+		return sessions
+	}
+
+	status := func(token string) int {
+		return request("GET", "/users/me", token, "").StatusCode
+	}
+
+	// Signing in on a second device keeps the first one signed in
+	laptop := signIn("Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0")
+	phone := signIn("Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Mobile Safari/537.36")
+	assert.Eq(status(laptop.AccessToken), 200)
+	assert.Eq(status(phone.AccessToken), 200)
+
+	// Only hashes of the tokens are stored
+	stored := func(token string) (count int) {
+		hash := sha256.Sum256([]byte(token))
+		err := db.QueryRow("SELECT COUNT(*) FROM sessions WHERE ? IN (access_token_hash, refresh_token_hash)", hex.EncodeToString(hash[:])).Scan(&count)
+		assert.Nil(err)
+		return count
+	}
+	assert.Eq(stored(laptop.AccessToken), 1)
+	assert.Eq(stored(laptop.RefreshToken), 1)
+	var plain int
+	err := db.QueryRow("SELECT COUNT(*) FROM sessions WHERE ? IN (access_token_hash, refresh_token_hash)", laptop.AccessToken).Scan(&plain)
+	assert.Nil(err)
+	assert.Eq(plain, 0)
+
+	list := sessions(phone.AccessToken)
+	assert.Eq(len(list), 2)
+	devices := map[string]rest.SessionData{}
+	for _, session := range list {
+		devices[session.Device] = session
+		assert.Eq(session.Ip, "127.0.0.1")
+	}
+	assert.True(devices["Chrome on Android"].Current)
+	assert.False(devices["Firefox on Linux"].Current)
+
+	// Refreshing only replaces the tokens of one session
+	laptopOld := laptop
+	laptop, code := refresh(laptop)
+	assert.Eq(code, 200)
+	assert.Eq(status(laptopOld.AccessToken), 401)
+	assert.Eq(status(laptop.AccessToken), 200)
+	assert.Eq(status(phone.AccessToken), 200)
+	_, code = refresh(laptopOld)
+	assert.Eq(code, 401)
+
+	// Concurrent refreshes of the same tokens only succeed once
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	succeeded := 0
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, code := refresh(phone)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if code == 200 {
+				succeeded++
+			} else {
+				assert.Eq(code, 401)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Eq(succeeded, 1)
+	phone = signIn("Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Mobile Safari/537.36")
+
+	// Signing out a device
+	laptopId := devices["Firefox on Linux"].SessionId
+	resp := request("DELETE", "/auth/sessions/"+laptopId, phone.AccessToken, "")
+	assert.Eq(resp.StatusCode, 200)
+	assert.Eq(status(laptop.AccessToken), 401)
+	assert.Eq(status(phone.AccessToken), 200)
+	resp = request("DELETE", "/auth/sessions/"+laptopId, phone.AccessToken, "")
+	assert.Eq(resp.StatusCode, 404)
+
+	// Sessions of other users can't be deleted
+	resp = request("DELETE", "/auth/sessions/session-02", tokenUser01, "")
+	assert.Eq(resp.StatusCode, 404)
+
+	// Signing out everywhere
+	assert.True(len(sessions(phone.AccessToken)) > 1)
+	resp = request("DELETE", "/auth/sessions", phone.AccessToken, "")
+	assert.Eq(resp.StatusCode, 200)
+	assert.Eq(status(phone.AccessToken), 401)
+	assert.Eq(status(tokenUser01), 200)
+}
+
+func TestHandleLogout(t *testing.T) {
+	db := utils.InitTestDB(path.Join(utils.ProjectRoot(), "db/testing/setup/0024-logout.sql"))
+	handler := rest.NewRESTApi(db)
+
+	api := httptest.NewServer(handler)
+	defer api.Close()
+
+	useFakeMicrosoftIdp(t, api.URL, map[string]string{
+		"alice": `{ "id": "entra-user-1", "displayName": "Alice", "mail": "alice@example.com", "userPrincipalName": "alice@example.com" }`,
+	})
+
+	tokenAdmin := "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+
+	client := noRedirectClient(api)
+	request := func(method string, endpoint string, token string, body string) int {
+		req, err := http.NewRequest(method, api.URL+endpoint, strings.NewReader(body))
+		assert.Nil(err)
+		req.Header.Add("Authorization", token)
+		resp, err := client.Do(req)
+		assert.Nil(err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	refresh := func(tokens testAuthTokens) int {
+		resp, err := client.Post(api.URL+"/auth/refresh", "application/json", strings.NewReader(`{ "refreshToken": "`+tokens.RefreshToken+`" }`))
+		assert.Nil(err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	protected := []struct{ method, endpoint string }{
+		{"POST", "/auth/logout"},
+		{"GET", "/auth/sessions"},
+		{"DELETE", "/auth/sessions"},
+		{"DELETE", "/auth/sessions/session-01"},
+		{"GET", "/auth/identities"},
+		{"POST", "/auth/identities/link/microsoft"},
+		{"POST", "/auth/identities/confirm-link"},
+		{"POST", "/auth/identities/by-id/google-01/unlink"},
+		{"POST", "/users/me/calendar-token"},
+		{"POST", "/users/me/calendar-token/revoke"},
+		{"GET", "/users/me"},
+		{"GET", "/users/by-id/NnCaPHQLC9"},
+		{"POST", "/users/by-id/NnCaPHQLC9/ban-status"},
+		{"GET", "/users/me/blocks"},
+		{"POST", "/users/by-id/NnCaPHQLC9/block"},
+		{"GET", "/users/me/conversations"},
+		{"POST", "/users/by-id/NnCaPHQLC9/conversation"},
+		{"POST", "/conversations/by-id/c/send-message"},
+		{"GET", "/conversations/by-id/c/messages"},
+		{"POST", "/conversations/by-id/c/chat-ticket"},
+		{"POST", "/conversations/by-id/c/read"},
+		{"GET", "/users/me/notifications"},
+		{"GET", "/users/me/notifications/events"},
+		{"POST", "/users/me/notifications/events/ticket"},
+		{"POST", "/users/me/notifications/by-id/n/read"},
+		{"POST", "/users/me/notifications/read-all"},
+		{"POST", "/groups"},
+		{"POST", "/groups/update"},
+		{"GET", "/groups/many"},
+		{"GET", "/groups/by-id/g"},
+		{"POST", "/groups/by-id/g/members/join"},
+		{"POST", "/groups/by-id/g/members/leave"},
+		{"POST", "/groups/by-id/g/members/ban"},
+		{"POST", "/groups/by-id/g/members/approve"},
+		{"POST", "/groups/by-id/g/moderators/add"},
+		{"POST", "/groups/by-id/g/moderators/remove"},
+		{"POST", "/groups/by-id/g/send-message"},
+		{"GET", "/groups/by-id/g/messages"},
+		{"POST", "/groups/by-id/g/chat-ticket"},
+		{"POST", "/groups/by-id/g/messages/by-id/m/edit"},
+		{"POST", "/groups/by-id/g/messages/by-id/m/reactions/add"},
+		{"POST", "/rides"},
+		{"POST", "/rides/update"},
+		{"POST", "/rides/join"},
+		{"POST", "/rides/leave"},
+		{"GET", "/rides/many"},
+		{"GET", "/rides/by-id/r"},
+		{"GET", "/rides/by-id/r/changes"},
+		{"POST", "/rides/events/ticket"},
+		{"POST", "/rides/by-id/r/send-message"},
+		{"GET", "/rides/by-id/r/messages"},
+		{"POST", "/rides/by-id/r/chat-ticket"},
+		{"POST", "/rides/by-id/r/messages/by-id/m/delete"},
+		{"GET", "/rides/upcoming/by-id/r"},
+	}
+
+	assertRevoked := func(tokens testAuthTokens) {
+		for _, route := range protected {
+			code := request(route.method, route.endpoint, tokens.AccessToken, `{ "groupId": "g", "rideEventId": "r", "isBanned": true }`)
+			assert.Eq(code, 401, route.method, route.endpoint)
+		}
+		assert.Eq(refresh(tokens), 401)
+	}
+
+	// Signing out only revokes the tokens of the current device
+	laptop := signInMicrosoft(client, api.URL, "alice")
+	phone := signInMicrosoft(client, api.URL, "alice")
+	assert.Eq(request("POST", "/auth/logout", laptop.AccessToken, ""), 200)
+	assertRevoked(laptop)
+	assert.Eq(request("GET", "/users/me", phone.AccessToken, ""), 200)
+
+	// Blocking a user revokes all their tokens
+	alice := getUserMe(client, api.URL, phone.AccessToken)
+	tablet := signInMicrosoft(client, api.URL, "alice")
+	_, script, err := rest.CreatePersonalToken(context.Background(), sqlc.New(db), alice.ID, "script", []string{rest.SCOPE_RIDES_READ}, time.Now().Add(time.Hour))
+	assert.Nil(err)
+	assert.Eq(request("GET", "/rides/many", script, ""), 200)
+	assert.Eq(request("POST", "/users/by-id/"+alice.ID+"/ban-status", tokenAdmin, `{ "isBanned": true }`), 200)
+	assertRevoked(phone)
+	assertRevoked(tablet)
+	assert.Eq(request("GET", "/rides/many", script, ""), 401)
+
+	// Unblocking doesn't make the old tokens valid again
+	assert.Eq(request("POST", "/users/by-id/"+alice.ID+"/ban-status", tokenAdmin, `{ "isBanned": false }`), 200)
+	assertRevoked(phone)
+	assertRevoked(tablet)
+	assert.Eq(request("GET", "/rides/many", script, ""), 401)
+	assert.Eq(request("GET", "/users/me", signInMicrosoft(client, api.URL, "alice").AccessToken, ""), 200)
+	assert.Eq(request("GET", "/users/me", tokenAdmin, ""), 200)
+}
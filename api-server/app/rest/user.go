@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -13,9 +14,18 @@ import (
 )
 
 func userHandlers(h *http.ServeMux) {
-	h.HandleFunc("GET /users/me", handle(getUserMe).with(bearerAuth(false)).build())
-	h.HandleFunc("GET /users/by-id/{id}", handle(getUserById).with(bearerAuth(false)).build())
-	h.HandleFunc("POST /users/by-id/{id}/ban-status", handle(setUserBanStatus).with(bearerAuth(false)).build())
+	h.HandleFunc("GET /users/me", handle(getUserMe).with(bearerAuth(false, allTokenScopes...)).build())
+	h.HandleFunc("GET /users/by-id/{id}", handle(getUserById).with(bearerAuth(false, allTokenScopes...)).build())
+	h.HandleFunc("POST /users/by-id/{id}/ban-status", handle(setUserBanStatus).with(bearerAuth(false)).with(requireAdmin()).build())
+	h.HandleFunc("GET /users/me/blocks", handle(getUserBlocks).with(bearerAuth(false, SCOPE_CHAT)).build())
+	h.HandleFunc("POST /users/by-id/{id}/block", handle(setUserBlockedByMe(true)).with(bearerAuth(false, SCOPE_CHAT)).build())
+	h.HandleFunc("POST /users/by-id/{id}/unblock", handle(setUserBlockedByMe(false)).with(bearerAuth(false, SCOPE_CHAT)).build())
+}
+
+type BlockedUserData struct {
+	UserId    string `json:"userId"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"createdAt"`
 }
 
 func getUserMe(w http.ResponseWriter, r *http.Request) {
@@ -95,7 +105,99 @@ func setUserBanStatus(w http.ResponseWriter, r *http.Request) {
 		ID:        id,
 	}
 
-	err = state.queries.UsersSetBlocked(r.Context(), args)
+	err = setUserBlocked(r.Context(), args)
 	assert.Nil(err)
 	w.WriteHeader(200)
 }
+
+// Blocking a user also signs them out on every device and revokes their
+// personal access tokens, so tokens handed out before can't be used anymore.
+func setUserBlocked(ctx context.Context, args sqlc.UsersSetBlockedParams) error {
+	tx, err := state.getDBTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queriesTx := state.queries.WithTx(tx)
+
+	err = queriesTx.UsersSetBlocked(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	if args.IsBlocked {
+		err = queriesTx.SessionsDeleteByUser(ctx, args.ID)
+		if err != nil {
+			return err
+		}
+
+		err = queriesTx.PersonalTokensDeleteByUser(ctx, args.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	if args.IsBlocked {
+		state.chat.disconnectUser(args.ID)
+		state.rideUpdates.disconnectUser(args.ID)
+		state.notifications.disconnectUser(args.ID)
+	}
+	return nil
+}
+
+// Returns the users the user blocked, latest first.
+func getUserBlocks(w http.ResponseWriter, r *http.Request) {
+	user := getMiddlewareData[sqlc.User](r, "user")
+
+	rows, err := state.queries.UserBlocksGet(r.Context(), user.ID)
+	assert.Nil(err)
+
+	blocks := make([]BlockedUserData, len(rows))
+	for idx, row := range rows {
+		blocks[idx] = BlockedUserData{UserId: row.BlockedUserID, Email: row.BlockedUserEmail, CreatedAt: row.CreatedAt}
+	}
+
+	resp, err := json.Marshal(blocks)
+	assert.Nil(err, "Failed to serialize blocked users.")
+	w.WriteHeader(200)
+	w.Write(resp)
+}
+
+// Users that blocked each other can't send direct messages to one another.
+// Blocking a user twice or unblocking a user that isn't blocked does nothing.
+func setUserBlockedByMe(blocked bool) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := getMiddlewareData[sqlc.User](r, "user")
+
+		other, err := state.queries.UsersGetById(r.Context(), r.PathValue("id"))
+		if errors.Is(err, sql.ErrNoRows) {
+			httpWriteErr(w, http.StatusNotFound, "No user exists with 'id'.")
+			return
+		}
+		assert.Nil(err)
+
+		if other.ID == user.ID {
+			httpWriteErr(w, http.StatusBadRequest, "You can't block yourself.")
+			return
+		}
+
+		if blocked {
+			_, err = state.queries.UserBlocksAdd(r.Context(), sqlc.UserBlocksAddParams{UserID: user.ID, BlockedUserID: other.ID})
+		} else {
+			_, err = state.queries.UserBlocksRemove(r.Context(), sqlc.UserBlocksRemoveParams{UserID: user.ID, BlockedUserID: other.ID})
+		}
+		if err != nil {
+			log.Println("Error: Failed to change blocked user.", "user id:", user.ID, "blocked user id:", other.ID, "error:", err)
+			httpWriteErr(w, http.StatusInternalServerError, "Failed to change blocked user.")
+			return
+		}
+
+		w.WriteHeader(200)
+	}
+}
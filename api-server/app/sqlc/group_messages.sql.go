@@ -10,24 +10,188 @@ import (
 	"database/sql"
 )
 
+const groupMessageEditsCreate = `-- name: GroupMessageEditsCreate :exec
+INSERT INTO
+    group_message_edits (message_id, content)
+VALUES
+    (?, ?)
+`
+
+type GroupMessageEditsCreateParams struct {
+	MessageID string `json:"messageId"`
+	Content   string `json:"content"`
+}
+
+func (q *Queries) GroupMessageEditsCreate(ctx context.Context, arg GroupMessageEditsCreateParams) error {
+	_, err := q.db.ExecContext(ctx, groupMessageEditsCreate, arg.MessageID, arg.Content)
+	return err
+}
+
+const groupMessageEditsGet = `-- name: GroupMessageEditsGet :many
+SELECT
+    content,
+    edited_at
+FROM
+    group_message_edits
+WHERE
+    message_id = ?
+ORDER BY
+    rowid
+`
+
+type GroupMessageEditsGetRow struct {
+	Content  string `json:"content"`
+	EditedAt string `json:"editedAt"`
+}
+
+func (q *Queries) GroupMessageEditsGet(ctx context.Context, messageID string) ([]GroupMessageEditsGetRow, error) {
+	rows, err := q.db.QueryContext(ctx, groupMessageEditsGet, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GroupMessageEditsGetRow
+	for rows.Next() {
+		var i GroupMessageEditsGetRow
+		if err := rows.Scan(&i.Content, &i.EditedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const groupMessageReactionsAdd = `-- name: GroupMessageReactionsAdd :execrows
+INSERT INTO
+    group_message_reactions (message_id, user_id, emoji)
+VALUES
+    (?, ?, ?) ON CONFLICT DO NOTHING
+`
+
+type GroupMessageReactionsAddParams struct {
+	MessageID string `json:"messageId"`
+	UserID    string `json:"userId"`
+	Emoji     string `json:"emoji"`
+}
+
+func (q *Queries) GroupMessageReactionsAdd(ctx context.Context, arg GroupMessageReactionsAddParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, groupMessageReactionsAdd, arg.MessageID, arg.UserID, arg.Emoji)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const groupMessageReactionsGet = `-- name: GroupMessageReactionsGet :many
+SELECT
+    emoji,
+    user_id
+FROM
+    group_message_reactions
+WHERE
+    message_id = ?
+ORDER BY
+    rowid
+`
+
+type GroupMessageReactionsGetRow struct {
+	Emoji  string `json:"emoji"`
+	UserID string `json:"userId"`
+}
+
+func (q *Queries) GroupMessageReactionsGet(ctx context.Context, messageID string) ([]GroupMessageReactionsGetRow, error) {
+	rows, err := q.db.QueryContext(ctx, groupMessageReactionsGet, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GroupMessageReactionsGetRow
+	for rows.Next() {
+		var i GroupMessageReactionsGetRow
+		if err := rows.Scan(&i.Emoji, &i.UserID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const groupMessageReactionsRemove = `-- name: GroupMessageReactionsRemove :execrows
+DELETE FROM group_message_reactions
+WHERE
+    message_id = ?
+    AND user_id = ?
+    AND emoji = ?
+`
+
+type GroupMessageReactionsRemoveParams struct {
+	MessageID string `json:"messageId"`
+	UserID    string `json:"userId"`
+	Emoji     string `json:"emoji"`
+}
+
+func (q *Queries) GroupMessageReactionsRemove(ctx context.Context, arg GroupMessageReactionsRemoveParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, groupMessageReactionsRemove, arg.MessageID, arg.UserID, arg.Emoji)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const groupMessagesCount = `-- name: GroupMessagesCount :one
+SELECT
+    COUNT(id)
+FROM
+    group_messages
+WHERE
+    group_id = ?
+    OR ride_event_id = ?
+`
+
+type GroupMessagesCountParams struct {
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+}
+
+func (q *Queries) GroupMessagesCount(ctx context.Context, arg GroupMessagesCountParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, groupMessagesCount, arg.GroupID, arg.RideEventID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const groupMessagesCreate = `-- name: GroupMessagesCreate :one
 INSERT INTO
-    group_messages (content, group_id, sent_by, replies_to)
+    group_messages (content, group_id, ride_event_id, sent_by, replies_to)
 VALUES
-    (?, ?, ?, ?) RETURNING id, group_id, content, sent_by, created_at, replies_to
+    (?, ?, ?, ?, ?) RETURNING id, group_id, ride_event_id, content, sent_by, created_at, replies_to, edited_at, deleted_at, deleted_by, changed_seq
 `
 
 type GroupMessagesCreateParams struct {
-	Content   string         `json:"content"`
-	GroupID   string         `json:"groupId"`
-	SentBy    string         `json:"sentBy"`
-	RepliesTo sql.NullString `json:"repliesTo"`
+	Content     string         `json:"content"`
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+	SentBy      string         `json:"sentBy"`
+	RepliesTo   sql.NullString `json:"repliesTo"`
 }
 
 func (q *Queries) GroupMessagesCreate(ctx context.Context, arg GroupMessagesCreateParams) (GroupMessage, error) {
 	row := q.db.QueryRowContext(ctx, groupMessagesCreate,
 		arg.Content,
 		arg.GroupID,
+		arg.RideEventID,
 		arg.SentBy,
 		arg.RepliesTo,
 	)
@@ -35,61 +199,357 @@ func (q *Queries) GroupMessagesCreate(ctx context.Context, arg GroupMessagesCrea
 	err := row.Scan(
 		&i.ID,
 		&i.GroupID,
+		&i.RideEventID,
 		&i.Content,
 		&i.SentBy,
 		&i.CreatedAt,
 		&i.RepliesTo,
+		&i.EditedAt,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.ChangedSeq,
 	)
 	return i, err
 }
 
-const groupMessagesGetMany = `-- name: GroupMessagesGetMany :many
+const groupMessagesDelete = `-- name: GroupMessagesDelete :exec
+UPDATE group_messages
+SET
+    deleted_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'),
+    deleted_by = ?
+WHERE
+    id = ?
+`
+
+type GroupMessagesDeleteParams struct {
+	DeletedBy sql.NullString `json:"deletedBy"`
+	ID        string         `json:"id"`
+}
+
+func (q *Queries) GroupMessagesDelete(ctx context.Context, arg GroupMessagesDeleteParams) error {
+	_, err := q.db.ExecContext(ctx, groupMessagesDelete, arg.DeletedBy, arg.ID)
+	return err
+}
+
+const groupMessagesGetById = `-- name: GroupMessagesGetById :one
 SELECT
     gm.id,
     gm.group_id,
+    gm.ride_event_id,
     gm.content,
     gm.sent_by,
     u.email AS sent_by_email,
     gm.created_at,
-    gm.replies_to
+    gm.replies_to,
+    gm.edited_at,
+    gm.deleted_at
 FROM
     group_messages gm
     INNER JOIN users u ON u.id = sent_by
 WHERE
-    group_id = ?
+    gm.id = ?
+`
+
+type GroupMessagesGetByIdRow struct {
+	ID          string         `json:"id"`
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+	Content     string         `json:"content"`
+	SentBy      string         `json:"sentBy"`
+	SentByEmail string         `json:"sentByEmail"`
+	CreatedAt   string         `json:"createdAt"`
+	RepliesTo   sql.NullString `json:"repliesTo"`
+	EditedAt    sql.NullString `json:"editedAt"`
+	DeletedAt   sql.NullString `json:"deletedAt"`
+}
+
+func (q *Queries) GroupMessagesGetById(ctx context.Context, id string) (GroupMessagesGetByIdRow, error) {
+	row := q.db.QueryRowContext(ctx, groupMessagesGetById, id)
+	var i GroupMessagesGetByIdRow
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.RideEventID,
+		&i.Content,
+		&i.SentBy,
+		&i.SentByEmail,
+		&i.CreatedAt,
+		&i.RepliesTo,
+		&i.EditedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const groupMessagesGetChangedSince = `-- name: GroupMessagesGetChangedSince :many
+SELECT
+    gm.id,
+    gm.group_id,
+    gm.ride_event_id,
+    gm.content,
+    gm.sent_by,
+    u.email AS sent_by_email,
+    gm.created_at,
+    gm.replies_to,
+    gm.edited_at,
+    gm.deleted_at
+FROM
+    group_messages gm
+    INNER JOIN users u ON u.id = gm.sent_by
+    INNER JOIN group_messages since ON since.id = ?
+WHERE
+    (
+        gm.group_id = ?
+        OR gm.ride_event_id = ?
+    )
+    AND gm.rowid <= since.rowid
+    AND gm.changed_seq >= since.rowid
+ORDER BY
+    gm.rowid
+LIMIT
+    ?
+`
+
+type GroupMessagesGetChangedSinceParams struct {
+	SinceID     string         `json:"sinceId"`
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+	PageSize    int64          `json:"pageSize"`
+}
+
+type GroupMessagesGetChangedSinceRow struct {
+	ID          string         `json:"id"`
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+	Content     string         `json:"content"`
+	SentBy      string         `json:"sentBy"`
+	SentByEmail string         `json:"sentByEmail"`
+	CreatedAt   string         `json:"createdAt"`
+	RepliesTo   sql.NullString `json:"repliesTo"`
+	EditedAt    sql.NullString `json:"editedAt"`
+	DeletedAt   sql.NullString `json:"deletedAt"`
+}
+
+// Returns the messages up to 'since_id' that changed after it was sent, oldest
+// first. Changes made before a newer message was sent are returned as well.
+func (q *Queries) GroupMessagesGetChangedSince(ctx context.Context, arg GroupMessagesGetChangedSinceParams) ([]GroupMessagesGetChangedSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, groupMessagesGetChangedSince,
+		arg.SinceID,
+		arg.GroupID,
+		arg.RideEventID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GroupMessagesGetChangedSinceRow
+	for rows.Next() {
+		var i GroupMessagesGetChangedSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.RideEventID,
+			&i.Content,
+			&i.SentBy,
+			&i.SentByEmail,
+			&i.CreatedAt,
+			&i.RepliesTo,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const groupMessagesGetPage = `-- name: GroupMessagesGetPage :many
+SELECT
+    gm.id,
+    gm.group_id,
+    gm.ride_event_id,
+    gm.content,
+    gm.sent_by,
+    u.email AS sent_by_email,
+    gm.created_at,
+    gm.replies_to,
+    gm.edited_at,
+    gm.deleted_at
+FROM
+    group_messages gm
+    INNER JOIN users u ON u.id = sent_by
+WHERE
+    (
+        gm.group_id = ?
+        OR gm.ride_event_id = ?
+    )
+    AND (
+        ? IS NULL
+        OR gm.rowid < (
+            SELECT
+                rowid
+            FROM
+                group_messages
+            WHERE
+                id = ?
+        )
+    )
+ORDER BY
+    gm.rowid DESC
+LIMIT
+    ?
+`
+
+type GroupMessagesGetPageParams struct {
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+	BeforeID    sql.NullString `json:"beforeId"`
+	PageSize    int64          `json:"pageSize"`
+}
+
+type GroupMessagesGetPageRow struct {
+	ID          string         `json:"id"`
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+	Content     string         `json:"content"`
+	SentBy      string         `json:"sentBy"`
+	SentByEmail string         `json:"sentByEmail"`
+	CreatedAt   string         `json:"createdAt"`
+	RepliesTo   sql.NullString `json:"repliesTo"`
+	EditedAt    sql.NullString `json:"editedAt"`
+	DeletedAt   sql.NullString `json:"deletedAt"`
+}
+
+// Messages are ordered by the rowid, which only grows, unlike 'created_at'
+// which can be the same for many messages. Returns the newest messages first.
+func (q *Queries) GroupMessagesGetPage(ctx context.Context, arg GroupMessagesGetPageParams) ([]GroupMessagesGetPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, groupMessagesGetPage,
+		arg.GroupID,
+		arg.RideEventID,
+		arg.BeforeID,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GroupMessagesGetPageRow
+	for rows.Next() {
+		var i GroupMessagesGetPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.RideEventID,
+			&i.Content,
+			&i.SentBy,
+			&i.SentByEmail,
+			&i.CreatedAt,
+			&i.RepliesTo,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const groupMessagesGetSince = `-- name: GroupMessagesGetSince :many
+SELECT
+    gm.id,
+    gm.group_id,
+    gm.ride_event_id,
+    gm.content,
+    gm.sent_by,
+    u.email AS sent_by_email,
+    gm.created_at,
+    gm.replies_to,
+    gm.edited_at,
+    gm.deleted_at
+FROM
+    group_messages gm
+    INNER JOIN users u ON u.id = sent_by
+WHERE
+    (
+        gm.group_id = ?
+        OR gm.ride_event_id = ?
+    )
+    AND gm.rowid > (
+        SELECT
+            rowid
+        FROM
+            group_messages
+        WHERE
+            id = ?
+    )
 ORDER BY
-    created_at
+    gm.rowid
+LIMIT
+    ?
 `
 
-type GroupMessagesGetManyRow struct {
+type GroupMessagesGetSinceParams struct {
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+	SinceID     string         `json:"sinceId"`
+	PageSize    int64          `json:"pageSize"`
+}
+
+type GroupMessagesGetSinceRow struct {
 	ID          string         `json:"id"`
-	GroupID     string         `json:"groupId"`
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
 	Content     string         `json:"content"`
 	SentBy      string         `json:"sentBy"`
 	SentByEmail string         `json:"sentByEmail"`
 	CreatedAt   string         `json:"createdAt"`
 	RepliesTo   sql.NullString `json:"repliesTo"`
+	EditedAt    sql.NullString `json:"editedAt"`
+	DeletedAt   sql.NullString `json:"deletedAt"`
 }
 
-// See sqlc docs for more information:
-// https://docs.sqlc.dev/en/latest/tutorials/getting-started-sqlite.html#schema-and-queries
-func (q *Queries) GroupMessagesGetMany(ctx context.Context, groupID string) ([]GroupMessagesGetManyRow, error) {
-	rows, err := q.db.QueryContext(ctx, groupMessagesGetMany, groupID)
+// Returns the oldest messages first.
+func (q *Queries) GroupMessagesGetSince(ctx context.Context, arg GroupMessagesGetSinceParams) ([]GroupMessagesGetSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, groupMessagesGetSince,
+		arg.GroupID,
+		arg.RideEventID,
+		arg.SinceID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GroupMessagesGetManyRow
+	var items []GroupMessagesGetSinceRow
 	for rows.Next() {
-		var i GroupMessagesGetManyRow
+		var i GroupMessagesGetSinceRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.GroupID,
+			&i.RideEventID,
 			&i.Content,
 			&i.SentBy,
 			&i.SentByEmail,
 			&i.CreatedAt,
 			&i.RepliesTo,
+			&i.EditedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -103,3 +563,22 @@ func (q *Queries) GroupMessagesGetMany(ctx context.Context, groupID string) ([]G
 	}
 	return items, nil
 }
+
+const groupMessagesUpdateContent = `-- name: GroupMessagesUpdateContent :exec
+UPDATE group_messages
+SET
+    content = ?,
+    edited_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE
+    id = ?
+`
+
+type GroupMessagesUpdateContentParams struct {
+	Content string `json:"content"`
+	ID      string `json:"id"`
+}
+
+func (q *Queries) GroupMessagesUpdateContent(ctx context.Context, arg GroupMessagesUpdateContentParams) error {
+	_, err := q.db.ExecContext(ctx, groupMessagesUpdateContent, arg.Content, arg.ID)
+	return err
+}
@@ -10,6 +10,20 @@ import (
 	"database/sql"
 )
 
+const groupsCount = `-- name: GroupsCount :one
+SELECT
+    COUNT(id)
+FROM
+    ride_groups
+`
+
+func (q *Queries) GroupsCount(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, groupsCount)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const groupsCreate = `-- name: GroupsCreate :one
 INSERT INTO
     ride_groups (name, description, created_by)
@@ -76,14 +90,26 @@ SELECT
     created_by
 FROM
     ride_groups
+WHERE
+    ? IS NULL
+    OR name > ?
+    OR (
+        name = ?
+        AND id > ?
+    )
 ORDER BY
-    name
+    name,
+    id
 LIMIT
-    50
-OFFSET
     ?
 `
 
+type GroupsGetManyParams struct {
+	CursorName sql.NullString `json:"cursorName"`
+	CursorID   sql.NullString `json:"cursorId"`
+	PageSize   int64          `json:"pageSize"`
+}
+
 type GroupsGetManyRow struct {
 	ID          string         `json:"id"`
 	Name        string         `json:"name"`
@@ -91,8 +117,14 @@ type GroupsGetManyRow struct {
 	CreatedBy   string         `json:"createdBy"`
 }
 
-func (q *Queries) GroupsGetMany(ctx context.Context, offset int64) ([]GroupsGetManyRow, error) {
-	rows, err := q.db.QueryContext(ctx, groupsGetMany, offset)
+func (q *Queries) GroupsGetMany(ctx context.Context, arg GroupsGetManyParams) ([]GroupsGetManyRow, error) {
+	rows, err := q.db.QueryContext(ctx, groupsGetMany,
+		arg.CursorName,
+		arg.CursorName,
+		arg.CursorName,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +156,8 @@ SELECT
     group_id,
     user_id,
     u.email,
-    join_status
+    join_status,
+    is_moderator
 FROM
     ride_group_members
     INNER JOIN users u ON u.id = user_id
@@ -142,10 +175,11 @@ ORDER BY
 `
 
 type GroupsMembersGetRow struct {
-	GroupID    string `json:"groupId"`
-	UserID     string `json:"userId"`
-	Email      string `json:"email"`
-	JoinStatus string `json:"joinStatus"`
+	GroupID     string `json:"groupId"`
+	UserID      string `json:"userId"`
+	Email       string `json:"email"`
+	JoinStatus  string `json:"joinStatus"`
+	IsModerator bool   `json:"isModerator"`
 }
 
 func (q *Queries) GroupsMembersGet(ctx context.Context, groupID string) ([]GroupsMembersGetRow, error) {
@@ -162,6 +196,7 @@ func (q *Queries) GroupsMembersGet(ctx context.Context, groupID string) ([]Group
 			&i.UserID,
 			&i.Email,
 			&i.JoinStatus,
+			&i.IsModerator,
 		); err != nil {
 			return nil, err
 		}
@@ -176,6 +211,34 @@ func (q *Queries) GroupsMembersGet(ctx context.Context, groupID string) ([]Group
 	return items, nil
 }
 
+const groupsMembersGetStatus = `-- name: GroupsMembersGetStatus :one
+SELECT
+    join_status,
+    is_moderator
+FROM
+    ride_group_members
+WHERE
+    group_id = ?
+    AND user_id = ?
+`
+
+type GroupsMembersGetStatusParams struct {
+	GroupID string `json:"groupId"`
+	UserID  string `json:"userId"`
+}
+
+type GroupsMembersGetStatusRow struct {
+	JoinStatus  string `json:"joinStatus"`
+	IsModerator bool   `json:"isModerator"`
+}
+
+func (q *Queries) GroupsMembersGetStatus(ctx context.Context, arg GroupsMembersGetStatusParams) (GroupsMembersGetStatusRow, error) {
+	row := q.db.QueryRowContext(ctx, groupsMembersGetStatus, arg.GroupID, arg.UserID)
+	var i GroupsMembersGetStatusRow
+	err := row.Scan(&i.JoinStatus, &i.IsModerator)
+	return i, err
+}
+
 const groupsMembersJoin = `-- name: GroupsMembersJoin :exec
 INSERT INTO
     ride_group_members (group_id, user_id)
@@ -218,6 +281,26 @@ func (q *Queries) GroupsMembersLeave(ctx context.Context, arg GroupsMembersLeave
 	return err
 }
 
+const groupsMembersSetModerator = `-- name: GroupsMembersSetModerator :exec
+UPDATE ride_group_members
+SET
+    is_moderator = ?
+WHERE
+    group_id = ?
+    AND user_id = ?
+`
+
+type GroupsMembersSetModeratorParams struct {
+	IsModerator bool   `json:"isModerator"`
+	GroupID     string `json:"groupId"`
+	UserID      string `json:"userId"`
+}
+
+func (q *Queries) GroupsMembersSetModerator(ctx context.Context, arg GroupsMembersSetModeratorParams) error {
+	_, err := q.db.ExecContext(ctx, groupsMembersSetModerator, arg.IsModerator, arg.GroupID, arg.UserID)
+	return err
+}
+
 const groupsMembersSetStatus = `-- name: GroupsMembersSetStatus :exec
 UPDATE ride_group_members
 SET
@@ -8,35 +8,143 @@ import (
 	"database/sql"
 )
 
-type GroupMessage struct {
+type AuthCode struct {
+	CodeHash      string         `json:"codeHash"`
+	UserID        string         `json:"userId"`
+	CodeChallenge sql.NullString `json:"codeChallenge"`
+	CreatedAt     string         `json:"createdAt"`
+}
+
+type AuthKey struct {
 	ID        string         `json:"id"`
-	GroupID   string         `json:"groupId"`
-	Content   string         `json:"content"`
-	SentBy    string         `json:"sentBy"`
+	Secret    string         `json:"secret"`
 	CreatedAt string         `json:"createdAt"`
-	RepliesTo sql.NullString `json:"repliesTo"`
+	RetiresAt sql.NullString `json:"retiresAt"`
+}
+
+type CalendarToken struct {
+	UserID    string `json:"userId"`
+	TokenHash string `json:"tokenHash"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type Conversation struct {
+	ID        string `json:"id"`
+	UserA     string `json:"userA"`
+	UserB     string `json:"userB"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type DirectMessage struct {
+	ID             string         `json:"id"`
+	ConversationID string         `json:"conversationId"`
+	Content        string         `json:"content"`
+	SentBy         string         `json:"sentBy"`
+	CreatedAt      string         `json:"createdAt"`
+	RepliesTo      sql.NullString `json:"repliesTo"`
+	ReadAt         sql.NullString `json:"readAt"`
+}
+
+type GroupMessage struct {
+	ID          string         `json:"id"`
+	GroupID     sql.NullString `json:"groupId"`
+	RideEventID sql.NullString `json:"rideEventId"`
+	Content     string         `json:"content"`
+	SentBy      string         `json:"sentBy"`
+	CreatedAt   string         `json:"createdAt"`
+	RepliesTo   sql.NullString `json:"repliesTo"`
+	EditedAt    sql.NullString `json:"editedAt"`
+	DeletedAt   sql.NullString `json:"deletedAt"`
+	DeletedBy   sql.NullString `json:"deletedBy"`
+	ChangedSeq  sql.NullInt64  `json:"changedSeq"`
+}
+
+type GroupMessageEdit struct {
+	ID        string `json:"id"`
+	MessageID string `json:"messageId"`
+	Content   string `json:"content"`
+	EditedAt  string `json:"editedAt"`
+}
+
+type GroupMessageReaction struct {
+	MessageID string `json:"messageId"`
+	UserID    string `json:"userId"`
+	Emoji     string `json:"emoji"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type IdentityLinkCode struct {
+	CodeHash      string `json:"codeHash"`
+	UserID        string `json:"userId"`
+	Provider      string `json:"provider"`
+	Subject       string `json:"subject"`
+	Email         string `json:"email"`
+	CodeChallenge string `json:"codeChallenge"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+type Notification struct {
+	ID             string         `json:"id"`
+	UserID         string         `json:"userId"`
+	Type           string         `json:"type"`
+	ActorID        sql.NullString `json:"actorId"`
+	RideEventID    sql.NullString `json:"rideEventId"`
+	GroupID        sql.NullString `json:"groupId"`
+	ConversationID sql.NullString `json:"conversationId"`
+	MessageID      sql.NullString `json:"messageId"`
+	CreatedAt      string         `json:"createdAt"`
+	ReadAt         sql.NullString `json:"readAt"`
+}
+
+type OauthState struct {
+	State         string         `json:"state"`
+	LinkUserID    sql.NullString `json:"linkUserId"`
+	CodeChallenge sql.NullString `json:"codeChallenge"`
+	CreatedAt     string         `json:"createdAt"`
+}
+
+type PersonalToken struct {
+	ID         string         `json:"id"`
+	UserID     string         `json:"userId"`
+	Name       string         `json:"name"`
+	TokenHash  string         `json:"tokenHash"`
+	Scopes     string         `json:"scopes"`
+	CreatedAt  string         `json:"createdAt"`
+	ExpiresAt  string         `json:"expiresAt"`
+	LastUsedAt sql.NullString `json:"lastUsedAt"`
 }
 
 type Ride struct {
-	ID             string `json:"id"`
-	LocationFrom   string `json:"locationFrom"`
-	LocationTo     string `json:"locationTo"`
-	TackingPlaceAt string `json:"tackingPlaceAt"`
-	CreatedBy      string `json:"createdBy"`
-	Driver         string `json:"driver"`
-	TransportLimit int64  `json:"transportLimit"`
-	CreatedAt      string `json:"createdAt"`
+	ID               string         `json:"id"`
+	LocationFrom     string         `json:"locationFrom"`
+	LocationTo       string         `json:"locationTo"`
+	TackingPlaceAt   string         `json:"tackingPlaceAt"`
+	CreatedBy        string         `json:"createdBy"`
+	Driver           string         `json:"driver"`
+	TransportLimit   int64          `json:"transportLimit"`
+	CreatedAt        string         `json:"createdAt"`
+	OccurrenceOffset int64          `json:"occurrenceOffset"`
+	GroupID          sql.NullString `json:"groupId"`
 }
 
 type RideEvent struct {
-	ID             string `json:"id"`
-	RideID         string `json:"rideId"`
-	LocationFrom   string `json:"locationFrom"`
-	LocationTo     string `json:"locationTo"`
-	Driver         string `json:"driver"`
-	Status         string `json:"status"`
-	TackingPlaceAt string `json:"tackingPlaceAt"`
-	TransportLimit int64  `json:"transportLimit"`
+	ID             string         `json:"id"`
+	RideID         string         `json:"rideId"`
+	LocationFrom   string         `json:"locationFrom"`
+	LocationTo     string         `json:"locationTo"`
+	Driver         string         `json:"driver"`
+	Status         string         `json:"status"`
+	TackingPlaceAt string         `json:"tackingPlaceAt"`
+	TransportLimit int64          `json:"transportLimit"`
+	OccurrenceAt   sql.NullString `json:"occurrenceAt"`
+}
+
+type RideEventChange struct {
+	ID          string `json:"id"`
+	RideEventID string `json:"rideEventId"`
+	ChangedBy   string `json:"changedBy"`
+	Changes     string `json:"changes"`
+	CreatedAt   string `json:"createdAt"`
 }
 
 type RideEventStatusOrdering struct {
@@ -52,9 +160,10 @@ type RideGroup struct {
 }
 
 type RideGroupMember struct {
-	GroupID    string `json:"groupId"`
-	UserID     string `json:"userId"`
-	JoinStatus string `json:"joinStatus"`
+	GroupID     string `json:"groupId"`
+	UserID      string `json:"userId"`
+	JoinStatus  string `json:"joinStatus"`
+	IsModerator bool   `json:"isModerator"`
 }
 
 type RideGroupMembersJoinStatusOrdering struct {
@@ -68,10 +177,12 @@ type RideParticipant struct {
 }
 
 type RideSchedule struct {
-	ID               string `json:"id"`
-	RideID           string `json:"rideId"`
-	ScheduleInterval int64  `json:"scheduleInterval"`
-	Unit             string `json:"unit"`
+	ID               string         `json:"id"`
+	RideID           string         `json:"rideId"`
+	ScheduleInterval int64          `json:"scheduleInterval"`
+	Unit             string         `json:"unit"`
+	Timezone         string         `json:"timezone"`
+	Rrule            sql.NullString `json:"rrule"`
 }
 
 type RideScheduleWeekday struct {
@@ -79,6 +190,30 @@ type RideScheduleWeekday struct {
 	Weekday        string `json:"weekday"`
 }
 
+type RideWaitlist struct {
+	RideEventID string `json:"rideEventId"`
+	UserID      string `json:"userId"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+type Session struct {
+	ID               string `json:"id"`
+	UserID           string `json:"userId"`
+	AccessTokenHash  string `json:"accessTokenHash"`
+	RefreshTokenHash string `json:"refreshTokenHash"`
+	Device           string `json:"device"`
+	Ip               string `json:"ip"`
+	CreatedAt        string `json:"createdAt"`
+	LastUsedAt       string `json:"lastUsedAt"`
+	RefreshedAt      string `json:"refreshedAt"`
+}
+
+type UsedRefreshToken struct {
+	TokenHash string `json:"tokenHash"`
+	SessionID string `json:"sessionId"`
+	UsedAt    string `json:"usedAt"`
+}
+
 type User struct {
 	ID           string         `json:"id"`
 	Name         string         `json:"name"`
@@ -89,3 +224,18 @@ type User struct {
 	IsAdmin      bool           `json:"isAdmin"`
 	IsBlocked    bool           `json:"isBlocked"`
 }
+
+type UserBlock struct {
+	UserID        string `json:"userId"`
+	BlockedUserID string `json:"blockedUserId"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+type UserIdentity struct {
+	ID        string `json:"id"`
+	UserID    string `json:"userId"`
+	Provider  string `json:"provider"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"createdAt"`
+}
@@ -8,8 +8,38 @@ package sqlc
 import (
 	"context"
 	"database/sql"
+	"strings"
 )
 
+const ridesCount = `-- name: RidesCount :one
+SELECT
+    COUNT(re.id)
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+WHERE
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = ?
+                AND gm.join_status = 'member'
+        )
+    )
+`
+
+func (q *Queries) RidesCount(ctx context.Context, userID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, ridesCount, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const ridesCountEventParticipants = `-- name: RidesCountEventParticipants :one
 SELECT
     COUNT(user_id)
@@ -34,19 +64,21 @@ INSERT INTO
         tacking_place_at,
         created_by,
         driver,
-        transport_limit
+        transport_limit,
+        group_id
     )
 VALUES
-    (?, ?, ?, ?, ?, ?) RETURNING id
+    (?, ?, ?, ?, ?, ?, ?) RETURNING id
 `
 
 type RidesCreateParams struct {
-	LocationFrom   string `json:"locationFrom"`
-	LocationTo     string `json:"locationTo"`
-	TackingPlaceAt string `json:"tackingPlaceAt"`
-	CreatedBy      string `json:"createdBy"`
-	Driver         string `json:"driver"`
-	TransportLimit int64  `json:"transportLimit"`
+	LocationFrom   string         `json:"locationFrom"`
+	LocationTo     string         `json:"locationTo"`
+	TackingPlaceAt string         `json:"tackingPlaceAt"`
+	CreatedBy      string         `json:"createdBy"`
+	Driver         string         `json:"driver"`
+	TransportLimit int64          `json:"transportLimit"`
+	GroupID        sql.NullString `json:"groupId"`
 }
 
 // See sqlc docs for more information:
@@ -59,62 +91,97 @@ func (q *Queries) RidesCreate(ctx context.Context, arg RidesCreateParams) (strin
 		arg.CreatedBy,
 		arg.Driver,
 		arg.TransportLimit,
+		arg.GroupID,
 	)
 	var id string
 	err := row.Scan(&id)
 	return id, err
 }
 
-const ridesCreateEvent = `-- name: RidesCreateEvent :exec
+const ridesCreateEvent = `-- name: RidesCreateEvent :one
 INSERT INTO
     ride_events (
         ride_id,
         location_from,
         location_to,
         driver,
+        status,
         tacking_Place_at,
+        occurrence_at,
         transport_limit
     )
 VALUES
-    (?, ?, ?, ?, ?, ?)
+    (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id
 `
 
 type RidesCreateEventParams struct {
-	RideID         string `json:"rideId"`
-	LocationFrom   string `json:"locationFrom"`
-	LocationTo     string `json:"locationTo"`
-	Driver         string `json:"driver"`
-	TackingPlaceAt string `json:"tackingPlaceAt"`
-	TransportLimit int64  `json:"transportLimit"`
+	RideID         string         `json:"rideId"`
+	LocationFrom   string         `json:"locationFrom"`
+	LocationTo     string         `json:"locationTo"`
+	Driver         string         `json:"driver"`
+	Status         string         `json:"status"`
+	TackingPlaceAt string         `json:"tackingPlaceAt"`
+	OccurrenceAt   sql.NullString `json:"occurrenceAt"`
+	TransportLimit int64          `json:"transportLimit"`
 }
 
-func (q *Queries) RidesCreateEvent(ctx context.Context, arg RidesCreateEventParams) error {
-	_, err := q.db.ExecContext(ctx, ridesCreateEvent,
+func (q *Queries) RidesCreateEvent(ctx context.Context, arg RidesCreateEventParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, ridesCreateEvent,
 		arg.RideID,
 		arg.LocationFrom,
 		arg.LocationTo,
 		arg.Driver,
+		arg.Status,
 		arg.TackingPlaceAt,
+		arg.OccurrenceAt,
 		arg.TransportLimit,
 	)
+	var id string
+	err := row.Scan(&id)
+	return id, err
+}
+
+const ridesCreateEventChange = `-- name: RidesCreateEventChange :exec
+INSERT INTO
+    ride_event_changes (ride_event_id, changed_by, changes)
+VALUES
+    (?, ?, ?)
+`
+
+type RidesCreateEventChangeParams struct {
+	RideEventID string `json:"rideEventId"`
+	ChangedBy   string `json:"changedBy"`
+	Changes     string `json:"changes"`
+}
+
+func (q *Queries) RidesCreateEventChange(ctx context.Context, arg RidesCreateEventChangeParams) error {
+	_, err := q.db.ExecContext(ctx, ridesCreateEventChange, arg.RideEventID, arg.ChangedBy, arg.Changes)
 	return err
 }
 
 const ridesCreateSchedule = `-- name: RidesCreateSchedule :one
 INSERT INTO
-    ride_schedules (ride_id, schedule_interval, unit)
+    ride_schedules (ride_id, schedule_interval, unit, timezone, rrule)
 VALUES
-    (?, ?, ?) RETURNING id
+    (?, ?, ?, ?, ?) RETURNING id
 `
 
 type RidesCreateScheduleParams struct {
-	RideID           string `json:"rideId"`
-	ScheduleInterval int64  `json:"scheduleInterval"`
-	Unit             string `json:"unit"`
+	RideID           string         `json:"rideId"`
+	ScheduleInterval int64          `json:"scheduleInterval"`
+	Unit             string         `json:"unit"`
+	Timezone         string         `json:"timezone"`
+	Rrule            sql.NullString `json:"rrule"`
 }
 
 func (q *Queries) RidesCreateSchedule(ctx context.Context, arg RidesCreateScheduleParams) (string, error) {
-	row := q.db.QueryRowContext(ctx, ridesCreateSchedule, arg.RideID, arg.ScheduleInterval, arg.Unit)
+	row := q.db.QueryRowContext(ctx, ridesCreateSchedule,
+		arg.RideID,
+		arg.ScheduleInterval,
+		arg.Unit,
+		arg.Timezone,
+		arg.Rrule,
+	)
 	var id string
 	err := row.Scan(&id)
 	return id, err
@@ -159,72 +226,7 @@ func (q *Queries) RidesDropScheduleWeekdays(ctx context.Context, rideScheduleID
 	return err
 }
 
-const ridesGetEvent = `-- name: RidesGetEvent :one
-SELECT
-    r.id AS ride_id,
-    re.id AS ride_event_id,
-    re.location_from,
-    re.location_to,
-    re.tacking_place_at,
-    r.created_by,
-    re.transport_limit,
-    re.driver,
-    re.status,
-    ud.email AS driver_email,
-    uc.email AS created_by_email,
-    rs.id AS ride_schedule_id,
-    rs.unit AS ride_schedule_unit,
-    rs.schedule_interval AS ride_schedule_interval
-FROM
-    ride_events re
-    INNER JOIN rides r ON re.ride_id = r.id
-    LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
-    INNER JOIN users ud ON r.driver = ud.id
-    INNER JOIN users uc ON r.created_by = uc.id
-WHERE
-    re.id = ?
-`
-
-type RidesGetEventRow struct {
-	RideID               string         `json:"rideId"`
-	RideEventID          string         `json:"rideEventId"`
-	LocationFrom         string         `json:"locationFrom"`
-	LocationTo           string         `json:"locationTo"`
-	TackingPlaceAt       string         `json:"tackingPlaceAt"`
-	CreatedBy            string         `json:"createdBy"`
-	TransportLimit       int64          `json:"transportLimit"`
-	Driver               string         `json:"driver"`
-	Status               string         `json:"status"`
-	DriverEmail          string         `json:"driverEmail"`
-	CreatedByEmail       string         `json:"createdByEmail"`
-	RideScheduleID       sql.NullString `json:"rideScheduleId"`
-	RideScheduleUnit     sql.NullString `json:"rideScheduleUnit"`
-	RideScheduleInterval sql.NullInt64  `json:"rideScheduleInterval"`
-}
-
-func (q *Queries) RidesGetEvent(ctx context.Context, id string) (RidesGetEventRow, error) {
-	row := q.db.QueryRowContext(ctx, ridesGetEvent, id)
-	var i RidesGetEventRow
-	err := row.Scan(
-		&i.RideID,
-		&i.RideEventID,
-		&i.LocationFrom,
-		&i.LocationTo,
-		&i.TackingPlaceAt,
-		&i.CreatedBy,
-		&i.TransportLimit,
-		&i.Driver,
-		&i.Status,
-		&i.DriverEmail,
-		&i.CreatedByEmail,
-		&i.RideScheduleID,
-		&i.RideScheduleUnit,
-		&i.RideScheduleInterval,
-	)
-	return i, err
-}
-
-const ridesGetLatest = `-- name: RidesGetLatest :one
+const ridesGetByGroup = `-- name: RidesGetByGroup :many
 SELECT
     r.id AS ride_id,
     re.id AS ride_event_id,
@@ -240,30 +242,24 @@ SELECT
     rs.id AS ride_schedule_id,
     rs.unit AS ride_schedule_unit,
     rs.schedule_interval AS ride_schedule_interval,
-    r.location_from AS base_location_from,
-    r.location_to AS base_location_to,
-    r.transport_limit AS base_transport_limit,
-    r.driver AS base_driver
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id
 FROM
     ride_events re
     INNER JOIN rides r ON re.ride_id = r.id
     LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
-    INNER JOIN users ud ON r.driver = ud.id
+    INNER JOIN users ud ON re.driver = ud.id
     INNER JOIN users uc ON r.created_by = uc.id
 WHERE
-    re.ride_id = ?
+    r.group_id = ?
     AND re.status = 'upcoming'
-    AND re.tacking_place_at = (
-        SELECT
-            MAX(tacking_place_at)
-        FROM
-            ride_events
-        WHERE
-            id = re.id
-    )
+ORDER BY
+    re.tacking_place_at,
+    re.id
 `
 
-type RidesGetLatestRow struct {
+type RidesGetByGroupRow struct {
 	RideID               string         `json:"rideId"`
 	RideEventID          string         `json:"rideEventId"`
 	LocationFrom         string         `json:"locationFrom"`
@@ -278,117 +274,137 @@ type RidesGetLatestRow struct {
 	RideScheduleID       sql.NullString `json:"rideScheduleId"`
 	RideScheduleUnit     sql.NullString `json:"rideScheduleUnit"`
 	RideScheduleInterval sql.NullInt64  `json:"rideScheduleInterval"`
-	BaseLocationFrom     string         `json:"baseLocationFrom"`
-	BaseLocationTo       string         `json:"baseLocationTo"`
-	BaseTransportLimit   int64          `json:"baseTransportLimit"`
-	BaseDriver           string         `json:"baseDriver"`
+	RideScheduleTimezone sql.NullString `json:"rideScheduleTimezone"`
+	RideScheduleRrule    sql.NullString `json:"rideScheduleRrule"`
+	GroupID              sql.NullString `json:"groupId"`
 }
 
-func (q *Queries) RidesGetLatest(ctx context.Context, rideID string) (RidesGetLatestRow, error) {
-	row := q.db.QueryRowContext(ctx, ridesGetLatest, rideID)
-	var i RidesGetLatestRow
-	err := row.Scan(
-		&i.RideID,
-		&i.RideEventID,
-		&i.LocationFrom,
-		&i.LocationTo,
-		&i.TackingPlaceAt,
-		&i.CreatedBy,
-		&i.TransportLimit,
-		&i.Driver,
-		&i.Status,
-		&i.DriverEmail,
-		&i.CreatedByEmail,
-		&i.RideScheduleID,
-		&i.RideScheduleUnit,
-		&i.RideScheduleInterval,
-		&i.BaseLocationFrom,
-		&i.BaseLocationTo,
-		&i.BaseTransportLimit,
-		&i.BaseDriver,
-	)
-	return i, err
+func (q *Queries) RidesGetByGroup(ctx context.Context, groupID sql.NullString) ([]RidesGetByGroupRow, error) {
+	rows, err := q.db.QueryContext(ctx, ridesGetByGroup, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RidesGetByGroupRow
+	for rows.Next() {
+		var i RidesGetByGroupRow
+		if err := rows.Scan(
+			&i.RideID,
+			&i.RideEventID,
+			&i.LocationFrom,
+			&i.LocationTo,
+			&i.TackingPlaceAt,
+			&i.CreatedBy,
+			&i.TransportLimit,
+			&i.Driver,
+			&i.Status,
+			&i.DriverEmail,
+			&i.CreatedByEmail,
+			&i.RideScheduleID,
+			&i.RideScheduleUnit,
+			&i.RideScheduleInterval,
+			&i.RideScheduleTimezone,
+			&i.RideScheduleRrule,
+			&i.GroupID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const ridesGetMany = `-- name: RidesGetMany :many
+const ridesGetCalendarEvents = `-- name: RidesGetCalendarEvents :many
 SELECT
-    r.id AS ride_id,
     re.id AS ride_event_id,
+    re.ride_id,
     re.location_from,
     re.location_to,
-    re.tacking_place_at,
-    r.created_by,
-    re.transport_limit,
-    re.driver,
     re.status,
-    ud.email AS driver_email,
-    uc.email AS created_by_email,
+    re.tacking_place_at,
+    re.occurrence_at,
+    u.name AS driver_name,
+    u.email AS driver_email,
+    r.tacking_place_at AS series_start,
     rs.id AS ride_schedule_id,
+    rs.schedule_interval AS ride_schedule_interval,
     rs.unit AS ride_schedule_unit,
-    rs.schedule_interval AS ride_schedule_interval
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id
 FROM
     ride_events re
-    INNER JOIN rides r ON re.ride_id = r.id
-    LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
-    INNER JOIN users ud ON r.driver = ud.id
-    INNER JOIN users uc ON r.created_by = uc.id
+    INNER JOIN rides r ON r.id = re.ride_id
+    INNER JOIN users u ON u.id = re.driver
+    LEFT JOIN ride_schedules rs ON rs.ride_id = re.ride_id
+WHERE
+    re.ride_id IN (/*SLICE:ride_ids*/?)
 ORDER BY
-    (
-        SELECT
-            ordering
-        FROM
-            ride_event_status_ordering
-        WHERE
-            status = re.status
-    ),
-    re.tacking_place_at DESC
-LIMIT
-    50
-OFFSET
-    ?
+    re.ride_id,
+    re.tacking_place_at
 `
 
-type RidesGetManyRow struct {
-	RideID               string         `json:"rideId"`
+type RidesGetCalendarEventsRow struct {
 	RideEventID          string         `json:"rideEventId"`
+	RideID               string         `json:"rideId"`
 	LocationFrom         string         `json:"locationFrom"`
 	LocationTo           string         `json:"locationTo"`
-	TackingPlaceAt       string         `json:"tackingPlaceAt"`
-	CreatedBy            string         `json:"createdBy"`
-	TransportLimit       int64          `json:"transportLimit"`
-	Driver               string         `json:"driver"`
 	Status               string         `json:"status"`
+	TackingPlaceAt       string         `json:"tackingPlaceAt"`
+	OccurrenceAt         sql.NullString `json:"occurrenceAt"`
+	DriverName           string         `json:"driverName"`
 	DriverEmail          string         `json:"driverEmail"`
-	CreatedByEmail       string         `json:"createdByEmail"`
+	SeriesStart          string         `json:"seriesStart"`
 	RideScheduleID       sql.NullString `json:"rideScheduleId"`
-	RideScheduleUnit     sql.NullString `json:"rideScheduleUnit"`
 	RideScheduleInterval sql.NullInt64  `json:"rideScheduleInterval"`
+	RideScheduleUnit     sql.NullString `json:"rideScheduleUnit"`
+	RideScheduleTimezone sql.NullString `json:"rideScheduleTimezone"`
+	RideScheduleRrule    sql.NullString `json:"rideScheduleRrule"`
+	GroupID              sql.NullString `json:"groupId"`
 }
 
-func (q *Queries) RidesGetMany(ctx context.Context, offset int64) ([]RidesGetManyRow, error) {
-	rows, err := q.db.QueryContext(ctx, ridesGetMany, offset)
+func (q *Queries) RidesGetCalendarEvents(ctx context.Context, rideIds []string) ([]RidesGetCalendarEventsRow, error) {
+	query := ridesGetCalendarEvents
+	var queryParams []interface{}
+	if len(rideIds) > 0 {
+		for _, v := range rideIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ride_ids*/?", strings.Repeat(",?", len(rideIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ride_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []RidesGetManyRow
+	var items []RidesGetCalendarEventsRow
 	for rows.Next() {
-		var i RidesGetManyRow
+		var i RidesGetCalendarEventsRow
 		if err := rows.Scan(
-			&i.RideID,
 			&i.RideEventID,
+			&i.RideID,
 			&i.LocationFrom,
 			&i.LocationTo,
-			&i.TackingPlaceAt,
-			&i.CreatedBy,
-			&i.TransportLimit,
-			&i.Driver,
 			&i.Status,
+			&i.TackingPlaceAt,
+			&i.OccurrenceAt,
+			&i.DriverName,
 			&i.DriverEmail,
-			&i.CreatedByEmail,
+			&i.SeriesStart,
 			&i.RideScheduleID,
-			&i.RideScheduleUnit,
 			&i.RideScheduleInterval,
+			&i.RideScheduleUnit,
+			&i.RideScheduleTimezone,
+			&i.RideScheduleRrule,
+			&i.GroupID,
 		); err != nil {
 			return nil, err
 		}
@@ -403,32 +419,47 @@ func (q *Queries) RidesGetMany(ctx context.Context, offset int64) ([]RidesGetMan
 	return items, nil
 }
 
-const ridesGetParticipants = `-- name: RidesGetParticipants :many
+const ridesGetCalendarParticipants = `-- name: RidesGetCalendarParticipants :many
 SELECT
-    u.id,
+    rp.ride_event_id,
+    u.name,
     u.email
 FROM
     ride_participants rp
-    INNER JOIN users u ON rp.user_id = u.id
+    INNER JOIN ride_events re ON re.id = rp.ride_event_id
+    INNER JOIN users u ON u.id = rp.user_id
 WHERE
-    rp.ride_event_id = ?
+    re.ride_id IN (/*SLICE:ride_ids*/?)
+ORDER BY
+    u.email
 `
 
-type RidesGetParticipantsRow struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
+type RidesGetCalendarParticipantsRow struct {
+	RideEventID string `json:"rideEventId"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
 }
 
-func (q *Queries) RidesGetParticipants(ctx context.Context, rideEventID string) ([]RidesGetParticipantsRow, error) {
-	rows, err := q.db.QueryContext(ctx, ridesGetParticipants, rideEventID)
+func (q *Queries) RidesGetCalendarParticipants(ctx context.Context, rideIds []string) ([]RidesGetCalendarParticipantsRow, error) {
+	query := ridesGetCalendarParticipants
+	var queryParams []interface{}
+	if len(rideIds) > 0 {
+		for _, v := range rideIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ride_ids*/?", strings.Repeat(",?", len(rideIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ride_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []RidesGetParticipantsRow
+	var items []RidesGetCalendarParticipantsRow
 	for rows.Next() {
-		var i RidesGetParticipantsRow
-		if err := rows.Scan(&i.ID, &i.Email); err != nil {
+		var i RidesGetCalendarParticipantsRow
+		if err := rows.Scan(&i.RideEventID, &i.Name, &i.Email); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -442,37 +473,595 @@ func (q *Queries) RidesGetParticipants(ctx context.Context, rideEventID string)
 	return items, nil
 }
 
-const ridesGetSchedule = `-- name: RidesGetSchedule :one
+const ridesGetCalendarRideIds = `-- name: RidesGetCalendarRideIds :many
 SELECT
-    id,
-    ride_id,
-    schedule_interval,
-    unit
+    r.id
 FROM
-    ride_schedules
+    rides r
 WHERE
-    ride_id = ?
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = ?
+                AND gm.join_status = 'member'
+        )
+    )
+    AND (
+        r.created_by = ?
+        OR r.driver = ?
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_events re
+                LEFT JOIN ride_participants rp ON rp.ride_event_id = re.id
+            WHERE
+                re.ride_id = r.id
+                AND (
+                    re.driver = ?
+                    OR rp.user_id = ?
+                )
+        )
+    )
 `
 
-func (q *Queries) RidesGetSchedule(ctx context.Context, rideID string) (RideSchedule, error) {
-	row := q.db.QueryRowContext(ctx, ridesGetSchedule, rideID)
-	var i RideSchedule
-	err := row.Scan(
-		&i.ID,
-		&i.RideID,
-		&i.ScheduleInterval,
-		&i.Unit,
+func (q *Queries) RidesGetCalendarRideIds(ctx context.Context, userID string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, ridesGetCalendarRideIds,
+		userID,
+		userID,
+		userID,
+		userID,
+		userID,
 	)
-	return i, err
-}
-
-const ridesGetScheduleWeekdays = `-- name: RidesGetScheduleWeekdays :many
-SELECT
-    weekday
-FROM
-    ride_schedule_weekdays
-WHERE
-    ride_schedule_id = ?
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ridesGetEvent = `-- name: RidesGetEvent :one
+SELECT
+    r.id AS ride_id,
+    re.id AS ride_event_id,
+    re.location_from,
+    re.location_to,
+    re.tacking_place_at,
+    r.created_by,
+    re.transport_limit,
+    re.driver,
+    re.status,
+    ud.email AS driver_email,
+    uc.email AS created_by_email,
+    rs.id AS ride_schedule_id,
+    rs.unit AS ride_schedule_unit,
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+    LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
+    INNER JOIN users ud ON re.driver = ud.id
+    INNER JOIN users uc ON r.created_by = uc.id
+WHERE
+    re.id = ?
+`
+
+type RidesGetEventRow struct {
+	RideID               string         `json:"rideId"`
+	RideEventID          string         `json:"rideEventId"`
+	LocationFrom         string         `json:"locationFrom"`
+	LocationTo           string         `json:"locationTo"`
+	TackingPlaceAt       string         `json:"tackingPlaceAt"`
+	CreatedBy            string         `json:"createdBy"`
+	TransportLimit       int64          `json:"transportLimit"`
+	Driver               string         `json:"driver"`
+	Status               string         `json:"status"`
+	DriverEmail          string         `json:"driverEmail"`
+	CreatedByEmail       string         `json:"createdByEmail"`
+	RideScheduleID       sql.NullString `json:"rideScheduleId"`
+	RideScheduleUnit     sql.NullString `json:"rideScheduleUnit"`
+	RideScheduleInterval sql.NullInt64  `json:"rideScheduleInterval"`
+	RideScheduleTimezone sql.NullString `json:"rideScheduleTimezone"`
+	RideScheduleRrule    sql.NullString `json:"rideScheduleRrule"`
+	GroupID              sql.NullString `json:"groupId"`
+}
+
+func (q *Queries) RidesGetEvent(ctx context.Context, id string) (RidesGetEventRow, error) {
+	row := q.db.QueryRowContext(ctx, ridesGetEvent, id)
+	var i RidesGetEventRow
+	err := row.Scan(
+		&i.RideID,
+		&i.RideEventID,
+		&i.LocationFrom,
+		&i.LocationTo,
+		&i.TackingPlaceAt,
+		&i.CreatedBy,
+		&i.TransportLimit,
+		&i.Driver,
+		&i.Status,
+		&i.DriverEmail,
+		&i.CreatedByEmail,
+		&i.RideScheduleID,
+		&i.RideScheduleUnit,
+		&i.RideScheduleInterval,
+		&i.RideScheduleTimezone,
+		&i.RideScheduleRrule,
+		&i.GroupID,
+	)
+	return i, err
+}
+
+const ridesGetEventChanges = `-- name: RidesGetEventChanges :many
+SELECT
+    rec.id,
+    rec.changed_by,
+    u.email AS changed_by_email,
+    rec.changes,
+    rec.created_at
+FROM
+    ride_event_changes rec
+    INNER JOIN users u ON rec.changed_by = u.id
+WHERE
+    rec.ride_event_id = ?
+ORDER BY
+    rec.created_at DESC,
+    rec.rowid DESC
+`
+
+type RidesGetEventChangesRow struct {
+	ID             string `json:"id"`
+	ChangedBy      string `json:"changedBy"`
+	ChangedByEmail string `json:"changedByEmail"`
+	Changes        string `json:"changes"`
+	CreatedAt      string `json:"createdAt"`
+}
+
+func (q *Queries) RidesGetEventChanges(ctx context.Context, rideEventID string) ([]RidesGetEventChangesRow, error) {
+	rows, err := q.db.QueryContext(ctx, ridesGetEventChanges, rideEventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RidesGetEventChangesRow
+	for rows.Next() {
+		var i RidesGetEventChangesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChangedBy,
+			&i.ChangedByEmail,
+			&i.Changes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ridesGetFollowingEvents = `-- name: RidesGetFollowingEvents :many
+SELECT
+    re.id,
+    re.location_from,
+    re.location_to,
+    re.driver,
+    u.email AS driver_email,
+    re.transport_limit,
+    re.tacking_place_at,
+    re.occurrence_at
+FROM
+    ride_events re
+    INNER JOIN users u ON re.driver = u.id
+WHERE
+    re.ride_id = ?
+    AND (
+        re.id = ?
+        OR (
+            re.status = 'upcoming'
+            AND re.occurrence_at > (
+                SELECT
+                    occurrence_at
+                FROM
+                    ride_events
+                WHERE
+                    id = ?
+            )
+        )
+    )
+ORDER BY
+    re.occurrence_at
+`
+
+type RidesGetFollowingEventsParams struct {
+	RideID      string `json:"rideId"`
+	RideEventID string `json:"rideEventId"`
+}
+
+type RidesGetFollowingEventsRow struct {
+	ID             string         `json:"id"`
+	LocationFrom   string         `json:"locationFrom"`
+	LocationTo     string         `json:"locationTo"`
+	Driver         string         `json:"driver"`
+	DriverEmail    string         `json:"driverEmail"`
+	TransportLimit int64          `json:"transportLimit"`
+	TackingPlaceAt string         `json:"tackingPlaceAt"`
+	OccurrenceAt   sql.NullString `json:"occurrenceAt"`
+}
+
+func (q *Queries) RidesGetFollowingEvents(ctx context.Context, arg RidesGetFollowingEventsParams) ([]RidesGetFollowingEventsRow, error) {
+	rows, err := q.db.QueryContext(ctx, ridesGetFollowingEvents, arg.RideID, arg.RideEventID, arg.RideEventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RidesGetFollowingEventsRow
+	for rows.Next() {
+		var i RidesGetFollowingEventsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LocationFrom,
+			&i.LocationTo,
+			&i.Driver,
+			&i.DriverEmail,
+			&i.TransportLimit,
+			&i.TackingPlaceAt,
+			&i.OccurrenceAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ridesGetLatest = `-- name: RidesGetLatest :one
+SELECT
+    r.id AS ride_id,
+    re.id AS ride_event_id,
+    re.location_from,
+    re.location_to,
+    re.tacking_place_at,
+    r.created_by,
+    re.transport_limit,
+    re.driver,
+    re.status,
+    ud.email AS driver_email,
+    uc.email AS created_by_email,
+    rs.id AS ride_schedule_id,
+    rs.unit AS ride_schedule_unit,
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id,
+    r.location_from AS base_location_from,
+    r.location_to AS base_location_to,
+    r.transport_limit AS base_transport_limit,
+    r.driver AS base_driver
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+    LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
+    INNER JOIN users ud ON re.driver = ud.id
+    INNER JOIN users uc ON r.created_by = uc.id
+WHERE
+    re.ride_id = ?
+    AND re.status = 'upcoming'
+    AND re.tacking_place_at = (
+        SELECT
+            MIN(tacking_place_at)
+        FROM
+            ride_events
+        WHERE
+            ride_id = re.ride_id
+            AND status = 'upcoming'
+    )
+`
+
+type RidesGetLatestRow struct {
+	RideID               string         `json:"rideId"`
+	RideEventID          string         `json:"rideEventId"`
+	LocationFrom         string         `json:"locationFrom"`
+	LocationTo           string         `json:"locationTo"`
+	TackingPlaceAt       string         `json:"tackingPlaceAt"`
+	CreatedBy            string         `json:"createdBy"`
+	TransportLimit       int64          `json:"transportLimit"`
+	Driver               string         `json:"driver"`
+	Status               string         `json:"status"`
+	DriverEmail          string         `json:"driverEmail"`
+	CreatedByEmail       string         `json:"createdByEmail"`
+	RideScheduleID       sql.NullString `json:"rideScheduleId"`
+	RideScheduleUnit     sql.NullString `json:"rideScheduleUnit"`
+	RideScheduleInterval sql.NullInt64  `json:"rideScheduleInterval"`
+	RideScheduleTimezone sql.NullString `json:"rideScheduleTimezone"`
+	RideScheduleRrule    sql.NullString `json:"rideScheduleRrule"`
+	GroupID              sql.NullString `json:"groupId"`
+	BaseLocationFrom     string         `json:"baseLocationFrom"`
+	BaseLocationTo       string         `json:"baseLocationTo"`
+	BaseTransportLimit   int64          `json:"baseTransportLimit"`
+	BaseDriver           string         `json:"baseDriver"`
+}
+
+func (q *Queries) RidesGetLatest(ctx context.Context, rideID string) (RidesGetLatestRow, error) {
+	row := q.db.QueryRowContext(ctx, ridesGetLatest, rideID)
+	var i RidesGetLatestRow
+	err := row.Scan(
+		&i.RideID,
+		&i.RideEventID,
+		&i.LocationFrom,
+		&i.LocationTo,
+		&i.TackingPlaceAt,
+		&i.CreatedBy,
+		&i.TransportLimit,
+		&i.Driver,
+		&i.Status,
+		&i.DriverEmail,
+		&i.CreatedByEmail,
+		&i.RideScheduleID,
+		&i.RideScheduleUnit,
+		&i.RideScheduleInterval,
+		&i.RideScheduleTimezone,
+		&i.RideScheduleRrule,
+		&i.GroupID,
+		&i.BaseLocationFrom,
+		&i.BaseLocationTo,
+		&i.BaseTransportLimit,
+		&i.BaseDriver,
+	)
+	return i, err
+}
+
+const ridesGetMany = `-- name: RidesGetMany :many
+SELECT
+    r.id AS ride_id,
+    re.id AS ride_event_id,
+    re.location_from,
+    re.location_to,
+    re.tacking_place_at,
+    r.created_by,
+    re.transport_limit,
+    re.driver,
+    re.status,
+    ud.email AS driver_email,
+    uc.email AS created_by_email,
+    rs.id AS ride_schedule_id,
+    rs.unit AS ride_schedule_unit,
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id,
+    so.ordering AS status_ordering
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+    INNER JOIN ride_event_status_ordering so ON so.status = re.status
+    LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
+    INNER JOIN users ud ON re.driver = ud.id
+    INNER JOIN users uc ON r.created_by = uc.id
+WHERE
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = ?
+                AND gm.join_status = 'member'
+        )
+    )
+    AND (
+        ? IS NULL
+        OR so.ordering > ?
+        OR (
+            so.ordering = ?
+            AND re.tacking_place_at < ?
+        )
+        OR (
+            so.ordering = ?
+            AND re.tacking_place_at = ?
+            AND re.id > ?
+        )
+    )
+ORDER BY
+    so.ordering,
+    re.tacking_place_at DESC,
+    re.id
+LIMIT
+    ?
+`
+
+type RidesGetManyParams struct {
+	UserID               string         `json:"userId"`
+	CursorOrdering       sql.NullInt64  `json:"cursorOrdering"`
+	CursorTackingPlaceAt sql.NullString `json:"cursorTackingPlaceAt"`
+	CursorID             sql.NullString `json:"cursorId"`
+	PageSize             int64          `json:"pageSize"`
+}
+
+type RidesGetManyRow struct {
+	RideID               string         `json:"rideId"`
+	RideEventID          string         `json:"rideEventId"`
+	LocationFrom         string         `json:"locationFrom"`
+	LocationTo           string         `json:"locationTo"`
+	TackingPlaceAt       string         `json:"tackingPlaceAt"`
+	CreatedBy            string         `json:"createdBy"`
+	TransportLimit       int64          `json:"transportLimit"`
+	Driver               string         `json:"driver"`
+	Status               string         `json:"status"`
+	DriverEmail          string         `json:"driverEmail"`
+	CreatedByEmail       string         `json:"createdByEmail"`
+	RideScheduleID       sql.NullString `json:"rideScheduleId"`
+	RideScheduleUnit     sql.NullString `json:"rideScheduleUnit"`
+	RideScheduleInterval sql.NullInt64  `json:"rideScheduleInterval"`
+	RideScheduleTimezone sql.NullString `json:"rideScheduleTimezone"`
+	RideScheduleRrule    sql.NullString `json:"rideScheduleRrule"`
+	GroupID              sql.NullString `json:"groupId"`
+	StatusOrdering       int64          `json:"statusOrdering"`
+}
+
+func (q *Queries) RidesGetMany(ctx context.Context, arg RidesGetManyParams) ([]RidesGetManyRow, error) {
+	rows, err := q.db.QueryContext(ctx, ridesGetMany,
+		arg.UserID,
+		arg.CursorOrdering,
+		arg.CursorOrdering,
+		arg.CursorOrdering,
+		arg.CursorTackingPlaceAt,
+		arg.CursorOrdering,
+		arg.CursorTackingPlaceAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RidesGetManyRow
+	for rows.Next() {
+		var i RidesGetManyRow
+		if err := rows.Scan(
+			&i.RideID,
+			&i.RideEventID,
+			&i.LocationFrom,
+			&i.LocationTo,
+			&i.TackingPlaceAt,
+			&i.CreatedBy,
+			&i.TransportLimit,
+			&i.Driver,
+			&i.Status,
+			&i.DriverEmail,
+			&i.CreatedByEmail,
+			&i.RideScheduleID,
+			&i.RideScheduleUnit,
+			&i.RideScheduleInterval,
+			&i.RideScheduleTimezone,
+			&i.RideScheduleRrule,
+			&i.GroupID,
+			&i.StatusOrdering,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ridesGetParticipants = `-- name: RidesGetParticipants :many
+SELECT
+    u.id,
+    u.email
+FROM
+    ride_participants rp
+    INNER JOIN users u ON rp.user_id = u.id
+WHERE
+    rp.ride_event_id = ?
+`
+
+type RidesGetParticipantsRow struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+func (q *Queries) RidesGetParticipants(ctx context.Context, rideEventID string) ([]RidesGetParticipantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, ridesGetParticipants, rideEventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RidesGetParticipantsRow
+	for rows.Next() {
+		var i RidesGetParticipantsRow
+		if err := rows.Scan(&i.ID, &i.Email); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ridesGetSchedule = `-- name: RidesGetSchedule :one
+SELECT
+    id,
+    ride_id,
+    schedule_interval,
+    unit,
+    timezone,
+    rrule
+FROM
+    ride_schedules
+WHERE
+    ride_id = ?
+`
+
+func (q *Queries) RidesGetSchedule(ctx context.Context, rideID string) (RideSchedule, error) {
+	row := q.db.QueryRowContext(ctx, ridesGetSchedule, rideID)
+	var i RideSchedule
+	err := row.Scan(
+		&i.ID,
+		&i.RideID,
+		&i.ScheduleInterval,
+		&i.Unit,
+		&i.Timezone,
+		&i.Rrule,
+	)
+	return i, err
+}
+
+const ridesGetScheduleWeekdays = `-- name: RidesGetScheduleWeekdays :many
+SELECT
+    weekday
+FROM
+    ride_schedule_weekdays
+WHERE
+    ride_schedule_id = ?
 `
 
 func (q *Queries) RidesGetScheduleWeekdays(ctx context.Context, rideScheduleID string) ([]string, error) {
@@ -498,6 +1087,142 @@ func (q *Queries) RidesGetScheduleWeekdays(ctx context.Context, rideScheduleID s
 	return items, nil
 }
 
+const ridesGetSchedulesWithLastEvent = `-- name: RidesGetSchedulesWithLastEvent :many
+SELECT
+    rs.id,
+    rs.ride_id,
+    rs.schedule_interval,
+    rs.unit,
+    rs.timezone,
+    rs.rrule,
+    r.tacking_place_at AS anchor,
+    r.location_from,
+    r.location_to,
+    r.driver,
+    r.transport_limit,
+    r.occurrence_offset,
+    re.occurrence_at AS last_occurrence_at,
+    (
+        SELECT
+            COUNT(id)
+        FROM
+            ride_events
+        WHERE
+            ride_id = rs.ride_id
+            AND tacking_place_at > ?
+    ) AS future_events
+FROM
+    ride_schedules rs
+    INNER JOIN rides r ON r.id = rs.ride_id
+    INNER JOIN ride_events re ON re.ride_id = rs.ride_id
+WHERE
+    re.occurrence_at = (
+        SELECT
+            MAX(occurrence_at)
+        FROM
+            ride_events
+        WHERE
+            ride_id = rs.ride_id
+    )
+`
+
+type RidesGetSchedulesWithLastEventRow struct {
+	ID               string         `json:"id"`
+	RideID           string         `json:"rideId"`
+	ScheduleInterval int64          `json:"scheduleInterval"`
+	Unit             string         `json:"unit"`
+	Timezone         string         `json:"timezone"`
+	Rrule            sql.NullString `json:"rrule"`
+	Anchor           string         `json:"anchor"`
+	LocationFrom     string         `json:"locationFrom"`
+	LocationTo       string         `json:"locationTo"`
+	Driver           string         `json:"driver"`
+	TransportLimit   int64          `json:"transportLimit"`
+	OccurrenceOffset int64          `json:"occurrenceOffset"`
+	LastOccurrenceAt sql.NullString `json:"lastOccurrenceAt"`
+	FutureEvents     int64          `json:"futureEvents"`
+}
+
+func (q *Queries) RidesGetSchedulesWithLastEvent(ctx context.Context, now string) ([]RidesGetSchedulesWithLastEventRow, error) {
+	rows, err := q.db.QueryContext(ctx, ridesGetSchedulesWithLastEvent, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RidesGetSchedulesWithLastEventRow
+	for rows.Next() {
+		var i RidesGetSchedulesWithLastEventRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.RideID,
+			&i.ScheduleInterval,
+			&i.Unit,
+			&i.Timezone,
+			&i.Rrule,
+			&i.Anchor,
+			&i.LocationFrom,
+			&i.LocationTo,
+			&i.Driver,
+			&i.TransportLimit,
+			&i.OccurrenceOffset,
+			&i.LastOccurrenceAt,
+			&i.FutureEvents,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ridesGetWaitlist = `-- name: RidesGetWaitlist :many
+SELECT
+    u.id,
+    u.email
+FROM
+    ride_waitlist rw
+    INNER JOIN users u ON rw.user_id = u.id
+WHERE
+    rw.ride_event_id = ?
+ORDER BY
+    rw.created_at,
+    rw.rowid
+`
+
+type RidesGetWaitlistRow struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+func (q *Queries) RidesGetWaitlist(ctx context.Context, rideEventID string) ([]RidesGetWaitlistRow, error) {
+	rows, err := q.db.QueryContext(ctx, ridesGetWaitlist, rideEventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RidesGetWaitlistRow
+	for rows.Next() {
+		var i RidesGetWaitlistRow
+		if err := rows.Scan(&i.ID, &i.Email); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const ridesJoinEvent = `-- name: RidesJoinEvent :exec
 INSERT INTO
     ride_participants (ride_event_id, user_id)
@@ -515,28 +1240,248 @@ func (q *Queries) RidesJoinEvent(ctx context.Context, arg RidesJoinEventParams)
 	return err
 }
 
-const ridesMarkPastEventsDone = `-- name: RidesMarkPastEventsDone :many
+const ridesLeaveEvent = `-- name: RidesLeaveEvent :execrows
+DELETE FROM ride_participants
+WHERE
+    ride_event_id = ?
+    AND user_id = ?
+`
+
+type RidesLeaveEventParams struct {
+	RideEventID string `json:"rideEventId"`
+	UserID      string `json:"userId"`
+}
+
+func (q *Queries) RidesLeaveEvent(ctx context.Context, arg RidesLeaveEventParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, ridesLeaveEvent, arg.RideEventID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ridesMarkPastEventsDone = `-- name: RidesMarkPastEventsDone :exec
 UPDATE ride_events
 SET
     status = 'done'
 WHERE
     status = 'upcoming'
-    AND tacking_place_at <= ? RETURNING id
+    AND tacking_place_at <= ?
+`
+
+func (q *Queries) RidesMarkPastEventsDone(ctx context.Context, tackingPlaceAt string) error {
+	_, err := q.db.ExecContext(ctx, ridesMarkPastEventsDone, tackingPlaceAt)
+	return err
+}
+
+const ridesSearch = `-- name: RidesSearch :many
+SELECT
+    r.id AS ride_id,
+    re.id AS ride_event_id,
+    re.location_from,
+    re.location_to,
+    re.tacking_place_at,
+    r.created_by,
+    re.transport_limit,
+    re.driver,
+    re.status,
+    ud.email AS driver_email,
+    uc.email AS created_by_email,
+    rs.id AS ride_schedule_id,
+    rs.unit AS ride_schedule_unit,
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id,
+    so.ordering AS status_ordering
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+    INNER JOIN ride_event_status_ordering so ON so.status = re.status
+    LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
+    INNER JOIN users ud ON re.driver = ud.id
+    INNER JOIN users uc ON r.created_by = uc.id
+WHERE
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = ?
+                AND gm.join_status = 'member'
+        )
+    )
+    AND (
+        ? IS NULL
+        OR re.status = ?
+    )
+    AND (
+        ? IS NULL
+        OR re.location_from = ?
+    )
+    AND (
+        ? IS NULL
+        OR re.location_to = ?
+    )
+    AND (
+        ? IS NULL
+        OR ud.email = ?
+    )
+    AND (
+        ? IS NULL
+        OR uc.email = ?
+    )
+    AND (
+        ? IS NULL
+        OR re.tacking_place_at <= ?
+    )
+    AND (
+        ? IS NULL
+        OR re.tacking_place_at >= ?
+    )
+    AND (
+        CAST(? AS INTEGER) = 0
+        OR (
+            SELECT
+                COUNT(DISTINCT pu.email)
+            FROM
+                ride_participants rp
+                INNER JOIN users pu ON rp.user_id = pu.id
+            WHERE
+                rp.ride_event_id = re.id
+                AND pu.email IN (/*SLICE:participants*/?)
+        ) = CAST(? AS INTEGER)
+    )
+    AND (
+        ? IS NULL
+        OR so.ordering > ?
+        OR (
+            so.ordering = ?
+            AND re.tacking_place_at < ?
+        )
+        OR (
+            so.ordering = ?
+            AND re.tacking_place_at = ?
+            AND re.id > ?
+        )
+    )
+ORDER BY
+    so.ordering,
+    re.tacking_place_at DESC,
+    re.id
+LIMIT
+    ?
 `
 
-func (q *Queries) RidesMarkPastEventsDone(ctx context.Context, tackingPlaceAt string) ([]string, error) {
-	rows, err := q.db.QueryContext(ctx, ridesMarkPastEventsDone, tackingPlaceAt)
+type RidesSearchParams struct {
+	UserID               string         `json:"userId"`
+	Status               sql.NullString `json:"status"`
+	LocationFrom         sql.NullString `json:"locationFrom"`
+	LocationTo           sql.NullString `json:"locationTo"`
+	DriverEmail          sql.NullString `json:"driverEmail"`
+	CreatedByEmail       sql.NullString `json:"createdByEmail"`
+	Before               sql.NullString `json:"before"`
+	After                sql.NullString `json:"after"`
+	ParticipantsCount    int64          `json:"participantsCount"`
+	Participants         []string       `json:"participants"`
+	CursorOrdering       sql.NullInt64  `json:"cursorOrdering"`
+	CursorTackingPlaceAt sql.NullString `json:"cursorTackingPlaceAt"`
+	CursorID             sql.NullString `json:"cursorId"`
+	PageSize             int64          `json:"pageSize"`
+}
+
+type RidesSearchRow struct {
+	RideID               string         `json:"rideId"`
+	RideEventID          string         `json:"rideEventId"`
+	LocationFrom         string         `json:"locationFrom"`
+	LocationTo           string         `json:"locationTo"`
+	TackingPlaceAt       string         `json:"tackingPlaceAt"`
+	CreatedBy            string         `json:"createdBy"`
+	TransportLimit       int64          `json:"transportLimit"`
+	Driver               string         `json:"driver"`
+	Status               string         `json:"status"`
+	DriverEmail          string         `json:"driverEmail"`
+	CreatedByEmail       string         `json:"createdByEmail"`
+	RideScheduleID       sql.NullString `json:"rideScheduleId"`
+	RideScheduleUnit     sql.NullString `json:"rideScheduleUnit"`
+	RideScheduleInterval sql.NullInt64  `json:"rideScheduleInterval"`
+	RideScheduleTimezone sql.NullString `json:"rideScheduleTimezone"`
+	RideScheduleRrule    sql.NullString `json:"rideScheduleRrule"`
+	GroupID              sql.NullString `json:"groupId"`
+	StatusOrdering       int64          `json:"statusOrdering"`
+}
+
+func (q *Queries) RidesSearch(ctx context.Context, arg RidesSearchParams) ([]RidesSearchRow, error) {
+	query := ridesSearch
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.UserID)
+	queryParams = append(queryParams, arg.Status)
+	queryParams = append(queryParams, arg.Status)
+	queryParams = append(queryParams, arg.LocationFrom)
+	queryParams = append(queryParams, arg.LocationFrom)
+	queryParams = append(queryParams, arg.LocationTo)
+	queryParams = append(queryParams, arg.LocationTo)
+	queryParams = append(queryParams, arg.DriverEmail)
+	queryParams = append(queryParams, arg.DriverEmail)
+	queryParams = append(queryParams, arg.CreatedByEmail)
+	queryParams = append(queryParams, arg.CreatedByEmail)
+	queryParams = append(queryParams, arg.Before)
+	queryParams = append(queryParams, arg.Before)
+	queryParams = append(queryParams, arg.After)
+	queryParams = append(queryParams, arg.After)
+	queryParams = append(queryParams, arg.ParticipantsCount)
+	if len(arg.Participants) > 0 {
+		for _, v := range arg.Participants {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:participants*/?", strings.Repeat(",?", len(arg.Participants))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:participants*/?", "NULL", 1)
+	}
+	queryParams = append(queryParams, arg.ParticipantsCount)
+	queryParams = append(queryParams, arg.CursorOrdering)
+	queryParams = append(queryParams, arg.CursorOrdering)
+	queryParams = append(queryParams, arg.CursorOrdering)
+	queryParams = append(queryParams, arg.CursorTackingPlaceAt)
+	queryParams = append(queryParams, arg.CursorOrdering)
+	queryParams = append(queryParams, arg.CursorTackingPlaceAt)
+	queryParams = append(queryParams, arg.CursorID)
+	queryParams = append(queryParams, arg.PageSize)
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []string
+	var items []RidesSearchRow
 	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
+		var i RidesSearchRow
+		if err := rows.Scan(
+			&i.RideID,
+			&i.RideEventID,
+			&i.LocationFrom,
+			&i.LocationTo,
+			&i.TackingPlaceAt,
+			&i.CreatedBy,
+			&i.TransportLimit,
+			&i.Driver,
+			&i.Status,
+			&i.DriverEmail,
+			&i.CreatedByEmail,
+			&i.RideScheduleID,
+			&i.RideScheduleUnit,
+			&i.RideScheduleInterval,
+			&i.RideScheduleTimezone,
+			&i.RideScheduleRrule,
+			&i.GroupID,
+			&i.StatusOrdering,
+		); err != nil {
 			return nil, err
 		}
-		items = append(items, id)
+		items = append(items, i)
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -547,6 +1492,184 @@ func (q *Queries) RidesMarkPastEventsDone(ctx context.Context, tackingPlaceAt st
 	return items, nil
 }
 
+const ridesSearchCount = `-- name: RidesSearchCount :one
+SELECT
+    COUNT(re.id)
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+    INNER JOIN users ud ON re.driver = ud.id
+    INNER JOIN users uc ON r.created_by = uc.id
+WHERE
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = ?
+                AND gm.join_status = 'member'
+        )
+    )
+    AND (
+        ? IS NULL
+        OR re.status = ?
+    )
+    AND (
+        ? IS NULL
+        OR re.location_from = ?
+    )
+    AND (
+        ? IS NULL
+        OR re.location_to = ?
+    )
+    AND (
+        ? IS NULL
+        OR ud.email = ?
+    )
+    AND (
+        ? IS NULL
+        OR uc.email = ?
+    )
+    AND (
+        ? IS NULL
+        OR re.tacking_place_at <= ?
+    )
+    AND (
+        ? IS NULL
+        OR re.tacking_place_at >= ?
+    )
+    AND (
+        CAST(? AS INTEGER) = 0
+        OR (
+            SELECT
+                COUNT(DISTINCT pu.email)
+            FROM
+                ride_participants rp
+                INNER JOIN users pu ON rp.user_id = pu.id
+            WHERE
+                rp.ride_event_id = re.id
+                AND pu.email IN (/*SLICE:participants*/?)
+        ) = CAST(? AS INTEGER)
+    )
+`
+
+type RidesSearchCountParams struct {
+	UserID            string         `json:"userId"`
+	Status            sql.NullString `json:"status"`
+	LocationFrom      sql.NullString `json:"locationFrom"`
+	LocationTo        sql.NullString `json:"locationTo"`
+	DriverEmail       sql.NullString `json:"driverEmail"`
+	CreatedByEmail    sql.NullString `json:"createdByEmail"`
+	Before            sql.NullString `json:"before"`
+	After             sql.NullString `json:"after"`
+	ParticipantsCount int64          `json:"participantsCount"`
+	Participants      []string       `json:"participants"`
+}
+
+func (q *Queries) RidesSearchCount(ctx context.Context, arg RidesSearchCountParams) (int64, error) {
+	query := ridesSearchCount
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.UserID)
+	queryParams = append(queryParams, arg.Status)
+	queryParams = append(queryParams, arg.Status)
+	queryParams = append(queryParams, arg.LocationFrom)
+	queryParams = append(queryParams, arg.LocationFrom)
+	queryParams = append(queryParams, arg.LocationTo)
+	queryParams = append(queryParams, arg.LocationTo)
+	queryParams = append(queryParams, arg.DriverEmail)
+	queryParams = append(queryParams, arg.DriverEmail)
+	queryParams = append(queryParams, arg.CreatedByEmail)
+	queryParams = append(queryParams, arg.CreatedByEmail)
+	queryParams = append(queryParams, arg.Before)
+	queryParams = append(queryParams, arg.Before)
+	queryParams = append(queryParams, arg.After)
+	queryParams = append(queryParams, arg.After)
+	queryParams = append(queryParams, arg.ParticipantsCount)
+	if len(arg.Participants) > 0 {
+		for _, v := range arg.Participants {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:participants*/?", strings.Repeat(",?", len(arg.Participants))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:participants*/?", "NULL", 1)
+	}
+	queryParams = append(queryParams, arg.ParticipantsCount)
+	row := q.db.QueryRowContext(ctx, query, queryParams...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const ridesUpdateBase = `-- name: RidesUpdateBase :exec
+UPDATE rides
+SET
+    location_from = COALESCE(?, location_from),
+    location_to = COALESCE(?, location_to),
+    driver = COALESCE(?, driver),
+    transport_limit = COALESCE(?, transport_limit),
+    occurrence_offset = COALESCE(?, occurrence_offset)
+WHERE
+    id = ?
+`
+
+type RidesUpdateBaseParams struct {
+	LocationFrom     sql.NullString `json:"locationFrom"`
+	LocationTo       sql.NullString `json:"locationTo"`
+	Driver           sql.NullString `json:"driver"`
+	TransportLimit   sql.NullInt64  `json:"transportLimit"`
+	OccurrenceOffset sql.NullInt64  `json:"occurrenceOffset"`
+	ID               string         `json:"id"`
+}
+
+func (q *Queries) RidesUpdateBase(ctx context.Context, arg RidesUpdateBaseParams) error {
+	_, err := q.db.ExecContext(ctx, ridesUpdateBase,
+		arg.LocationFrom,
+		arg.LocationTo,
+		arg.Driver,
+		arg.TransportLimit,
+		arg.OccurrenceOffset,
+		arg.ID,
+	)
+	return err
+}
+
+const ridesUpdateEventDetails = `-- name: RidesUpdateEventDetails :exec
+UPDATE ride_events
+SET
+    location_from = COALESCE(?, location_from),
+    location_to = COALESCE(?, location_to),
+    driver = COALESCE(?, driver),
+    transport_limit = COALESCE(?, transport_limit),
+    tacking_place_at = COALESCE(?, tacking_place_at)
+WHERE
+    id = ?
+`
+
+type RidesUpdateEventDetailsParams struct {
+	LocationFrom   sql.NullString `json:"locationFrom"`
+	LocationTo     sql.NullString `json:"locationTo"`
+	Driver         sql.NullString `json:"driver"`
+	TransportLimit sql.NullInt64  `json:"transportLimit"`
+	TackingPlaceAt sql.NullString `json:"tackingPlaceAt"`
+	ID             string         `json:"id"`
+}
+
+func (q *Queries) RidesUpdateEventDetails(ctx context.Context, arg RidesUpdateEventDetailsParams) error {
+	_, err := q.db.ExecContext(ctx, ridesUpdateEventDetails,
+		arg.LocationFrom,
+		arg.LocationTo,
+		arg.Driver,
+		arg.TransportLimit,
+		arg.TackingPlaceAt,
+		arg.ID,
+	)
+	return err
+}
+
 const ridesUpdateEventStatus = `-- name: RidesUpdateEventStatus :exec
 UPDATE ride_events
 SET
@@ -564,3 +1687,40 @@ func (q *Queries) RidesUpdateEventStatus(ctx context.Context, arg RidesUpdateEve
 	_, err := q.db.ExecContext(ctx, ridesUpdateEventStatus, arg.Status, arg.ID)
 	return err
 }
+
+const ridesWaitlistAdd = `-- name: RidesWaitlistAdd :exec
+INSERT INTO
+    ride_waitlist (ride_event_id, user_id)
+VALUES
+    (?, ?)
+`
+
+type RidesWaitlistAddParams struct {
+	RideEventID string `json:"rideEventId"`
+	UserID      string `json:"userId"`
+}
+
+func (q *Queries) RidesWaitlistAdd(ctx context.Context, arg RidesWaitlistAddParams) error {
+	_, err := q.db.ExecContext(ctx, ridesWaitlistAdd, arg.RideEventID, arg.UserID)
+	return err
+}
+
+const ridesWaitlistRemove = `-- name: RidesWaitlistRemove :execrows
+DELETE FROM ride_waitlist
+WHERE
+    ride_event_id = ?
+    AND user_id = ?
+`
+
+type RidesWaitlistRemoveParams struct {
+	RideEventID string `json:"rideEventId"`
+	UserID      string `json:"userId"`
+}
+
+func (q *Queries) RidesWaitlistRemove(ctx context.Context, arg RidesWaitlistRemoveParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, ridesWaitlistRemove, arg.RideEventID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -7,9 +7,161 @@ package sqlc
 
 import (
 	"context"
-	"database/sql"
 )
 
+const userBlocksAdd = `-- name: UserBlocksAdd :execrows
+INSERT INTO
+    user_blocks (user_id, blocked_user_id)
+VALUES
+    (?, ?) ON CONFLICT DO NOTHING
+`
+
+type UserBlocksAddParams struct {
+	UserID        string `json:"userId"`
+	BlockedUserID string `json:"blockedUserId"`
+}
+
+func (q *Queries) UserBlocksAdd(ctx context.Context, arg UserBlocksAddParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, userBlocksAdd, arg.UserID, arg.BlockedUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const userBlocksBetween = `-- name: UserBlocksBetween :one
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            user_blocks
+        WHERE
+            (
+                user_id = ?
+                AND blocked_user_id = ?
+            )
+            OR (
+                user_id = ?
+                AND blocked_user_id = ?
+            )
+    )
+`
+
+type UserBlocksBetweenParams struct {
+	UserA string `json:"userA"`
+	UserB string `json:"userB"`
+}
+
+// Checks if either of the users blocked the other.
+func (q *Queries) UserBlocksBetween(ctx context.Context, arg UserBlocksBetweenParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, userBlocksBetween,
+		arg.UserA,
+		arg.UserB,
+		arg.UserB,
+		arg.UserA,
+	)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const userBlocksGet = `-- name: UserBlocksGet :many
+SELECT
+    ub.blocked_user_id,
+    u.email AS blocked_user_email,
+    ub.created_at
+FROM
+    user_blocks ub
+    INNER JOIN users u ON u.id = ub.blocked_user_id
+WHERE
+    ub.user_id = ?
+ORDER BY
+    ub.created_at DESC
+`
+
+type UserBlocksGetRow struct {
+	BlockedUserID    string `json:"blockedUserId"`
+	BlockedUserEmail string `json:"blockedUserEmail"`
+	CreatedAt        string `json:"createdAt"`
+}
+
+func (q *Queries) UserBlocksGet(ctx context.Context, userID string) ([]UserBlocksGetRow, error) {
+	rows, err := q.db.QueryContext(ctx, userBlocksGet, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserBlocksGetRow
+	for rows.Next() {
+		var i UserBlocksGetRow
+		if err := rows.Scan(&i.BlockedUserID, &i.BlockedUserEmail, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const userBlocksRemove = `-- name: UserBlocksRemove :execrows
+DELETE FROM user_blocks
+WHERE
+    user_id = ?
+    AND blocked_user_id = ?
+`
+
+type UserBlocksRemoveParams struct {
+	UserID        string `json:"userId"`
+	BlockedUserID string `json:"blockedUserId"`
+}
+
+func (q *Queries) UserBlocksRemove(ctx context.Context, arg UserBlocksRemoveParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, userBlocksRemove, arg.UserID, arg.BlockedUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const usersCountByEmail = `-- name: UsersCountByEmail :one
+SELECT
+    COUNT(DISTINCT u.id)
+FROM
+    users u
+    LEFT JOIN user_identities ui ON ui.user_id = u.id
+WHERE
+    lower(?) IN (lower(u.email), lower(ui.email))
+`
+
+func (q *Queries) UsersCountByEmail(ctx context.Context, email string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, usersCountByEmail, email)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const usersCountIdentities = `-- name: UsersCountIdentities :one
+SELECT
+    COUNT(*)
+FROM
+    user_identities
+WHERE
+    user_id = ?
+`
+
+func (q *Queries) UsersCountIdentities(ctx context.Context, userID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, usersCountIdentities, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const usersCreate = `-- name: UsersCreate :one
 INSERT INTO
     users (id, name, email, provider)
@@ -47,6 +199,103 @@ func (q *Queries) UsersCreate(ctx context.Context, arg UsersCreateParams) (User,
 	return i, err
 }
 
+const usersCreateIdentity = `-- name: UsersCreateIdentity :one
+INSERT INTO
+    user_identities (user_id, provider, subject, email)
+VALUES
+    (?, ?, ?, ?) RETURNING id, user_id, provider, subject, email, created_at
+`
+
+type UsersCreateIdentityParams struct {
+	UserID   string `json:"userId"`
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) UsersCreateIdentity(ctx context.Context, arg UsersCreateIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRowContext(ctx, usersCreateIdentity,
+		arg.UserID,
+		arg.Provider,
+		arg.Subject,
+		arg.Email,
+	)
+	var i UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const usersDeleteIdentity = `-- name: UsersDeleteIdentity :execrows
+DELETE FROM user_identities
+WHERE
+    id = ?
+    AND user_id = ?
+`
+
+type UsersDeleteIdentityParams struct {
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+}
+
+func (q *Queries) UsersDeleteIdentity(ctx context.Context, arg UsersDeleteIdentityParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, usersDeleteIdentity, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const usersDropCalendarToken = `-- name: UsersDropCalendarToken :exec
+DELETE FROM calendar_tokens
+WHERE
+    user_id = ?
+`
+
+func (q *Queries) UsersDropCalendarToken(ctx context.Context, userID string) error {
+	_, err := q.db.ExecContext(ctx, usersDropCalendarToken, userID)
+	return err
+}
+
+const usersGetByCalendarToken = `-- name: UsersGetByCalendarToken :one
+SELECT
+    u.id,
+    u.name,
+    u.email,
+    u.provider,
+    u.access_token,
+    u.refresh_token,
+    u.is_admin,
+    u.is_blocked
+FROM
+    users u
+    INNER JOIN calendar_tokens ct ON ct.user_id = u.id
+WHERE
+    ct.token_hash = ?
+`
+
+func (q *Queries) UsersGetByCalendarToken(ctx context.Context, tokenHash string) (User, error) {
+	row := q.db.QueryRowContext(ctx, usersGetByCalendarToken, tokenHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Provider,
+		&i.AccessToken,
+		&i.RefreshToken,
+		&i.IsAdmin,
+		&i.IsBlocked,
+	)
+	return i, err
+}
+
 const usersGetById = `-- name: UsersGetById :one
 SELECT
     id, name, email, provider, access_token, refresh_token, is_admin, is_blocked
@@ -72,6 +321,77 @@ func (q *Queries) UsersGetById(ctx context.Context, id string) (User, error) {
 	return i, err
 }
 
+const usersGetIdentities = `-- name: UsersGetIdentities :many
+SELECT
+    id, user_id, provider, subject, email, created_at
+FROM
+    user_identities
+WHERE
+    user_id = ?
+ORDER BY
+    created_at,
+    rowid
+`
+
+func (q *Queries) UsersGetIdentities(ctx context.Context, userID string) ([]UserIdentity, error) {
+	rows, err := q.db.QueryContext(ctx, usersGetIdentities, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserIdentity
+	for rows.Next() {
+		var i UserIdentity
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Provider,
+			&i.Subject,
+			&i.Email,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const usersGetIdentity = `-- name: UsersGetIdentity :one
+SELECT
+    id, user_id, provider, subject, email, created_at
+FROM
+    user_identities
+WHERE
+    provider = ?
+    AND subject = ?
+`
+
+type UsersGetIdentityParams struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+}
+
+func (q *Queries) UsersGetIdentity(ctx context.Context, arg UsersGetIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRowContext(ctx, usersGetIdentity, arg.Provider, arg.Subject)
+	var i UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const usersSetBlocked = `-- name: UsersSetBlocked :exec
 UPDATE users
 SET
@@ -90,53 +410,41 @@ func (q *Queries) UsersSetBlocked(ctx context.Context, arg UsersSetBlockedParams
 	return err
 }
 
-const usersSetTokens = `-- name: UsersSetTokens :exec
-UPDATE users
+const usersSetCalendarToken = `-- name: UsersSetCalendarToken :exec
+INSERT INTO
+    calendar_tokens (user_id, token_hash)
+VALUES
+    (?, ?) ON CONFLICT (user_id) DO
+UPDATE
 SET
-    access_token = ?,
-    refresh_token = ?
-WHERE
-    id = ?
+    token_hash = excluded.token_hash,
+    created_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
 `
 
-type UsersSetTokensParams struct {
-	AccessToken  sql.NullString `json:"accessToken"`
-	RefreshToken sql.NullString `json:"refreshToken"`
-	ID           string         `json:"id"`
+type UsersSetCalendarTokenParams struct {
+	UserID    string `json:"userId"`
+	TokenHash string `json:"tokenHash"`
 }
 
-func (q *Queries) UsersSetTokens(ctx context.Context, arg UsersSetTokensParams) error {
-	_, err := q.db.ExecContext(ctx, usersSetTokens, arg.AccessToken, arg.RefreshToken, arg.ID)
+func (q *Queries) UsersSetCalendarToken(ctx context.Context, arg UsersSetCalendarTokenParams) error {
+	_, err := q.db.ExecContext(ctx, usersSetCalendarToken, arg.UserID, arg.TokenHash)
 	return err
 }
 
-const usersUpdateNameAndEmail = `-- name: UsersUpdateNameAndEmail :one
-UPDATE users
+const usersUpdateIdentityEmail = `-- name: UsersUpdateIdentityEmail :exec
+UPDATE user_identities
 SET
-    name = ?,
     email = ?
 WHERE
-    id = ? RETURNING id, name, email, provider, access_token, refresh_token, is_admin, is_blocked
+    id = ?
 `
 
-type UsersUpdateNameAndEmailParams struct {
-	Name  string `json:"name"`
+type UsersUpdateIdentityEmailParams struct {
 	Email string `json:"email"`
 	ID    string `json:"id"`
 }
 
-func (q *Queries) UsersUpdateNameAndEmail(ctx context.Context, arg UsersUpdateNameAndEmailParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, usersUpdateNameAndEmail, arg.Name, arg.Email, arg.ID)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Email,
-		&i.Provider,
-		&i.AccessToken,
-		&i.RefreshToken,
-		&i.IsAdmin,
-		&i.IsBlocked,
-	)
-	return i, err
+func (q *Queries) UsersUpdateIdentityEmail(ctx context.Context, arg UsersUpdateIdentityEmailParams) error {
+	_, err := q.db.ExecContext(ctx, usersUpdateIdentityEmail, arg.Email, arg.ID)
+	return err
 }
@@ -2,6 +2,9 @@ package utils
 
 import (
 	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"ride_sharing_api/app/assert"
@@ -11,6 +14,13 @@ import (
 	"syscall"
 )
 
+// Access tokens of the users in 'db/testing/setup/no-init-add-three-users.sql'.
+const (
+	TEST_TOKEN_USER_01 = "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.u1-ygjEG66RCRHlwrbHvgNXI0zp4lutEzruwxyTglHKkMCFlkkAHliwv096g5Vq9sYnt1hHvKk3v79D_NeBLB02xLIXm2Tuijcj4a5-W7j0BDSYNnFgWWtOZnsI_IJFKr9rAkMS6-3GiKS0z8Q"
+	TEST_TOKEN_USER_02 = "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.w19_ESPa5AgLpH2IV9CrwSPq4XQcJJHgAqVf2bNmcOsdamLKJL_JntjmB-Er356m2bH4OlXg0hYagmU6irxwLymqB7QCGhsF1ixtMwyrGq8lFeePVwb41uD_5xRRkJxjB0GeIO_AIyCl9yCVa3djzcvx-6EBC1f_E9_t"
+	TEST_TOKEN_USER_03 = "eyJhbGciOiJBMjU2R0NNIiwia2lkIjoidGVzdC1rZXktMDEifQ.cevnH03gVtsxMU7jy3vx4c7caO9X1tQuTTTyHR6a4ZAmqi3Icut3oi9UPfToSx7hnzvBk265_VIt7W5u-Tu2s7SXG6RPEBWlHzQ6DFEZjDsXLd1eqVONAPgWifhpD3UrooBLYzUyJ0lDh05O5gOZxaKYB1o"
+)
+
 var existingDBs = make([]string, 0)
 
 func InitTestDB(setupFilePath string) *sql.DB {
@@ -56,3 +66,18 @@ func InitTestDB(setupFilePath string) *sql.DB {
 
 	return db
 }
+
+// Sends a request to the test server and returns the status code and body of
+// the response. An empty `token` sends no Authorization header.
+func TestRequest(api *httptest.Server, method string, endpoint string, token string, body string) (int, string) {
+	req, err := http.NewRequest(method, api.URL+endpoint, strings.NewReader(body))
+	assert.Nil(err)
+	if token != "" {
+		req.Header.Add("Authorization", token)
+	}
+	resp, err := api.Client().Do(req)
+	assert.Nil(err)
+	data, err := io.ReadAll(resp.Body)
+	assert.Nil(err)
+	return resp.StatusCode, string(data)
+}
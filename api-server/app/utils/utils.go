@@ -42,6 +42,17 @@ func GetEnvRequired(key string) string {
 	return val
 }
 
+// Get the value of the environment variable `key`. Returns `fallback` if the
+// variable isn't set.
+func GetEnvOrDefault(key string, fallback string) string {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+
+	return val
+}
+
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -74,6 +85,14 @@ func SqlNullStrWrapped(str string) sql.NullString {
 	return sql.NullString{String: str, Valid: true}
 }
 
+func SqlNullStrToPtr(str sql.NullString) *string {
+	if !str.Valid {
+		return nil
+	}
+
+	return &str.String
+}
+
 func InitDb(dbFile string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", "file:"+dbFile)
 	if err != nil {
@@ -0,0 +1,2 @@
+ALTER TABLE ride_schedules
+ADD timezone TEXT NOT NULL DEFAULT ('UTC');
@@ -0,0 +1,6 @@
+SELECT
+    timezone
+FROM
+    ride_schedules
+LIMIT
+    1;
@@ -0,0 +1,2 @@
+ALTER TABLE ride_schedules
+ADD rrule TEXT;
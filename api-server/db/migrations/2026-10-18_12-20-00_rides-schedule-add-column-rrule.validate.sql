@@ -0,0 +1,6 @@
+SELECT
+    rrule
+FROM
+    ride_schedules
+LIMIT
+    1;
@@ -0,0 +1,8 @@
+SELECT
+    user_id,
+    token_hash,
+    created_at
+FROM
+    calendar_tokens
+LIMIT
+    1;
@@ -0,0 +1,8 @@
+CREATE TABLE ride_waitlist (
+    ride_event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (ride_event_id, user_id),
+    FOREIGN KEY (ride_event_id) REFERENCES ride_events (id),
+    FOREIGN KEY (user_id) REFERENCES users (id)
+);
@@ -0,0 +1,8 @@
+SELECT
+    ride_event_id,
+    user_id,
+    created_at
+FROM
+    ride_waitlist
+LIMIT
+    1;
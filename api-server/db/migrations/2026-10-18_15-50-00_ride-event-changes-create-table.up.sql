@@ -0,0 +1,9 @@
+CREATE TABLE ride_event_changes (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    ride_event_id TEXT NOT NULL,
+    changed_by TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    FOREIGN KEY (ride_event_id) REFERENCES ride_events (id),
+    FOREIGN KEY (changed_by) REFERENCES users (id)
+);
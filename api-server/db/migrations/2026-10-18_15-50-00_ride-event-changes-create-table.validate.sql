@@ -0,0 +1,10 @@
+SELECT
+    id,
+    ride_event_id,
+    changed_by,
+    changes,
+    created_at
+FROM
+    ride_event_changes
+LIMIT
+    1;
@@ -0,0 +1,34 @@
+-- The occurrence of the schedule an event was created for. Unlike
+-- 'tacking_place_at' it isn't changed when the event is moved, so the
+-- scheduler and calendar feeds still know which occurrence an event stands
+-- for.
+ALTER TABLE ride_events
+ADD occurrence_at TEXT;
+
+
+UPDATE ride_events
+SET
+    occurrence_at = tacking_place_at;
+
+
+-- Events created without an occurrence, like the first event of a ride, take
+-- place at their occurrence.
+CREATE TRIGGER ride_event_set_occurrence AFTER INSERT ON ride_events WHEN new.occurrence_at IS NULL BEGIN
+UPDATE ride_events
+SET
+    occurrence_at = new.tacking_place_at
+WHERE
+    id = new.id;
+
+
+END;
+
+
+CREATE TRIGGER ride_event_keep_occurrence BEFORE
+UPDATE OF occurrence_at ON ride_events WHEN old.occurrence_at IS NOT NULL
+AND new.occurrence_at IS NOT old.occurrence_at BEGIN
+SELECT
+    RAISE(ABORT, 'occurrence_at of a ride event can''t be changed');
+
+
+END;
@@ -0,0 +1,6 @@
+SELECT
+    occurrence_at
+FROM
+    ride_events
+LIMIT
+    1;
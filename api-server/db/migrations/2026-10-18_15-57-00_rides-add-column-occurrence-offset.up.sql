@@ -0,0 +1,6 @@
+-- Seconds that events created from the schedule are moved from their
+-- occurrence, on the wall clock of the schedule's time zone. Moving the
+-- following events of a series changes the offset instead of the start of the
+-- series, so the recurrence rule keeps counting from the first ride.
+ALTER TABLE rides
+ADD occurrence_offset INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+SELECT
+    occurrence_offset
+FROM
+    rides
+LIMIT
+    1;
@@ -0,0 +1,2 @@
+ALTER TABLE rides
+ADD group_id TEXT REFERENCES ride_groups (id);
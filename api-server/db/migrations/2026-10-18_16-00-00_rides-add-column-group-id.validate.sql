@@ -0,0 +1,6 @@
+SELECT
+    group_id
+FROM
+    rides
+LIMIT
+    1;
@@ -0,0 +1,2 @@
+ALTER TABLE ride_group_members
+ADD is_moderator BOOLEAN NOT NULL DEFAULT FALSE;
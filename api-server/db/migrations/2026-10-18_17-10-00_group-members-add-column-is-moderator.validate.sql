@@ -0,0 +1,6 @@
+SELECT
+    is_moderator
+FROM
+    ride_group_members
+LIMIT
+    1;
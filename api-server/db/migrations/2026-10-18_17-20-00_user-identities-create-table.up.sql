@@ -0,0 +1,21 @@
+CREATE TABLE user_identities (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    user_id TEXT NOT NULL REFERENCES users (id),
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    UNIQUE (provider, subject)
+);
+
+
+-- Until now the id of a user was the id of their account at the provider.
+INSERT INTO
+    user_identities (user_id, provider, subject, email)
+SELECT
+    id,
+    provider,
+    id,
+    email
+FROM
+    users;
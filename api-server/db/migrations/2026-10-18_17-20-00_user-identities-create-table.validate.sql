@@ -0,0 +1,11 @@
+SELECT
+    id,
+    user_id,
+    provider,
+    subject,
+    email,
+    created_at
+FROM
+    user_identities
+LIMIT
+    1;
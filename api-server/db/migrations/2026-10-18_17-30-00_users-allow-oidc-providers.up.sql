@@ -0,0 +1,46 @@
+-- SQLite can't change a CHECK constraint, so the table is rebuilt. Users of
+-- OpenID Connect providers have the provider 'oidc/{name}'.
+CREATE TABLE users_new (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    provider TEXT NOT NULL CHECK (
+        provider IN ('google', 'microsoft')
+        OR provider LIKE 'oidc/%'
+    ),
+    access_token TEXT,
+    refresh_token TEXT,
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    is_blocked BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+
+INSERT INTO
+    users_new (
+        id,
+        name,
+        email,
+        provider,
+        access_token,
+        refresh_token,
+        is_admin,
+        is_blocked
+    )
+SELECT
+    id,
+    name,
+    email,
+    provider,
+    access_token,
+    refresh_token,
+    is_admin,
+    is_blocked
+FROM
+    users;
+
+
+DROP TABLE users;
+
+
+ALTER TABLE users_new
+RENAME TO users;
@@ -0,0 +1,12 @@
+-- Only the CHECK constraint changed, so selecting the columns can't fail.
+-- Parsing invalid JSON raises an error if the constraint is still the old one.
+SELECT
+    CASE
+        WHEN sql LIKE '%oidc/%' THEN 1
+        ELSE json('users.provider does not allow OpenID Connect providers')
+    END
+FROM
+    sqlite_master
+WHERE
+    type = 'table'
+    AND name = 'users';
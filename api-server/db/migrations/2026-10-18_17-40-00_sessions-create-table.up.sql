@@ -0,0 +1,27 @@
+-- A user can be signed in on several devices at once, each with their own
+-- tokens. Only SHA-256 hashes of the tokens are stored.
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    user_id TEXT NOT NULL REFERENCES users (id),
+    access_token_hash TEXT NOT NULL UNIQUE,
+    refresh_token_hash TEXT NOT NULL,
+    device TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    last_used_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+
+CREATE INDEX sessions_user_id ON sessions (user_id);
+
+
+CREATE UNIQUE INDEX sessions_refresh_token_hash ON sessions (refresh_token_hash);
+
+
+-- The tokens of users can't be hashed here, so everyone has to sign in again.
+-- The columns stay, the validation of the migration that added them selects
+-- them.
+UPDATE users
+SET
+    access_token = NULL,
+    refresh_token = NULL;
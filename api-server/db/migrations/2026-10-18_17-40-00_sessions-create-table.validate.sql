@@ -0,0 +1,13 @@
+SELECT
+    id,
+    user_id,
+    access_token_hash,
+    refresh_token_hash,
+    device,
+    ip,
+    created_at,
+    last_used_at
+FROM
+    sessions
+LIMIT
+    1;
@@ -0,0 +1,40 @@
+-- Keys access tokens are sealed with. The newest key seals new tokens, older
+-- keys open tokens until they retire.
+CREATE TABLE auth_keys (
+    id TEXT PRIMARY KEY,
+    secret TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    retires_at TEXT
+);
+
+
+-- Refresh tokens that were exchanged for new tokens. Using one of them again
+-- means the tokens of the session were stolen.
+CREATE TABLE used_refresh_tokens (
+    token_hash TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    used_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+
+-- Refresh tokens expire, so used ones only have to be remembered until the
+-- token would have expired anyway.
+CREATE INDEX used_refresh_tokens_used_at ON used_refresh_tokens (used_at);
+
+
+CREATE TRIGGER session_delete_used_refresh_tokens AFTER DELETE ON sessions BEGIN
+DELETE FROM used_refresh_tokens
+WHERE
+    session_id = old.id;
+
+END;
+
+
+-- Refresh tokens expire some time after they were handed out.
+ALTER TABLE sessions
+ADD refreshed_at TEXT NOT NULL DEFAULT '';
+
+
+UPDATE sessions
+SET
+    refreshed_at = last_used_at;
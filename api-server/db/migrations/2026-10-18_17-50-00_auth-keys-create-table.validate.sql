@@ -0,0 +1,15 @@
+SELECT
+    k.id,
+    k.secret,
+    k.created_at,
+    k.retires_at,
+    u.token_hash,
+    u.session_id,
+    u.used_at,
+    s.refreshed_at
+FROM
+    auth_keys k,
+    used_refresh_tokens u,
+    sessions s
+LIMIT
+    1;
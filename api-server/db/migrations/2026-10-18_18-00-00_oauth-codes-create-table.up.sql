@@ -0,0 +1,19 @@
+-- Logins that were started at an auth provider but haven't returned to the
+-- callback yet. Stored in the database, so logins survive a restart.
+CREATE TABLE oauth_states (
+    state TEXT PRIMARY KEY,
+    -- Set if the identity should be linked to this user instead of signing in.
+    link_user_id TEXT,
+    code_challenge TEXT,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+
+-- One-time codes the web app exchanges for the tokens of a new session after
+-- a login. Only the hash of a code is stored.
+CREATE TABLE auth_codes (
+    code_hash TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    code_challenge TEXT,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
@@ -0,0 +1,14 @@
+SELECT
+    s.state,
+    s.link_user_id,
+    s.code_challenge,
+    s.created_at,
+    c.code_hash,
+    c.user_id,
+    c.code_challenge,
+    c.created_at
+FROM
+    oauth_states s,
+    auth_codes c
+LIMIT
+    1;
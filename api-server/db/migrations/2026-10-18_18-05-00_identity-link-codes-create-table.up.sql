@@ -0,0 +1,12 @@
+-- Identities that returned to the callback of a link, waiting for the web app
+-- that started the link to confirm it with the verifier of its code
+-- challenge. Only the hash of a code is stored.
+CREATE TABLE identity_link_codes (
+    code_hash TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL,
+    code_challenge TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
@@ -0,0 +1,12 @@
+SELECT
+    code_hash,
+    user_id,
+    provider,
+    subject,
+    email,
+    code_challenge,
+    created_at
+FROM
+    identity_link_codes
+LIMIT
+    1;
@@ -0,0 +1,16 @@
+-- Long-lived tokens users create for scripts and integrations. Only the hash
+-- of a token is stored, the token itself is shown once when it is created.
+CREATE TABLE personal_tokens (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    user_id TEXT NOT NULL REFERENCES users (id),
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    -- Space separated, e.g. 'rides:read rides:write'.
+    scopes TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    expires_at TEXT NOT NULL,
+    last_used_at TEXT
+);
+
+
+CREATE INDEX personal_tokens_user_id ON personal_tokens (user_id);
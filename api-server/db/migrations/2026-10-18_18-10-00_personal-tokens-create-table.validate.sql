@@ -0,0 +1,13 @@
+SELECT
+    id,
+    user_id,
+    name,
+    token_hash,
+    scopes,
+    created_at,
+    expires_at,
+    last_used_at
+FROM
+    personal_tokens
+LIMIT
+    1;
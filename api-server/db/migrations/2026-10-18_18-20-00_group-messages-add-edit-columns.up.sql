@@ -0,0 +1,12 @@
+-- Deleted messages are kept, so replies to them still work, but their content
+-- isn't shown anymore.
+ALTER TABLE group_messages
+ADD edited_at TEXT;
+
+
+ALTER TABLE group_messages
+ADD deleted_at TEXT;
+
+
+ALTER TABLE group_messages
+ADD deleted_by TEXT REFERENCES users (id);
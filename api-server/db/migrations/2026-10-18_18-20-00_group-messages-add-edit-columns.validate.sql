@@ -0,0 +1,8 @@
+SELECT
+    edited_at,
+    deleted_at,
+    deleted_by
+FROM
+    group_messages
+LIMIT
+    1;
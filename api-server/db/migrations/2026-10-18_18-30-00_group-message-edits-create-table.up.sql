@@ -0,0 +1,11 @@
+-- The previous contents of edited messages.
+CREATE TABLE group_message_edits (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    message_id TEXT NOT NULL REFERENCES group_messages (id),
+    content TEXT NOT NULL,
+    -- When the content was replaced.
+    edited_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+
+CREATE INDEX group_message_edits_message_id ON group_message_edits (message_id);
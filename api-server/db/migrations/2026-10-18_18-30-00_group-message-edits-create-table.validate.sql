@@ -0,0 +1,9 @@
+SELECT
+    id,
+    message_id,
+    content,
+    edited_at
+FROM
+    group_message_edits
+LIMIT
+    1;
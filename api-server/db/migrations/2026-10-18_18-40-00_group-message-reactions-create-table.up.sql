@@ -0,0 +1,7 @@
+CREATE TABLE group_message_reactions (
+    message_id TEXT NOT NULL REFERENCES group_messages (id),
+    user_id TEXT NOT NULL REFERENCES users (id),
+    emoji TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    PRIMARY KEY (message_id, user_id, emoji)
+);
@@ -0,0 +1,9 @@
+SELECT
+    message_id,
+    user_id,
+    emoji,
+    created_at
+FROM
+    group_message_reactions
+LIMIT
+    1;
@@ -0,0 +1,56 @@
+-- Clients reconnecting to a chat have to learn about the changes to messages
+-- they already received. 'changed_seq' is the rowid of the newest message when
+-- the message was last edited, deleted or reacted to. Unlike the timestamps it
+-- orders changes after the messages sent before them.
+ALTER TABLE group_messages
+ADD changed_seq INTEGER;
+
+
+CREATE TRIGGER group_message_changed AFTER
+UPDATE OF content,
+deleted_at ON group_messages BEGIN
+UPDATE group_messages
+SET
+    changed_seq = (
+        SELECT
+            MAX(rowid)
+        FROM
+            group_messages
+    )
+WHERE
+    id = new.id;
+
+
+END;
+
+
+CREATE TRIGGER group_message_reaction_added AFTER INSERT ON group_message_reactions BEGIN
+UPDATE group_messages
+SET
+    changed_seq = (
+        SELECT
+            MAX(rowid)
+        FROM
+            group_messages
+    )
+WHERE
+    id = new.message_id;
+
+
+END;
+
+
+CREATE TRIGGER group_message_reaction_removed AFTER DELETE ON group_message_reactions BEGIN
+UPDATE group_messages
+SET
+    changed_seq = (
+        SELECT
+            MAX(rowid)
+        FROM
+            group_messages
+    )
+WHERE
+    id = old.message_id;
+
+
+END;
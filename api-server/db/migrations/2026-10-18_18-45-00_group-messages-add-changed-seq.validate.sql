@@ -0,0 +1,6 @@
+SELECT
+    changed_seq
+FROM
+    group_messages
+LIMIT
+    1;
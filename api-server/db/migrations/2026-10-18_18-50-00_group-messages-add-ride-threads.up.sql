@@ -0,0 +1,118 @@
+-- Messages belong to the chat of a group or to the thread of a ride event.
+-- SQLite can't drop the NOT NULL constraint of 'group_id', so the table is
+-- created again. The rowids are kept, messages are ordered by them. The
+-- triggers of the messages and their reactions are created again at the end,
+-- SQLite doesn't rename a table that triggers refer to.
+DROP TRIGGER group_message_reaction_added;
+
+
+DROP TRIGGER group_message_reaction_removed;
+
+
+CREATE TABLE group_messages_new (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    group_id TEXT REFERENCES ride_groups (id),
+    ride_event_id TEXT REFERENCES ride_events (id),
+    content TEXT NOT NULL,
+    sent_by TEXT NOT NULL REFERENCES users (id),
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    replies_to TEXT REFERENCES group_messages (id),
+    edited_at TEXT,
+    deleted_at TEXT,
+    deleted_by TEXT REFERENCES users (id),
+    changed_seq INTEGER,
+    CHECK ((group_id IS NULL) != (ride_event_id IS NULL))
+);
+
+
+INSERT INTO
+    group_messages_new (
+        rowid,
+        id,
+        group_id,
+        content,
+        sent_by,
+        created_at,
+        replies_to,
+        edited_at,
+        deleted_at,
+        deleted_by,
+        changed_seq
+    )
+SELECT
+    rowid,
+    id,
+    group_id,
+    content,
+    sent_by,
+    created_at,
+    replies_to,
+    edited_at,
+    deleted_at,
+    deleted_by,
+    changed_seq
+FROM
+    group_messages;
+
+
+DROP TABLE group_messages;
+
+
+ALTER TABLE group_messages_new
+RENAME TO group_messages;
+
+
+CREATE INDEX group_messages_group_id ON group_messages (group_id);
+
+
+CREATE INDEX group_messages_ride_event_id ON group_messages (ride_event_id);
+
+
+CREATE TRIGGER group_message_changed AFTER
+UPDATE OF content,
+deleted_at ON group_messages BEGIN
+UPDATE group_messages
+SET
+    changed_seq = (
+        SELECT
+            MAX(rowid)
+        FROM
+            group_messages
+    )
+WHERE
+    id = new.id;
+
+
+END;
+
+
+CREATE TRIGGER group_message_reaction_added AFTER INSERT ON group_message_reactions BEGIN
+UPDATE group_messages
+SET
+    changed_seq = (
+        SELECT
+            MAX(rowid)
+        FROM
+            group_messages
+    )
+WHERE
+    id = new.message_id;
+
+
+END;
+
+
+CREATE TRIGGER group_message_reaction_removed AFTER DELETE ON group_message_reactions BEGIN
+UPDATE group_messages
+SET
+    changed_seq = (
+        SELECT
+            MAX(rowid)
+        FROM
+            group_messages
+    )
+WHERE
+    id = old.message_id;
+
+
+END;
@@ -0,0 +1,6 @@
+SELECT
+    ride_event_id
+FROM
+    group_messages
+LIMIT
+    1;
@@ -0,0 +1,8 @@
+-- Users that blocked each other can't send direct messages to one another.
+CREATE TABLE user_blocks (
+    user_id TEXT NOT NULL REFERENCES users (id),
+    blocked_user_id TEXT NOT NULL REFERENCES users (id),
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    PRIMARY KEY (user_id, blocked_user_id),
+    CHECK (user_id != blocked_user_id)
+);
@@ -0,0 +1,8 @@
+SELECT
+    user_id,
+    blocked_user_id,
+    created_at
+FROM
+    user_blocks
+LIMIT
+    1;
@@ -0,0 +1,13 @@
+-- Direct messages between two users. The ids of the users are ordered, so
+-- there is only one conversation for every pair of users.
+CREATE TABLE conversations (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    user_a TEXT NOT NULL REFERENCES users (id),
+    user_b TEXT NOT NULL REFERENCES users (id),
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    UNIQUE (user_a, user_b),
+    CHECK (user_a < user_b)
+);
+
+
+CREATE INDEX conversations_user_b ON conversations (user_b);
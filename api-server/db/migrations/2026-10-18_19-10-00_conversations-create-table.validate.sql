@@ -0,0 +1,9 @@
+SELECT
+    id,
+    user_a,
+    user_b,
+    created_at
+FROM
+    conversations
+LIMIT
+    1;
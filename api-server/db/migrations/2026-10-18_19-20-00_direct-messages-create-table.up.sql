@@ -0,0 +1,13 @@
+CREATE TABLE direct_messages (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    conversation_id TEXT NOT NULL REFERENCES conversations (id),
+    content TEXT NOT NULL,
+    sent_by TEXT NOT NULL REFERENCES users (id),
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    replies_to TEXT REFERENCES direct_messages (id),
+    -- When the recipient read the message, NULL while it is unread.
+    read_at TEXT
+);
+
+
+CREATE INDEX direct_messages_conversation_id ON direct_messages (conversation_id);
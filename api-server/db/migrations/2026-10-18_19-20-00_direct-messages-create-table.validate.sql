@@ -0,0 +1,12 @@
+SELECT
+    id,
+    conversation_id,
+    content,
+    sent_by,
+    created_at,
+    replies_to,
+    read_at
+FROM
+    direct_messages
+LIMIT
+    1;
@@ -0,0 +1,19 @@
+CREATE TABLE notifications (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(8)))),
+    user_id TEXT NOT NULL REFERENCES users (id),
+    type TEXT NOT NULL,
+    -- The user whose action caused the notification.
+    actor_id TEXT REFERENCES users (id),
+    -- What the notification is about, depending on its type.
+    ride_event_id TEXT REFERENCES ride_events (id),
+    group_id TEXT REFERENCES ride_groups (id),
+    conversation_id TEXT REFERENCES conversations (id),
+    -- A group message or a direct message, so it has no foreign key.
+    message_id TEXT,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    -- NULL while the notification is unread.
+    read_at TEXT
+);
+
+
+CREATE INDEX notifications_user_id ON notifications (user_id);
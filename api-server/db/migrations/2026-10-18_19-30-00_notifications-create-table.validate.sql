@@ -0,0 +1,15 @@
+SELECT
+    id,
+    user_id,
+    type,
+    actor_id,
+    ride_event_id,
+    group_id,
+    conversation_id,
+    message_id,
+    created_at,
+    read_at
+FROM
+    notifications
+LIMIT
+    1;
@@ -0,0 +1,141 @@
+-- See sqlc docs for more information:
+-- https://docs.sqlc.dev/en/latest/tutorials/getting-started-sqlite.html#schema-and-queries
+--
+-- name: AuthKeysCreate :one
+INSERT INTO
+    auth_keys (id, secret)
+VALUES
+    (?, ?) RETURNING *;
+
+
+-- name: AuthKeysGetMany :many
+SELECT
+    *
+FROM
+    auth_keys
+ORDER BY
+    created_at DESC,
+    rowid DESC;
+
+
+-- name: AuthKeysGetValid :many
+SELECT
+    *
+FROM
+    auth_keys
+WHERE
+    retires_at IS NULL
+    OR retires_at > strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+ORDER BY
+    created_at DESC,
+    rowid DESC;
+
+
+-- name: AuthKeysRetire :exec
+UPDATE auth_keys
+SET
+    retires_at = sqlc.arg('retires_at')
+WHERE
+    id != sqlc.arg('active_id')
+    AND (
+        retires_at IS NULL
+        OR retires_at > sqlc.arg('retires_at')
+    );
+
+
+-- name: AuthKeysDeleteRetired :execrows
+DELETE FROM auth_keys
+WHERE
+    retires_at <= strftime('%Y-%m-%dT%H:%M:%SZ', 'now');
+
+
+-- name: UsedRefreshTokensCreate :exec
+INSERT INTO
+    used_refresh_tokens (token_hash, session_id)
+VALUES
+    (?, ?);
+
+
+-- name: UsedRefreshTokensDeleteBefore :exec
+DELETE FROM used_refresh_tokens
+WHERE
+    used_at < ?;
+
+
+-- name: UsedRefreshTokensGet :one
+SELECT
+    *
+FROM
+    used_refresh_tokens
+WHERE
+    token_hash = ?;
+
+
+-- name: OauthStatesCreate :exec
+INSERT INTO
+    oauth_states (state, link_user_id, code_challenge)
+VALUES
+    (?, ?, ?);
+
+
+-- name: OauthStatesConsume :one
+-- A state can only be used once, so it is deleted when it is read.
+DELETE FROM oauth_states
+WHERE
+    state = sqlc.arg('state')
+    AND created_at > sqlc.arg('created_after') RETURNING *;
+
+
+-- name: OauthStatesDeleteExpired :exec
+DELETE FROM oauth_states
+WHERE
+    created_at <= sqlc.arg('created_before');
+
+
+-- name: AuthCodesCreate :exec
+INSERT INTO
+    auth_codes (code_hash, user_id, code_challenge)
+VALUES
+    (?, ?, ?);
+
+
+-- name: AuthCodesConsume :one
+-- A code can only be exchanged once, so it is deleted when it is read.
+DELETE FROM auth_codes
+WHERE
+    code_hash = sqlc.arg('code_hash')
+    AND created_at > sqlc.arg('created_after') RETURNING *;
+
+
+-- name: AuthCodesDeleteExpired :exec
+DELETE FROM auth_codes
+WHERE
+    created_at <= sqlc.arg('created_before');
+
+
+-- name: IdentityLinkCodesCreate :exec
+INSERT INTO
+    identity_link_codes (
+        code_hash,
+        user_id,
+        provider,
+        subject,
+        email,
+        code_challenge
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?);
+
+
+-- name: IdentityLinkCodesConsume :one
+-- A code can only be confirmed once, so it is deleted when it is read.
+DELETE FROM identity_link_codes
+WHERE
+    code_hash = sqlc.arg('code_hash')
+    AND created_at > sqlc.arg('created_after') RETURNING *;
+
+
+-- name: IdentityLinkCodesDeleteExpired :exec
+DELETE FROM identity_link_codes
+WHERE
+    created_at <= sqlc.arg('created_before');
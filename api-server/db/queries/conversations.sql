@@ -0,0 +1,204 @@
+-- See sqlc docs for more information:
+-- https://docs.sqlc.dev/en/latest/tutorials/getting-started-sqlite.html#schema-and-queries
+--
+-- name: ConversationsCreate :exec
+-- Does nothing if the users already have a conversation. 'user_a' has to be
+-- the smaller id.
+INSERT INTO
+    conversations (user_a, user_b)
+VALUES
+    (?, ?) ON CONFLICT DO NOTHING;
+
+
+-- name: ConversationsGetByUsers :one
+SELECT
+    *
+FROM
+    conversations
+WHERE
+    user_a = ?
+    AND user_b = ?;
+
+
+-- name: ConversationsGetById :one
+SELECT
+    *
+FROM
+    conversations
+WHERE
+    id = ?;
+
+
+-- name: ConversationsGetForUser :many
+-- Returns the conversations with the latest message first, conversations
+-- without messages last. Only returns the conversation with the id
+-- 'conversation_id' if it is set.
+SELECT
+    c.id,
+    u.id AS other_user_id,
+    u.email AS other_user_email,
+    c.created_at,
+    (
+        SELECT
+            COUNT(id)
+        FROM
+            direct_messages
+        WHERE
+            conversation_id = c.id
+            AND sent_by != sqlc.arg('user_id')
+            AND read_at IS NULL
+    ) AS unread_count,
+    EXISTS (
+        SELECT
+            1
+        FROM
+            user_blocks
+        WHERE
+            (
+                user_id = c.user_a
+                AND blocked_user_id = c.user_b
+            )
+            OR (
+                user_id = c.user_b
+                AND blocked_user_id = c.user_a
+            )
+    ) AS is_blocked,
+    dm.id AS last_message_id,
+    dm.content AS last_message_content,
+    dm.sent_by AS last_message_sent_by,
+    dm.created_at AS last_message_created_at
+FROM
+    conversations c
+    INNER JOIN users u ON u.id = CASE
+        WHEN c.user_a = sqlc.arg('user_id') THEN c.user_b
+        ELSE c.user_a
+    END
+    LEFT JOIN direct_messages dm ON dm.rowid = (
+        SELECT
+            MAX(rowid)
+        FROM
+            direct_messages
+        WHERE
+            conversation_id = c.id
+    )
+WHERE
+    (
+        c.user_a = sqlc.arg('user_id')
+        OR c.user_b = sqlc.arg('user_id')
+    )
+    AND (
+        sqlc.narg('conversation_id') IS NULL
+        OR c.id = sqlc.narg('conversation_id')
+    )
+ORDER BY
+    dm.rowid IS NULL,
+    dm.rowid DESC,
+    c.created_at DESC;
+
+
+-- name: DirectMessagesCreate :one
+INSERT INTO
+    direct_messages (conversation_id, content, sent_by, replies_to)
+VALUES
+    (?, ?, ?, ?) RETURNING *;
+
+
+-- name: DirectMessagesGetById :one
+SELECT
+    dm.id,
+    dm.conversation_id,
+    dm.content,
+    dm.sent_by,
+    u.email AS sent_by_email,
+    dm.created_at,
+    dm.replies_to,
+    dm.read_at
+FROM
+    direct_messages dm
+    INNER JOIN users u ON u.id = sent_by
+WHERE
+    dm.id = ?;
+
+
+-- name: DirectMessagesGetPage :many
+-- Ordered by the rowid like group messages, returns the newest messages
+-- first.
+SELECT
+    dm.id,
+    dm.conversation_id,
+    dm.content,
+    dm.sent_by,
+    u.email AS sent_by_email,
+    dm.created_at,
+    dm.replies_to,
+    dm.read_at
+FROM
+    direct_messages dm
+    INNER JOIN users u ON u.id = sent_by
+WHERE
+    dm.conversation_id = sqlc.arg('conversation_id')
+    AND (
+        sqlc.narg('before_id') IS NULL
+        OR dm.rowid < (
+            SELECT
+                rowid
+            FROM
+                direct_messages
+            WHERE
+                id = sqlc.narg('before_id')
+        )
+    )
+ORDER BY
+    dm.rowid DESC
+LIMIT
+    sqlc.arg('page_size');
+
+
+-- name: DirectMessagesGetSince :many
+-- Returns the oldest messages first.
+SELECT
+    dm.id,
+    dm.conversation_id,
+    dm.content,
+    dm.sent_by,
+    u.email AS sent_by_email,
+    dm.created_at,
+    dm.replies_to,
+    dm.read_at
+FROM
+    direct_messages dm
+    INNER JOIN users u ON u.id = sent_by
+WHERE
+    dm.conversation_id = sqlc.arg('conversation_id')
+    AND dm.rowid > (
+        SELECT
+            rowid
+        FROM
+            direct_messages
+        WHERE
+            id = sqlc.arg('since_id')
+    )
+ORDER BY
+    dm.rowid
+LIMIT
+    sqlc.arg('page_size');
+
+
+-- name: DirectMessagesCount :one
+SELECT
+    COUNT(id)
+FROM
+    direct_messages
+WHERE
+    conversation_id = ?;
+
+
+-- name: DirectMessagesMarkRead :execrows
+-- Marks the messages the user received as read.
+UPDATE direct_messages
+SET
+    read_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE
+    conversation_id = ?
+    AND sent_by != ?
+    AND read_at IS NULL;
@@ -1,26 +1,206 @@
 -- See sqlc docs for more information:
 -- https://docs.sqlc.dev/en/latest/tutorials/getting-started-sqlite.html#schema-and-queries
 --
--- name: GroupMessagesGetMany :many
+-- name: GroupMessagesGetById :one
 SELECT
     gm.id,
     gm.group_id,
+    gm.ride_event_id,
     gm.content,
     gm.sent_by,
     u.email AS sent_by_email,
     gm.created_at,
-    gm.replies_to
+    gm.replies_to,
+    gm.edited_at,
+    gm.deleted_at
 FROM
     group_messages gm
     INNER JOIN users u ON u.id = sent_by
 WHERE
-    group_id = ?
+    gm.id = ?;
+
+
+-- name: GroupMessagesGetPage :many
+-- Messages are ordered by the rowid, which only grows, unlike 'created_at'
+-- which can be the same for many messages. Returns the newest messages first.
+SELECT
+    gm.id,
+    gm.group_id,
+    gm.ride_event_id,
+    gm.content,
+    gm.sent_by,
+    u.email AS sent_by_email,
+    gm.created_at,
+    gm.replies_to,
+    gm.edited_at,
+    gm.deleted_at
+FROM
+    group_messages gm
+    INNER JOIN users u ON u.id = sent_by
+WHERE
+    (
+        gm.group_id = sqlc.narg('group_id')
+        OR gm.ride_event_id = sqlc.narg('ride_event_id')
+    )
+    AND (
+        sqlc.narg('before_id') IS NULL
+        OR gm.rowid < (
+            SELECT
+                rowid
+            FROM
+                group_messages
+            WHERE
+                id = sqlc.narg('before_id')
+        )
+    )
+ORDER BY
+    gm.rowid DESC
+LIMIT
+    sqlc.arg('page_size');
+
+
+-- name: GroupMessagesGetSince :many
+-- Returns the oldest messages first.
+SELECT
+    gm.id,
+    gm.group_id,
+    gm.ride_event_id,
+    gm.content,
+    gm.sent_by,
+    u.email AS sent_by_email,
+    gm.created_at,
+    gm.replies_to,
+    gm.edited_at,
+    gm.deleted_at
+FROM
+    group_messages gm
+    INNER JOIN users u ON u.id = sent_by
+WHERE
+    (
+        gm.group_id = sqlc.narg('group_id')
+        OR gm.ride_event_id = sqlc.narg('ride_event_id')
+    )
+    AND gm.rowid > (
+        SELECT
+            rowid
+        FROM
+            group_messages
+        WHERE
+            id = sqlc.arg('since_id')
+    )
+ORDER BY
+    gm.rowid
+LIMIT
+    sqlc.arg('page_size');
+
+
+-- name: GroupMessagesGetChangedSince :many
+-- Returns the messages up to 'since_id' that changed after it was sent, oldest
+-- first. Changes made before a newer message was sent are returned as well.
+SELECT
+    gm.id,
+    gm.group_id,
+    gm.ride_event_id,
+    gm.content,
+    gm.sent_by,
+    u.email AS sent_by_email,
+    gm.created_at,
+    gm.replies_to,
+    gm.edited_at,
+    gm.deleted_at
+FROM
+    group_messages gm
+    INNER JOIN users u ON u.id = gm.sent_by
+    INNER JOIN group_messages since ON since.id = sqlc.arg('since_id')
+WHERE
+    (
+        gm.group_id = sqlc.narg('group_id')
+        OR gm.ride_event_id = sqlc.narg('ride_event_id')
+    )
+    AND gm.rowid <= since.rowid
+    AND gm.changed_seq >= since.rowid
 ORDER BY
-    created_at;
+    gm.rowid
+LIMIT
+    sqlc.arg('page_size');
+
+
+-- name: GroupMessagesCount :one
+SELECT
+    COUNT(id)
+FROM
+    group_messages
+WHERE
+    group_id = sqlc.narg('group_id')
+    OR ride_event_id = sqlc.narg('ride_event_id');
 
 
 -- name: GroupMessagesCreate :one
 INSERT INTO
-    group_messages (content, group_id, sent_by, replies_to)
+    group_messages (content, group_id, ride_event_id, sent_by, replies_to)
 VALUES
-    (?, ?, ?, ?) RETURNING *;
+    (?, ?, ?, ?, ?) RETURNING *;
+
+
+-- name: GroupMessagesUpdateContent :exec
+UPDATE group_messages
+SET
+    content = ?,
+    edited_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE
+    id = ?;
+
+
+-- name: GroupMessagesDelete :exec
+UPDATE group_messages
+SET
+    deleted_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'),
+    deleted_by = ?
+WHERE
+    id = ?;
+
+
+-- name: GroupMessageEditsCreate :exec
+INSERT INTO
+    group_message_edits (message_id, content)
+VALUES
+    (?, ?);
+
+
+-- name: GroupMessageEditsGet :many
+SELECT
+    content,
+    edited_at
+FROM
+    group_message_edits
+WHERE
+    message_id = ?
+ORDER BY
+    rowid;
+
+
+-- name: GroupMessageReactionsAdd :execrows
+INSERT INTO
+    group_message_reactions (message_id, user_id, emoji)
+VALUES
+    (?, ?, ?) ON CONFLICT DO NOTHING;
+
+
+-- name: GroupMessageReactionsRemove :execrows
+DELETE FROM group_message_reactions
+WHERE
+    message_id = ?
+    AND user_id = ?
+    AND emoji = ?;
+
+
+-- name: GroupMessageReactionsGet :many
+SELECT
+    emoji,
+    user_id
+FROM
+    group_message_reactions
+WHERE
+    message_id = ?
+ORDER BY
+    rowid;
@@ -16,12 +16,25 @@ SELECT
     created_by
 FROM
     ride_groups
+WHERE
+    sqlc.narg('cursor_name') IS NULL
+    OR name > sqlc.narg('cursor_name')
+    OR (
+        name = sqlc.narg('cursor_name')
+        AND id > sqlc.narg('cursor_id')
+    )
 ORDER BY
-    name
+    name,
+    id
 LIMIT
-    50
-OFFSET
-    ?;
+    sqlc.arg('page_size');
+
+
+-- name: GroupsCount :one
+SELECT
+    COUNT(id)
+FROM
+    ride_groups;
 
 
 -- name: GroupsGetById :one
@@ -57,7 +70,8 @@ SELECT
     group_id,
     user_id,
     u.email,
-    join_status
+    join_status,
+    is_moderator
 FROM
     ride_group_members
     INNER JOIN users u ON u.id = user_id
@@ -74,6 +88,17 @@ ORDER BY
     );
 
 
+-- name: GroupsMembersGetStatus :one
+SELECT
+    join_status,
+    is_moderator
+FROM
+    ride_group_members
+WHERE
+    group_id = ?
+    AND user_id = ?;
+
+
 -- name: GroupsMembersJoin :exec
 INSERT INTO
     ride_group_members (group_id, user_id)
@@ -103,3 +128,12 @@ SET
 WHERE
     group_id = ?
     AND user_id = ?;
+
+
+-- name: GroupsMembersSetModerator :exec
+UPDATE ride_group_members
+SET
+    is_moderator = ?
+WHERE
+    group_id = ?
+    AND user_id = ?;
@@ -0,0 +1,108 @@
+-- See sqlc docs for more information:
+-- https://docs.sqlc.dev/en/latest/tutorials/getting-started-sqlite.html#schema-and-queries
+--
+-- name: NotificationsCreate :one
+INSERT INTO
+    notifications (
+        user_id,
+        type,
+        actor_id,
+        ride_event_id,
+        group_id,
+        conversation_id,
+        message_id
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?) RETURNING *;
+
+
+-- name: NotificationsGetById :one
+SELECT
+    n.id,
+    n.user_id,
+    n.type,
+    n.actor_id,
+    u.email AS actor_email,
+    n.ride_event_id,
+    n.group_id,
+    n.conversation_id,
+    n.message_id,
+    n.created_at,
+    n.read_at
+FROM
+    notifications n
+    LEFT JOIN users u ON u.id = n.actor_id
+WHERE
+    n.id = ?;
+
+
+-- name: NotificationsGetPage :many
+-- Returns the newest notifications first, only unread ones if 'unread_only'
+-- is set.
+SELECT
+    n.id,
+    n.user_id,
+    n.type,
+    n.actor_id,
+    u.email AS actor_email,
+    n.ride_event_id,
+    n.group_id,
+    n.conversation_id,
+    n.message_id,
+    n.created_at,
+    n.read_at
+FROM
+    notifications n
+    LEFT JOIN users u ON u.id = n.actor_id
+WHERE
+    n.user_id = sqlc.arg('user_id')
+    AND (
+        NOT CAST(sqlc.arg('unread_only') AS BOOLEAN)
+        OR n.read_at IS NULL
+    )
+    AND (
+        sqlc.narg('cursor_id') IS NULL
+        OR n.rowid < (
+            SELECT
+                rowid
+            FROM
+                notifications
+            WHERE
+                id = sqlc.narg('cursor_id')
+        )
+    )
+ORDER BY
+    n.rowid DESC
+LIMIT
+    sqlc.arg('page_size');
+
+
+-- name: NotificationsCount :one
+SELECT
+    COUNT(id)
+FROM
+    notifications
+WHERE
+    user_id = sqlc.arg('user_id')
+    AND (
+        NOT CAST(sqlc.arg('unread_only') AS BOOLEAN)
+        OR read_at IS NULL
+    );
+
+
+-- name: NotificationsMarkRead :execrows
+UPDATE notifications
+SET
+    read_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE
+    id = ?
+    AND read_at IS NULL;
+
+
+-- name: NotificationsMarkAllRead :execrows
+UPDATE notifications
+SET
+    read_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE
+    user_id = ?
+    AND read_at IS NULL;
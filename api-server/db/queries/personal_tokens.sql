@@ -0,0 +1,56 @@
+-- See sqlc docs for more information:
+-- https://docs.sqlc.dev/en/latest/tutorials/getting-started-sqlite.html#schema-and-queries
+--
+-- name: PersonalTokensCreate :one
+INSERT INTO
+    personal_tokens (user_id, name, token_hash, scopes, expires_at)
+VALUES
+    (?, ?, ?, ?, ?) RETURNING *;
+
+
+-- name: PersonalTokensGetByHash :one
+SELECT
+    *
+FROM
+    personal_tokens
+WHERE
+    token_hash = ?;
+
+
+-- name: PersonalTokensGetByUser :many
+SELECT
+    *
+FROM
+    personal_tokens
+WHERE
+    user_id = ?
+ORDER BY
+    created_at DESC,
+    rowid DESC;
+
+
+-- name: PersonalTokensTouch :exec
+-- Only writes if the token wasn't used for a minute, so not every request
+-- writes to the database.
+UPDATE personal_tokens
+SET
+    last_used_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE
+    id = ?
+    AND (
+        last_used_at IS NULL
+        OR last_used_at < strftime('%Y-%m-%dT%H:%M:%SZ', 'now', '-1 minute')
+    );
+
+
+-- name: PersonalTokensDelete :execrows
+DELETE FROM personal_tokens
+WHERE
+    id = ?
+    AND user_id = ?;
+
+
+-- name: PersonalTokensDeleteByUser :exec
+DELETE FROM personal_tokens
+WHERE
+    user_id = ?;
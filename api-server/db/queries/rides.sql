@@ -9,31 +9,34 @@ INSERT INTO
         tacking_place_at,
         created_by,
         driver,
-        transport_limit
+        transport_limit,
+        group_id
     )
 VALUES
-    (?, ?, ?, ?, ?, ?) RETURNING id;
+    (?, ?, ?, ?, ?, ?, ?) RETURNING id;
 
 
--- name: RidesCreateEvent :exec
+-- name: RidesCreateEvent :one
 INSERT INTO
     ride_events (
         ride_id,
         location_from,
         location_to,
         driver,
+        status,
         tacking_Place_at,
+        occurrence_at,
         transport_limit
     )
 VALUES
-    (?, ?, ?, ?, ?, ?);
+    (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;
 
 
 -- name: RidesCreateSchedule :one
 INSERT INTO
-    ride_schedules (ride_id, schedule_interval, unit)
+    ride_schedules (ride_id, schedule_interval, unit, timezone, rrule)
 VALUES
-    (?, ?, ?) RETURNING id;
+    (?, ?, ?, ?, ?) RETURNING id;
 
 
 -- name: RidesUpdateEventStatus :exec
@@ -51,13 +54,13 @@ VALUES
     (?, ?);
 
 
--- name: RidesMarkPastEventsDone :many
+-- name: RidesMarkPastEventsDone :exec
 UPDATE ride_events
 SET
     status = 'done'
 WHERE
     status = 'upcoming'
-    AND tacking_place_at <= ? RETURNING id;
+    AND tacking_place_at <= ?;
 
 
 -- name: RidesGetLatest :one
@@ -76,6 +79,9 @@ SELECT
     rs.id AS ride_schedule_id,
     rs.unit AS ride_schedule_unit,
     rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id,
     r.location_from AS base_location_from,
     r.location_to AS base_location_to,
     r.transport_limit AS base_transport_limit,
@@ -84,18 +90,19 @@ FROM
     ride_events re
     INNER JOIN rides r ON re.ride_id = r.id
     LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
-    INNER JOIN users ud ON r.driver = ud.id
+    INNER JOIN users ud ON re.driver = ud.id
     INNER JOIN users uc ON r.created_by = uc.id
 WHERE
     re.ride_id = ?
     AND re.status = 'upcoming'
     AND re.tacking_place_at = (
         SELECT
-            MAX(tacking_place_at)
+            MIN(tacking_place_at)
         FROM
             ride_events
         WHERE
-            id = re.id
+            ride_id = re.ride_id
+            AND status = 'upcoming'
     );
 
 
@@ -125,12 +132,15 @@ SELECT
     uc.email AS created_by_email,
     rs.id AS ride_schedule_id,
     rs.unit AS ride_schedule_unit,
-    rs.schedule_interval AS ride_schedule_interval
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id
 FROM
     ride_events re
     INNER JOIN rides r ON re.ride_id = r.id
     LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
-    INNER JOIN users ud ON r.driver = ud.id
+    INNER JOIN users ud ON re.driver = ud.id
     INNER JOIN users uc ON r.created_by = uc.id
 WHERE
     re.id = ?;
@@ -141,13 +151,54 @@ SELECT
     id,
     ride_id,
     schedule_interval,
-    unit
+    unit,
+    timezone,
+    rrule
 FROM
     ride_schedules
 WHERE
     ride_id = ?;
 
 
+-- name: RidesGetSchedulesWithLastEvent :many
+SELECT
+    rs.id,
+    rs.ride_id,
+    rs.schedule_interval,
+    rs.unit,
+    rs.timezone,
+    rs.rrule,
+    r.tacking_place_at AS anchor,
+    r.location_from,
+    r.location_to,
+    r.driver,
+    r.transport_limit,
+    r.occurrence_offset,
+    re.occurrence_at AS last_occurrence_at,
+    (
+        SELECT
+            COUNT(id)
+        FROM
+            ride_events
+        WHERE
+            ride_id = rs.ride_id
+            AND tacking_place_at > sqlc.arg('now')
+    ) AS future_events
+FROM
+    ride_schedules rs
+    INNER JOIN rides r ON r.id = rs.ride_id
+    INNER JOIN ride_events re ON re.ride_id = rs.ride_id
+WHERE
+    re.occurrence_at = (
+        SELECT
+            MAX(occurrence_at)
+        FROM
+            ride_events
+        WHERE
+            ride_id = rs.ride_id
+    );
+
+
 -- name: RidesGetScheduleWeekdays :many
 SELECT
     weekday
@@ -184,27 +235,274 @@ SELECT
     uc.email AS created_by_email,
     rs.id AS ride_schedule_id,
     rs.unit AS ride_schedule_unit,
-    rs.schedule_interval AS ride_schedule_interval
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id,
+    so.ordering AS status_ordering
 FROM
     ride_events re
     INNER JOIN rides r ON re.ride_id = r.id
+    INNER JOIN ride_event_status_ordering so ON so.status = re.status
     LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
-    INNER JOIN users ud ON r.driver = ud.id
+    INNER JOIN users ud ON re.driver = ud.id
     INNER JOIN users uc ON r.created_by = uc.id
+WHERE
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = sqlc.arg('user_id')
+                AND gm.join_status = 'member'
+        )
+    )
+    AND (
+        sqlc.narg('cursor_ordering') IS NULL
+        OR so.ordering > sqlc.narg('cursor_ordering')
+        OR (
+            so.ordering = sqlc.narg('cursor_ordering')
+            AND re.tacking_place_at < sqlc.narg('cursor_tacking_place_at')
+        )
+        OR (
+            so.ordering = sqlc.narg('cursor_ordering')
+            AND re.tacking_place_at = sqlc.narg('cursor_tacking_place_at')
+            AND re.id > sqlc.narg('cursor_id')
+        )
+    )
 ORDER BY
+    so.ordering,
+    re.tacking_place_at DESC,
+    re.id
+LIMIT
+    sqlc.arg('page_size');
+
+
+-- name: RidesSearch :many
+SELECT
+    r.id AS ride_id,
+    re.id AS ride_event_id,
+    re.location_from,
+    re.location_to,
+    re.tacking_place_at,
+    r.created_by,
+    re.transport_limit,
+    re.driver,
+    re.status,
+    ud.email AS driver_email,
+    uc.email AS created_by_email,
+    rs.id AS ride_schedule_id,
+    rs.unit AS ride_schedule_unit,
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id,
+    so.ordering AS status_ordering
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+    INNER JOIN ride_event_status_ordering so ON so.status = re.status
+    LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
+    INNER JOIN users ud ON re.driver = ud.id
+    INNER JOIN users uc ON r.created_by = uc.id
+WHERE
     (
-        SELECT
-            ordering
-        FROM
-            ride_event_status_ordering
-        WHERE
-            status = re.status
-    ),
-    re.tacking_place_at DESC
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = sqlc.arg('user_id')
+                AND gm.join_status = 'member'
+        )
+    )
+    AND (
+        sqlc.narg('status') IS NULL
+        OR re.status = sqlc.narg('status')
+    )
+    AND (
+        sqlc.narg('location_from') IS NULL
+        OR re.location_from = sqlc.narg('location_from')
+    )
+    AND (
+        sqlc.narg('location_to') IS NULL
+        OR re.location_to = sqlc.narg('location_to')
+    )
+    AND (
+        sqlc.narg('driver_email') IS NULL
+        OR ud.email = sqlc.narg('driver_email')
+    )
+    AND (
+        sqlc.narg('created_by_email') IS NULL
+        OR uc.email = sqlc.narg('created_by_email')
+    )
+    AND (
+        sqlc.narg('before') IS NULL
+        OR re.tacking_place_at <= sqlc.narg('before')
+    )
+    AND (
+        sqlc.narg('after') IS NULL
+        OR re.tacking_place_at >= sqlc.narg('after')
+    )
+    AND (
+        CAST(sqlc.arg('participants_count') AS INTEGER) = 0
+        OR (
+            SELECT
+                COUNT(DISTINCT pu.email)
+            FROM
+                ride_participants rp
+                INNER JOIN users pu ON rp.user_id = pu.id
+            WHERE
+                rp.ride_event_id = re.id
+                AND pu.email IN (sqlc.slice('participants'))
+        ) = CAST(sqlc.arg('participants_count') AS INTEGER)
+    )
+    AND (
+        sqlc.narg('cursor_ordering') IS NULL
+        OR so.ordering > sqlc.narg('cursor_ordering')
+        OR (
+            so.ordering = sqlc.narg('cursor_ordering')
+            AND re.tacking_place_at < sqlc.narg('cursor_tacking_place_at')
+        )
+        OR (
+            so.ordering = sqlc.narg('cursor_ordering')
+            AND re.tacking_place_at = sqlc.narg('cursor_tacking_place_at')
+            AND re.id > sqlc.narg('cursor_id')
+        )
+    )
+ORDER BY
+    so.ordering,
+    re.tacking_place_at DESC,
+    re.id
 LIMIT
-    50
-OFFSET
-    ?;
+    sqlc.arg('page_size');
+
+
+-- name: RidesGetByGroup :many
+SELECT
+    r.id AS ride_id,
+    re.id AS ride_event_id,
+    re.location_from,
+    re.location_to,
+    re.tacking_place_at,
+    r.created_by,
+    re.transport_limit,
+    re.driver,
+    re.status,
+    ud.email AS driver_email,
+    uc.email AS created_by_email,
+    rs.id AS ride_schedule_id,
+    rs.unit AS ride_schedule_unit,
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+    LEFT OUTER JOIN ride_schedules rs ON rs.ride_id = r.id
+    INNER JOIN users ud ON re.driver = ud.id
+    INNER JOIN users uc ON r.created_by = uc.id
+WHERE
+    r.group_id = ?
+    AND re.status = 'upcoming'
+ORDER BY
+    re.tacking_place_at,
+    re.id;
+
+
+-- name: RidesCount :one
+SELECT
+    COUNT(re.id)
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+WHERE
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = sqlc.arg('user_id')
+                AND gm.join_status = 'member'
+        )
+    );
+
+
+-- name: RidesSearchCount :one
+SELECT
+    COUNT(re.id)
+FROM
+    ride_events re
+    INNER JOIN rides r ON re.ride_id = r.id
+    INNER JOIN users ud ON re.driver = ud.id
+    INNER JOIN users uc ON r.created_by = uc.id
+WHERE
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = sqlc.arg('user_id')
+                AND gm.join_status = 'member'
+        )
+    )
+    AND (
+        sqlc.narg('status') IS NULL
+        OR re.status = sqlc.narg('status')
+    )
+    AND (
+        sqlc.narg('location_from') IS NULL
+        OR re.location_from = sqlc.narg('location_from')
+    )
+    AND (
+        sqlc.narg('location_to') IS NULL
+        OR re.location_to = sqlc.narg('location_to')
+    )
+    AND (
+        sqlc.narg('driver_email') IS NULL
+        OR ud.email = sqlc.narg('driver_email')
+    )
+    AND (
+        sqlc.narg('created_by_email') IS NULL
+        OR uc.email = sqlc.narg('created_by_email')
+    )
+    AND (
+        sqlc.narg('before') IS NULL
+        OR re.tacking_place_at <= sqlc.narg('before')
+    )
+    AND (
+        sqlc.narg('after') IS NULL
+        OR re.tacking_place_at >= sqlc.narg('after')
+    )
+    AND (
+        CAST(sqlc.arg('participants_count') AS INTEGER) = 0
+        OR (
+            SELECT
+                COUNT(DISTINCT pu.email)
+            FROM
+                ride_participants rp
+                INNER JOIN users pu ON rp.user_id = pu.id
+            WHERE
+                rp.ride_event_id = re.id
+                AND pu.email IN (sqlc.slice('participants'))
+        ) = CAST(sqlc.arg('participants_count') AS INTEGER)
+    );
 
 
 -- name: RidesJoinEvent :exec
@@ -214,6 +512,122 @@ VALUES
     (?, ?);
 
 
+-- name: RidesLeaveEvent :execrows
+DELETE FROM ride_participants
+WHERE
+    ride_event_id = ?
+    AND user_id = ?;
+
+
+-- name: RidesGetWaitlist :many
+SELECT
+    u.id,
+    u.email
+FROM
+    ride_waitlist rw
+    INNER JOIN users u ON rw.user_id = u.id
+WHERE
+    rw.ride_event_id = ?
+ORDER BY
+    rw.created_at,
+    rw.rowid;
+
+
+-- name: RidesWaitlistAdd :exec
+INSERT INTO
+    ride_waitlist (ride_event_id, user_id)
+VALUES
+    (?, ?);
+
+
+-- name: RidesWaitlistRemove :execrows
+DELETE FROM ride_waitlist
+WHERE
+    ride_event_id = ?
+    AND user_id = ?;
+
+
+-- name: RidesGetFollowingEvents :many
+SELECT
+    re.id,
+    re.location_from,
+    re.location_to,
+    re.driver,
+    u.email AS driver_email,
+    re.transport_limit,
+    re.tacking_place_at,
+    re.occurrence_at
+FROM
+    ride_events re
+    INNER JOIN users u ON re.driver = u.id
+WHERE
+    re.ride_id = sqlc.arg('ride_id')
+    AND (
+        re.id = sqlc.arg('ride_event_id')
+        OR (
+            re.status = 'upcoming'
+            AND re.occurrence_at > (
+                SELECT
+                    occurrence_at
+                FROM
+                    ride_events
+                WHERE
+                    id = sqlc.arg('ride_event_id')
+            )
+        )
+    )
+ORDER BY
+    re.occurrence_at;
+
+
+-- name: RidesUpdateEventDetails :exec
+UPDATE ride_events
+SET
+    location_from = COALESCE(sqlc.narg('location_from'), location_from),
+    location_to = COALESCE(sqlc.narg('location_to'), location_to),
+    driver = COALESCE(sqlc.narg('driver'), driver),
+    transport_limit = COALESCE(sqlc.narg('transport_limit'), transport_limit),
+    tacking_place_at = COALESCE(sqlc.narg('tacking_place_at'), tacking_place_at)
+WHERE
+    id = sqlc.arg('id');
+
+
+-- name: RidesUpdateBase :exec
+UPDATE rides
+SET
+    location_from = COALESCE(sqlc.narg('location_from'), location_from),
+    location_to = COALESCE(sqlc.narg('location_to'), location_to),
+    driver = COALESCE(sqlc.narg('driver'), driver),
+    transport_limit = COALESCE(sqlc.narg('transport_limit'), transport_limit),
+    occurrence_offset = COALESCE(sqlc.narg('occurrence_offset'), occurrence_offset)
+WHERE
+    id = sqlc.arg('id');
+
+
+-- name: RidesCreateEventChange :exec
+INSERT INTO
+    ride_event_changes (ride_event_id, changed_by, changes)
+VALUES
+    (?, ?, ?);
+
+
+-- name: RidesGetEventChanges :many
+SELECT
+    rec.id,
+    rec.changed_by,
+    u.email AS changed_by_email,
+    rec.changes,
+    rec.created_at
+FROM
+    ride_event_changes rec
+    INNER JOIN users u ON rec.changed_by = u.id
+WHERE
+    rec.ride_event_id = ?
+ORDER BY
+    rec.created_at DESC,
+    rec.rowid DESC;
+
+
 -- name: RidesCountEventParticipants :one
 SELECT
     COUNT(user_id)
@@ -221,3 +635,86 @@ FROM
     ride_participants
 WHERE
     ride_event_id = ?;
+
+
+-- name: RidesGetCalendarRideIds :many
+SELECT
+    r.id
+FROM
+    rides r
+WHERE
+    (
+        r.group_id IS NULL
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_group_members gm
+            WHERE
+                gm.group_id = r.group_id
+                AND gm.user_id = sqlc.arg('user_id')
+                AND gm.join_status = 'member'
+        )
+    )
+    AND (
+        r.created_by = sqlc.arg('user_id')
+        OR r.driver = sqlc.arg('user_id')
+        OR EXISTS (
+            SELECT
+                1
+            FROM
+                ride_events re
+                LEFT JOIN ride_participants rp ON rp.ride_event_id = re.id
+            WHERE
+                re.ride_id = r.id
+                AND (
+                    re.driver = sqlc.arg('user_id')
+                    OR rp.user_id = sqlc.arg('user_id')
+                )
+        )
+    );
+
+
+-- name: RidesGetCalendarEvents :many
+SELECT
+    re.id AS ride_event_id,
+    re.ride_id,
+    re.location_from,
+    re.location_to,
+    re.status,
+    re.tacking_place_at,
+    re.occurrence_at,
+    u.name AS driver_name,
+    u.email AS driver_email,
+    r.tacking_place_at AS series_start,
+    rs.id AS ride_schedule_id,
+    rs.schedule_interval AS ride_schedule_interval,
+    rs.unit AS ride_schedule_unit,
+    rs.timezone AS ride_schedule_timezone,
+    rs.rrule AS ride_schedule_rrule,
+    r.group_id
+FROM
+    ride_events re
+    INNER JOIN rides r ON r.id = re.ride_id
+    INNER JOIN users u ON u.id = re.driver
+    LEFT JOIN ride_schedules rs ON rs.ride_id = re.ride_id
+WHERE
+    re.ride_id IN (sqlc.slice('ride_ids'))
+ORDER BY
+    re.ride_id,
+    re.tacking_place_at;
+
+
+-- name: RidesGetCalendarParticipants :many
+SELECT
+    rp.ride_event_id,
+    u.name,
+    u.email
+FROM
+    ride_participants rp
+    INNER JOIN ride_events re ON re.id = rp.ride_event_id
+    INNER JOIN users u ON u.id = rp.user_id
+WHERE
+    re.ride_id IN (sqlc.slice('ride_ids'))
+ORDER BY
+    u.email;
@@ -0,0 +1,100 @@
+-- See sqlc docs for more information:
+-- https://docs.sqlc.dev/en/latest/tutorials/getting-started-sqlite.html#schema-and-queries
+--
+-- name: SessionsCreate :one
+INSERT INTO
+    sessions (
+        user_id,
+        access_token_hash,
+        refresh_token_hash,
+        device,
+        ip,
+        refreshed_at
+    )
+VALUES
+    (
+        ?,
+        ?,
+        ?,
+        ?,
+        ?,
+        strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+    ) RETURNING *;
+
+
+-- name: SessionsGetById :one
+SELECT
+    *
+FROM
+    sessions
+WHERE
+    id = ?;
+
+
+-- name: SessionsGetByAccessTokenHash :one
+SELECT
+    *
+FROM
+    sessions
+WHERE
+    access_token_hash = ?;
+
+
+-- name: SessionsGetByRefreshTokenHash :one
+SELECT
+    *
+FROM
+    sessions
+WHERE
+    refresh_token_hash = ?;
+
+
+-- name: SessionsGetByUser :many
+SELECT
+    *
+FROM
+    sessions
+WHERE
+    user_id = ?
+ORDER BY
+    last_used_at DESC,
+    rowid DESC;
+
+
+-- name: SessionsSetTokens :execrows
+UPDATE sessions
+SET
+    access_token_hash = ?,
+    refresh_token_hash = ?,
+    last_used_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now'),
+    refreshed_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE
+    id = ?;
+
+
+-- name: SessionsTouch :exec
+-- Only writes if the session wasn't used for a minute or the IP changed, so
+-- not every request writes to the database.
+UPDATE sessions
+SET
+    ip = sqlc.arg('ip'),
+    last_used_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE
+    id = sqlc.arg('id')
+    AND (
+        ip != sqlc.arg('ip')
+        OR last_used_at < strftime('%Y-%m-%dT%H:%M:%SZ', 'now', '-1 minute')
+    );
+
+
+-- name: SessionsDelete :execrows
+DELETE FROM sessions
+WHERE
+    id = ?
+    AND user_id = ?;
+
+
+-- name: SessionsDeleteByUser :exec
+DELETE FROM sessions
+WHERE
+    user_id = ?;
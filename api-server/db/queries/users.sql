@@ -17,27 +17,154 @@ WHERE
     id = ?;
 
 
--- name: UsersUpdateNameAndEmail :one
+-- name: UsersSetBlocked :exec
 UPDATE users
 SET
-    name = ?,
-    email = ?
+    is_blocked = ?
 WHERE
-    id = ? RETURNING *;
+    id = ?;
 
 
--- name: UsersSetTokens :exec
-UPDATE users
+-- name: UsersSetCalendarToken :exec
+INSERT INTO
+    calendar_tokens (user_id, token_hash)
+VALUES
+    (?, ?) ON CONFLICT (user_id) DO
+UPDATE
 SET
-    access_token = ?,
-    refresh_token = ?
+    token_hash = excluded.token_hash,
+    created_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now');
+
+
+-- name: UsersDropCalendarToken :exec
+DELETE FROM calendar_tokens
 WHERE
-    id = ?;
+    user_id = ?;
 
 
--- name: UsersSetBlocked :exec
-UPDATE users
+-- name: UsersGetByCalendarToken :one
+SELECT
+    u.id,
+    u.name,
+    u.email,
+    u.provider,
+    u.access_token,
+    u.refresh_token,
+    u.is_admin,
+    u.is_blocked
+FROM
+    users u
+    INNER JOIN calendar_tokens ct ON ct.user_id = u.id
+WHERE
+    ct.token_hash = ?;
+
+
+-- name: UsersGetIdentity :one
+SELECT
+    *
+FROM
+    user_identities
+WHERE
+    provider = ?
+    AND subject = ?;
+
+
+-- name: UsersGetIdentities :many
+SELECT
+    *
+FROM
+    user_identities
+WHERE
+    user_id = ?
+ORDER BY
+    created_at,
+    rowid;
+
+
+-- name: UsersCreateIdentity :one
+INSERT INTO
+    user_identities (user_id, provider, subject, email)
+VALUES
+    (?, ?, ?, ?) RETURNING *;
+
+
+-- name: UsersUpdateIdentityEmail :exec
+UPDATE user_identities
 SET
-    is_blocked = ?
+    email = ?
 WHERE
     id = ?;
+
+
+-- name: UsersDeleteIdentity :execrows
+DELETE FROM user_identities
+WHERE
+    id = ?
+    AND user_id = ?;
+
+
+-- name: UsersCountIdentities :one
+SELECT
+    COUNT(*)
+FROM
+    user_identities
+WHERE
+    user_id = ?;
+
+
+-- name: UsersCountByEmail :one
+SELECT
+    COUNT(DISTINCT u.id)
+FROM
+    users u
+    LEFT JOIN user_identities ui ON ui.user_id = u.id
+WHERE
+    lower(sqlc.arg('email')) IN (lower(u.email), lower(ui.email));
+
+
+-- name: UserBlocksAdd :execrows
+INSERT INTO
+    user_blocks (user_id, blocked_user_id)
+VALUES
+    (?, ?) ON CONFLICT DO NOTHING;
+
+
+-- name: UserBlocksRemove :execrows
+DELETE FROM user_blocks
+WHERE
+    user_id = ?
+    AND blocked_user_id = ?;
+
+
+-- name: UserBlocksGet :many
+SELECT
+    ub.blocked_user_id,
+    u.email AS blocked_user_email,
+    ub.created_at
+FROM
+    user_blocks ub
+    INNER JOIN users u ON u.id = ub.blocked_user_id
+WHERE
+    ub.user_id = ?
+ORDER BY
+    ub.created_at DESC;
+
+
+-- name: UserBlocksBetween :one
+-- Checks if either of the users blocked the other.
+SELECT
+    EXISTS (
+        SELECT
+            1
+        FROM
+            user_blocks
+        WHERE
+            (
+                user_id = sqlc.arg('user_a')
+                AND blocked_user_id = sqlc.arg('user_b')
+            )
+            OR (
+                user_id = sqlc.arg('user_b')
+                AND blocked_user_id = sqlc.arg('user_a')
+            )
+    );
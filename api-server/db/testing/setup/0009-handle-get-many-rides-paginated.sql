@@ -0,0 +1,2 @@
+-- :require ./no-init-add-three-users.sql
+-- :require ./0002-handle-get-many-rides-three-items.sql
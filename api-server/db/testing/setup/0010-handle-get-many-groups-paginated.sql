@@ -0,0 +1,2 @@
+-- :require ./no-init-add-three-users.sql
+-- :require ./0006-handle-get-many-groups-three-items.sql
@@ -0,0 +1,53 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit
+    )
+VALUES
+    (
+        'weekly',
+        'Graz',
+        'Wien',
+        '2044-11-26T15:00:00Z',
+        'NnCaPHQLC9',
+        'm6SYNABgAw',
+        4
+    ),
+    (
+        'weekdays',
+        'Graz',
+        'Leibnitz',
+        '2044-11-25T08:00:00Z',
+        'nmBSHcxyvn',
+        'nmBSHcxyvn',
+        2
+    ),
+    (
+        'once',
+        'Tokyo',
+        'NYC',
+        '2044-11-20T10:00:00Z',
+        'm6SYNABgAw',
+        'm6SYNABgAw',
+        3
+    );
+
+
+INSERT INTO
+    ride_schedules (id, ride_id, schedule_interval, unit)
+VALUES
+    ('s-weekly', 'weekly', 1, 'weeks'),
+    ('s-weekdays', 'weekdays', 1, 'weekdays');
+
+
+INSERT INTO
+    ride_schedule_weekdays (ride_schedule_id, weekday)
+VALUES
+    ('s-weekdays', 'monday'),
+    ('s-weekdays', 'wednesday');
@@ -0,0 +1,90 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit
+    )
+VALUES
+    (
+        'weekly',
+        'Graz',
+        'Wien',
+        '2044-11-01T06:30:00Z',
+        'NnCaPHQLC9',
+        'm6SYNABgAw',
+        4
+    ),
+    (
+        'once',
+        'Tokyo',
+        'NYC',
+        '2044-11-20T10:00:00Z',
+        'm6SYNABgAw',
+        'm6SYNABgAw',
+        3
+    );
+
+
+INSERT INTO
+    ride_schedules (id, ride_id, schedule_interval, unit, timezone, rrule)
+VALUES
+    (
+        's-weekly',
+        'weekly',
+        1,
+        'weekdays',
+        'Europe/Vienna',
+        'RRULE:FREQ=WEEKLY;BYDAY=TU
+EXDATE:20441122T063000Z'
+    );
+
+
+INSERT INTO
+    ride_schedule_weekdays (ride_schedule_id, weekday)
+VALUES
+    ('s-weekly', 'tuesday');
+
+
+INSERT INTO
+    ride_events (
+        id,
+        ride_id,
+        location_from,
+        location_to,
+        driver,
+        status,
+        tacking_place_at,
+        transport_limit
+    )
+VALUES
+    (
+        'weekly-2',
+        'weekly',
+        'Graz',
+        'Wien',
+        'm6SYNABgAw',
+        'canceled',
+        '2044-11-08T06:30:00Z',
+        4
+    ),
+    (
+        'weekly-3',
+        'weekly',
+        'Graz',
+        'Wien',
+        'm6SYNABgAw',
+        'upcoming',
+        '2044-11-15T06:30:00Z',
+        4
+    );
+
+
+INSERT INTO
+    ride_participants (user_id, ride_event_id)
+VALUES
+    ('nmBSHcxyvn', 'weekly-3');
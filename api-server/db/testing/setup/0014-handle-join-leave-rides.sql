@@ -0,0 +1,34 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit
+    )
+VALUES
+    (
+        'full',
+        'Graz',
+        'Wien',
+        '2044-11-26T15:00:00Z',
+        'm6SYNABgAw',
+        'm6SYNABgAw',
+        1
+    );
+
+
+UPDATE ride_events
+SET
+    id = 'full-event'
+WHERE
+    ride_id = 'full';
+
+
+INSERT INTO
+    ride_participants (user_id, ride_event_id)
+VALUES
+    ('m6SYNABgAw', 'full-event');
@@ -0,0 +1,72 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit
+    )
+VALUES
+    (
+        'weekly',
+        'Graz',
+        'Wien',
+        '2044-11-26T15:00:00Z',
+        'NnCaPHQLC9',
+        'm6SYNABgAw',
+        4
+    );
+
+
+UPDATE ride_events
+SET
+    id = 'weekly-1'
+WHERE
+    ride_id = 'weekly';
+
+
+INSERT INTO
+    ride_schedules (id, ride_id, schedule_interval, unit)
+VALUES
+    ('s-weekly', 'weekly', 1, 'weeks');
+
+
+INSERT INTO
+    ride_events (
+        id,
+        ride_id,
+        location_from,
+        location_to,
+        driver,
+        tacking_place_at,
+        transport_limit
+    )
+VALUES
+    (
+        'weekly-2',
+        'weekly',
+        'Graz',
+        'Wien',
+        'm6SYNABgAw',
+        '2044-12-03T15:00:00Z',
+        4
+    ),
+    (
+        'weekly-3',
+        'weekly',
+        'Graz',
+        'Wien',
+        'm6SYNABgAw',
+        '2044-12-10T15:00:00Z',
+        4
+    );
+
+
+INSERT INTO
+    ride_participants (user_id, ride_event_id)
+VALUES
+    ('nmBSHcxyvn', 'weekly-2'),
+    ('m6SYNABgAw', 'weekly-2');
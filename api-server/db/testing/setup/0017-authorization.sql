@@ -0,0 +1,46 @@
+-- :require ./no-init-add-three-users.sql
+UPDATE users
+SET
+    is_admin = TRUE
+WHERE
+    id = 'NnCaPHQLC9';
+
+
+INSERT INTO
+    ride_groups (id, name, created_by)
+VALUES
+    ('g', 'G1', 'nmBSHcxyvn');
+
+
+INSERT INTO
+    ride_group_members (group_id, user_id, join_status)
+VALUES
+    ('g', 'NnCaPHQLC9', 'pending'),
+    ('g', 'm6SYNABgAw', 'member');
+
+
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit
+    )
+VALUES
+    (
+        'r',
+        'Graz',
+        'Wien',
+        '2044-11-26T15:00:00Z',
+        'nmBSHcxyvn',
+        'nmBSHcxyvn',
+        4
+    );
+
+
+UPDATE ride_events
+SET
+    id = ride_id || '-event';
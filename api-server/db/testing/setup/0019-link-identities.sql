@@ -0,0 +1,18 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    user_identities (id, user_id, provider, subject, email)
+VALUES
+    (
+        'google-01',
+        'NnCaPHQLC9',
+        'google',
+        'NnCaPHQLC9',
+        'test@example.com'
+    ),
+    (
+        'google-02',
+        'nmBSHcxyvn',
+        'google',
+        'nmBSHcxyvn',
+        'WDZHw/GNwrQ5vhtWojbR@gmail.com'
+    );
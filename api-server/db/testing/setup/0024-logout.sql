@@ -0,0 +1,6 @@
+-- :require ./no-init-add-three-users.sql
+UPDATE users
+SET
+    is_admin = TRUE
+WHERE
+    id = 'NnCaPHQLC9';
@@ -0,0 +1,50 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    ride_groups (id, name, created_by)
+VALUES
+    ('g', 'G1', 'NnCaPHQLC9');
+
+
+INSERT INTO
+    ride_group_members (group_id, user_id, join_status)
+VALUES
+    ('g', 'nmBSHcxyvn', 'member');
+
+
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit,
+        group_id
+    )
+VALUES
+    (
+        'public',
+        'Graz',
+        'Wien',
+        '2044-11-26T15:00:00Z',
+        'm6SYNABgAw',
+        'm6SYNABgAw',
+        4,
+        NULL
+    ),
+    (
+        'private',
+        'Graz',
+        'Linz',
+        '2044-11-27T15:00:00Z',
+        'NnCaPHQLC9',
+        'NnCaPHQLC9',
+        4,
+        'g'
+    );
+
+
+UPDATE ride_events
+SET
+    id = ride_id || '-event';
@@ -0,0 +1,19 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    ride_groups (id, name, created_by)
+VALUES
+    ('g', 'G1', 'NnCaPHQLC9'),
+    ('other', 'G2', 'm6SYNABgAw');
+
+
+INSERT INTO
+    ride_group_members (group_id, user_id, join_status)
+VALUES
+    ('g', 'nmBSHcxyvn', 'member'),
+    ('g', 'm6SYNABgAw', 'pending');
+
+
+INSERT INTO
+    group_messages (id, content, group_id, sent_by, created_at)
+VALUES
+    ('history-01', 'First', 'g', 'NnCaPHQLC9', '2044-11-26T15:00:00Z');
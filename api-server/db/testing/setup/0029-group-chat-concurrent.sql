@@ -0,0 +1,11 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    ride_groups (id, name, created_by)
+VALUES
+    ('g', 'G1', 'NnCaPHQLC9');
+
+
+INSERT INTO
+    ride_group_members (group_id, user_id, join_status)
+VALUES
+    ('g', 'nmBSHcxyvn', 'member');
@@ -0,0 +1,79 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    ride_groups (id, name, created_by)
+VALUES
+    ('g', 'G1', 'NnCaPHQLC9'),
+    ('other', 'G2', 'm6SYNABgAw');
+
+
+INSERT INTO
+    ride_group_members (group_id, user_id, join_status)
+VALUES
+    ('g', 'nmBSHcxyvn', 'member'),
+    ('g', 'm6SYNABgAw', 'pending');
+
+
+INSERT INTO
+    group_messages (id, content, group_id, sent_by, created_at)
+VALUES
+    ('msg-01', 'Message 1', 'g', 'NnCaPHQLC9', '2044-11-26T15:00:00Z'),
+    ('msg-02', 'Message 2', 'g', 'nmBSHcxyvn', '2044-11-26T15:01:00Z'),
+    ('msg-03', 'Message 3', 'g', 'NnCaPHQLC9', '2044-11-26T15:02:00Z'),
+    ('msg-04', 'Message 4', 'g', 'nmBSHcxyvn', '2044-11-26T15:03:00Z'),
+    ('msg-05', 'Message 5', 'g', 'NnCaPHQLC9', '2044-11-26T15:04:00Z'),
+    ('msg-06', 'Message 6', 'g', 'nmBSHcxyvn', '2044-11-26T15:05:00Z'),
+    ('msg-07', 'Message 7', 'g', 'NnCaPHQLC9', '2044-11-26T15:06:00Z'),
+    ('msg-08', 'Message 8', 'g', 'nmBSHcxyvn', '2044-11-26T15:07:00Z'),
+    ('msg-09', 'Message 9', 'g', 'NnCaPHQLC9', '2044-11-26T15:08:00Z'),
+    ('msg-10', 'Message 10', 'g', 'nmBSHcxyvn', '2044-11-26T15:09:00Z'),
+    ('msg-11', 'Message 11', 'g', 'NnCaPHQLC9', '2044-11-26T15:10:00Z'),
+    ('msg-12', 'Message 12', 'g', 'nmBSHcxyvn', '2044-11-26T15:11:00Z'),
+    ('msg-13', 'Message 13', 'g', 'NnCaPHQLC9', '2044-11-26T15:12:00Z'),
+    ('msg-14', 'Message 14', 'g', 'nmBSHcxyvn', '2044-11-26T15:13:00Z'),
+    ('msg-15', 'Message 15', 'g', 'NnCaPHQLC9', '2044-11-26T15:14:00Z'),
+    ('msg-16', 'Message 16', 'g', 'nmBSHcxyvn', '2044-11-26T15:15:00Z'),
+    ('msg-17', 'Message 17', 'g', 'NnCaPHQLC9', '2044-11-26T15:16:00Z'),
+    ('msg-18', 'Message 18', 'g', 'nmBSHcxyvn', '2044-11-26T15:17:00Z'),
+    ('msg-19', 'Message 19', 'g', 'NnCaPHQLC9', '2044-11-26T15:18:00Z'),
+    ('msg-20', 'Message 20', 'g', 'nmBSHcxyvn', '2044-11-26T15:19:00Z'),
+    ('msg-21', 'Message 21', 'g', 'NnCaPHQLC9', '2044-11-26T15:20:00Z'),
+    ('msg-22', 'Message 22', 'g', 'nmBSHcxyvn', '2044-11-26T15:21:00Z'),
+    ('msg-23', 'Message 23', 'g', 'NnCaPHQLC9', '2044-11-26T15:22:00Z'),
+    ('msg-24', 'Message 24', 'g', 'nmBSHcxyvn', '2044-11-26T15:23:00Z'),
+    ('msg-25', 'Message 25', 'g', 'NnCaPHQLC9', '2044-11-26T15:24:00Z'),
+    ('msg-26', 'Message 26', 'g', 'nmBSHcxyvn', '2044-11-26T15:25:00Z'),
+    ('msg-27', 'Message 27', 'g', 'NnCaPHQLC9', '2044-11-26T15:26:00Z'),
+    ('msg-28', 'Message 28', 'g', 'nmBSHcxyvn', '2044-11-26T15:27:00Z'),
+    ('msg-29', 'Message 29', 'g', 'NnCaPHQLC9', '2044-11-26T15:28:00Z'),
+    ('msg-30', 'Message 30', 'g', 'nmBSHcxyvn', '2044-11-26T15:29:00Z'),
+    ('msg-31', 'Message 31', 'g', 'NnCaPHQLC9', '2044-11-26T15:30:00Z'),
+    ('msg-32', 'Message 32', 'g', 'nmBSHcxyvn', '2044-11-26T15:31:00Z'),
+    ('msg-33', 'Message 33', 'g', 'NnCaPHQLC9', '2044-11-26T15:32:00Z'),
+    ('msg-34', 'Message 34', 'g', 'nmBSHcxyvn', '2044-11-26T15:33:00Z'),
+    ('msg-35', 'Message 35', 'g', 'NnCaPHQLC9', '2044-11-26T15:34:00Z'),
+    ('msg-36', 'Message 36', 'g', 'nmBSHcxyvn', '2044-11-26T15:35:00Z'),
+    ('msg-37', 'Message 37', 'g', 'NnCaPHQLC9', '2044-11-26T15:36:00Z'),
+    ('msg-38', 'Message 38', 'g', 'nmBSHcxyvn', '2044-11-26T15:37:00Z'),
+    ('msg-39', 'Message 39', 'g', 'NnCaPHQLC9', '2044-11-26T15:38:00Z'),
+    ('msg-40', 'Message 40', 'g', 'nmBSHcxyvn', '2044-11-26T15:39:00Z'),
+    ('msg-41', 'Message 41', 'g', 'NnCaPHQLC9', '2044-11-26T15:40:00Z'),
+    ('msg-42', 'Message 42', 'g', 'nmBSHcxyvn', '2044-11-26T15:41:00Z'),
+    ('msg-43', 'Message 43', 'g', 'NnCaPHQLC9', '2044-11-26T15:42:00Z'),
+    ('msg-44', 'Message 44', 'g', 'nmBSHcxyvn', '2044-11-26T15:43:00Z'),
+    ('msg-45', 'Message 45', 'g', 'NnCaPHQLC9', '2044-11-26T15:44:00Z'),
+    ('msg-46', 'Message 46', 'g', 'nmBSHcxyvn', '2044-11-26T15:45:00Z'),
+    ('msg-47', 'Message 47', 'g', 'NnCaPHQLC9', '2044-11-26T15:46:00Z'),
+    ('msg-48', 'Message 48', 'g', 'nmBSHcxyvn', '2044-11-26T15:47:00Z'),
+    ('msg-49', 'Message 49', 'g', 'NnCaPHQLC9', '2044-11-26T15:48:00Z'),
+    ('msg-50', 'Message 50', 'g', 'nmBSHcxyvn', '2044-11-26T15:49:00Z'),
+    ('msg-51', 'Message 51', 'g', 'NnCaPHQLC9', '2044-11-26T15:50:00Z'),
+    ('msg-52', 'Message 52', 'g', 'nmBSHcxyvn', '2044-11-26T15:51:00Z'),
+    ('msg-53', 'Message 53', 'g', 'NnCaPHQLC9', '2044-11-26T15:52:00Z'),
+    ('msg-54', 'Message 54', 'g', 'nmBSHcxyvn', '2044-11-26T15:53:00Z'),
+    ('msg-55', 'Message 55', 'g', 'NnCaPHQLC9', '2044-11-26T15:54:00Z'),
+    ('msg-56', 'Message 56', 'g', 'nmBSHcxyvn', '2044-11-26T15:55:00Z'),
+    ('msg-57', 'Message 57', 'g', 'NnCaPHQLC9', '2044-11-26T15:56:00Z'),
+    ('msg-58', 'Message 58', 'g', 'nmBSHcxyvn', '2044-11-26T15:57:00Z'),
+    ('msg-59', 'Message 59', 'g', 'NnCaPHQLC9', '2044-11-26T15:58:00Z'),
+    ('msg-60', 'Message 60', 'g', 'nmBSHcxyvn', '2044-11-26T15:59:00Z'),
+    ('other-01', 'Other', 'other', 'm6SYNABgAw', '2044-11-26T15:00:00Z');
@@ -0,0 +1,21 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    ride_groups (id, name, created_by)
+VALUES
+    ('g', 'G1', 'NnCaPHQLC9'),
+    ('other', 'G2', 'm6SYNABgAw');
+
+
+INSERT INTO
+    ride_group_members (group_id, user_id, join_status)
+VALUES
+    ('g', 'nmBSHcxyvn', 'member'),
+    ('g', 'm6SYNABgAw', 'member');
+
+
+INSERT INTO
+    group_messages (id, content, group_id, sent_by, created_at)
+VALUES
+    ('msg-01', 'Original', 'g', 'nmBSHcxyvn', '2044-11-26T15:00:00Z'),
+    ('msg-02', 'Second', 'g', 'm6SYNABgAw', '2044-11-26T15:01:00Z'),
+    ('other-01', 'Other', 'other', 'm6SYNABgAw', '2044-11-26T15:00:00Z');
@@ -0,0 +1,18 @@
+-- :require ./no-init-add-three-users.sql
+UPDATE users
+SET
+    is_admin = TRUE
+WHERE
+    id = 'NnCaPHQLC9';
+
+
+INSERT INTO
+    conversations (id, user_a, user_b)
+VALUES
+    ('private', 'm6SYNABgAw', 'nmBSHcxyvn');
+
+
+INSERT INTO
+    direct_messages (id, conversation_id, content, sent_by, created_at)
+VALUES
+    ('private-01', 'private', 'Secret', 'm6SYNABgAw', '2044-11-25T15:00:00Z');
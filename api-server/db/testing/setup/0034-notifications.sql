@@ -0,0 +1,56 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    ride_groups (id, name, created_by)
+VALUES
+    ('g', 'G1', 'NnCaPHQLC9');
+
+
+INSERT INTO
+    ride_group_members (group_id, user_id, join_status, is_moderator)
+VALUES
+    ('g', 'nmBSHcxyvn', 'member', TRUE);
+
+
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit,
+        group_id
+    )
+VALUES
+    (
+        'ride',
+        'Graz',
+        'Wien',
+        '2044-11-26T15:00:00Z',
+        'NnCaPHQLC9',
+        'NnCaPHQLC9',
+        3,
+        NULL
+    ),
+    (
+        'small',
+        'Graz',
+        'Linz',
+        '2044-11-27T15:00:00Z',
+        'NnCaPHQLC9',
+        'NnCaPHQLC9',
+        1,
+        NULL
+    );
+
+
+UPDATE ride_events
+SET
+    id = ride_id || '-event';
+
+
+INSERT INTO
+    group_messages (id, content, group_id, sent_by, created_at)
+VALUES
+    ('group-01', 'Hello group', 'g', 'nmBSHcxyvn', '2044-11-25T15:00:00Z');
@@ -0,0 +1 @@
+-- :require ./no-init-add-three-users.sql
@@ -0,0 +1,46 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit
+    )
+VALUES
+    (
+        'broken',
+        'Graz',
+        'Wien',
+        '2044-11-26T15:00:00Z',
+        'NnCaPHQLC9',
+        'NnCaPHQLC9',
+        4
+    ),
+    (
+        'working',
+        'Graz',
+        'Linz',
+        '2044-11-26T16:00:00Z',
+        'NnCaPHQLC9',
+        'NnCaPHQLC9',
+        4
+    );
+
+
+INSERT INTO
+    ride_schedules (id, ride_id, schedule_interval, unit)
+VALUES
+    ('s-broken', 'broken', 1, 'weeks'),
+    ('s-working', 'working', 1, 'weeks');
+
+
+-- Fails the scheduler after it created the first event of the ride.
+CREATE TRIGGER fail_broken_ride BEFORE INSERT ON ride_events WHEN NEW.ride_id = 'broken'
+AND NEW.tacking_place_at >= '2044-12-10T00:00:00Z' BEGIN
+SELECT
+    RAISE(ABORT, 'broken ride');
+
+END;
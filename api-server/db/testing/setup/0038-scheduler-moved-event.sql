@@ -0,0 +1,27 @@
+-- :require ./no-init-add-three-users.sql
+INSERT INTO
+    rides (
+        id,
+        location_from,
+        location_to,
+        tacking_place_at,
+        created_by,
+        driver,
+        transport_limit
+    )
+VALUES
+    (
+        'weekly',
+        'Graz',
+        'Wien',
+        '2044-11-26T15:00:00Z',
+        'NnCaPHQLC9',
+        'm6SYNABgAw',
+        4
+    );
+
+
+INSERT INTO
+    ride_schedules (id, ride_id, schedule_interval, unit, rrule)
+VALUES
+    ('s-weekly', 'weekly', 1, 'weeks', 'FREQ=WEEKLY;COUNT=6');
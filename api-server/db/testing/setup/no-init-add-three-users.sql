@@ -1,34 +1,40 @@
 INSERT INTO
-    users (
-        id,
-        name,
-        email,
-        provider,
-        access_token,
-        refresh_token
-    )
+    users (id, name, email, provider)
+VALUES
+    ('NnCaPHQLC9', 'test-user-01', 'test@example.com', 'google'),
+    ('nmBSHcxyvn', 'test-user-02', 'WDZHw/GNwrQ5vhtWojbR@gmail.com', 'google'),
+    ('m6SYNABgAw', 'test-user-03', 'KluwXy24KzJnN@proton.me', 'google');
+
+
+-- Seals the access tokens of the sessions below.
+INSERT INTO
+    auth_keys (id, secret)
+VALUES
+    ('test-key-01', 'hSikFXLOW3JSixYpFIJ+4sDVpDJ2xUtqNz5WmhCzMxw=');
+
+
+-- Sessions store SHA-256 hashes of the tokens the tests sign in with.
+INSERT INTO
+    sessions (id, user_id, access_token_hash, refresh_token_hash, refreshed_at)
 VALUES
     (
+        'session-01',
         'NnCaPHQLC9',
-        'test-user-01',
-        'test@example.com',
-        'google',
-        'yr0osJ1-kQrQsOXzMGNVhbGUzdA0seGWAMK70WERFRWU5NzKcrQ1R2U_8ofXubwLbWxJYQK9hvj9xonabMMroA6oPjfnFuFR_zwOugdNGZVOwo6l8zczvFYRnGUdncOWv5Ckdy5eyB0leWpH7sDI_hbAxyiKljceGnKX-hcvB9MwjnsAiJMZ6EC_nAV-6ujEwM-YbPbYwndTEyY7CgDBp9gYrcOlvs9z_yf5sM_WQlziZFVVyGVoJyWDl-a1XbyLiagscmTeDs0pxQO0BH0oBF5qW8IRDIWAOuaSz3K9eygpqKQIxTFVq_psqaZT_qrhHI-3k-OPBbtWq9pF32-wVxNoFJMB3YvY17DgQfxxvzgckUH5YFlNks1cUgroHk2CIjtgs-9eskUzOrCzBKW3-EBcuyNrttnIePAkdVl2NC586fkBCVnKqfVIKYwm-ZrdCHxQVTZwGcswGnUP-YajlwZhmM-jgBjXIAJfWihcQTrDGmWz-0z8R8kycMdASguZXnQolGTvUOsOT21kFC4fwF-XQRi0tPh4mg0Bj1QN9y5sgibripVhCXQ7ma9QbbYL9ooAax6wqEU7b5-Gfai_r1ZLI5WcjOkI0ePAa2PikIC1b5nAMaz0c9y7Sv-hVAYtVzW5VB6PRJ4f5DoI_6KlGx6jE1AzmIEyPp_4_ImIUhHBlGUa7kikZkqUTtr9vSaz84EvQzT81wt3ULBLvA89Cr5rOWgAlNfmul3JZtJwfUuW39Mxc6QQN1mLUyKIUiofZImwkLqlACuriArAhMM_E8qo2V9sHSRVhZA_NOnKOYujsoFTTdr4vb2CWyeVIAEWT2YCueSMXinGL1Gmbxcczy9Hi2LoupnGYlQr9KgP5V_UrRvl_isC1MgUArQ25nIkdBNpUREW7a31bqWibAamOCgLP8bS20DERUD3-bKcDYDSDq9cEP2pKBRm_WyVQqCNYPIUpDOmDd9SEAZ3J_WveApSIDJlDt0j_nTibImctu6he92Kp63L5_A8nG6wBWW363CZ7tgktoY3KidPwbByX35BQRTUyE7wYxAqzdcF8Jd_n24SLHxC',
-        'w74x6I32icXpeXNFfZxnc4e2-DpsVQUUAvqGvfall17wn7vI1mBpMa7slwX0zOSLzKySZEcGa_L84w5PL6Pivz2xZc2rCl22DVUGvYvSRZVOq0LYJbUvuYQWDrk6GRsoghYEOfHaHkWsXGBMA8Va86tU8FzE18qWYU52IQGKZ_Nekn54Hn4FP1r9meDf1FGsTH-OyPqcWuX6YEqWE100ZJIBqkPK66Co-5hpq7DwxjltJ_anJQiSVRO1BYJAhh8PImpOyLOPg0gYb3cawMtmXGz4U4eeaR-Q7rPkZhCSnHlSPwf9cr4bsxShlp2vdZmT1-Wri3XdTEJC7x5p1iL8EG3AzVm7LPdJ0v29clNuQiFCNHIB_7a5I04ZCrXJXd-lTbcIcr96DffWW2UlXz70-2A9kINpFfWSyzzIP_qBsUdQuHQzuzkgLhFy5a6dFRcoZujFrm3MCcXyS4hOiGzonFMzkTm1UubLYgeqWIWtijBlYlcILAVaLlX9yxpEmgCGtQZ5Z9rXpQtdOKyixxw7wNhX9vq5Ispev9UxYFgLFHmKoY5L2IEWA4QhWHaAR5GVHBvqcGSTxzpZEeaErBYTrXNJLOe_bxAox4G7MIdxXyiMpRY9dG4gT1T4zKJt1MeeiqvDLYUKcw9kEDcJ0yS1g5dFsSP90zQmYKwcdDSJ_4A='
+        '85249da4dca201b9a8d263fe40f32644d1a01d5d9ecd2bd838d27cbc4d73ce8b',
+        '414b9553756c2efb5110312cb6e5eb866f3ac26a3f2781baf843e6f0be366ba4',
+        strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
     ),
     (
+        'session-02',
         'nmBSHcxyvn',
-        'test-user-02',
-        'WDZHw/GNwrQ5vhtWojbR@gmail.com',
-        'google',
-        'X9zRE-UX7LywAzDse_vtbxqCU_5VNPqyRqTt-5JW5Ut2CNDinGZcCRlMgEAKj4MkInY16qrKlkvxU07NkSax8s4dCNi7OMv1krdrwkdHKzRdiOmI-nJ3mQN56zYkeH3OzJrqm-beBKf7G0EaFnOv2dqYNT093J9Z0URKWtOZNyMPNTOoggfjQpShGXNRV7VIwqOoGlbcGKo8YQqeVzJaH4KGdAeBUh46cou9AIc-YZBpvjeOwckr3wBXBdH8J3HTgypVyYwryAiS-WGWmtW2p7TftdhGxtHEPUSCJ3BNJV9-Dsp6Z3owReeTHa8xZvIQwjCf4ruul4JGA_9qw46wd9z6DEOxwQyErcmpUByOa7-Y2CSSUg84YRLbhqoajdRg6VtdU_9uhMPXNoCPAuLjcWsszPEYLHwP8FiKxLV5wYOwZaB3SPCz6yoTpbfWY8PVMicBF71U_IBFAtYrtOOtKqeMOG9oplvSQq60skIdwJEutbQwMyaARnwqIFxFwmqZkEGIJDbYzsimNRb5UWpWY1av2jeyp4OosZEN36cev1TLeho4Viyrr50j-rAyH7LM-NIFXPrm0CaAt9qb2V2MjQetcULUUHG1FdVQRxgKdbLTFNb5RVrefj9S2tTU_TFWAMP6WNMoST9PcCXTMJVWCgLzRvqpTuxiD3aQd0ylaM9WUpSvU7bRbpesgxT2KPxWjja3-o8yXrjA3685Uhfk9E6wFUolfwLozHvGlJO8M2HZ93df0vy1G767bRS5mfvKSKO_PY2YWozWCHeUaFYd5inEN0XMHGfzc1a0F54-RRPDkR4wr5RyBLXJ2VHg7moBL0nbgwKOa3LzL8QywIDB87GInQLh5_tSbWoyVFtxi4P9ARWJPc9gaZASMYPzknmvUl6CREquMoJEbvwCB72MEx8iYetNnznd9dhWNSDmiJXroZkw8sHIcmMZ5XnjIp-CXcDt3l6J2mzdh2QU9jxOL4tMpKcCVql30dk__FIZ3X0_RKemdsvxSN8iw1SAal7O1OnmzEoiPyTqklOi41zTtC5Hy5KWcT5FOBoKAKgr36z_mQ4eL32EtQ7oT7oemcWQMMIzo_4=',
-        'ckm_yhJR4FuU2TwOPlpe8_kOt9UzOUpO9BiFbBtca7bsqdFEklbBsc8Iuc3VF_7OYpylpYgESmoIASYjN9sAMbKPByhXa_ozKpNKgZ15ZRNCA2fVITLAQt-AjBUJaIjaiE-vauejs7R0IUW-CYBCe7X400hnbe8woR6I_GFi14V-bevZM2aWptIF_6LO-TE9YnjUX63us2j9-Xboh3lV7LVKF35vzxIeuX3hIbONIK9E85I7pE6B0b8zwyJ8lWlwoV--ZwceWxr8d9TO2mEAJMIC8TKfSktra-Jhchictqv4fAR2HzpnWKhFjL1xxaRy2jGHFEorsDqHk0vjcoo3A7TGx5BQe3zL_0GL0FdB3PJeVzMcxl5bK1uuKLl0KvtP_XFFUhh_4YiQeA20-iLx1Pq3Fxq_zP0TI8apqMuvrBTrrh_70Hv-wqPf6pui9D6kR-YZ2i-zl4Eyi2u7gGoftpAYLWbO_q1bpGglGX6rcSsvgLIAFQR74LMFBNlhU65cwTM-WTJ9VvnnZ_JA6Ml7TTzYwrpcdGYUS7HPBd82GWLwk2snVQCm7Cw_6qH9lC5Y1LPq6Zpbf3Qt4gpfAAIvJOUimt-zCeI2nvJNAQxBjUs8U68UE3cLI5Vw4qulsZvLKvCJkr9LBF21KHKrfmTHVn3MBUc0wdt4iqE8awEdrEY='
+        '108ae0acffba432cbc676f0276cd16694147a20b138e0277aeebbf47855c37de',
+        'b41e634477b4f8d02379c04a344501790d8372c27fa9726268c71cf4cf04b64f',
+        strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
     ),
     (
+        'session-03',
         'm6SYNABgAw',
-        'test-user-03',
-        'KluwXy24KzJnN@proton.me',
-        'google',
-        'ZUExX_hWfpvcmB5fJA5uI1CdULsuliYwuDPlNzz9hVO6SAabPYDc0DXWszuPUYf72r_eYpOoFQbYjn-FWZ52S0UoGH9jBWPd_Jha6kx0EYf_F-xAZpJIgFHXVIMxyvz1MpZnOF3Ni7DwjGfKV4krv28if2QpZWqGh1I9o712NAvUBMpbxREi8hDBtjJ0lQgNPw-TUnLtMgev4GPF762T0k4RAWIj8Llx1ICsxENauVbrYNc6lRYnApBuyzxfTuy4joJwmN-TI6wFCye-rckrX4zf0PRBCv0qWj5sT8ijiHmvAcIf1O9Mei9Z0yKVEblu-u-4QdpKSfMBI1jNgwiS040m1H6gWgC0nj4voeK4qD9ywCanYueOEPw-83riuzekLBguuMtdOmAu640h8JHVOx7jH6qdHblzLy1yDRd8fppkosg9s8eGPDJF2042SN52aJTPE-hMdGKTUYUXUJk_sIEnTh94KPkk3bbogssVIR07xNdIb2NNQCKPj8dsiB2E3t4Hi_WN7ip6IQhBr4XpKfo5_Pio8vyggadhVSYBVxsh1x0gziOlpRObh6rRFZgUT9In7ihnC8kge89j8lJNZDX2RtEQ2Y0ugUirBesLji7X0D8xKYYjUe8cEQsJYvZGCyZhUN037VW9LVrV7kDSo1Tk6QGWUvMwI4OBPcTn6djYtmmDnH1p2wNGemehh1laZmL3NQwLaylMdBfE_VBfo3mnZAFNkVrV7hYbZrtntaaWkPvsoe1P5v2IJbIDEDo87E6lRrPldZahFNW_vcfHbL3TSAikCrMbohSithvOmAKmFbXfh-A_tk2AbitNNulLV8Ju_skHs0XmuZIt0ToDHlUE37ojGh9YBUXE_Wx1rMFDADAJ-kK4aIiII3IBfWvrZQvN2rKnKOzNo_uSU88prmK-JvcqyB6KUBmjJGI8w0KC66Eqsu0XOGO0W-m3YnaVi_TYgKyhDWfm81pgOkw3kKqBTc3gJxIeiYfIlL7-lL6bMva5MFK5PbYF4ih9V-OgsSpos989i6XVFnWuSri93y4MmKJYGkpqyQ8rPNIwbV1EtxfQYIB3G-vR1p0dpvs=',
-        'OGzqcy0hMZbl4L8mCJqp0E08-IZUH2mT46zJUHnd-Yg33s0X54Bn4iOV5OrsNPa_72eSm-ykj3HJ9wtw8Dx172tK3FO7ede-StL66qpw7UguL-P6ScqF3T-nf0ibrycdTm6Z1HcVtS7MdGQcq6ewOWhHv8zJG2fs7n74FDUiqg6X0fhaZkmslk6SKP59j4rn4716-AeNqVowXL3_oodiYP6pBtaThrbMB9n91APWQ5MXruSSoegJPFoXJKk2nsFLR4pjFpRD-R5Am4UZOeQpmLKe2rvZ_HYG4_mR16Ogc4F3nKU7uluIC8ngiiLAsSXK7Y4gcGXBw68jQDZvvqoLVwcURp_VKLEt96G1X7W6u66Y0pvAOy2ArY95JgAqt7mRUN9qwqXEJHXMJ0XQua5AUz9goWkNstgWuYmuLXLiyemR-R4RTXKrJg7YhHKotRhMnnBaDYeAoPEW0QI-6aGV51BeAdkuKGx3uVpM-sn0eK2bCximTBWGZpHRNSVp20RjesYXf1IgGX9fytE1qEeY9bihQWi1lgd1D49SUap7VvNVoZwlVxHq9buazAS9xt7waWr0a9pdESSLqPbvsDu6E5AB23oMuuJTGwTo_IN5SXmk7wL671-TUOeCpTKYePsnf0oTxTgBKnCCp9NMIx8Uh-KUAfWQM3QH0VMnGHuaJB8='
+        'd757c3aae3ecc136e5ade33291e6be3c4433fc851914219e299d08ccbe2a5381',
+        'ba5e6834b271613481506c5d4160705bde934caa4bb65070e13839d89d62f397',
+        strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
     );
@@ -0,0 +1,145 @@
+import { useQuery } from "@tanstack/react-query";
+import { isRestErr, QUERY_KEYS, RestError, toastRestErr } from "./utils";
+import { AuthTokens } from "./models/user";
+
+// A provider users can sign in with, OpenID Connect providers have ids like
+// 'oidc/{name}'.
+export type AuthProvider = {
+  id: string;
+  name: string;
+};
+
+export function isOidcProvider(provider: AuthProvider) {
+  return provider.id.startsWith("oidc/");
+}
+
+export function useAuthProviders() {
+  return useQuery({
+    queryKey: [QUERY_KEYS.authProviders],
+    queryFn: async () => {
+      const res = await fetch(`${import.meta.env.VITE_API_URI}/auth/providers`, {
+        method: "GET",
+        headers: {
+          Accept: "application/json",
+        },
+      });
+
+      const data = await res.json();
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        throw new Error("Failed to load login providers.");
+      }
+
+      return data as AuthProvider[];
+    },
+  });
+}
+
+// The verifier of the login that was started last. Logins return a one-time
+// code that can only be exchanged for tokens together with the verifier.
+const CODE_VERIFIER_KEY = "auth-code-verifier";
+
+// The verifier of the link that was started last. The account is only linked
+// once the code the API redirects back with is confirmed with the verifier.
+const LINK_VERIFIER_KEY = "link-code-verifier";
+
+export async function loginUrl(providerId: string) {
+  const params = await codeChallenge(CODE_VERIFIER_KEY);
+  return `${import.meta.env.VITE_API_URI}/auth/${providerId}/login?${params}`;
+}
+
+// Starts linking an account of the provider, the returned URL has to be
+// opened.
+export async function linkUrl(
+  providerId: string,
+  accessToken: string,
+): Promise<RestError | { url: string }> {
+  const params = await codeChallenge(LINK_VERIFIER_KEY);
+  const res = await fetch(
+    `${import.meta.env.VITE_API_URI}/auth/identities/link/${providerId}?${params}`,
+    {
+      method: "POST",
+      headers: {
+        Authorization: accessToken,
+        Accept: "application/json",
+      },
+    },
+  );
+
+  return await res.json();
+}
+
+export async function confirmLink(
+  code: string,
+  accessToken: string,
+): Promise<RestError | undefined> {
+  const codeVerifier = sessionStorage.getItem(LINK_VERIFIER_KEY) ?? "";
+  sessionStorage.removeItem(LINK_VERIFIER_KEY);
+
+  const res = await fetch(
+    `${import.meta.env.VITE_API_URI}/auth/identities/confirm-link`,
+    {
+      method: "POST",
+      headers: {
+        Authorization: accessToken,
+        Accept: "application/json",
+      },
+      body: JSON.stringify({ code, codeVerifier }),
+    },
+  );
+
+  if (!res.ok) {
+    return await res.json();
+  }
+}
+
+// Stores a new verifier under `key` and returns the parameters with its
+// challenge.
+async function codeChallenge(key: string) {
+  const bytes = crypto.getRandomValues(new Uint8Array(32));
+  const verifier = base64Url(bytes);
+  sessionStorage.setItem(key, verifier);
+
+  const hash = await crypto.subtle.digest(
+    "SHA-256",
+    new TextEncoder().encode(verifier),
+  );
+  return new URLSearchParams({
+    code_challenge: base64Url(new Uint8Array(hash)),
+    code_challenge_method: "S256",
+  });
+}
+
+export async function exchangeAuthCode(code: string): Promise<AuthTokens> {
+  const codeVerifier = sessionStorage.getItem(CODE_VERIFIER_KEY) ?? undefined;
+  sessionStorage.removeItem(CODE_VERIFIER_KEY);
+
+  const res = await fetch(`${import.meta.env.VITE_API_URI}/auth/exchange`, {
+    method: "POST",
+    headers: {
+      Accept: "application/json",
+    },
+    body: JSON.stringify({ code, codeVerifier }),
+  });
+
+  if (!res.ok) {
+    throw new Error(`Login failed with message ${await res.text()}`);
+  }
+
+  const data = await res.json();
+  if (
+    typeof data.accessToken !== "string" ||
+    typeof data.refreshToken !== "string"
+  ) {
+    throw new Error("Invalid authentication token data.");
+  }
+
+  return { accessToken: data.accessToken, refreshToken: data.refreshToken };
+}
+
+function base64Url(bytes: Uint8Array) {
+  return btoa(String.fromCharCode(...bytes))
+    .replace(/\+/g, "-")
+    .replace(/\//g, "_")
+    .replace(/=+$/, "");
+}
@@ -7,7 +7,14 @@ import { STYLES } from "../utils";
 import { RideSchedule } from "../models/ride";
 import { createRide } from "../createRide";
 
-export function CreateRideForm({ afterSubmit }: { afterSubmit?: () => void }) {
+export function CreateRideForm({
+  afterSubmit,
+  groupId,
+}: {
+  afterSubmit?: () => void;
+  // creates a ride only visible to members of this group
+  groupId?: string;
+}) {
   const navigate = useNavigate();
   const queryClient = useQueryClient();
   const { user, setUser } = useUserStore();
@@ -29,7 +36,7 @@ export function CreateRideForm({ afterSubmit }: { afterSubmit?: () => void }) {
   const form = useForm({
     defaultValues,
     onSubmit: async ({ value }) => {
-      await createRide(defaultValues, user, setUser, queryClient).mutateAsync(value);
+      await createRide(defaultValues, user, setUser, queryClient, groupId).mutateAsync(value);
       afterSubmit?.();
     },
   });
@@ -228,7 +235,40 @@ const validWeekdays = [
   "sunday",
 ];
 
+const rruleFreqUnits: Record<string, string> = {
+  daily: "days",
+  weekly: "weeks",
+  monthly: "months",
+  yearly: "years",
+};
+
+// e.g. 'FREQ=MONTHLY;BYDAY=1MO' or 'RRULE:FREQ=WEEKLY;BYDAY=TU,TH EXDATE:20250415T073000Z'.
+// The rule is validated by the server, unit and interval are only a summary.
+function parseRRule(text: string, timezone: string): RideSchedule | undefined {
+  const freq = text.match(/FREQ=([a-z]+)/i);
+  const unit = freq ? rruleFreqUnits[freq[1].toLowerCase()] : undefined;
+  if (unit === undefined) {
+    return undefined;
+  }
+
+  const interval = text.match(/INTERVAL=(\d+)/i);
+  return {
+    unit,
+    interval: interval ? parseInt(interval[1]) : 1,
+    weekdays: null,
+    timezone,
+    rrule: text,
+  };
+}
+
 export function parseRecuring(text: string): RideSchedule | undefined {
+  // the time of day of recurring rides is kept in the time zone of the user
+  const timezone = Intl.DateTimeFormat().resolvedOptions().timeZone;
+
+  if (/^(RRULE:)?FREQ=/i.test(text.trim())) {
+    return parseRRule(text.trim(), timezone);
+  }
+
   let t = text.trim().toLowerCase();
   if (t.startsWith("every")) {
     t = t.substring("every".length).trim();
@@ -261,11 +301,13 @@ export function parseRecuring(text: string): RideSchedule | undefined {
   const unit = validBasicUnits.find(
     (u) => u === partUnit || u + "s" === partUnit,
   );
+
   if (unit) {
     return {
       unit: unit + "s",
       interval,
       weekdays: null,
+      timezone,
     };
   }
 
@@ -284,5 +326,6 @@ export function parseRecuring(text: string): RideSchedule | undefined {
     unit: "weekdays",
     interval,
     weekdays,
+    timezone,
   };
 }
@@ -5,7 +5,7 @@ import { parseRecuring } from "./components/CreateRideForm";
 import { isRestErr, QUERY_KEYS, toastRestErr } from "./utils";
 
 
-export const createRide = (defaultValues: DefaultValues, user: UserLoggedIn, setUser: (user: User) => void, queryClient: QueryClient) => {
+export const createRide = (defaultValues: DefaultValues, user: UserLoggedIn, setUser: (user: User) => void, queryClient: QueryClient, groupId?: string) => {
   return useMutation({
     mutationKey: ["create-ride-from-submmit"],
     mutationFn: async (params: typeof defaultValues) => {
@@ -17,6 +17,7 @@ export const createRide = (defaultValues: DefaultValues, user: UserLoggedIn, set
           transportLimit: parseInt(params.transportLimit),
           driver: user.id,
           schedule: parseRecuring(params.recurs),
+          groupId,
         }),
         headers: {
           Authorization: user.tokens.accessToken,
@@ -38,6 +39,7 @@ export const createRide = (defaultValues: DefaultValues, user: UserLoggedIn, set
       }
 
       queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.rideItems] });
+      queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.groupSingle] });
     },
   });
 }
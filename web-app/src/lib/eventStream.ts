@@ -0,0 +1,88 @@
+import { useEffect, useRef } from "react";
+import { UserLoggedIn } from "./models/user";
+
+const RECONNECT_DELAY_MS = 5000;
+
+// Calls `onEvent` with the data of every event of the Server-Sent Events
+// stream at `path` while the component is mounted. The stream is read with
+// fetch instead of EventSource, which can't send the 'Authorization' header.
+export function useEventStream<T>(
+  user: UserLoggedIn,
+  path: string,
+  onEvent: (data: T) => void,
+) {
+  const accessToken = user.tokens.accessToken;
+
+  // the latest callback is used without reconnecting on every render
+  const onEventRef = useRef(onEvent);
+  onEventRef.current = onEvent;
+
+  useEffect(() => {
+    const controller = new AbortController();
+
+    const connect = async () => {
+      while (!controller.signal.aborted) {
+        try {
+          await streamEvents<T>(path, accessToken, controller.signal, (data) =>
+            onEventRef.current(data),
+          );
+        } catch (err) {
+          if (controller.signal.aborted) {
+            return;
+          }
+          console.error(`Event stream '${path}' disconnected.`, err);
+        }
+
+        await new Promise((resolve) =>
+          setTimeout(resolve, RECONNECT_DELAY_MS),
+        );
+      }
+    };
+
+    connect();
+    return () => controller.abort();
+  }, [accessToken, path]);
+}
+
+async function streamEvents<T>(
+  path: string,
+  accessToken: string,
+  signal: AbortSignal,
+  onEvent: (data: T) => void,
+) {
+  const res = await fetch(`${import.meta.env.VITE_API_URI}${path}`, {
+    method: "GET",
+    headers: {
+      Authorization: accessToken,
+      Accept: "text/event-stream",
+    },
+    signal,
+  });
+
+  if (!res.ok || res.body === null) {
+    throw new Error(`Failed to subscribe with status ${res.status}.`);
+  }
+
+  const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffered = "";
+  for (;;) {
+    const { done, value } = await reader.read();
+    if (done) {
+      return;
+    }
+
+    // events are separated by a blank line, lines starting with ':' are
+    // keep-alive comments
+    buffered += value;
+    const events = buffered.split("\n\n");
+    buffered = events.pop() ?? "";
+    for (const event of events) {
+      const data = event
+        .split("\n")
+        .find((line) => line.startsWith("data: "));
+      if (data !== undefined) {
+        onEvent(JSON.parse(data.slice("data: ".length)) as T);
+      }
+    }
+  }
+}
@@ -1,27 +1,94 @@
+import { RideEvent } from "./ride";
+
 export type Group = {
   groupId: string;
   name: string;
   description?: string;
   createdBy: string; // user id
   members: GroupMemeber[];
+  rides: RideEvent[]; // upcoming rides, empty for non-members
 };
 
 export type GroupMemeber = {
   userId: string;
   email: string;
   joinStatus: string;
+  isModerator: boolean;
 };
 
+// messages are sent in the chat of a group, the thread of a ride event or a
+// conversation between two users, only one of the ids is set
 export type GroupMessage = {
-  groupId: string;
+  groupId: string | null;
+  rideEventId: string | null;
+  conversationId: string | null;
   messageId: string;
   content: string;
   sentBy: string; // user id
   sentByEmail: string;
   createdAt: string;
   repliesTo?: string;
+  editedAt: string | null;
+  // the content of deleted messages is empty
+  deletedAt: string | null;
+  reactions: GroupMessageReaction[];
+};
+
+export type GroupMessageReaction = {
+  emoji: string;
+  userIds: string[];
+};
+
+// A group chat, the thread of a ride event or a conversation, their routes are
+// below '/{kind}/by-id/{id}' and the socket is '/{kind}/messages/{id}'.
+export type ChatTarget = {
+  kind: "groups" | "rides" | "conversations";
+  id: string;
+};
+
+// Direct messages with another user.
+export type Conversation = {
+  conversationId: string;
+  userId: string; // the other user
+  userEmail: string;
+  createdAt: string;
+  unreadCount: number;
+  // either user blocked the other, no messages can be sent
+  isBlocked: boolean;
+  lastMessage: {
+    messageId: string;
+    content: string;
+    sentBy: string;
+    createdAt: string;
+  } | null;
+};
+
+export type BlockedUser = {
+  userId: string;
+  email: string;
+  createdAt: string;
 };
 
+// Frames sent by a chat, see '/{kind}/messages/{id}'.
+export type ChatFrame =
+  | { type: "message"; message: GroupMessage }
+  // sent with the message after the change
+  | {
+      type: "message.edited" | "message.deleted" | "message.changed";
+      message: GroupMessage;
+    }
+  | {
+      type: "reaction.added" | "reaction.removed";
+      message: GroupMessage;
+      reaction: { emoji: string; userId: string };
+    }
+  // has to be answered with a 'pong' frame
+  | { type: "ping" }
+  | { type: "error"; error: string }
+  // messages older than 'before' were missed and have to be loaded from
+  // '/{kind}/by-id/{id}/messages'
+  | { type: "gap"; before: string };
+
 export type DefaultValues = {
   locationFrom: string,
   locationTo: string,
@@ -29,3 +96,34 @@ export type DefaultValues = {
   transportLimit: string,
   recurs: string
 }
+
+export type Page<T> = {
+  items: T[];
+  nextCursor: string | null;
+  total: number;
+};
+
+export type NotificationType =
+  | "ride.joined"
+  | "ride.seat_assigned"
+  | "ride.canceled"
+  | "ride.changed"
+  | "group.join_requested"
+  | "group.join_approved"
+  | "message.reply"
+  | "message.direct";
+
+export type Notification = {
+  notificationId: string;
+  userId: string;
+  type: NotificationType;
+  // the user whose action caused the notification
+  actorId: string | null;
+  actorEmail: string | null;
+  rideEventId: string | null;
+  groupId: string | null;
+  conversationId: string | null;
+  messageId: string | null;
+  createdAt: string;
+  readAt: string | null;
+};
@@ -1,3 +1,5 @@
+import { GroupMessage, Page } from "./models";
+
 export type RideEvent = {
   rideId: string;
   rideEventId: string;
@@ -12,15 +14,49 @@ export type RideEvent = {
   status: string;
   schedule: RideSchedule | null;
   participants: RideParticipant[];
+  // users waiting for a seat, in order
+  waitlist: RideParticipant[];
+  // only members of this group can see the ride
+  groupId: string | null;
+  // latest messages of the thread, only sent to users who can use it
+  thread?: Page<GroupMessage>;
 };
 
 export type RideSchedule = {
   unit: string;
   interval: number;
   weekdays: string[] | null;
+  timezone?: string;
+  // RFC 5545 recurrence rule, takes precedence over unit/interval/weekdays
+  rrule?: string | null;
 };
 
 export type RideParticipant = {
   userId: string;
   email: string;
 };
+
+export type RideEventChange = {
+  id: string;
+  changedBy: string;
+  changedByEmail: string;
+  changes: {
+    field: string;
+    from: string;
+    to: string;
+  }[];
+  createdAt: string;
+};
+
+// A change to a ride event, streamed from '/rides/events'.
+export type RideUpdate = {
+  type:
+    | "ride.created"
+    | "ride.joined"
+    | "ride.left"
+    | "ride.updated"
+    | "ride.canceled";
+  // empty for changes made by the scheduler
+  userId: string;
+  ride: RideEvent;
+};
@@ -21,3 +21,31 @@ export type AuthTokens = {
   accessToken: string;
   refreshToken: string;
 };
+
+// An account at a login provider that is linked to the user.
+export type UserIdentity = {
+  identityId: string;
+  provider: string;
+  email: string;
+  createdAt: string;
+};
+
+// A device the user is signed in on.
+export type UserSession = {
+  sessionId: string;
+  device: string;
+  ip: string;
+  createdAt: string;
+  lastUsedAt: string;
+  current: boolean;
+};
+
+// A token for scripts and integrations, see '/users/me/tokens'.
+export type PersonalToken = {
+  tokenId: string;
+  name: string;
+  scopes: string[];
+  createdAt: string;
+  expiresAt: string;
+  lastUsedAt: string | null;
+};
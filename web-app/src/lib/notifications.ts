@@ -0,0 +1,43 @@
+import { useQueryClient } from "@tanstack/react-query";
+import { toast } from "react-toastify";
+import { QUERY_KEYS } from "./utils";
+import { Notification } from "./models/models";
+import { UserLoggedIn } from "./models/user";
+import { useEventStream } from "./eventStream";
+
+// Shows new notifications as toasts and keeps the loaded notifications up to
+// date while the component is mounted.
+export function useNotifications(user: UserLoggedIn) {
+  const queryClient = useQueryClient();
+
+  useEventStream<Notification>(
+    user,
+    "/users/me/notifications/events",
+    (notification) => {
+      queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.notifications] });
+      toast(describeNotification(notification), { type: "info" });
+    },
+  );
+}
+
+export function describeNotification(notification: Notification): string {
+  const actor = notification.actorEmail ?? "Someone";
+  switch (notification.type) {
+    case "ride.joined":
+      return `${actor} joined your ride.`;
+    case "ride.seat_assigned":
+      return "You got a seat of a ride you were waiting for.";
+    case "ride.canceled":
+      return `${actor} canceled a ride you take part in.`;
+    case "ride.changed":
+      return `${actor} changed a ride you take part in.`;
+    case "group.join_requested":
+      return `${actor} wants to join your group.`;
+    case "group.join_approved":
+      return `${actor} approved your request to join the group.`;
+    case "message.reply":
+      return `${actor} replied to your message.`;
+    case "message.direct":
+      return `${actor} sent you a message.`;
+  }
+}
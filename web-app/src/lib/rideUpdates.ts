@@ -0,0 +1,15 @@
+import { useQueryClient } from "@tanstack/react-query";
+import { QUERY_KEYS } from "./utils";
+import { RideUpdate } from "./models/ride";
+import { UserLoggedIn } from "./models/user";
+import { useEventStream } from "./eventStream";
+
+// Keeps the loaded rides up to date while the component is mounted.
+export function useRideUpdates(user: UserLoggedIn) {
+  const queryClient = useQueryClient();
+
+  useEventStream<RideUpdate>(user, "/rides/events", () => {
+    queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.rideItems] });
+    queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.rideSingle] });
+  });
+}
@@ -3,6 +3,7 @@ import { CreateRideForm } from "../components/CreateRideForm";
 import { useUserStore } from "../stores";
 import { AuthTokens } from "../models/user";
 import { RideEvent, RideSchedule } from "../models/ride";
+import { Page } from "../models/models";
 import { Link, ReactNode, useNavigate } from "@tanstack/react-router";
 import { useQuery } from "@tanstack/react-query";
 import { LoadingSpinner } from "../components/Spinner";
@@ -116,7 +117,7 @@ function RideList({ tokens }: { tokens: AuthTokens }) {
         throw new Error("Failed to load rides.");
       }
 
-      return data as RideEvent[];
+      return (data as Page<RideEvent>).items;
     },
   });
 
@@ -226,6 +227,10 @@ export function displaySchedule(schedule: RideSchedule | null): string {
     return "---";
   }
 
+  if (schedule.rrule) {
+    return schedule.rrule;
+  }
+
   if (schedule.unit === "weekdays") {
     if (schedule.weekdays === null) {
       return "---";
@@ -5,6 +5,8 @@ export const QUERY_KEYS = {
   groupItems: "group-items",
   rideSingle: "ride-single",
   groupSingle: "group-single",
+  authProviders: "auth-providers",
+  notifications: "notifications",
 } as const;
 
 export const STYLES = {
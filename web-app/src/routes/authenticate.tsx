@@ -1,18 +1,18 @@
 import { createFileRoute } from "@tanstack/react-router";
 import { useEffect } from "react";
-import { AuthTokens } from "../lib/models/user";
 
 export const Route = createFileRoute("/authenticate")({
-  validateSearch: validateAuthTokens,
+  validateSearch: validateAuthCode,
   component: ReceiveAuthRedirect,
 });
 
+// The API redirects here after a login with a one-time code. The window that
+// started the login exchanges the code for tokens.
 function ReceiveAuthRedirect() {
-  const { accessToken, refreshToken } = Route.useSearch();
+  const { code } = Route.useSearch();
   useEffect(() => {
     if (window.opener) {
-      const data = { accessToken, refreshToken };
-      window.opener.postMessage(data);
+      window.opener.postMessage({ code });
       window.close();
     }
   }, []);
@@ -20,18 +20,13 @@ function ReceiveAuthRedirect() {
   return <span>Redirecting...</span>;
 }
 
-export function validateAuthTokens(data: Record<string, unknown>): AuthTokens {
-  const accessToken = data["accessToken"];
-  if (typeof accessToken !== "string") {
-    throw new Error("Invalid/Missing search parameter 'accessToken'.");
+export function validateAuthCode(data: Record<string, unknown>): {
+  code: string;
+} {
+  const code = data["code"];
+  if (typeof code !== "string") {
+    throw new Error("Invalid/Missing search parameter 'code'.");
   }
 
-  const refreshToken = data["refreshToken"];
-  if (typeof refreshToken !== "string") {
-    throw new Error("Invalid/Missing search parameter 'refreshToken'.");
-  }
-  return {
-    accessToken,
-    refreshToken,
-  };
+  return { code };
 }
@@ -14,10 +14,18 @@ import { useUserStore } from "../lib/stores";
 import { LoadingSpinner } from "../lib/components/Spinner";
 import { AuthTokens, UserLoggedIn } from "../lib/models/user";
 import { RideEvent, RideSchedule } from "../lib/models/ride";
-import { Group, GroupMessage } from "../lib/models/models";
+import {
+  ChatFrame,
+  ChatTarget,
+  Group,
+  GroupMessage,
+  Page,
+} from "../lib/models/models";
+import { toast } from "react-toastify";
 import { useForm } from "@tanstack/react-form";
-import { parseSearchString, SearchFilters } from "../lib/search";
 import { SearchInput } from "../lib/components/SearchInput";
+import { useRideUpdates } from "../lib/rideUpdates";
+import { useNotifications } from "../lib/notifications";
 
 export const Route = createFileRoute("/dashboard")({
   component: Dashboard,
@@ -110,7 +118,7 @@ function GroupCol({ user }: { user: UserLoggedIn }) {
         throw new Error("Failed to load groups.");
       }
 
-      return data as Group[];
+      return (data as Page<Group>).items;
     },
   });
 
@@ -151,7 +159,47 @@ function GroupCol({ user }: { user: UserLoggedIn }) {
 }
 
 function GroupChat({ group, user }: { group?: Group; user: UserLoggedIn }) {
+  if (group === undefined) {
+    return null;
+  }
+
+  return (
+    <Chat
+      chat={{ kind: "groups", id: group.groupId }}
+      moderatorId={group.createdBy}
+      user={user}
+      title={
+        <>
+          Messages from{" "}
+          <Link
+            to="/groups/$groupId"
+            className="italic underline"
+            params={{ groupId: group.groupId }}
+          >
+            {group.name}
+          </Link>
+        </>
+      }
+    />
+  );
+}
+
+// Used for group chats, the threads of ride events and conversations. The
+// moderator can delete the messages of others. Direct messages can't be
+// changed or reacted to.
+export function Chat({
+  chat,
+  moderatorId,
+  title,
+  user,
+}: {
+  chat: ChatTarget;
+  moderatorId?: string;
+  title: ReactNode;
+  user: UserLoggedIn;
+}) {
   const { setUser } = useUserStore();
+  const { kind, id } = chat;
   const ws = useRef<WebSocket | null>(null);
 
   const msgContainerRef = useRef<HTMLDivElement>(null);
@@ -162,61 +210,28 @@ function GroupChat({ group, user }: { group?: Group; user: UserLoggedIn }) {
   );
 
   const [msgs, setMsgs] = useState<GroupMessage[]>([]);
+  const [hasOlder, setHasOlder] = useState(true);
+  const [connected, setConnected] = useState(false);
 
   useEffect(() => {
     setMsgs([]);
-
-    if (ws.current) {
-      ws.current.close();
-    }
-
-    if (group !== undefined) {
-      ws.current = new WebSocket(
-        `${import.meta.env.VITE_API_URI}/groups/messages/${group?.groupId}`,
-      );
-
-      ws.current.onmessage = (m) => {
-        const msg = JSON.parse(m.data);
-        setMsgs((msgs) => [...msgs, msg]);
-      };
-    }
-  }, [group]);
-
-  useEffect(() => {
-    if (!msgContainerRef.current) {
-      return;
-    }
-
-    msgContainerRef.current.scrollTop = msgContainerRef.current.scrollHeight;
-  }, [msgs]);
-
-  const sendMessage = useMutation({
-    mutationKey: ["send-message"],
-    onError: (err) => {
-      console.error(err);
-    },
-    onSuccess: () => {
-      setContent("");
-      setRepliesTo(undefined);
-    },
-    mutationFn: async ({ content }: { content: string }) => {
-      if (!group) {
-        return;
-      }
-
+    setHasOlder(true);
+    setConnected(false);
+
+    let closed = false;
+    let reconnectTimeout: ReturnType<typeof setTimeout> | undefined;
+    // reconnects only replay the messages after the last one received
+    let lastMessageId: string | undefined;
+    const connect = async () => {
+      // browsers can't send the 'Authorization' header with a WebSocket
       const res = await fetch(
-        `${import.meta.env.VITE_API_URI}/groups/by-id/${group.groupId}/send-message`,
+        `${import.meta.env.VITE_API_URI}/${kind}/by-id/${id}/chat-ticket`,
         {
           method: "POST",
           headers: {
             Authorization: user.tokens.accessToken,
             Accept: "application/json",
           },
-          body: JSON.stringify({
-            groupId: group.groupId,
-            content,
-            repliesTo: repliesTo?.messageId,
-          }),
         },
       );
 
@@ -227,35 +242,182 @@ function GroupChat({ group, user }: { group?: Group; user: UserLoggedIn }) {
       const data = await res.json();
       if (isRestErr(data)) {
         toastRestErr(data);
-        throw new Error("Failed to send message.");
+        return;
       }
-    },
-  });
 
-  if (group === undefined) {
-    return null;
-  }
+      if (closed) {
+        return;
+      }
+
+      const since =
+        lastMessageId === undefined
+          ? ""
+          : `&since=${encodeURIComponent(lastMessageId)}`;
+      const socket = new WebSocket(
+        `${import.meta.env.VITE_API_URI}/${kind}/messages/${id}?ticket=${encodeURIComponent(data.ticket)}${since}`,
+      );
+      ws.current = socket;
+
+      socket.onopen = () => setConnected(true);
+      socket.onclose = () => {
+        setConnected(false);
+        if (!closed) {
+          reconnectTimeout = setTimeout(connect, 2000);
+        }
+      };
+      socket.onmessage = (m) => {
+        const frame = JSON.parse(m.data) as ChatFrame;
+        switch (frame.type) {
+          case "ping":
+            socket.send(JSON.stringify({ type: "pong" }));
+            break;
+          case "error":
+            toast(frame.error, { type: "error" });
+            break;
+          case "gap":
+            // only the latest messages are replayed, older ones can be loaded again
+            setMsgs([]);
+            setHasOlder(true);
+            break;
+          case "message.edited":
+          case "message.deleted":
+          case "message.changed":
+          case "reaction.added":
+          case "reaction.removed":
+            setMsgs((msgs) =>
+              msgs.map((msg) =>
+                msg.messageId === frame.message.messageId ? frame.message : msg,
+              ),
+            );
+            break;
+          case "message":
+            lastMessageId = frame.message.messageId;
+            setMsgs((msgs) =>
+              msgs.some((msg) => msg.messageId === frame.message.messageId)
+                ? msgs
+                : [...msgs, frame.message],
+            );
+            break;
+        }
+      };
+    };
+
+    connect();
+    return () => {
+      closed = true;
+      clearTimeout(reconnectTimeout);
+      ws.current?.close();
+      ws.current = null;
+    };
+  }, [kind, id, user.tokens.accessToken, setUser]);
+
+  // older messages are added to the top, only scroll for new ones
+  const newestMessageId = msgs[msgs.length - 1]?.messageId;
+  useEffect(() => {
+    if (!msgContainerRef.current) {
+      return;
+    }
+
+    msgContainerRef.current.scrollTop = msgContainerRef.current.scrollHeight;
+  }, [newestMessageId]);
+
+  const loadOlder = async () => {
+    const before =
+      msgs.length > 0 ? `&before=${encodeURIComponent(msgs[0].messageId)}` : "";
+    const res = await fetch(
+      `${import.meta.env.VITE_API_URI}/${kind}/by-id/${id}/messages?limit=50${before}`,
+      {
+        method: "GET",
+        headers: {
+          Authorization: user.tokens.accessToken,
+          Accept: "application/json",
+        },
+      },
+    );
+
+    if (res.status === 401) {
+      setUser({ type: "logged-out" });
+    }
+
+    const data = await res.json();
+    if (isRestErr(data)) {
+      toastRestErr(data);
+      return;
+    }
+
+    const page = data as Page<GroupMessage>;
+    setMsgs((msgs) => [
+      ...page.items.filter(
+        (older) => !msgs.some((msg) => msg.messageId === older.messageId),
+      ),
+      ...msgs,
+    ]);
+    setHasOlder(page.nextCursor !== null);
+  };
+
+  // changes are sent to everyone in the chat, including this client
+  const changeMessage = async (
+    msg: GroupMessage,
+    action: string,
+    body: object = {},
+  ) => {
+    const res = await fetch(
+      `${import.meta.env.VITE_API_URI}/${kind}/by-id/${id}/messages/by-id/${msg.messageId}/${action}`,
+      {
+        method: "POST",
+        headers: {
+          Authorization: user.tokens.accessToken,
+          Accept: "application/json",
+        },
+        body: JSON.stringify(body),
+      },
+    );
+
+    if (res.status === 401) {
+      setUser({ type: "logged-out" });
+    }
+
+    const data = await res.json();
+    if (isRestErr(data)) {
+      toastRestErr(data);
+    }
+  };
+
+  const sendMessage = (content: string) => {
+    if (ws.current === null || ws.current.readyState !== WebSocket.OPEN) {
+      return;
+    }
+
+    ws.current.send(
+      JSON.stringify({
+        type: "message",
+        content,
+        repliesTo: repliesTo?.messageId,
+      }),
+    );
+    setContent("");
+    setRepliesTo(undefined);
+  };
 
   return (
     <div className="flex max-h-full flex-1 flex-grow flex-col gap-4 overflow-y-auto break-words">
-      <span className="truncate text-wrap text-2xl">
-        Messages from{" "}
-        <Link
-          to="/groups/$groupId"
-          className="italic underline"
-          params={{ groupId: group.groupId }}
-        >
-          {group.name}
-        </Link>
-      </span>
+      <span className="truncate text-wrap text-2xl">{title}</span>
       <div
         ref={msgContainerRef}
         className="flex max-h-full flex-1 flex-grow flex-col gap-8 overflow-y-auto p-4"
       >
-        {msgs.map((msg, idx) => {
+        {hasOlder && (
+          <button
+            className="self-center text-neutral-500 underline"
+            onClick={loadOlder}
+          >
+            Load older messages
+          </button>
+        )}
+        {msgs.map((msg) => {
           return (
             <div
-              key={idx}
+              key={msg.messageId}
               className={`relative max-w-[80%] rounded-md p-2 ${msg.sentBy === user.id
                 ? "self-end border-b-4 border-l-4 border-cyan-900 bg-cyan-800"
                 : "self-start border-b-4 border-r-4 border-neutral-400 bg-neutral-300 dark:border-neutral-800 dark:bg-neutral-700"
@@ -283,8 +445,29 @@ function GroupChat({ group, user }: { group?: Group; user: UserLoggedIn }) {
                     user={user}
                   />
                 )}
-                <span className="text-lg">{msg.content}</span>
+                {msg.deletedAt !== null ? (
+                  <span className="text-lg italic text-neutral-500">
+                    Message deleted
+                  </span>
+                ) : (
+                  <span className="text-lg">{msg.content}</span>
+                )}
+                {msg.editedAt !== null && msg.deletedAt === null && (
+                  <span className="text-sm text-neutral-500"> (edited)</span>
+                )}
               </div>
+              {msg.deletedAt === null && (
+                <MessageActions
+                  msg={msg}
+                  user={user}
+                  canChange={kind !== "conversations"}
+                  canDelete={
+                    msg.sentBy === user.id || moderatorId === user.id
+                  }
+                  changeMessage={changeMessage}
+                  reply={() => setRepliesTo(msg)}
+                />
+              )}
             </div>
           );
         })}
@@ -303,7 +486,7 @@ function GroupChat({ group, user }: { group?: Group; user: UserLoggedIn }) {
         type="text"
         autoComplete="off"
         placeholder="Send a message..."
-        disabled={sendMessage.isPending}
+        disabled={!connected}
         value={content}
         ref={sendMessageInputRef}
         onChange={(e) => {
@@ -319,13 +502,102 @@ function GroupChat({ group, user }: { group?: Group; user: UserLoggedIn }) {
             return;
           }
 
-          sendMessage.mutate({ content });
+          sendMessage(content);
         }}
       />
     </div>
   );
 }
 
+const QUICK_REACTIONS = ["👍", "❤️", "😂", "😮"];
+
+function MessageActions({
+  msg,
+  user,
+  canChange,
+  canDelete,
+  changeMessage,
+  reply,
+}: {
+  msg: GroupMessage;
+  user: UserLoggedIn;
+  // can be edited and reacted to
+  canChange: boolean;
+  canDelete: boolean;
+  changeMessage: (msg: GroupMessage, action: string, body?: object) => void;
+  reply: () => void;
+}) {
+  const hasReacted = (emoji: string) =>
+    msg.reactions.some(
+      (reaction) =>
+        reaction.emoji === emoji && reaction.userIds.includes(user.id),
+    );
+
+  const toggleReaction = (emoji: string) =>
+    changeMessage(
+      msg,
+      hasReacted(emoji) ? "reactions/remove" : "reactions/add",
+      { emoji },
+    );
+
+  const emojis = canChange
+    ? [
+        ...msg.reactions.map((reaction) => reaction.emoji),
+        ...QUICK_REACTIONS.filter(
+          (emoji) =>
+            !msg.reactions.some((reaction) => reaction.emoji === emoji),
+        ),
+      ]
+    : [];
+
+  return (
+    <div className="mt-1 flex flex-wrap items-center gap-2 text-sm">
+      {emojis.map((emoji) => {
+        const count =
+          msg.reactions.find((reaction) => reaction.emoji === emoji)?.userIds
+            .length ?? 0;
+        return (
+          <button
+            key={emoji}
+            className={`rounded-full px-2 ${hasReacted(emoji) ? "bg-cyan-600" : "bg-neutral-400 dark:bg-neutral-800"}`}
+            onClick={() => toggleReaction(emoji)}
+          >
+            {emoji} {count > 0 ? count : ""}
+          </button>
+        );
+      })}
+      <button className="underline" onClick={reply}>
+        Reply
+      </button>
+      {canChange && msg.sentBy === user.id && (
+        <button
+          className="underline"
+          onClick={() => {
+            const content = prompt("Edit message", msg.content);
+            if (content !== null && content.length > 0) {
+              changeMessage(msg, "edit", { content });
+            }
+          }}
+        >
+          Edit
+        </button>
+      )}
+      {canChange && canDelete && (
+        <button
+          className="underline"
+          onClick={() => {
+            if (confirm("Delete this message?")) {
+              changeMessage(msg, "delete");
+            }
+          }}
+        >
+          Delete
+        </button>
+      )}
+    </div>
+  );
+}
+
 function Reply({
   msgs,
   reply,
@@ -356,8 +628,10 @@ function RideList({ user }: { user: UserLoggedIn }) {
   const tokens = user.tokens;
   const inputRefRideSearch = useRef<HTMLInputElement>(null);
   const { setUser } = useUserStore();
+  useRideUpdates(user);
+  useNotifications(user);
 
-  const [filters, setFilters] = useState<SearchFilters>({});
+  const [search, setSearch] = useState("");
 
   const columns: {
     [K in keyof RideEvent]?: {
@@ -418,15 +692,19 @@ function RideList({ user }: { user: UserLoggedIn }) {
     error,
     data: rides,
   } = useQuery({
-    queryKey: [QUERY_KEYS.rideItems],
+    queryKey: [QUERY_KEYS.rideItems, search],
     queryFn: async () => {
-      const res = await fetch(`${import.meta.env.VITE_API_URI}/rides/many`, {
-        method: "GET",
-        headers: {
-          Authorization: tokens.accessToken,
-          Accept: "application/json",
+      const query = new URLSearchParams({ q: search });
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/rides/many?${query}`,
+        {
+          method: "GET",
+          headers: {
+            Authorization: tokens.accessToken,
+            Accept: "application/json",
+          },
         },
-      });
+      );
 
       if (res.status === 401) {
         setUser({ type: "logged-out" });
@@ -438,8 +716,9 @@ function RideList({ user }: { user: UserLoggedIn }) {
         throw new Error("Failed to load rides.");
       }
 
-      return data as RideEvent[];
+      return (data as Page<RideEvent>).items;
     },
+    placeholderData: (previous) => previous,
   });
 
   if (isPending) {
@@ -450,7 +729,7 @@ function RideList({ user }: { user: UserLoggedIn }) {
     return <span className="text-red-500">Failed to load rides</span>;
   }
 
-  if (rides.length === 0) {
+  if (rides.length === 0 && search === "") {
     return <span>No rides found</span>;
   }
 
@@ -463,16 +742,14 @@ function RideList({ user }: { user: UserLoggedIn }) {
           placeholder=":from My cool city"
           type="text"
           autoComplete="off"
+          defaultValue={search}
           ref={inputRefRideSearch}
           onKeyDown={(e) => {
             if (e.key !== "Enter" || !inputRefRideSearch.current) {
               return;
             }
 
-            const newFilters = parseSearchString(
-              inputRefRideSearch.current.value,
-            );
-            setFilters(newFilters);
+            setSearch(inputRefRideSearch.current.value);
           }}
         />
       </div>
@@ -487,7 +764,6 @@ function RideList({ user }: { user: UserLoggedIn }) {
         </thead>
         <tbody>
           {rides
-            .filter((ride) => rideMatchesFilters(ride, user, filters))
             .map((ride, idx) => {
               return (
                 <RideListRow
@@ -575,6 +851,10 @@ export function displaySchedule(schedule: RideSchedule | null): string {
     return "---";
   }
 
+  if (schedule.rrule) {
+    return schedule.rrule;
+  }
+
   if (schedule.unit === "weekdays") {
     if (schedule.weekdays === null) {
       return "---";
@@ -739,71 +1019,3 @@ function CreateGroupForm({
     </div>
   );
 }
-
-function rideMatchesFilters(
-  ride: RideEvent,
-  me: UserLoggedIn,
-  filters: SearchFilters,
-): boolean {
-  if (filters.source !== undefined && ride.locationFrom !== filters.source) {
-    return false;
-  }
-
-  if (
-    filters.destination !== undefined &&
-    ride.locationTo !== filters.destination
-  ) {
-    return false;
-  }
-
-  if (filters.status !== undefined && ride.status !== filters.status) {
-    return false;
-  }
-
-  if (
-    filters.owner !== undefined &&
-    aliasedEmail(me, ride.createdByEmail) !== aliasedEmail(me, filters.owner)
-  ) {
-    return false;
-  }
-
-  if (
-    filters.driver !== undefined &&
-    aliasedEmail(me, ride.driverEmail) !== aliasedEmail(me, filters.driver)
-  ) {
-    return false;
-  }
-
-  const rideDate = new Date(Date.parse(ride.tackingPlaceAt));
-  if (filters.dateAfter !== undefined && rideDate < filters.dateAfter) {
-    return false;
-  }
-
-  if (filters.dateBefore !== undefined && rideDate > filters.dateBefore) {
-    return false;
-  }
-
-  if (
-    filters.participants !== undefined &&
-    !filters.participants.every((participant) => {
-      const hasParticipant = ride.participants.some(
-        (rideParticipant) =>
-          aliasedEmail(me, rideParticipant.email) ===
-          aliasedEmail(me, participant),
-      );
-      return hasParticipant;
-    })
-  ) {
-    return false;
-  }
-
-  return true;
-}
-
-function aliasedEmail(me: UserLoggedIn, email: string): string {
-  if (email === "me") {
-    return me.email;
-  }
-
-  return email;
-}
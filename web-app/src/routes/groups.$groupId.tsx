@@ -1,7 +1,7 @@
 import * as React from "react";
 import banIcon from "../assets/ban.svg";
-import { createFileRoute, useNavigate } from "@tanstack/react-router";
-import { Group } from "../lib/models/models";
+import { createFileRoute, Link, useNavigate } from "@tanstack/react-router";
+import { Group, GroupMemeber } from "../lib/models/models";
 import { UserLoggedIn } from "../lib/models/user";
 import { useUserStore } from "../lib/stores";
 import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
@@ -9,6 +9,7 @@ import { isRestErr, QUERY_KEYS, STYLES, toastRestErr } from "../lib/utils";
 import { LoadingSpinner } from "../lib/components/Spinner";
 import { toast } from "react-toastify";
 import checkmarkIcon from "../assets/checkmark.svg";
+import { CreateRideForm } from "../lib/components/CreateRideForm";
 
 export const Route = createFileRoute("/groups/$groupId")({
   component: RouteComponent,
@@ -38,6 +39,7 @@ function GroupData({ groupId, user }: { groupId: string; user: UserLoggedIn }) {
   const { setUser } = useUserStore();
   const queryClient = useQueryClient();
   const inputNameRef = React.useRef<HTMLInputElement>(null);
+  const dialogRideRef = React.useRef<HTMLDialogElement>(null);
   const inputDescriptionRef = React.useRef<HTMLInputElement>(null);
 
   const {
@@ -283,6 +285,54 @@ function GroupData({ groupId, user }: { groupId: string; user: UserLoggedIn }) {
     },
   });
 
+  const groupSetModerator = useMutation({
+    mutationKey: [`group-set-moderator-${groupId}`],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async ({
+      userId,
+      action,
+    }: {
+      userId: string;
+      action: "add" | "remove";
+    }) => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/groups/by-id/${groupId}/moderators/${action}`,
+        {
+          method: "POST",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+          body: JSON.stringify({
+            userId,
+          }),
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      if (!res.ok) {
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return;
+        }
+      }
+
+      queryClient.invalidateQueries({
+        queryKey: [QUERY_KEYS.groupSingle, `group-${groupId}`],
+      });
+
+      const message =
+        action === "add" ? "Made user a moderator." : "Removed moderator.";
+      toast(message, { type: "success" });
+    },
+  });
+
   if (isPending) {
     return <LoadingSpinner content={<span>Getting group...</span>} />;
   }
@@ -298,6 +348,14 @@ function GroupData({ groupId, user }: { groupId: string; user: UserLoggedIn }) {
 
   const g = group.data;
   const canEdit = g.createdBy === user.id;
+  const isModerator = g.members.some(
+    (m) => m.userId === user.id && m.joinStatus === "member" && m.isModerator,
+  );
+  // moderators can approve and ban members, but not the owner or other moderators
+  const canManage = (m: GroupMemeber) =>
+    m.userId !== user.id &&
+    m.userId !== g.createdBy &&
+    (canEdit || (isModerator && !m.isModerator));
   const isMember = !g.members.some((m) => m.userId === user.id);
 
   return (
@@ -384,9 +442,26 @@ function GroupData({ groupId, user }: { groupId: string; user: UserLoggedIn }) {
                     className={`ml-2 p-1 ${m.joinStatus === "banned" ? "text-red-500 line-through" : ""} ${m.joinStatus === "pending" ? "text-neutral-300 dark:text-neutral-600" : ""}`}
                   >
                     {m.email}
+                    {m.isModerator && m.joinStatus === "member" ? (
+                      <em className="ml-2 text-base">(moderator)</em>
+                    ) : null}
                   </span>
-                  {!canEdit || m.userId === user.id ? null : (
+                  {!canManage(m) ? null : (
                     <div className="flex gap-2">
+                      {canEdit && m.joinStatus === "member" && (
+                        <button
+                          className="text-base text-cyan-600"
+                          disabled={groupSetModerator.isPending}
+                          onClick={() => {
+                            groupSetModerator.mutate({
+                              userId: m.userId,
+                              action: m.isModerator ? "remove" : "add",
+                            });
+                          }}
+                        >
+                          {m.isModerator ? "Unmod" : "Mod"}
+                        </button>
+                      )}
                       {m.joinStatus !== "member" && (
                         <button
                           disabled={groupApproveOrBanMember.isPending}
@@ -430,6 +505,48 @@ function GroupData({ groupId, user }: { groupId: string; user: UserLoggedIn }) {
             <span className="ml-2 p-1">---</span>
           )}
         </div>
+
+        {g.members.some(
+          (m) => m.userId === user.id && m.joinStatus === "member",
+        ) ? (
+          <div className="flex w-full flex-col">
+            <div className="flex justify-between">
+              <span className="font-semibold">Rides: </span>
+              <button
+                className="font-bold text-cyan-600"
+                onClick={() => dialogRideRef.current?.showModal()}
+              >
+                +
+              </button>
+            </div>
+            {g.rides.length > 0 ? (
+              g.rides.map((r) => {
+                return (
+                  <Link
+                    key={r.rideEventId}
+                    to="/rides/$rideId"
+                    params={{ rideId: r.rideEventId }}
+                    className="ml-2 p-1 underline"
+                  >
+                    {r.locationFrom} → {r.locationTo},{" "}
+                    {new Date(r.tackingPlaceAt).toLocaleString()}
+                  </Link>
+                );
+              })
+            ) : (
+              <span className="ml-2 p-1">---</span>
+            )}
+            <dialog
+              className="bg-transparent"
+              ref={dialogRideRef}
+            >
+              <CreateRideForm
+                groupId={g.groupId}
+                afterSubmit={() => dialogRideRef.current?.close()}
+              />
+            </dialog>
+          </div>
+        ) : null}
       </div>
     </div>
   );
@@ -1,12 +1,19 @@
 import { createFileRoute, useNavigate } from "@tanstack/react-router";
 import { useUserStore } from "../lib/stores";
 import { useMutation } from "@tanstack/react-query";
-import { validateAuthTokens } from "./authenticate";
+import { validateAuthCode } from "./authenticate";
 import googleIcon from "../assets/google-icon.svg";
+import microsoftIcon from "../assets/microsoft-icon.svg";
 import { ReactNode, useState } from "react";
 import { LoadingSpinner } from "../lib/components/Spinner";
 import { STYLES } from "../lib/utils";
 import { toast } from "react-toastify";
+import {
+  exchangeAuthCode,
+  isOidcProvider,
+  loginUrl,
+  useAuthProviders,
+} from "../lib/authProviders";
 
 export const Route = createFileRoute("/")({
   component: LoginPage,
@@ -19,6 +26,7 @@ function LoginPage() {
   const navigate = useNavigate();
 
   const loginAsUser = useLoginAsUser();
+  const { data: providers } = useAuthProviders();
 
   const state: LoginPageState =
     user.type === "logged-in"
@@ -61,6 +69,26 @@ function LoginPage() {
             />
             <span>Login with Google</span>
           </button>
+          <button
+            className={`flex gap-2 rounded-lg border border-slate-200 px-4 py-2 transition duration-150 hover:border-slate-400 light:hover:shadow dark:border-slate-700 dark:hover:border-slate-500`}
+            onClick={() => authenticateMicrosoft()}
+          >
+            <img
+              className="h-6 w-6"
+              src={microsoftIcon}
+              alt="microsoft logo"
+            />
+            <span>Login with Microsoft</span>
+          </button>
+          {(providers ?? []).filter(isOidcProvider).map((provider) => (
+            <button
+              key={provider.id}
+              className={`flex gap-2 rounded-lg border border-slate-200 px-4 py-2 transition duration-150 hover:border-slate-400 light:hover:shadow dark:border-slate-700 dark:hover:border-slate-500`}
+              onClick={() => authenticateOidc(provider.id)}
+            >
+              <span>Login with {provider.name}</span>
+            </button>
+          ))}
           <DevLogin />
         </Shell>
       );
@@ -168,7 +196,15 @@ function DevLogin() {
 }
 
 async function authenticateGoogle() {
-  openSignInWindow(`${import.meta.env.VITE_API_URI}/auth/google/login`);
+  openSignInWindow(await loginUrl("google"));
+}
+
+async function authenticateMicrosoft() {
+  openSignInWindow(await loginUrl("microsoft"));
+}
+
+async function authenticateOidc(providerId: string) {
+  openSignInWindow(await loginUrl(providerId));
 }
 
 let windowObjectReference: Window | null = null;
@@ -194,18 +230,20 @@ function openSignInWindow(url: string) {
   previousUrl = url;
 }
 
-function receiveAuthMessage(event: MessageEvent) {
+async function receiveAuthMessage(event: MessageEvent) {
   if (event.origin !== window.location.origin) {
     return;
   }
 
   try {
-    const tokens = validateAuthTokens(event.data);
+    const { code } = validateAuthCode(event.data);
+    const tokens = await exchangeAuthCode(code);
     useUserStore.getState().setUser({
       type: "before-logged-in",
       tokens,
     });
   } catch (err) {
-    console.error("Invalid authentication token data.", err);
+    console.error("Failed to exchange authentication code.", err);
+    toast("Failed to login", { type: "error" });
   }
 }
@@ -1,14 +1,15 @@
-import { createFileRoute, useNavigate } from "@tanstack/react-router";
+import { createFileRoute, Link, useNavigate } from "@tanstack/react-router";
 import { LoadingSpinner } from "../lib/components/Spinner";
 import { useUserStore } from "../lib/stores";
 import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
 import { isRestErr, QUERY_KEYS, STYLES, toastRestErr } from "../lib/utils";
-import { RideEvent, RideSchedule } from "../lib/models/ride";
+import { RideEvent, RideEventChange, RideSchedule } from "../lib/models/ride";
 import { UserLoggedIn } from "../lib/models/user";
-import { displaySchedule } from "./dashboard";
+import { Chat, displaySchedule } from "./dashboard";
 import { toast } from "react-toastify";
 import { parseRecuring } from "../lib/components/CreateRideForm";
-import { useRef } from "react";
+import { useRef, useState } from "react";
+import { useRideUpdates } from "../lib/rideUpdates";
 
 export const Route = createFileRoute("/rides/$rideId")({
   component: RouteComponent,
@@ -38,6 +39,8 @@ function RideData({ user, rideId }: { user: UserLoggedIn; rideId: string }) {
   const { setUser } = useUserStore();
   const queryClient = useQueryClient();
   const inputScheduleRef = useRef<HTMLInputElement>(null);
+  const [applyToFollowing, setApplyToFollowing] = useState(false);
+  useRideUpdates(user);
 
   const {
     isPending,
@@ -121,6 +124,70 @@ function RideData({ user, rideId }: { user: UserLoggedIn; rideId: string }) {
     },
   });
 
+  const { data: changes } = useQuery({
+    queryKey: [QUERY_KEYS.rideSingle, `ride-changes-${rideId}`],
+    queryFn: async () => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/rides/by-id/${rideId}/changes`,
+        {
+          method: "GET",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (!res.ok) {
+        return [];
+      }
+
+      return (await res.json()) as RideEventChange[];
+    },
+  });
+
+  const updateDetails = useMutation({
+    mutationKey: ["update-ride-details"],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async ({
+      details,
+      rideEventId,
+    }: {
+      details: { locationFrom?: string; locationTo?: string };
+      rideEventId: string;
+    }) => {
+      const res = await fetch(`${import.meta.env.VITE_API_URI}/rides/update`, {
+        method: "POST",
+        headers: {
+          Authorization: user.tokens.accessToken,
+          Accept: "application/json",
+        },
+        body: JSON.stringify({
+          ...details,
+          rideEventId,
+          scope: applyToFollowing ? "following" : "single",
+        }),
+      });
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      if (!res.ok) {
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return;
+        }
+      }
+
+      queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.rideSingle] });
+      toast("Updated ride", { type: "success" });
+    },
+  });
+
   const joinRide = useMutation({
     mutationKey: ["join-ride"],
     onError: (err) => {
@@ -151,8 +218,49 @@ function RideData({ user, rideId }: { user: UserLoggedIn; rideId: string }) {
         }
       }
 
+      const data = res.ok ? await res.json() : undefined;
+      queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.rideSingle] });
+      if (data?.status === "waitlisted") {
+        toast(`Ride is full, added to waitlist (#${data.waitlistPosition})`, {
+          type: "info",
+        });
+      } else {
+        toast("Joined ride", { type: "success" });
+      }
+    },
+  });
+
+  const leaveRide = useMutation({
+    mutationKey: ["leave-ride"],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async (rideEventId: string) => {
+      const res = await fetch(`${import.meta.env.VITE_API_URI}/rides/leave`, {
+        method: "POST",
+        headers: {
+          Authorization: user.tokens.accessToken,
+          Accept: "application/json",
+        },
+        body: JSON.stringify({
+          rideEventId,
+        }),
+      });
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      if (!res.ok) {
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return;
+        }
+      }
+
       queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.rideSingle] });
-      toast("Joined ride", { type: "success" });
+      toast("Left ride", { type: "success" });
     },
   });
 
@@ -206,7 +314,9 @@ function RideData({ user, rideId }: { user: UserLoggedIn; rideId: string }) {
 
   const r = ride.data;
   const canEdit = r.createdBy === user.id;
-  const canJoin = !r.participants.some((p) => p.userId === user.id);
+  const isWaiting = r.waitlist.some((p) => p.userId === user.id);
+  const canJoin =
+    !r.participants.some((p) => p.userId === user.id) && !isWaiting;
 
   return (
     <div className="flex aspect-video min-w-[420px] flex-col gap-4 rounded bg-neutral-200 p-4 text-xl shadow-lg dark:bg-neutral-800 dark:shadow-none">
@@ -226,19 +336,77 @@ function RideData({ user, rideId }: { user: UserLoggedIn; rideId: string }) {
             {joinRide.isPending ? <LoadingSpinner /> : <>Join</>}
           </button>
         )}
+        {!canJoin && (
+          <button
+            className={STYLES.button}
+            disabled={leaveRide.isPending}
+            onClick={() => {
+              leaveRide.mutate(r.rideEventId);
+            }}
+          >
+            {leaveRide.isPending ? (
+              <LoadingSpinner />
+            ) : isWaiting ? (
+              <>Leave waitlist</>
+            ) : (
+              <>Leave</>
+            )}
+          </button>
+        )}
       </div>
 
       <div className="flex">
         <div className="flex w-1/2 flex-col">
           <span className="font-semibold">To: </span>
-          <span className="ml-2 p-1">{r.locationTo}</span>
+          <input
+            key={r.locationTo}
+            disabled={!canEdit}
+            className="ml-2 border-b border-neutral-200 bg-transparent p-1 focus:border-cyan-500 focus:outline-none disabled:border-none dark:border-neutral-500"
+            defaultValue={r.locationTo}
+            onKeyDown={(e) => {
+              if (e.key !== "Enter" || e.currentTarget.value === "") {
+                return;
+              }
+
+              updateDetails.mutate({
+                details: { locationTo: e.currentTarget.value },
+                rideEventId: r.rideEventId,
+              });
+            }}
+          />
         </div>
         <div className="flex w-1/2 flex-col">
           <span className="font-semibold">From: </span>
-          <span className="ml-2 p-1">{r.locationFrom}</span>
+          <input
+            key={r.locationFrom}
+            disabled={!canEdit}
+            className="ml-2 border-b border-neutral-200 bg-transparent p-1 focus:border-cyan-500 focus:outline-none disabled:border-none dark:border-neutral-500"
+            defaultValue={r.locationFrom}
+            onKeyDown={(e) => {
+              if (e.key !== "Enter" || e.currentTarget.value === "") {
+                return;
+              }
+
+              updateDetails.mutate({
+                details: { locationFrom: e.currentTarget.value },
+                rideEventId: r.rideEventId,
+              });
+            }}
+          />
         </div>
       </div>
 
+      {canEdit && r.schedule !== null ? (
+        <label className="flex items-center gap-2 text-base">
+          <input
+            type="checkbox"
+            checked={applyToFollowing}
+            onChange={(e) => setApplyToFollowing(e.target.checked)}
+          />
+          Apply changes to all following rides
+        </label>
+      ) : null}
+
       <div className="flex flex-col">
         <span className="font-semibold">When: </span>
         <span className="ml-2 p-1">
@@ -263,6 +431,19 @@ function RideData({ user, rideId }: { user: UserLoggedIn; rideId: string }) {
         </div>
       </div>
 
+      {r.groupId !== null ? (
+        <div className="flex flex-col">
+          <span className="font-semibold">Group: </span>
+          <Link
+            to="/groups/$groupId"
+            params={{ groupId: r.groupId }}
+            className="ml-2 p-1 underline"
+          >
+            Only visible to group members
+          </Link>
+        </div>
+      ) : null}
+
       <div className="flex flex-col">
         <span className="font-semibold">Driver: </span>
         <span className="ml-2 p-1">{r.driverEmail}</span>
@@ -301,6 +482,47 @@ function RideData({ user, rideId }: { user: UserLoggedIn; rideId: string }) {
           <span className="ml-2 p-1">---</span>
         )}
       </div>
+
+      {changes !== undefined && changes.length > 0 ? (
+        <div className="flex w-full flex-col">
+          <span className="font-semibold">Changes: </span>
+          {changes.map((c) => {
+            return (
+              <span
+                key={c.id}
+                className="ml-2 p-1 text-base"
+              >
+                {new Date(c.createdAt).toLocaleString()}, {c.changedByEmail}:{" "}
+                {c.changes
+                  .map((f) => `${f.field} ${f.from} → ${f.to}`)
+                  .join(", ")}
+              </span>
+            );
+          })}
+        </div>
+      ) : null}
+
+      {r.waitlist.length > 0 ? (
+        <div className="flex w-full flex-col">
+          <span className="font-semibold">Waitlist: </span>
+          {r.waitlist.map((p, idx) => {
+            return (
+              <span className="ml-2 p-1">
+                {idx + 1}. {p.email}
+              </span>
+            );
+          })}
+        </div>
+      ) : null}
+
+      {r.thread !== undefined ? (
+        <Chat
+          chat={{ kind: "rides", id: r.rideEventId }}
+          moderatorId={r.createdBy}
+          user={user}
+          title="Thread"
+        />
+      ) : null}
     </div>
   );
 }
@@ -1,12 +1,32 @@
-import { createFileRoute, useNavigate } from "@tanstack/react-router";
-import { UserLoggedIn } from "../lib/models/user";
+import { createFileRoute, Link, useNavigate } from "@tanstack/react-router";
+import {
+  PersonalToken,
+  UserIdentity,
+  UserLoggedIn,
+  UserSession,
+} from "../lib/models/user";
 import { useUserStore } from "../lib/stores";
 import { useMutation, useQuery, useQueryClient } from "@tanstack/react-query";
-import { isRestErr, STYLES, toastRestErr } from "../lib/utils";
+import { isRestErr, QUERY_KEYS, STYLES, toastRestErr } from "../lib/utils";
 import { LoadingSpinner } from "../lib/components/Spinner";
 import { toast } from "react-toastify";
+import { confirmLink, linkUrl, useAuthProviders } from "../lib/authProviders";
+import { useEffect, useRef, useState } from "react";
+import {
+  BlockedUser,
+  Conversation,
+  Notification,
+  Page,
+} from "../lib/models/models";
+import { Chat } from "./dashboard";
+import { describeNotification, useNotifications } from "../lib/notifications";
 
 export const Route = createFileRoute("/user/$userId")({
+  // set by the API after linking an account, see `LinkedAccounts`
+  validateSearch: (search: Record<string, unknown>): { link_code?: string } =>
+    typeof search["link_code"] === "string"
+      ? { link_code: search["link_code"] }
+      : {},
   component: RouteComponent,
 });
 
@@ -180,6 +200,869 @@ function UserData({ userId, user }: { userId: string; user: UserLoggedIn }) {
           <span className="font-semibold">Email: </span>
           <span>{u.email}</span>
         </div>
+
+        {u.id === user.id ? <Notifications user={user} /> : null}
+        {u.id === user.id ? (
+          <Conversations user={user} />
+        ) : (
+          <DirectMessages
+            user={user}
+            other={u}
+          />
+        )}
+        {u.id === user.id ? <LinkedAccounts user={user} /> : null}
+        {u.id === user.id ? <Sessions user={user} /> : null}
+        {u.id === user.id ? <PersonalTokens user={user} /> : null}
+      </div>
+    </div>
+  );
+}
+
+function Notifications({ user }: { user: UserLoggedIn }) {
+  const queryClient = useQueryClient();
+  const { setUser } = useUserStore();
+  const [unreadOnly, setUnreadOnly] = useState(true);
+  useNotifications(user);
+
+  const { data: page } = useQuery({
+    queryKey: [QUERY_KEYS.notifications, unreadOnly],
+    queryFn: async () => {
+      const query = new URLSearchParams({ unread: String(unreadOnly) });
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/users/me/notifications?${query}`,
+        {
+          method: "GET",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      const data = await res.json();
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        throw new Error("Failed to load notifications.");
+      }
+
+      return data as Page<Notification>;
+    },
+  });
+
+  // marks all notifications as read without an id
+  const markRead = useMutation({
+    mutationKey: ["notifications-read"],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async ({ notificationId }: { notificationId?: string }) => {
+      const path =
+        notificationId === undefined
+          ? "read-all"
+          : `by-id/${notificationId}/read`;
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/users/me/notifications/${path}`,
+        {
+          method: "POST",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      if (!res.ok) {
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return;
+        }
+      }
+
+      queryClient.invalidateQueries({ queryKey: [QUERY_KEYS.notifications] });
+    },
+  });
+
+  const notifications = page?.items ?? [];
+
+  return (
+    <div className="flex w-full flex-col">
+      <div className="flex items-center justify-between">
+        <span className="font-semibold">
+          Notifications
+          {unreadOnly && (page?.total ?? 0) > 0 ? ` (${page?.total})` : ""}:
+        </span>
+        <div className="flex gap-2 text-base">
+          <button
+            className="underline"
+            onClick={() => setUnreadOnly(!unreadOnly)}
+          >
+            {unreadOnly ? "Show all" : "Show unread"}
+          </button>
+          <button
+            disabled={markRead.isPending}
+            className="underline"
+            onClick={() => markRead.mutate({})}
+          >
+            Mark all as read
+          </button>
+        </div>
+      </div>
+      {notifications.length === 0 ? (
+        <span className="ml-2 p-1">---</span>
+      ) : null}
+      {notifications.map((notification) => (
+        <div
+          key={notification.notificationId}
+          className="ml-2 flex w-full justify-between gap-2 p-1"
+        >
+          <span
+            className={`truncate ${notification.readAt === null ? "font-semibold" : ""}`}
+          >
+            <NotificationLink notification={notification} />{" "}
+            <em className="text-base">
+              {new Date(notification.createdAt).toLocaleString()}
+            </em>
+          </span>
+          {notification.readAt === null ? (
+            <button
+              disabled={markRead.isPending}
+              className="text-base underline"
+              onClick={() =>
+                markRead.mutate({
+                  notificationId: notification.notificationId,
+                })
+              }
+            >
+              Read
+            </button>
+          ) : null}
+        </div>
+      ))}
+    </div>
+  );
+}
+
+function NotificationLink({ notification }: { notification: Notification }) {
+  const description = describeNotification(notification);
+
+  if (notification.rideEventId !== null) {
+    return (
+      <Link
+        to="/rides/$rideId"
+        params={{ rideId: notification.rideEventId }}
+      >
+        {description}
+      </Link>
+    );
+  }
+
+  if (notification.groupId !== null) {
+    return (
+      <Link
+        to="/groups/$groupId"
+        params={{ groupId: notification.groupId }}
+      >
+        {description}
+      </Link>
+    );
+  }
+
+  if (notification.actorId !== null) {
+    return (
+      <Link
+        to="/user/$userId"
+        params={{ userId: notification.actorId }}
+      >
+        {description}
+      </Link>
+    );
+  }
+
+  return <span>{description}</span>;
+}
+
+function Conversations({ user }: { user: UserLoggedIn }) {
+  const { setUser } = useUserStore();
+
+  const { data: conversations } = useQuery({
+    queryKey: [`user-conversations-${user.id}`],
+    queryFn: async () => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/users/me/conversations`,
+        {
+          method: "GET",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      const data = await res.json();
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        throw new Error("Failed to load conversations.");
+      }
+
+      return data as Conversation[];
+    },
+  });
+
+  return (
+    <div className="flex w-full flex-col">
+      <span className="font-semibold">Conversations: </span>
+      {(conversations ?? []).length === 0 ? (
+        <span className="ml-2 p-1">---</span>
+      ) : null}
+      {(conversations ?? []).map((conversation) => (
+        <Link
+          key={conversation.conversationId}
+          to="/user/$userId"
+          params={{ userId: conversation.userId }}
+          className="ml-2 flex w-full justify-between p-1"
+        >
+          <span className="truncate">
+            {conversation.userEmail}{" "}
+            <em className="text-base">
+              {conversation.lastMessage?.content ?? ""}
+            </em>
+          </span>
+          {conversation.unreadCount > 0 ? (
+            <span className="rounded-full bg-cyan-700 px-2 text-base text-white">
+              {conversation.unreadCount}
+            </span>
+          ) : null}
+        </Link>
+      ))}
+    </div>
+  );
+}
+
+function DirectMessages({
+  user,
+  other,
+}: {
+  user: UserLoggedIn;
+  other: { id: string; email: string };
+}) {
+  const { setUser } = useUserStore();
+  const queryClient = useQueryClient();
+  const [conversation, setConversation] = useState<Conversation | undefined>(
+    undefined,
+  );
+
+  const { data: blocks } = useQuery({
+    queryKey: [`user-blocks-${user.id}`],
+    queryFn: async () => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/users/me/blocks`,
+        {
+          method: "GET",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      const data = await res.json();
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        throw new Error("Failed to load blocked users.");
+      }
+
+      return data as BlockedUser[];
+    },
+  });
+
+  // opening the conversation marks its messages as read
+  const open = useMutation({
+    mutationKey: [`user-conversation-${other.id}`],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async () => {
+      const post = async (endpoint: string) => {
+        const res = await fetch(`${import.meta.env.VITE_API_URI}${endpoint}`, {
+          method: "POST",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        });
+
+        if (res.status === 401) {
+          setUser({ type: "logged-out" });
+        }
+
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return undefined;
+        }
+
+        return data as Conversation;
+      };
+
+      const opened = await post(`/users/by-id/${other.id}/conversation`);
+      if (opened === undefined) {
+        return;
+      }
+
+      setConversation(opened);
+      await post(`/conversations/by-id/${opened.conversationId}/read`);
+      queryClient.invalidateQueries({
+        queryKey: [`user-conversations-${user.id}`],
+      });
+    },
+  });
+
+  const isBlocked = (blocks ?? []).some((b) => b.userId === other.id);
+  const setBlocked = useMutation({
+    mutationKey: [`user-block-${other.id}`],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async (blocked: boolean) => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/users/by-id/${other.id}/${blocked ? "block" : "unblock"}`,
+        {
+          method: "POST",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      if (!res.ok) {
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return;
+        }
+      }
+
+      queryClient.invalidateQueries({
+        queryKey: [`user-blocks-${user.id}`],
+      });
+      toast(blocked ? "Blocked user." : "Unblocked user.", {
+        type: "success",
+      });
+    },
+  });
+
+  return (
+    <div className="flex w-full flex-col gap-2">
+      <div className="flex gap-2">
+        {conversation === undefined && !isBlocked ? (
+          <button
+            className={STYLES.button}
+            disabled={open.isPending}
+            onClick={() => open.mutate()}
+          >
+            Message
+          </button>
+        ) : null}
+        <button
+          className={isBlocked ? STYLES.button : STYLES.buttonDanger}
+          disabled={setBlocked.isPending}
+          onClick={() => setBlocked.mutate(!isBlocked)}
+        >
+          {isBlocked ? "Unblock" : "Block"}
+        </button>
+      </div>
+      {conversation !== undefined ? (
+        <Chat
+          chat={{ kind: "conversations", id: conversation.conversationId }}
+          user={user}
+          title={`Messages with ${other.email}`}
+        />
+      ) : null}
+    </div>
+  );
+}
+
+function LinkedAccounts({ user }: { user: UserLoggedIn }) {
+  const queryClient = useQueryClient();
+  const { setUser } = useUserStore();
+  const { data: providers } = useAuthProviders();
+
+  const { data: identities } = useQuery({
+    queryKey: [`user-identities-${user.id}`],
+    queryFn: async () => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/auth/identities`,
+        {
+          method: "GET",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      const data = await res.json();
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        throw new Error("Failed to load linked accounts.");
+      }
+
+      return data as UserIdentity[];
+    },
+  });
+
+  const link = useMutation({
+    mutationKey: [`user-identities-link-${user.id}`],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async (provider: string) => {
+      const data = await linkUrl(provider, user.tokens.accessToken);
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        return;
+      }
+
+      // the provider redirects back to this page with a code to confirm
+      window.location.href = data.url;
+    },
+  });
+
+  // the code is only confirmed once, even if the effect runs twice
+  const { link_code: linkCode } = Route.useSearch();
+  const navigate = useNavigate();
+  const confirmed = useRef(false);
+  useEffect(() => {
+    if (linkCode === undefined || confirmed.current) {
+      return;
+    }
+    confirmed.current = true;
+
+    confirmLink(linkCode, user.tokens.accessToken).then((err) => {
+      if (err !== undefined) {
+        toastRestErr(err);
+      } else {
+        toast("Linked account.", { type: "success" });
+        queryClient.invalidateQueries({
+          queryKey: [`user-identities-${user.id}`],
+        });
+      }
+      navigate({
+        to: "/user/$userId",
+        params: { userId: user.id },
+        replace: true,
+      });
+    });
+  }, [linkCode]);
+
+  const unlink = useMutation({
+    mutationKey: [`user-identities-unlink-${user.id}`],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async (identityId: string) => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/auth/identities/by-id/${identityId}/unlink`,
+        {
+          method: "POST",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      if (!res.ok) {
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return;
+        }
+      }
+
+      queryClient.invalidateQueries({
+        queryKey: [`user-identities-${user.id}`],
+      });
+      toast("Unlinked account.", { type: "success" });
+    },
+  });
+
+  return (
+    <div className="flex w-full flex-col">
+      <span className="font-semibold">Linked accounts: </span>
+      {(identities ?? []).map((identity) => (
+        <div
+          key={identity.identityId}
+          className="flex w-full justify-between"
+        >
+          <span className="ml-2 p-1">
+            {identity.email} <em className="text-base">({identity.provider})</em>
+          </span>
+          {identities !== undefined && identities.length > 1 ? (
+            <button
+              className="text-base text-red-500"
+              disabled={unlink.isPending}
+              onClick={() => unlink.mutate(identity.identityId)}
+            >
+              Unlink
+            </button>
+          ) : null}
+        </div>
+      ))}
+      <div className="ml-2 flex gap-2 p-1">
+        {(providers ?? []).map((provider) => (
+          <button
+            key={provider.id}
+            className="text-base text-cyan-600"
+            disabled={link.isPending}
+            onClick={() => link.mutate(provider.id)}
+          >
+            Link {provider.name}
+          </button>
+        ))}
+      </div>
+    </div>
+  );
+}
+
+function Sessions({ user }: { user: UserLoggedIn }) {
+  const queryClient = useQueryClient();
+  const { setUser } = useUserStore();
+
+  const { data: sessions } = useQuery({
+    queryKey: [`user-sessions-${user.id}`],
+    queryFn: async () => {
+      const res = await fetch(`${import.meta.env.VITE_API_URI}/auth/sessions`, {
+        method: "GET",
+        headers: {
+          Authorization: user.tokens.accessToken,
+          Accept: "application/json",
+        },
+      });
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      const data = await res.json();
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        throw new Error("Failed to load sessions.");
+      }
+
+      return data as UserSession[];
+    },
+  });
+
+  const signOut = useMutation({
+    mutationKey: [`user-sessions-delete-${user.id}`],
+    onError: (err) => {
+      console.error(err);
+    },
+    // Signs out every device if no session is given
+    mutationFn: async (session?: UserSession) => {
+      const [method, endpoint] =
+        session === undefined
+          ? ["DELETE", "/auth/sessions"]
+          : session.current
+            ? ["POST", "/auth/logout"]
+            : ["DELETE", `/auth/sessions/${session.sessionId}`];
+      const res = await fetch(`${import.meta.env.VITE_API_URI}${endpoint}`, {
+        method,
+        headers: {
+          Authorization: user.tokens.accessToken,
+          Accept: "application/json",
+        },
+      });
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      if (!res.ok) {
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return;
+        }
+      }
+
+      if (session === undefined || session.current) {
+        setUser({ type: "logged-out" });
+        return;
+      }
+
+      queryClient.invalidateQueries({
+        queryKey: [`user-sessions-${user.id}`],
+      });
+      toast("Signed out device.", { type: "success" });
+    },
+  });
+
+  return (
+    <div className="flex w-full flex-col">
+      <span className="font-semibold">Signed in devices: </span>
+      {(sessions ?? []).map((session) => (
+        <div
+          key={session.sessionId}
+          className="flex w-full justify-between"
+        >
+          <span className="ml-2 p-1">
+            {session.device || "Unknown device"}{" "}
+            <em className="text-base">
+              ({session.current ? "this device, " : ""}
+              {session.ip}, last used{" "}
+              {new Date(session.lastUsedAt).toLocaleString()})
+            </em>
+          </span>
+          <button
+            className="text-base text-red-500"
+            disabled={signOut.isPending}
+            onClick={() => signOut.mutate(session)}
+          >
+            Sign out
+          </button>
+        </div>
+      ))}
+      <div className="ml-2 p-1">
+        <button
+          className="text-base text-red-500"
+          disabled={signOut.isPending}
+          onClick={() => signOut.mutate(undefined)}
+        >
+          Sign out everywhere
+        </button>
+      </div>
+    </div>
+  );
+}
+
+const TOKEN_SCOPES = ["rides:read", "rides:write", "groups", "chat"] as const;
+
+function PersonalTokens({ user }: { user: UserLoggedIn }) {
+  const { setUser } = useUserStore();
+  const queryClient = useQueryClient();
+  const [name, setName] = useState("");
+  const [scopes, setScopes] = useState<string[]>(["rides:read"]);
+  const [expiresInDays, setExpiresInDays] = useState(30);
+  // Only shown once after creating a token
+  const [created, setCreated] = useState<string | undefined>(undefined);
+
+  const { data: tokens } = useQuery({
+    queryKey: [`user-tokens-${user.id}`],
+    queryFn: async () => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/users/me/tokens`,
+        {
+          method: "GET",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      const data = await res.json();
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        throw new Error("Failed to load personal access tokens.");
+      }
+
+      return data as PersonalToken[];
+    },
+  });
+
+  const createToken = useMutation({
+    mutationKey: [`user-tokens-create-${user.id}`],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async () => {
+      const expiresAt = new Date(
+        Date.now() + expiresInDays * 24 * 60 * 60 * 1000,
+      ).toISOString();
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/users/me/tokens`,
+        {
+          method: "POST",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+          body: JSON.stringify({ name, scopes, expiresAt }),
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      const data = await res.json();
+      if (isRestErr(data)) {
+        toastRestErr(data);
+        return;
+      }
+
+      setCreated(data.token);
+      setName("");
+      queryClient.invalidateQueries({
+        queryKey: [`user-tokens-${user.id}`],
+      });
+    },
+  });
+
+  const revokeToken = useMutation({
+    mutationKey: [`user-tokens-delete-${user.id}`],
+    onError: (err) => {
+      console.error(err);
+    },
+    mutationFn: async (token: PersonalToken) => {
+      const res = await fetch(
+        `${import.meta.env.VITE_API_URI}/users/me/tokens/${token.tokenId}`,
+        {
+          method: "DELETE",
+          headers: {
+            Authorization: user.tokens.accessToken,
+            Accept: "application/json",
+          },
+        },
+      );
+
+      if (res.status === 401) {
+        setUser({ type: "logged-out" });
+      }
+
+      if (!res.ok) {
+        const data = await res.json();
+        if (isRestErr(data)) {
+          toastRestErr(data);
+          return;
+        }
+      }
+
+      queryClient.invalidateQueries({
+        queryKey: [`user-tokens-${user.id}`],
+      });
+      toast("Revoked token.", { type: "success" });
+    },
+  });
+
+  return (
+    <div className="flex w-full flex-col">
+      <span className="font-semibold">Personal access tokens: </span>
+      {(tokens ?? []).map((token) => (
+        <div
+          key={token.tokenId}
+          className="flex w-full justify-between"
+        >
+          <span className="ml-2 p-1">
+            {token.name}{" "}
+            <em className="text-base">
+              ({token.scopes.join(", ")}, expires{" "}
+              {new Date(token.expiresAt).toLocaleDateString()}, last used{" "}
+              {token.lastUsedAt
+                ? new Date(token.lastUsedAt).toLocaleString()
+                : "never"}
+              )
+            </em>
+          </span>
+          <button
+            className="text-base text-red-500"
+            disabled={revokeToken.isPending}
+            onClick={() => revokeToken.mutate(token)}
+          >
+            Revoke
+          </button>
+        </div>
+      ))}
+      {created !== undefined ? (
+        <div className="ml-2 flex flex-col p-1">
+          <span className="text-base">
+            Copy the token now, it won't be shown again:
+          </span>
+          <code className="break-all text-base">{created}</code>
+        </div>
+      ) : null}
+      <div className="ml-2 flex flex-col gap-2 p-1">
+        <input
+          placeholder="Token name..."
+          className={STYLES.input}
+          value={name}
+          onChange={(e) => setName(e.target.value)}
+        />
+        <div className="flex flex-wrap gap-4 text-base">
+          {TOKEN_SCOPES.map((scope) => (
+            <label
+              key={scope}
+              className="flex items-center gap-1"
+            >
+              <input
+                type="checkbox"
+                checked={scopes.includes(scope)}
+                onChange={(e) =>
+                  setScopes(
+                    e.target.checked
+                      ? [...scopes, scope]
+                      : scopes.filter((s) => s !== scope),
+                  )
+                }
+              />
+              {scope}
+            </label>
+          ))}
+          <select
+            value={expiresInDays}
+            onChange={(e) => setExpiresInDays(Number(e.target.value))}
+          >
+            <option value={7}>7 days</option>
+            <option value={30}>30 days</option>
+            <option value={90}>90 days</option>
+            <option value={365}>1 year</option>
+          </select>
+        </div>
+        <button
+          className={STYLES.button}
+          disabled={
+            createToken.isPending || name.length === 0 || scopes.length === 0
+          }
+          onClick={() => createToken.mutate()}
+        >
+          Create token
+        </button>
       </div>
     </div>
   );